@@ -0,0 +1,91 @@
+// Package actions enumerates the standard actions of Grafana, the kinds of the resources and their attributes.
+//
+// The constants are generated from actions.json: run `go generate ./actions` after editing it.
+package actions
+
+//go:generate go run ./internal/gen
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/grafana/authlib/errs"
+)
+
+var ErrUnknownAction = errors.New("unknown action")
+
+var known = func() map[string]bool {
+	m := make(map[string]bool, len(knownActions))
+	for _, action := range knownActions {
+		m[action] = true
+	}
+	return m
+}()
+
+// IsKnown returns whether the action is a standard action of Grafana.
+func IsKnown(action string) bool {
+	return known[action]
+}
+
+// Known returns the standard actions of Grafana.
+func Known() []string {
+	return append([]string(nil), knownActions...)
+}
+
+// Attributes returns the attributes identifying the resources of the kind (ex: "uid" for "dashboards").
+func Attributes(kind string) []string {
+	return append([]string(nil), kindAttributes[kind]...)
+}
+
+// Validate returns an *errs.Batch, with an error wrapping ErrUnknownAction per unknown action,
+// suggesting the closest standard action. Services validate the actions they check at startup,
+// to catch typos (ex: "dashbords:read") rather than silently denying the checks.
+func Validate(actions ...string) error {
+	batch := &errs.Batch{}
+	for i, action := range actions {
+		if IsKnown(action) {
+			continue
+		}
+		if suggestion := closest(action); suggestion != "" {
+			batch.Add(i, fmt.Errorf("%w %q, did you mean %q?", ErrUnknownAction, action, suggestion))
+		} else {
+			batch.Add(i, fmt.Errorf("%w %q", ErrUnknownAction, action))
+		}
+	}
+	return batch.Err()
+}
+
+// maxSuggestionDistance is the maximum edit distance of the suggested actions.
+const maxSuggestionDistance = 3
+
+// closest returns the standard action closest to the action, if close enough.
+func closest(action string) string {
+	best, bestDistance := "", maxSuggestionDistance+1
+	for _, candidate := range knownActions {
+		if d := distance(action, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,31 @@
+package actions
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/errs"
+)
+
+func TestIsKnown(t *testing.T) {
+	require.True(t, IsKnown(DashboardsRead))
+	require.True(t, IsKnown("org.users:add"))
+	require.False(t, IsKnown("dashbords:read"))
+	require.Contains(t, Known(), FoldersPermissionsWrite)
+	require.Equal(t, []string{AttrUID}, Attributes(KindDashboards))
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(DashboardsRead, FoldersWrite))
+
+	err := Validate(DashboardsRead, "dashbords:read", "some.plugin:do")
+	require.ErrorIs(t, err, ErrUnknownAction)
+
+	var batch *errs.Batch
+	require.True(t, errors.As(err, &batch))
+	require.Equal(t, []int{1, 2}, batch.Failed())
+	require.Contains(t, batch.Errors()[0].Error(), `did you mean "dashboards:read"?`)
+	require.NotContains(t, batch.Errors()[1].Error(), "did you mean")
+}
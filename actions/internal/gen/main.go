@@ -0,0 +1,95 @@
+// Command gen generates the constants of the actions package from actions.json.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+	"unicode"
+)
+
+type source struct {
+	Attributes []string `json:"attributes"`
+	Kinds      []struct {
+		Name       string   `json:"name"`
+		Attributes []string `json:"attributes"`
+	} `json:"kinds"`
+	Actions []string `json:"actions"`
+}
+
+var initialisms = map[string]string{"id": "ID", "uid": "UID", "serviceaccounts": "ServiceAccounts"}
+
+// identifier converts the name to an exported Go identifier (ex: "org.users:add" -> "OrgUsersAdd").
+func identifier(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if initialism, ok := initialisms[word]; ok {
+			b.WriteString(initialism)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+func main() {
+	data, err := os.ReadFile("actions.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var src source
+	if err := json.Unmarshal(data, &src); err != nil {
+		log.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "// Code generated by actions/internal/gen from actions.json. DO NOT EDIT.")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "package actions")
+
+	fmt.Fprintln(buf, "\n// Attributes identifying the resources in the scopes.\nconst (")
+	for _, attr := range src.Attributes {
+		fmt.Fprintf(buf, "Attr%s = %q\n", identifier(attr), attr)
+	}
+	fmt.Fprintln(buf, ")")
+
+	fmt.Fprintln(buf, "\n// Kinds of the resources in the scopes.\nconst (")
+	for _, kind := range src.Kinds {
+		fmt.Fprintf(buf, "Kind%s = %q\n", identifier(kind.Name), kind.Name)
+	}
+	fmt.Fprintln(buf, ")")
+
+	fmt.Fprintln(buf, "\n// Actions of Grafana.\nconst (")
+	for _, action := range src.Actions {
+		fmt.Fprintf(buf, "%s = %q\n", identifier(action), action)
+	}
+	fmt.Fprintln(buf, ")")
+
+	fmt.Fprintln(buf, "\nvar knownActions = []string{")
+	for _, action := range src.Actions {
+		fmt.Fprintf(buf, "%s,\n", identifier(action))
+	}
+	fmt.Fprintln(buf, "}")
+
+	fmt.Fprintln(buf, "\nvar kindAttributes = map[string][]string{")
+	for _, kind := range src.Kinds {
+		attrs := make([]string, len(kind.Attributes))
+		for i, attr := range kind.Attributes {
+			attrs[i] = "Attr" + identifier(attr)
+		}
+		fmt.Fprintf(buf, "Kind%s: {%s},\n", identifier(kind.Name), strings.Join(attrs, ", "))
+	}
+	fmt.Fprintln(buf, "}")
+
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("zz_generated.go", out, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,145 @@
+// Package apiserver authenticates the requests of an API server with the authn verifiers,
+// for Grafana apps embedding an aggregated API server.
+//
+// The package does not depend on k8s.io/apiserver: User implements user.Info, and the authenticator.Token
+// and authenticator.Request of k8s.io/apiserver are implemented by the Authenticator of the
+// github.com/grafana/authlib/k8s module.
+package apiserver
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/authlib/errs"
+)
+
+const (
+	// ExtraNamespaceKey is the user extra holding the namespace of the identity.
+	ExtraNamespaceKey = "grafana.app/namespace"
+	// ExtraOrgIDKey is the user extra holding the org ID of the namespace, if any.
+	ExtraOrgIDKey = "grafana.app/org-id"
+	// ExtraStackIDKey is the user extra holding the stack ID of the namespace, if any.
+	ExtraStackIDKey = "grafana.app/stack-id"
+)
+
+var ErrNamespaceMismatch = errs.New(errs.KindUnauthenticated, "namespace mismatch")
+
+// User implements the user.Info interface of k8s.io/apiserver, with the namespace, org and stack of the
+// identity as extras (see ExtraNamespaceKey). The claims are available with claims.AuthInfo.
+type User struct {
+	claims.AuthInfo
+	extra map[string][]string
+}
+
+func newUser(info *authn.AuthInfo) *User {
+	extra := info.GetExtra()
+	ns := info.IdentityClaims.Namespace()
+	extra[ExtraNamespaceKey] = []string{ns}
+	if parsed, err := claims.ParseNamespace(ns); err == nil {
+		if parsed.OrgID > 0 {
+			extra[ExtraOrgIDKey] = []string{strconv.FormatInt(parsed.OrgID, 10)}
+		}
+		if parsed.StackID > 0 {
+			extra[ExtraStackIDKey] = []string{strconv.FormatInt(parsed.StackID, 10)}
+		}
+	}
+	return &User{AuthInfo: info, extra: extra}
+}
+
+// GetExtra implements user.Info.
+func (u *User) GetExtra() map[string][]string {
+	return u.extra
+}
+
+type Option func(*Authenticator)
+
+// WithAccessTokenVerifierOption also verifies the access token of the requests (see AuthenticateRequest).
+// The namespace of the access token must match the namespace of the ID token.
+func WithAccessTokenVerifierOption(verifier authn.Verifier[authn.AccessTokenClaims]) Option {
+	return func(a *Authenticator) {
+		a.accessVerifier = verifier
+	}
+}
+
+// WithHeadersOption sets the headers the access token and the ID token are extracted from.
+// Defaults to "Authorization" and "X-Grafana-Id".
+func WithHeadersOption(accessTokenHeader, idTokenHeader string) Option {
+	return func(a *Authenticator) {
+		a.accessTokenHeader = accessTokenHeader
+		a.idTokenHeader = idTokenHeader
+	}
+}
+
+// Authenticator authenticates the requests of an API server with Grafana ID tokens.
+type Authenticator struct {
+	idVerifier     authn.Verifier[authn.IDTokenClaims]
+	accessVerifier authn.Verifier[authn.AccessTokenClaims]
+
+	accessTokenHeader string
+	idTokenHeader     string
+}
+
+// New creates an authenticator verifying ID tokens with the verifier (ex: authn.NewVerifier).
+func New(idVerifier authn.Verifier[authn.IDTokenClaims], opts ...Option) *Authenticator {
+	a := &Authenticator{
+		idVerifier:        idVerifier,
+		accessTokenHeader: authn.DefaultAccessTokenHeader,
+		idTokenHeader:     authn.DefaultIDTokenHeader,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// AuthenticateToken authenticates the ID token.
+func (a *Authenticator) AuthenticateToken(ctx context.Context, token string) (*User, bool, error) {
+	idClaims, err := a.idVerifier.Verify(ctx, token)
+	if err != nil {
+		return nil, false, err
+	}
+	return newUser(&authn.AuthInfo{IdentityClaims: authn.NewIdentityClaims(*idClaims)}), true, nil
+}
+
+// AuthenticateRequest authenticates the ID token of the request.
+// Requests without ID token are left to the other authenticators.
+// When an access token verifier is configured, the access token of the request is required and verified too.
+func (a *Authenticator) AuthenticateRequest(req *http.Request) (*User, bool, error) {
+	idToken := bearerToken(req.Header.Get(a.idTokenHeader))
+	if idToken == "" {
+		return nil, false, nil
+	}
+
+	ctx := req.Context()
+	idClaims, err := a.idVerifier.Verify(ctx, idToken)
+	if err != nil {
+		return nil, false, err
+	}
+	info := &authn.AuthInfo{IdentityClaims: authn.NewIdentityClaims(*idClaims)}
+
+	if a.accessVerifier != nil {
+		accessClaims, err := a.accessVerifier.Verify(ctx, bearerToken(req.Header.Get(a.accessTokenHeader)))
+		if err != nil {
+			return nil, false, err
+		}
+		info.AccessClaims = authn.NewAccessClaims(*accessClaims)
+
+		if !claims.NamespaceMatches(info.AccessClaims, idClaims.Rest.Namespace) {
+			return nil, false, ErrNamespaceMismatch
+		}
+	}
+
+	return newUser(info), true, nil
+}
+
+func bearerToken(value string) string {
+	const prefix = "Bearer "
+	if len(value) >= len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+		return strings.TrimSpace(value[len(prefix):])
+	}
+	return strings.TrimSpace(value)
+}
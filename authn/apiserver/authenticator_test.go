@@ -0,0 +1,131 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/claims"
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+type fakeVerifier[T any] struct {
+	tokens map[string]*authn.Claims[T]
+}
+
+func (f fakeVerifier[T]) Verify(_ context.Context, token string) (*authn.Claims[T], error) {
+	c, ok := f.tokens[token]
+	if !ok {
+		return nil, errInvalidToken
+	}
+	return c, nil
+}
+
+func newIDVerifier() fakeVerifier[authn.IDTokenClaims] {
+	return fakeVerifier[authn.IDTokenClaims]{tokens: map[string]*authn.Claims[authn.IDTokenClaims]{
+		"stack-user": {
+			Claims: &jwt.Claims{Subject: "user:1"},
+			Rest:   authn.IDTokenClaims{Identifier: "1", Type: claims.TypeUser, Namespace: "stacks-12", Username: "admin"},
+		},
+		"org-user": {
+			Claims: &jwt.Claims{Subject: "user:2"},
+			Rest:   authn.IDTokenClaims{Identifier: "2", Type: claims.TypeUser, Namespace: "org-3"},
+		},
+	}}
+}
+
+func TestAuthenticator_AuthenticateToken(t *testing.T) {
+	a := New(newIDVerifier())
+
+	u, ok, err := a.AuthenticateToken(context.Background(), "stack-user")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "user:1", u.GetUID())
+	require.Equal(t, []string{"stacks-12"}, u.GetExtra()[ExtraNamespaceKey])
+	require.Equal(t, []string{"12"}, u.GetExtra()[ExtraStackIDKey])
+	require.Equal(t, []string{"1"}, u.GetExtra()[ExtraOrgIDKey])
+	require.Equal(t, "user:1", u.GetIdentity().Subject())
+
+	u, ok, err = a.AuthenticateToken(context.Background(), "org-user")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []string{"3"}, u.GetExtra()[ExtraOrgIDKey])
+	require.NotContains(t, u.GetExtra(), ExtraStackIDKey)
+
+	_, ok, err = a.AuthenticateToken(context.Background(), "unknown")
+	require.ErrorIs(t, err, errInvalidToken)
+	require.False(t, ok)
+}
+
+func TestAuthenticator_AuthenticateRequest(t *testing.T) {
+	accessVerifier := fakeVerifier[authn.AccessTokenClaims]{tokens: map[string]*authn.Claims[authn.AccessTokenClaims]{
+		"stack-service": {Claims: &jwt.Claims{Subject: "access-policy:1"}, Rest: authn.AccessTokenClaims{Namespace: "stacks-12"}},
+		"other-service": {Claims: &jwt.Claims{Subject: "access-policy:2"}, Rest: authn.AccessTokenClaims{Namespace: "stacks-13"}},
+	}}
+	request := func(accessToken, idToken string) *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, "/apis", nil)
+		if accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		if idToken != "" {
+			req.Header.Set("X-Grafana-Id", idToken)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		req     *http.Request
+		wantOK  bool
+		wantErr error
+	}{
+		{name: "id token", req: request("", "stack-user"), wantOK: true},
+		{name: "no id token", req: request("stack-service", "")},
+		{name: "invalid id token", req: request("", "unknown"), wantErr: errInvalidToken},
+		{
+			name:   "id and access tokens",
+			opts:   []Option{WithAccessTokenVerifierOption(accessVerifier)},
+			req:    request("stack-service", "stack-user"),
+			wantOK: true,
+		},
+		{
+			name:    "missing access token",
+			opts:    []Option{WithAccessTokenVerifierOption(accessVerifier)},
+			req:     request("", "stack-user"),
+			wantErr: errInvalidToken,
+		},
+		{
+			name:    "namespace mismatch",
+			opts:    []Option{WithAccessTokenVerifierOption(accessVerifier)},
+			req:     request("other-service", "stack-user"),
+			wantErr: ErrNamespaceMismatch,
+		},
+		{
+			name:   "custom headers",
+			opts:   []Option{WithHeadersOption("X-Access-Token", "Authorization")},
+			req:    request("stack-user", ""),
+			wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, ok, err := New(newIDVerifier(), tt.opts...).AuthenticateRequest(tt.req)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				require.Equal(t, "user:1", u.GetUID())
+			}
+		})
+	}
+}
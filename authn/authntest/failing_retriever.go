@@ -0,0 +1,36 @@
+// Package authntest provides test doubles of the authn dependencies and a token factory, to test the services
+// verifying tokens end to end, and their resilience to authn failures.
+package authntest
+
+import (
+	"context"
+
+	"github.com/go-jose/go-jose/v3"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/internal/fault"
+)
+
+// Faults is the latency and the errors injected in the calls.
+type Faults = fault.Config
+
+var _ authn.KeyRetriever = (*FailingRetriever)(nil)
+
+// FailingRetriever is a KeyRetriever injecting latency and errors in the retrieval of the signing keys.
+// The failing retrievals return authn.ErrFetchingSigningKey, like the DefaultKeyRetriever does
+// when the signing keys endpoint fails, unless Faults.Err is set.
+type FailingRetriever struct {
+	keys   authn.KeyRetriever
+	faults *fault.Injector
+}
+
+func NewFailingRetriever(keys authn.KeyRetriever, faults Faults) *FailingRetriever {
+	return &FailingRetriever{keys: keys, faults: fault.New(faults, authn.ErrFetchingSigningKey)}
+}
+
+func (r *FailingRetriever) Get(ctx context.Context, keyID string) (*jose.JSONWebKey, error) {
+	if err := r.faults.Inject(ctx); err != nil {
+		return nil, err
+	}
+	return r.keys.Get(ctx, keyID)
+}
@@ -0,0 +1,30 @@
+package authntest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+)
+
+type staticKeys struct{}
+
+func (staticKeys) Get(_ context.Context, keyID string) (*jose.JSONWebKey, error) {
+	return &jose.JSONWebKey{KeyID: keyID}, nil
+}
+
+func TestFailingRetriever(t *testing.T) {
+	t.Run("should forward the retrievals without faults", func(t *testing.T) {
+		key, err := NewFailingRetriever(staticKeys{}, Faults{}).Get(context.Background(), "kid")
+		require.NoError(t, err)
+		require.Equal(t, "kid", key.KeyID)
+	})
+
+	t.Run("should fail like the signing keys endpoint", func(t *testing.T) {
+		_, err := NewFailingRetriever(staticKeys{}, Faults{ErrorRate: 1}).Get(context.Background(), "kid")
+		require.ErrorIs(t, err, authn.ErrFetchingSigningKey)
+	})
+}
@@ -0,0 +1,132 @@
+package authntest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3"
+
+	"github.com/grafana/authlib/authn"
+)
+
+const (
+	jwksPath      = "/jwks"
+	discoveryPath = "/.well-known/openid-configuration"
+)
+
+// JWKSServer is a local signing keys endpoint, serving the keys of generated token factories, to test
+// the DefaultKeyRetriever against key rotations and outages. It also serves the OpenID discovery document,
+// with the server URL as issuer. It is safe for concurrent use.
+//
+//	keys := authntest.NewJWKSServer(t)
+//	retriever := authn.NewKeyRetriever(authn.KeyRetrieverConfig{SigningKeysURL: keys.JWKSURL()})
+//	token := keys.Tokens().AccessToken(jwt.Claims{}, authn.AccessTokenClaims{Namespace: "stacks-12"})
+type JWKSServer struct {
+	t   testing.TB
+	srv *httptest.Server
+
+	mtx      sync.Mutex
+	current  *TokenFactory
+	previous []*TokenFactory
+	outage   int
+	requests int
+}
+
+// NewJWKSServer starts a JWKSServer serving a generated key, it is closed when the test ends.
+func NewJWKSServer(t testing.TB) *JWKSServer {
+	t.Helper()
+
+	s := &JWKSServer{t: t}
+	mux := http.NewServeMux()
+	mux.HandleFunc(jwksPath, s.serveJWKS)
+	mux.HandleFunc(discoveryPath, s.serveDiscovery)
+	s.srv = httptest.NewServer(mux)
+	t.Cleanup(s.srv.Close)
+
+	s.current = NewTokenFactory(t, authn.SignerConfig{Issuer: s.srv.URL})
+	return s
+}
+
+// URL returns the URL of the server, the issuer of its discovery document and tokens.
+func (s *JWKSServer) URL() string {
+	return s.srv.URL
+}
+
+// JWKSURL returns the URL of the signing keys endpoint.
+func (s *JWKSServer) JWKSURL() string {
+	return s.srv.URL + jwksPath
+}
+
+// Tokens returns the TokenFactory signing with the current key.
+func (s *JWKSServer) Tokens() *TokenFactory {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.current
+}
+
+// Rotate generates a new signing key and returns its TokenFactory. The previous keys are still served,
+// like during a rotation, until Retire is called.
+func (s *JWKSServer) Rotate() *TokenFactory {
+	s.t.Helper()
+
+	tokens := NewTokenFactory(s.t, authn.SignerConfig{Issuer: s.srv.URL})
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.previous = append(s.previous, s.current)
+	s.current = tokens
+	return tokens
+}
+
+// Retire stops serving the keys previous to the current one.
+func (s *JWKSServer) Retire() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.previous = nil
+}
+
+// Outage makes the signing keys endpoint respond with the status code (ex: http.StatusServiceUnavailable),
+// until Recover is called.
+func (s *JWKSServer) Outage(status int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.outage = status
+}
+
+// Recover ends the outage.
+func (s *JWKSServer) Recover() {
+	s.Outage(0)
+}
+
+// Requests returns the number of requests of the signing keys, failed ones included.
+func (s *JWKSServer) Requests() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.requests
+}
+
+func (s *JWKSServer) serveJWKS(w http.ResponseWriter, _ *http.Request) {
+	s.mtx.Lock()
+	s.requests++
+	outage := s.outage
+	jwks := jose.JSONWebKeySet{Keys: s.current.PublicKeys().Keys}
+	for _, tokens := range s.previous {
+		jwks.Keys = append(jwks.Keys, tokens.PublicKeys().Keys...)
+	}
+	s.mtx.Unlock()
+
+	if outage != 0 {
+		w.WriteHeader(outage)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(jwks)
+}
+
+func (s *JWKSServer) serveDiscovery(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"issuer": s.srv.URL, "jwks_uri": s.JWKSURL()})
+}
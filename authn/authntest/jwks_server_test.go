@@ -0,0 +1,61 @@
+package authntest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+)
+
+func TestJWKSServer(t *testing.T) {
+	ctx := context.Background()
+
+	verify := func(keys authn.KeyRetriever, tokens *TokenFactory) error {
+		_, err := authn.NewAccessTokenVerifier(authn.VerifierConfig{}, keys).Verify(ctx, tokens.AccessToken(jwt.Claims{}, authn.AccessTokenClaims{}))
+		return err
+	}
+
+	t.Run("should serve the signing keys", func(t *testing.T) {
+		srv := NewJWKSServer(t)
+		require.NoError(t, verify(authn.NewKeyRetriever(authn.KeyRetrieverConfig{SigningKeysURL: srv.JWKSURL()}), srv.Tokens()))
+		require.Equal(t, 1, srv.Requests())
+	})
+
+	t.Run("should serve the discovery document", func(t *testing.T) {
+		srv := NewJWKSServer(t)
+		require.NoError(t, verify(authn.NewKeyRetriever(authn.KeyRetrieverConfig{Issuer: srv.URL()}), srv.Tokens()))
+	})
+
+	t.Run("should rotate the signing keys", func(t *testing.T) {
+		srv := NewJWKSServer(t)
+		keys := authn.NewKeyRetriever(authn.KeyRetrieverConfig{SigningKeysURL: srv.JWKSURL()})
+		previous := srv.Tokens()
+		require.NoError(t, verify(keys, previous))
+
+		current := srv.Rotate()
+		require.Same(t, current, srv.Tokens())
+		require.NoError(t, verify(keys, current))
+		require.Equal(t, 2, srv.Requests())
+
+		// The previous key is served until retired
+		require.NoError(t, verify(authn.NewKeyRetriever(authn.KeyRetrieverConfig{SigningKeysURL: srv.JWKSURL()}), previous))
+		srv.Retire()
+		require.ErrorIs(t, verify(authn.NewKeyRetriever(authn.KeyRetrieverConfig{SigningKeysURL: srv.JWKSURL()}), previous), authn.ErrInvalidSigningKey)
+	})
+
+	t.Run("should simulate outages", func(t *testing.T) {
+		srv := NewJWKSServer(t)
+		keys := authn.NewKeyRetriever(authn.KeyRetrieverConfig{SigningKeysURL: srv.JWKSURL()})
+
+		srv.Outage(http.StatusServiceUnavailable)
+		require.ErrorIs(t, verify(keys, srv.Tokens()), authn.ErrFetchingSigningKey)
+
+		srv.Recover()
+		require.NoError(t, verify(keys, srv.Tokens()))
+		require.Equal(t, 2, srv.Requests())
+	})
+}
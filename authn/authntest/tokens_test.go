@@ -0,0 +1,82 @@
+package authntest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/authz"
+	"github.com/grafana/authlib/authz/authztest"
+	"github.com/grafana/authlib/claims"
+)
+
+func TestTokenFactory(t *testing.T) {
+	ctx := context.Background()
+	tokens := NewTokenFactory(t, authn.SignerConfig{Issuer: "authntest"})
+
+	t.Run("should mint verifiable ID tokens", func(t *testing.T) {
+		token := tokens.IDToken(jwt.Claims{}, authn.IDTokenClaims{Type: claims.TypeUser, Identifier: "1", Namespace: "stacks-12"})
+
+		got, err := authn.NewIDTokenVerifier(authn.VerifierConfig{}, tokens.Keys()).Verify(ctx, token)
+		require.NoError(t, err)
+		require.Equal(t, "user:1", got.Subject)
+		require.Equal(t, "authntest", got.Issuer)
+		require.Equal(t, "stacks-12", got.Rest.Namespace)
+	})
+
+	t.Run("should mint verifiable access tokens", func(t *testing.T) {
+		token := tokens.AccessToken(jwt.Claims{Subject: "access-policy:1", Audience: jwt.Audience{"authz"}}, authn.AccessTokenClaims{
+			Namespace:   "stacks-12",
+			Permissions: []string{"dashboards:read"},
+		})
+
+		got, err := authn.NewAccessTokenVerifier(authn.VerifierConfig{AllowedAudiences: jwt.Audience{"authz"}}, tokens.Keys()).Verify(ctx, token)
+		require.NoError(t, err)
+		require.Equal(t, "access-policy:1", got.Subject)
+		require.Equal(t, []string{"dashboards:read"}, got.Rest.Permissions)
+	})
+
+	t.Run("should mint expired tokens", func(t *testing.T) {
+		token := tokens.AccessToken(jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(-time.Hour))}, authn.AccessTokenClaims{})
+
+		_, err := authn.NewAccessTokenVerifier(authn.VerifierConfig{}, tokens.Keys()).Verify(ctx, token)
+		require.ErrorIs(t, err, authn.ErrExpiredToken)
+	})
+
+	t.Run("should not verify the tokens of another factory", func(t *testing.T) {
+		token := NewTokenFactory(t, authn.SignerConfig{}).AccessToken(jwt.Claims{}, authn.AccessTokenClaims{})
+
+		_, err := authn.NewAccessTokenVerifier(authn.VerifierConfig{}, tokens.Keys()).Verify(ctx, token)
+		require.Error(t, err)
+	})
+
+	t.Run("should check the permissions of the verified tokens", func(t *testing.T) {
+		access, err := authn.NewAccessTokenVerifier(authn.VerifierConfig{}, tokens.Keys()).Verify(ctx, tokens.AccessToken(
+			jwt.Claims{Subject: "access-policy:1"},
+			authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+		))
+		require.NoError(t, err)
+		id, err := authn.NewIDTokenVerifier(authn.VerifierConfig{}, tokens.Keys()).Verify(ctx, tokens.IDToken(
+			jwt.Claims{},
+			authn.IDTokenClaims{Type: claims.TypeUser, Identifier: "1", Namespace: "stacks-12"},
+		))
+		require.NoError(t, err)
+
+		srv := authztest.NewServer()
+		srv.Set(12, "user:1", "dashboards:read", "dashboards:uid:1")
+		client := authztest.NewLegacyClient(t, srv)
+
+		allowed, err := client.Check(ctx, &authz.CheckRequest{
+			Caller:   &authn.AuthInfo{AccessClaims: authn.NewAccessClaims(*access), IdentityClaims: authn.NewIdentityClaims(*id)},
+			StackID:  12,
+			Action:   "dashboards:read",
+			Resource: &authz.Resource{Kind: "dashboards", Attr: "uid", ID: "1"},
+		})
+		require.NoError(t, err)
+		require.True(t, allowed)
+	})
+}
@@ -38,7 +38,7 @@ func (c *CallerAuthInfo) GetExtra() map[string][]string {
 	if c.IDTokenClaims != nil && c.IDTokenClaims.token != "" {
 		// Currently required for external k8s aggregation
 		// but this should be removed in the not-to-distant future
-		return map[string][]string{"id-token": {c.IDTokenClaims.token}}
+		return map[string][]string{"id-token": {c.IDTokenClaims.token.Reveal()}}
 	}
 	return map[string][]string{}
 }
@@ -62,10 +62,10 @@ type CallerAuthInfoContextKey struct{}
 
 // Deprecated: use claims.With(...)
 func AddCallerAuthInfoToContext(ctx context.Context, info CallerAuthInfo) context.Context {
-	return context.WithValue(claims.WithClaims(ctx, &info), CallerAuthInfoContextKey{}, info)
+	return context.WithValue(claims.WithAuthInfo(ctx, &info), CallerAuthInfoContextKey{}, info)
 }
 
-// Deprecated: use claims.From(...)
+// Deprecated: use claims.AuthInfoFrom(...)
 func GetCallerAuthInfoFromContext(ctx context.Context) (CallerAuthInfo, bool) {
 	info, ok := ctx.Value(CallerAuthInfoContextKey{}).(CallerAuthInfo)
 	return info, ok
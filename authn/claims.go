@@ -1,26 +1,44 @@
 package authn
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-jose/go-jose/v3/jwt"
 
 	"github.com/grafana/authlib/claims"
+	"github.com/grafana/authlib/internal/redact"
 )
 
 var (
-	_ claims.IdentityClaims = &Identity{}
-	_ claims.AccessClaims   = &Access{}
-	_ claims.AuthInfo       = &AuthInfo{}
-	_ claims.TokenClaims    = &jwtClaims{}
+	_ claims.IdentityClaims     = &Identity{}
+	_ claims.ImpersonatedClaims = &Identity{}
+	_ claims.BreakGlassClaims   = &Identity{}
+	_ claims.MembershipClaims   = &Identity{}
+	_ claims.BreakGlass         = &breakGlass{}
+	_ claims.Actor              = &actor{}
+	_ claims.AccessClaims       = &Access{}
+	_ claims.AuthInfo           = &AuthInfo{}
+	_ claims.TokenClaims        = &jwtClaims{}
 )
 
 type Claims[T any] struct {
 	*jwt.Claims
 	Rest T
 
-	// The original raw token
-	token string
+	// The original raw token, redacted when printed
+	token redact.Secret
+}
+
+// Format prints the claims with the raw token redacted.
+func (c Claims[T]) Format(f fmt.State, verb rune) {
+	var registered any
+	if c.Claims != nil {
+		registered = *c.Claims
+	}
+	fmt.Fprintf(f, "{Claims:%+v Rest:%+v token:%s}", registered, c.Rest, c.token)
 }
 
 type AuthInfo struct {
@@ -33,7 +51,7 @@ func (c *AuthInfo) GetExtra() map[string][]string {
 	if c.IdentityClaims != nil && !c.IdentityClaims.IsNil() && c.IdentityClaims.claims.token != "" {
 		// Currently required for external k8s aggregation
 		// but this should be removed in the not-to-distant future
-		return map[string][]string{"id-token": {c.IdentityClaims.claims.token}}
+		return map[string][]string{"id-token": {c.IdentityClaims.claims.token.Reveal()}}
 	}
 	return map[string][]string{}
 }
@@ -175,6 +193,63 @@ func (c *Identity) IsNil() bool {
 	return c == nil
 }
 
+// Actor implements claims.ImpersonatedClaims.
+func (c *Identity) Actor() claims.Actor {
+	if c.claims.Rest.Actor == nil {
+		return nil
+	}
+	return &actor{claims: c.claims.Rest.Actor}
+}
+
+type actor struct {
+	claims *ActorClaims
+}
+
+// Subject implements claims.Actor.
+func (a *actor) Subject() string {
+	return a.claims.Subject
+}
+
+// DelegatedPermissions implements claims.Actor.
+func (a *actor) DelegatedPermissions() []string {
+	return a.claims.DelegatedPermissions
+}
+
+// BreakGlass implements claims.BreakGlassClaims.
+func (c *Identity) BreakGlass() claims.BreakGlass {
+	if c.claims.Rest.BreakGlass == nil {
+		return nil
+	}
+	return &breakGlass{claims: c.claims.Rest.BreakGlass}
+}
+
+// Groups implements claims.MembershipClaims.
+func (c *Identity) Groups() []string {
+	return c.claims.Rest.Groups
+}
+
+// Teams implements claims.MembershipClaims.
+func (c *Identity) Teams() []string {
+	return c.claims.Rest.Teams
+}
+
+type breakGlass struct {
+	claims *BreakGlassClaims
+}
+
+// Reason implements claims.BreakGlass.
+func (b *breakGlass) Reason() string {
+	return b.claims.Reason
+}
+
+// IssuedAt implements claims.BreakGlass.
+func (b *breakGlass) IssuedAt() time.Time {
+	if b.claims.IssuedAt == nil {
+		return time.Time{}
+	}
+	return b.claims.IssuedAt.Time()
+}
+
 // Audience implements claims.IdentityClaims.
 func (c *Access) Audience() []string {
 	return c.claims.Audience
@@ -242,6 +317,18 @@ func (c *Access) Scopes() []string {
 	return c.claims.Rest.Scopes
 }
 
+// OAuthScopes returns the OAuth scopes of the "scope" and "scp" claims, see ScopeChecker.
+func (c *Access) OAuthScopes() []string {
+	rest := c.claims.Rest
+	scopes := strings.Fields(rest.Scope)
+	for _, scope := range rest.Scp {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
 // IsNil implements claims.AccessClaims.
 func (c *Access) IsNil() bool {
 	return c == nil
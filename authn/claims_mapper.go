@@ -0,0 +1,215 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/grafana/authlib/claims"
+)
+
+var ErrMappingClaims = errors.New("unable to map external claims")
+
+// ExternalClaims holds the custom claims of a token issued by a third-party identity provider.
+// It can be used as the custom claims of a Verifier: NewVerifier[ExternalClaims](...).
+type ExternalClaims map[string]any
+
+// MappedClaims holds the Grafana claims produced by a ClaimsMapper.
+type MappedClaims struct {
+	// Claims are the standard jwt claims, initialized from the external token.
+	Claims jwt.Claims
+	// Identity holds the claims of the ID token.
+	Identity IDTokenClaims
+	// Access holds the claims of the access token.
+	Access AccessTokenClaims
+}
+
+// ClaimsMapper converts external claims into Grafana claims.
+// Mappers are composable, each one enriches the claims produced by the previous ones.
+type ClaimsMapper func(ctx context.Context, src ExternalClaims, dst *MappedClaims) error
+
+// ChainClaimsMappers returns a ClaimsMapper running all mappers in order.
+func ChainClaimsMappers(mappers ...ClaimsMapper) ClaimsMapper {
+	return func(ctx context.Context, src ExternalClaims, dst *MappedClaims) error {
+		for _, m := range mappers {
+			if err := m(ctx, src, dst); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// MapExternalClaims runs the mapper over verified external claims and returns the resulting AuthInfo.
+// The returned AuthInfo can be used with the authz clients like the one produced by the GrpcAuthenticator.
+func MapExternalClaims(ctx context.Context, src *Claims[ExternalClaims], mapper ClaimsMapper) (*AuthInfo, error) {
+	if src == nil || src.Claims == nil {
+		return nil, fmt.Errorf("%w: missing claims", ErrMappingClaims)
+	}
+
+	dst := MappedClaims{Claims: *src.Claims}
+	if err := mapper(ctx, src.Rest, &dst); err != nil {
+		return nil, err
+	}
+
+	if dst.Identity.Type == claims.TypeEmpty {
+		dst.Identity.Type = claims.TypeUser
+	}
+	if dst.Identity.Identifier == "" {
+		return nil, fmt.Errorf("%w: missing identifier", ErrMappingClaims)
+	}
+
+	// The identity subject is always typed
+	idClaims := dst.Claims
+	idClaims.Subject = claims.NewTypeID(dst.Identity.Type, dst.Identity.Identifier)
+	if dst.Identity.Namespace == "" {
+		dst.Identity.Namespace = dst.Access.Namespace
+	}
+	if dst.Access.Namespace == "" {
+		dst.Access.Namespace = dst.Identity.Namespace
+	}
+
+	atClaims := dst.Claims
+
+	return &AuthInfo{
+		IdentityClaims: NewIdentityClaims(Claims[IDTokenClaims]{Claims: &idClaims, Rest: dst.Identity, token: src.token}),
+		AccessClaims:   NewAccessClaims(Claims[AccessTokenClaims]{Claims: &atClaims, Rest: dst.Access, token: src.token}),
+	}, nil
+}
+
+// IdentifierClaimsMapper uses the value of the claim as the identity identifier.
+// When the claim is not set, the subject of the token is used.
+func IdentifierClaimsMapper(claim string) ClaimsMapper {
+	return func(_ context.Context, src ExternalClaims, dst *MappedClaims) error {
+		dst.Identity.Identifier = src.String(claim)
+		if dst.Identity.Identifier == "" {
+			dst.Identity.Identifier = dst.Claims.Subject
+		}
+		return nil
+	}
+}
+
+// ProfileClaimsMapper maps the OpenID Connect profile claims (email, email_verified, name, preferred_username).
+func ProfileClaimsMapper() ClaimsMapper {
+	return func(_ context.Context, src ExternalClaims, dst *MappedClaims) error {
+		dst.Identity.Email = src.String("email")
+		dst.Identity.EmailVerified, _ = src["email_verified"].(bool)
+		dst.Identity.DisplayName = src.String("name")
+		dst.Identity.Username = src.String("preferred_username")
+		return nil
+	}
+}
+
+// StaticNamespaceClaimsMapper sets the namespace of both identity and access claims.
+func StaticNamespaceClaimsMapper(namespace string) ClaimsMapper {
+	return func(_ context.Context, _ ExternalClaims, dst *MappedClaims) error {
+		dst.Identity.Namespace = namespace
+		dst.Access.Namespace = namespace
+		return nil
+	}
+}
+
+// TenantNamespaceClaimsMapper derives the namespace from a tenant claim using the tenants lookup.
+// Tokens from unknown tenants are rejected.
+func TenantNamespaceClaimsMapper(claim string, tenants map[string]string) ClaimsMapper {
+	return func(ctx context.Context, src ExternalClaims, dst *MappedClaims) error {
+		tenant := src.String(claim)
+		namespace, ok := tenants[tenant]
+		if !ok {
+			return fmt.Errorf("%w: unknown tenant '%s'", ErrMappingClaims, tenant)
+		}
+		return StaticNamespaceClaimsMapper(namespace)(ctx, src, dst)
+	}
+}
+
+// RolePermissionsClaimsMapper delegates the permissions associated to the roles (or groups) listed in the claim.
+// The token acts on behalf of the user: like the delegated permissions of an exchanged token, they bound the actions
+// checked against the permissions of the user by the authz clients. Roles missing from the lookup are ignored.
+func RolePermissionsClaimsMapper(claim string, rolePermissions map[string][]string) ClaimsMapper {
+	return func(_ context.Context, src ExternalClaims, dst *MappedClaims) error {
+		for _, role := range src.Strings(claim) {
+			dst.Access.DelegatedPermissions = appendMissing(dst.Access.DelegatedPermissions, rolePermissions[role]...)
+		}
+		return nil
+	}
+}
+
+// ScopesClaimsMapper maps the OAuth scopes listed in the claim, see Access.OAuthScopes.
+// Both space delimited strings (RFC 8693) and lists are supported.
+func ScopesClaimsMapper(claim string) ClaimsMapper {
+	return func(_ context.Context, src ExternalClaims, dst *MappedClaims) error {
+		dst.Access.Scp = appendMissing(dst.Access.Scp, src.Strings(claim)...)
+		return nil
+	}
+}
+
+// AzureADClaimsMapper maps Azure AD (Entra ID) tokens: the object id identifies the user,
+// the tenant id selects the namespace and app roles delegate permissions.
+func AzureADClaimsMapper(tenants map[string]string, rolePermissions map[string][]string) ClaimsMapper {
+	return ChainClaimsMappers(
+		IdentifierClaimsMapper("oid"),
+		ProfileClaimsMapper(),
+		func(_ context.Context, src ExternalClaims, dst *MappedClaims) error {
+			// Azure AD v2 tokens do not always carry the email claim
+			if dst.Identity.Email == "" {
+				dst.Identity.Email = src.String("upn")
+			}
+			return nil
+		},
+		TenantNamespaceClaimsMapper("tid", tenants),
+		RolePermissionsClaimsMapper("roles", rolePermissions),
+		ScopesClaimsMapper("scp"),
+	)
+}
+
+// OktaClaimsMapper maps Okta tokens: the uid identifies the user, the namespace is static
+// and groups delegate permissions.
+func OktaClaimsMapper(namespace string, groupPermissions map[string][]string) ClaimsMapper {
+	return ChainClaimsMappers(
+		IdentifierClaimsMapper("uid"),
+		ProfileClaimsMapper(),
+		StaticNamespaceClaimsMapper(namespace),
+		RolePermissionsClaimsMapper("groups", groupPermissions),
+		ScopesClaimsMapper("scp"),
+	)
+}
+
+// String returns the claim value as a string, or an empty string if the claim is not a string.
+func (c ExternalClaims) String(claim string) string {
+	v, _ := c[claim].(string)
+	return v
+}
+
+// Strings returns the claim value as a list of strings.
+// Space delimited strings are split.
+func (c ExternalClaims) Strings(claim string) []string {
+	switch v := c[claim].(type) {
+	case string:
+		return strings.Fields(v)
+	case []string:
+		return v
+	case []any:
+		res := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	default:
+		return nil
+	}
+}
+
+func appendMissing(dst []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(dst, v) {
+			dst = append(dst, v)
+		}
+	}
+	return dst
+}
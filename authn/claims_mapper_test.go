@@ -0,0 +1,114 @@
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/claims"
+)
+
+func externalClaims(t *testing.T, raw string) ExternalClaims {
+	t.Helper()
+	var c ExternalClaims
+	require.NoError(t, json.Unmarshal([]byte(raw), &c))
+	return c
+}
+
+func TestMapExternalClaims(t *testing.T) {
+	rolePermissions := map[string][]string{
+		"Viewer": {"dashboards:read"},
+		"Editor": {"dashboards:read", "dashboards:write"},
+	}
+
+	t.Run("should map Azure AD token", func(t *testing.T) {
+		src := &Claims[ExternalClaims]{
+			Claims: &jwt.Claims{Subject: "azure-sub", Issuer: "https://login.microsoftonline.com/tenant-1/v2.0"},
+			Rest: externalClaims(t, `{
+				"oid": "object-1",
+				"tid": "tenant-1",
+				"name": "John Doe",
+				"preferred_username": "jdoe",
+				"upn": "jdoe@example.org",
+				"roles": ["Viewer", "Editor", "Unknown"],
+				"scp": "user.read profile"
+			}`),
+		}
+
+		info, err := MapExternalClaims(context.Background(), src, AzureADClaimsMapper(map[string]string{"tenant-1": "stacks-1"}, rolePermissions))
+		require.NoError(t, err)
+
+		assert.Equal(t, "user:object-1", info.GetUID())
+		assert.Equal(t, "John Doe", info.GetName())
+		assert.Equal(t, "user:object-1", info.GetIdentity().Subject())
+		assert.Equal(t, claims.TypeUser, info.GetIdentity().IdentityType())
+		assert.Equal(t, "jdoe@example.org", info.GetIdentity().Email())
+		assert.Equal(t, "jdoe", info.GetIdentity().Username())
+		assert.Equal(t, "stacks-1", info.GetIdentity().Namespace())
+		assert.Equal(t, "https://login.microsoftonline.com/tenant-1/v2.0", info.GetIdentity().Issuer())
+		assert.Equal(t, "stacks-1", info.GetAccess().Namespace())
+		assert.Equal(t, []string{"dashboards:read", "dashboards:write"}, info.GetAccess().DelegatedPermissions())
+		assert.Equal(t, []string{"user.read", "profile"}, info.GetAccess().(*Access).OAuthScopes())
+	})
+
+	t.Run("should reject Azure AD token from unknown tenant", func(t *testing.T) {
+		src := &Claims[ExternalClaims]{
+			Claims: &jwt.Claims{Subject: "azure-sub"},
+			Rest:   externalClaims(t, `{"oid": "object-1", "tid": "tenant-2"}`),
+		}
+
+		_, err := MapExternalClaims(context.Background(), src, AzureADClaimsMapper(map[string]string{"tenant-1": "stacks-1"}, rolePermissions))
+		require.ErrorIs(t, err, ErrMappingClaims)
+	})
+
+	t.Run("should map Okta token", func(t *testing.T) {
+		src := &Claims[ExternalClaims]{
+			Claims: &jwt.Claims{Subject: "jdoe@example.org"},
+			Rest: externalClaims(t, `{
+				"uid": "okta-1",
+				"email": "jdoe@example.org",
+				"email_verified": true,
+				"groups": ["Viewer"],
+				"scp": ["openid", "email"]
+			}`),
+		}
+
+		info, err := MapExternalClaims(context.Background(), src, OktaClaimsMapper("default", rolePermissions))
+		require.NoError(t, err)
+
+		assert.Equal(t, "user:okta-1", info.GetUID())
+		assert.True(t, info.GetIdentity().EmailVerified())
+		assert.Equal(t, "default", info.GetIdentity().Namespace())
+		assert.Equal(t, []string{"dashboards:read"}, info.GetAccess().DelegatedPermissions())
+		assert.Equal(t, []string{"openid", "email"}, info.GetAccess().(*Access).OAuthScopes())
+	})
+
+	t.Run("should fallback to token subject and chain custom mappers", func(t *testing.T) {
+		src := &Claims[ExternalClaims]{
+			Claims: &jwt.Claims{Subject: "sub-1"},
+			Rest:   externalClaims(t, `{}`),
+		}
+
+		mapper := ChainClaimsMappers(
+			IdentifierClaimsMapper("missing"),
+			func(_ context.Context, _ ExternalClaims, dst *MappedClaims) error {
+				dst.Identity.Type = claims.TypeServiceAccount
+				return nil
+			},
+		)
+
+		info, err := MapExternalClaims(context.Background(), src, mapper)
+		require.NoError(t, err)
+		assert.Equal(t, "service-account:sub-1", info.GetIdentity().Subject())
+	})
+
+	t.Run("should fail without identifier", func(t *testing.T) {
+		src := &Claims[ExternalClaims]{Claims: &jwt.Claims{}, Rest: ExternalClaims{}}
+		_, err := MapExternalClaims(context.Background(), src, ProfileClaimsMapper())
+		require.ErrorIs(t, err, ErrMappingClaims)
+	})
+}
@@ -1,14 +1,31 @@
 package authn
 
 import (
+	"context"
 	"flag"
 	"strings"
+	"time"
 
+	"github.com/go-jose/go-jose/v3"
 	"github.com/go-jose/go-jose/v3/jwt"
 )
 
 type VerifierConfig struct {
 	AllowedAudiences jwt.Audience `yaml:"allowedAudiences"`
+	// StrictClaims rejects tokens carrying top-level claims other than the registered JWT claims,
+	// the claims of the token type and AllowedClaims.
+	StrictClaims bool `yaml:"strictClaims"`
+	// AllowedClaims are the additional top-level claims accepted when StrictClaims is enabled.
+	AllowedClaims []string `yaml:"allowedClaims"`
+	// AllowedAlgorithms rejects tokens signed with other algorithms (header "alg"), to protect against
+	// algorithm-confusion attacks. Any algorithm supported by the signing key is accepted when empty.
+	AllowedAlgorithms []jose.SignatureAlgorithm `yaml:"allowedAlgorithms"`
+	// ExpectedNamespace rejects tokens whose namespace claim does not match the namespace of the serving tenant,
+	// see claims.NamespaceMatches. Tokens without namespace claim are rejected when set.
+	ExpectedNamespace string `yaml:"expectedNamespace"`
+	// ExpectedNamespaceFunc returns the expected namespace of the request, for the multi-tenant services.
+	// It takes precedence over ExpectedNamespace. An empty namespace accepts any namespace.
+	ExpectedNamespaceFunc func(ctx context.Context) string `yaml:"-"`
 }
 
 func (c *VerifierConfig) RegisterFlags(prefix string, fs *flag.FlagSet) {
@@ -16,14 +33,65 @@ func (c *VerifierConfig) RegisterFlags(prefix string, fs *flag.FlagSet) {
 		c.AllowedAudiences = jwt.Audience(strings.Split(v, ","))
 		return nil
 	})
+	fs.BoolVar(&c.StrictClaims, prefix+".strict-claims", false, "Reject tokens carrying unknown claims.")
+	fs.Func(prefix+".allowed-claims", "Specifies a comma-separated list of additional claims accepted in strict mode.", func(v string) error {
+		c.AllowedClaims = strings.Split(v, ",")
+		return nil
+	})
+	fs.StringVar(&c.ExpectedNamespace, prefix+".expected-namespace", "", "Reject tokens of other namespaces.")
+	fs.Func(prefix+".allowed-algorithms", "Specifies a comma-separated list of allowed signature algorithms.", func(v string) error {
+		c.AllowedAlgorithms = nil
+		for _, alg := range strings.Split(v, ",") {
+			c.AllowedAlgorithms = append(c.AllowedAlgorithms, jose.SignatureAlgorithm(alg))
+		}
+		return nil
+	})
 }
 
 type KeyRetrieverConfig struct {
 	SigningKeysURL string `yaml:"signingKeysUrl"`
+	// Issuer is the URL of an OpenID provider, whose signing keys URL is resolved from its discovery document
+	// (/.well-known/openid-configuration). It is only used when SigningKeysURL is not set.
+	Issuer string `yaml:"issuer"`
+	// TLS configures the requests of the signing keys and the discovery document, for private authorities.
+	// It is ignored when the HTTP client is set with WithHTTPClientKeyRetrieverOpt.
+	TLS TLSConfig `yaml:"tls"`
 }
 
 func (c *KeyRetrieverConfig) RegisterFlags(prefix string, fs *flag.FlagSet) {
 	fs.StringVar(&c.SigningKeysURL, prefix+".signing-keys-url", "", "URL to jwks endpoint.")
+	fs.StringVar(&c.Issuer, prefix+".issuer", "", "URL of the OpenID provider, to discover the jwks endpoint.")
+	c.TLS.RegisterFlags(prefix+".tls", fs)
+}
+
+type IntrospectionConfig struct {
+	// IntrospectionURL is the OAuth 2.0 token introspection endpoint (RFC 7662).
+	IntrospectionURL string `yaml:"introspectionUrl"`
+	// ClientID and ClientSecret authenticate the introspection requests, if set.
+	ClientID     string `yaml:"clientId"`
+	ClientSecret string `yaml:"clientSecret"`
+}
+
+func (c *IntrospectionConfig) RegisterFlags(prefix string, fs *flag.FlagSet) {
+	fs.StringVar(&c.IntrospectionURL, prefix+".introspection-url", "", "URL to the token introspection endpoint.")
+	fs.StringVar(&c.ClientID, prefix+".client-id", "", "Client ID authenticating the introspection requests.")
+	fs.StringVar(&c.ClientSecret, prefix+".client-secret", "", "Client secret authenticating the introspection requests.")
+}
+
+type RevocationListConfig struct {
+	// URL serving the revocation list.
+	URL string `yaml:"url"`
+	// PollInterval is how often the revocation list is refreshed. Defaults to 30s.
+	PollInterval time.Duration `yaml:"pollInterval"`
+	// FailClosed rejects the tokens with ErrRevocationsNotReady until the list is fetched for the first time.
+	// By default, the tokens are accepted meanwhile (fail open).
+	FailClosed bool `yaml:"failClosed"`
+}
+
+func (c *RevocationListConfig) RegisterFlags(prefix string, fs *flag.FlagSet) {
+	fs.StringVar(&c.URL, prefix+".url", "", "URL to the revocation list.")
+	fs.DurationVar(&c.PollInterval, prefix+".poll-interval", DefaultRevocationPollInterval, "How often the revocation list is refreshed.")
+	fs.BoolVar(&c.FailClosed, prefix+".fail-closed", false, "Reject the tokens until the revocation list is fetched for the first time.")
 }
 
 type TokenExchangeConfig struct {
@@ -37,3 +105,15 @@ func (c *TokenExchangeConfig) RegisterFlags(prefix string, fs *flag.FlagSet) {
 	fs.StringVar(&c.Token, prefix+".token", "", "Token used to perform the exchange request.")
 	fs.StringVar(&c.TokenExchangeURL, prefix+".token-exchange-url", "", "Url called to perform exchange request.")
 }
+
+type SignerConfig struct {
+	// Issuer set on the "iss" claim of signed tokens.
+	Issuer string `yaml:"issuer"`
+	// TokenTTL is the lifetime of signed tokens. Defaults to 10 minutes.
+	TokenTTL time.Duration `yaml:"tokenTtl"`
+}
+
+func (c *SignerConfig) RegisterFlags(prefix string, fs *flag.FlagSet) {
+	fs.StringVar(&c.Issuer, prefix+".issuer", "", "Issuer set on signed tokens.")
+	fs.DurationVar(&c.TokenTTL, prefix+".token-ttl", 10*time.Minute, "Lifetime of signed tokens.")
+}
@@ -3,7 +3,9 @@ package authn
 import (
 	"flag"
 	"testing"
+	"time"
 
+	"github.com/go-jose/go-jose/v3"
 	"github.com/go-jose/go-jose/v3/jwt"
 	"github.com/stretchr/testify/require"
 )
@@ -16,6 +18,20 @@ func TestVerifierConfig_RegisterFlags(t *testing.T) {
 	err := fs.Parse([]string{"-test.allowed-audiences", "a,b,c"})
 	require.NoError(t, err)
 	require.Equal(t, jwt.Audience{"a", "b", "c"}, cfg.AllowedAudiences)
+	require.False(t, cfg.StrictClaims)
+
+	err = fs.Parse([]string{"-test.strict-claims", "-test.allowed-claims", "role,team"})
+	require.NoError(t, err)
+	require.True(t, cfg.StrictClaims)
+	require.Equal(t, []string{"role", "team"}, cfg.AllowedClaims)
+
+	err = fs.Parse([]string{"-test.allowed-algorithms", "ES256,RS256"})
+	require.NoError(t, err)
+	require.Equal(t, []jose.SignatureAlgorithm{jose.ES256, jose.RS256}, cfg.AllowedAlgorithms)
+
+	err = fs.Parse([]string{"-test.expected-namespace", "stacks-1"})
+	require.NoError(t, err)
+	require.Equal(t, "stacks-1", cfg.ExpectedNamespace)
 }
 
 func TestKeyRetrieverConfig_RegisterFlags(t *testing.T) {
@@ -26,6 +42,10 @@ func TestKeyRetrieverConfig_RegisterFlags(t *testing.T) {
 	err := fs.Parse([]string{"-test.signing-keys-url", "http://127.0.0.1/keys"})
 	require.NoError(t, err)
 	require.Equal(t, "http://127.0.0.1/keys", cfg.SigningKeysURL)
+
+	err = fs.Parse([]string{"-test.issuer", "http://127.0.0.1"})
+	require.NoError(t, err)
+	require.Equal(t, "http://127.0.0.1", cfg.Issuer)
 }
 
 func TestTokenExchangeConfig_RegisterFlags(t *testing.T) {
@@ -38,3 +58,24 @@ func TestTokenExchangeConfig_RegisterFlags(t *testing.T) {
 	require.Equal(t, "my-token", cfg.Token)
 	require.Equal(t, "http://127.0.0.1/token", cfg.TokenExchangeURL)
 }
+
+func TestSignerConfig_RegisterFlags(t *testing.T) {
+	var cfg SignerConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.RegisterFlags("test", fs)
+
+	err := fs.Parse([]string{"-test.issuer", "my-issuer", "-test.token-ttl", "5m"})
+	require.NoError(t, err)
+	require.Equal(t, "my-issuer", cfg.Issuer)
+	require.Equal(t, 5*time.Minute, cfg.TokenTTL)
+}
+
+func TestIntrospectionConfig_RegisterFlags(t *testing.T) {
+	var cfg IntrospectionConfig
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.RegisterFlags("test", fs)
+
+	err := fs.Parse([]string{"-test.introspection-url", "http://127.0.0.1/introspect", "-test.client-id", "client", "-test.client-secret", "secret"})
+	require.NoError(t, err)
+	require.Equal(t, IntrospectionConfig{IntrospectionURL: "http://127.0.0.1/introspect", ClientID: "client", ClientSecret: "secret"}, cfg)
+}
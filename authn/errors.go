@@ -3,21 +3,34 @@ package authn
 import (
 	"errors"
 	"fmt"
+
+	"github.com/grafana/authlib/errs"
+	"github.com/grafana/authlib/internal/tlsconfig"
 )
 
 var (
-	ErrFetchingSigningKey = errors.New("unable to fetch signing keys")
+	ErrFetchingSigningKey  = errs.New(errs.KindUnavailable, "unable to fetch signing keys")
+	ErrIntrospection       = errs.New(errs.KindUnavailable, "unable to introspect token")
+	ErrFetchingRevocations = errs.New(errs.KindUnavailable, "unable to fetch revocation list")
+	ErrRevocationsNotReady = errs.New(errs.KindUnavailable, "revocation list not fetched yet")
 
-	// Private error we wrap all other exported errors with
-	errInvalidToken      = errors.New("invalid token")
+	// Private error we wrap all other exported errors with, matching errs.ErrUnauthenticated
+	errInvalidToken      = errs.New(errs.KindUnauthenticated, "invalid token")
 	ErrParseToken        = fmt.Errorf("%w: failed to parse as jwt token", errInvalidToken)
+	ErrDecryptToken      = fmt.Errorf("%w: failed to decrypt token", errInvalidToken)
 	ErrInvalidTokenType  = fmt.Errorf("%w: invalid token type", errInvalidToken)
 	ErrInvalidSigningKey = fmt.Errorf("%w: unrecognized signing key", errInvalidToken)
+	ErrInvalidAlgorithm  = fmt.Errorf("%w: signature algorithm not allowed", errInvalidToken)
 
-	ErrExpiredToken    = fmt.Errorf("%w: expired token", errInvalidToken)
-	ErrInvalidAudience = fmt.Errorf("%w: invalid audience", errInvalidToken)
+	ErrExpiredToken      = fmt.Errorf("%w: expired token", errInvalidToken)
+	ErrInvalidAudience   = fmt.Errorf("%w: invalid audience", errInvalidToken)
+	ErrUnknownClaim      = fmt.Errorf("%w: unknown claim", errInvalidToken)
+	ErrInactiveToken     = fmt.Errorf("%w: inactive token", errInvalidToken)
+	ErrRevokedToken      = fmt.Errorf("%w: revoked token", errInvalidToken)
+	ErrNamespaceMismatch = fmt.Errorf("%w: namespace mismatch", errInvalidToken)
 
-	ErrMissingConfig = errors.New("missing config")
+	ErrMissingConfig    = errors.New("missing config")
+	ErrInvalidTLSConfig = tlsconfig.ErrInvalidTLSConfig
 )
 
 func IsInvalidTokenErr(err error) bool {
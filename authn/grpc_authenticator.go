@@ -23,6 +23,7 @@ var (
 	ErrorNamespacesMismatch = status.Error(codes.PermissionDenied, "unauthorized: access and id token namespaces mismatch")
 	ErrorInvalidSubject     = status.Error(codes.PermissionDenied, "unauthorized: invalid subject")
 	ErrorInvalidSubjectType = status.Error(codes.PermissionDenied, "unauthorized: invalid subject type")
+	ErrorMissingAuthInfo    = status.Error(codes.Unauthenticated, "unauthenticated: missing auth info")
 )
 
 // GrpcAuthenticatorOptions
@@ -61,6 +62,9 @@ type GrpcAuthenticator struct {
 	atVerifier   Verifier[AccessTokenClaims]
 	idVerifier   Verifier[IDTokenClaims]
 	tracer       trace.Tracer
+
+	// unauthenticatedMethods lists the gRPC methods the server interceptors do not authenticate.
+	unauthenticatedMethods []string
 }
 
 func WithKeyRetrieverOption(kr KeyRetriever) GrpcAuthenticatorOption {
@@ -203,7 +207,7 @@ func (ga *GrpcAuthenticator) Authenticate(ctx context.Context) (context.Context,
 		}
 	}
 
-	return claims.WithClaims(ctx, &authInfo), nil
+	return claims.WithAuthInfo(ctx, &authInfo), nil
 }
 
 func (ga *GrpcAuthenticator) authenticateService(ctx context.Context, md metadata.MD) (*Claims[AccessTokenClaims], error) {
@@ -248,7 +252,7 @@ func (ga *GrpcAuthenticator) authenticateUser(ctx context.Context, md metadata.M
 		return nil, fmt.Errorf("id token subject '%s' is not valid: %w", idClaims.Subject, ErrorInvalidSubject)
 	}
 
-	if typ != claims.TypeUser && typ != claims.TypeServiceAccount {
+	if !claims.IsIdentityType(typ, claims.TypeUser, claims.TypeServiceAccount, claims.TypeAPIKey) {
 		return nil, fmt.Errorf("id token subject '%s' type is not allowed: %w", typ, ErrorInvalidSubjectType)
 	}
 
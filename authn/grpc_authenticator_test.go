@@ -254,7 +254,7 @@ func TestGrpcAuthenticator_Authenticate(t *testing.T) {
 				return
 			}
 
-			got, ok := claims.From(ctx)
+			got, ok := claims.AuthInfoFrom(ctx)
 			require.True(t, ok)
 
 			require.NoError(t, err)
@@ -400,12 +400,26 @@ func TestGrpcAuthenticator_authenticateUser(t *testing.T) {
 			md:   metadata.Pairs(DefaultIdTokenMetadataKey, "id-token"),
 			initEnv: func(env *testEnv) {
 				env.idVerifier.expectedClaims = &Claims[IDTokenClaims]{
-					Claims: &jwt.Claims{Subject: claims.NewTypeID(claims.TypeAPIKey, "3")},
+					Claims: &jwt.Claims{Subject: claims.NewTypeID(claims.TypeRenderService, "3")},
 					Rest:   IDTokenClaims{Namespace: "stacks-12"},
 				}
 			},
 			wantErr: ErrorInvalidSubjectType,
 		},
+		{
+			name: "valid api key id token",
+			md:   metadata.Pairs(DefaultIdTokenMetadataKey, "id-token"),
+			initEnv: func(env *testEnv) {
+				env.idVerifier.expectedClaims = &Claims[IDTokenClaims]{
+					Claims: &jwt.Claims{Subject: claims.NewAPIKeyTypeID("3")},
+					Rest:   IDTokenClaims{Namespace: "stacks-12", Type: claims.TypeAPIKey, Identifier: "3"},
+				}
+			},
+			want: &Claims[IDTokenClaims]{
+				Claims: &jwt.Claims{Subject: claims.NewAPIKeyTypeID("3")},
+				Rest:   IDTokenClaims{Namespace: "stacks-12", Type: claims.TypeAPIKey, Identifier: "3"},
+			},
+		},
 		{
 			name: "valid id token",
 			md:   metadata.Pairs(DefaultIdTokenMetadataKey, "id-token"),
@@ -9,7 +9,12 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"go.opentelemetry.io/otel/trace/noop"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/authlib/internal/logger"
 )
 
 const (
@@ -26,10 +31,11 @@ type GrpcClientConfig struct {
 	// Not required if IDTokenExtractor is provided. Defaults to "X-Id-Token".
 	IDTokenMetadataKey string
 	// TokenClientConfig holds the configuration for the token exchange client.
-	// Not required if TokenClient is provided.
+	// Not required if TokenClient or TokenProvider is provided.
 	TokenClientConfig *TokenExchangeConfig
 	// TokenRequest is the token request to be used for token exchange.
 	// This assumes the token request is static and does not change.
+	// Not required if TokenProvider is provided.
 	TokenRequest *TokenExchangeRequest
 
 	// accessTokenAuthEnabled is a flag to enable access token authentication.
@@ -41,8 +47,11 @@ type GrpcClientConfig struct {
 type GrpcClientInterceptor struct {
 	cfg                *GrpcClientConfig
 	tokenClient        TokenExchanger
+	tokenProvider      TokenProvider
+	audience           string
 	metadataExtractors []ContextMetadataExtractor
 	tracer             trace.Tracer
+	logger             Logger
 }
 
 type ContextMetadataExtractor func(context.Context) (key string, values []string, err error)
@@ -55,6 +64,16 @@ func WithTokenClientOption(tokenClient TokenExchanger) GrpcClientInterceptorOpti
 	}
 }
 
+// WithTokenProviderOption sets the provider of the access tokens attached to outgoing requests.
+// Tokens are requested for the audience of the downstream service.
+// When set, the token exchange client and token request are not used.
+func WithTokenProviderOption(provider TokenProvider, audience string) GrpcClientInterceptorOption {
+	return func(gci *GrpcClientInterceptor) {
+		gci.tokenProvider = provider
+		gci.audience = audience
+	}
+}
+
 func WithIDTokenExtractorOption(extractor func(context.Context) (string, error)) GrpcClientInterceptorOption {
 	return func(gci *GrpcClientInterceptor) {
 		WithMetadataExtractorOption(func(ctx context.Context) (key string, values []string, err error) {
@@ -67,6 +86,20 @@ func WithIDTokenExtractorOption(extractor func(context.Context) (string, error))
 	}
 }
 
+// WithCallerIDTokenOption forwards the ID token of the caller found in the context (see claims.WithAuthInfo).
+// Nothing is forwarded when the caller has no ID token, for instance when a service is acting on its own behalf.
+func WithCallerIDTokenOption() GrpcClientInterceptorOption {
+	return func(gci *GrpcClientInterceptor) {
+		WithMetadataExtractorOption(func(ctx context.Context) (key string, values []string, err error) {
+			caller, ok := claims.AuthInfoFrom(ctx)
+			if !ok {
+				return gci.cfg.IDTokenMetadataKey, nil, nil
+			}
+			return gci.cfg.IDTokenMetadataKey, caller.GetExtra()["id-token"], nil
+		})(gci)
+	}
+}
+
 func WithMetadataExtractorOption(extractors ...ContextMetadataExtractor) GrpcClientInterceptorOption {
 	return func(gci *GrpcClientInterceptor) {
 		gci.metadataExtractors = append(gci.metadataExtractors, extractors...)
@@ -88,6 +121,13 @@ func WithTracerOption(tracer trace.Tracer) GrpcClientInterceptorOption {
 	}
 }
 
+// WithLoggerOption sets the logger of the calls retried with a new access token.
+func WithLoggerOption(logger Logger) GrpcClientInterceptorOption {
+	return func(c *GrpcClientInterceptor) {
+		c.logger = logger
+	}
+}
+
 func setGrpcClientCfgDefaults(cfg *GrpcClientConfig) {
 	if cfg.AccessTokenMetadataKey == "" {
 		cfg.AccessTokenMetadataKey = DefaultAccessTokenMetadataKey
@@ -110,6 +150,17 @@ func NewGrpcClientInterceptor(cfg *GrpcClientConfig, opts ...GrpcClientIntercept
 		gci.tracer = noop.Tracer{}
 	}
 
+	if gci.logger == nil {
+		gci.logger = logger.Nop{}
+	}
+
+	if gci.tokenProvider != nil {
+		if gci.audience == "" {
+			return nil, fmt.Errorf("missing required token audience: %w", ErrMissingConfig)
+		}
+		return gci, nil
+	}
+
 	if gci.cfg.TokenRequest == nil && gci.cfg.accessTokenAuthEnabled {
 		return nil, fmt.Errorf("missing required token request: %w", ErrMissingConfig)
 	}
@@ -129,16 +180,31 @@ func NewGrpcClientInterceptor(cfg *GrpcClientConfig, opts ...GrpcClientIntercept
 	return gci, nil
 }
 
+// UnaryClientInterceptor attaches the tokens to the outgoing calls. Calls rejected as Unauthenticated because the
+// access token expired in flight are retried once with a new token, when the token provider or exchanger is refreshable.
+// Streams are not retried.
 func (gci *GrpcClientInterceptor) UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
 	ctx, span := gci.tracer.Start(ctx, "GrpcClientInterceptor.UnaryClientInterceptor")
 	defer span.End()
 
-	ctx, err := gci.wrapContext(ctx)
+	outCtx, err := gci.wrapContext(ctx)
 	if err != nil {
 		return err
 	}
 
-	return invoker(ctx, method, req, reply, cc, opts...)
+	err = invoker(outCtx, method, req, reply, cc, opts...)
+	if status.Code(err) != codes.Unauthenticated || !gci.invalidateToken(outCtx) {
+		return err
+	}
+
+	// The access token expired in flight, retry once with a new one
+	span.SetAttributes(attribute.Bool("token_retry", true))
+	gci.logger.Debug("retrying with a new access token", "method", method, "error", err)
+	outCtx, err = gci.wrapContext(ctx)
+	if err != nil {
+		return err
+	}
+	return invoker(outCtx, method, req, reply, cc, opts...)
 }
 
 func (gci *GrpcClientInterceptor) StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
@@ -160,14 +226,14 @@ func (gci *GrpcClientInterceptor) wrapContext(ctx context.Context) (context.Cont
 	md := metadata.Pairs()
 
 	if gci.cfg.accessTokenAuthEnabled {
-		token, err := gci.tokenClient.Exchange(ctx, *gci.cfg.TokenRequest)
+		token, err := gci.accessToken(ctx)
 		if err != nil {
 			span.RecordError(err)
 			return ctx, err
 		}
 
 		span.SetAttributes(attribute.Bool("with_accesstoken", true))
-		md.Set(gci.cfg.AccessTokenMetadataKey, token.Token)
+		md.Set(gci.cfg.AccessTokenMetadataKey, token)
 	}
 
 	keys := make([]string, 0, len(gci.metadataExtractors))
@@ -177,6 +243,9 @@ func (gci *GrpcClientInterceptor) wrapContext(ctx context.Context) (context.Cont
 			span.RecordError(err)
 			return ctx, err
 		}
+		if len(v) == 0 {
+			continue
+		}
 		keys = append(keys, k)
 		md.Set(k, v...)
 		span.SetAttributes(attribute.String("keys", strings.Join(keys, ",")))
@@ -184,3 +253,43 @@ func (gci *GrpcClientInterceptor) wrapContext(ctx context.Context) (context.Cont
 
 	return metadata.NewOutgoingContext(ctx, md), nil
 }
+
+func (gci *GrpcClientInterceptor) accessToken(ctx context.Context) (string, error) {
+	if gci.tokenProvider != nil {
+		return gci.tokenProvider.Token(ctx, gci.audience)
+	}
+
+	token, err := gci.tokenClient.Exchange(ctx, *gci.cfg.TokenRequest)
+	if err != nil {
+		return "", err
+	}
+	return token.Token, nil
+}
+
+// invalidateToken drops the access token of the outgoing context when it has expired, or is about to,
+// and returns whether the call can be retried with a new token.
+func (gci *GrpcClientInterceptor) invalidateToken(ctx context.Context) bool {
+	if !gci.cfg.accessTokenAuthEnabled {
+		return false
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	tokens := md.Get(gci.cfg.AccessTokenMetadataKey)
+	if len(tokens) == 0 || !tokenExpiring(tokens[0]) {
+		return false
+	}
+
+	if gci.tokenProvider != nil {
+		provider, ok := gci.tokenProvider.(RefreshableTokenProvider)
+		if ok {
+			provider.Invalidate(ctx, gci.audience, tokens[0])
+		}
+		return ok
+	}
+
+	exchanger, ok := gci.tokenClient.(RefreshableTokenExchanger)
+	if ok {
+		exchanger.Invalidate(ctx, *gci.cfg.TokenRequest, tokens[0])
+	}
+	return ok
+}
@@ -5,8 +5,12 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/go-jose/go-jose/v3/jwt"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+
+	"github.com/grafana/authlib/claims"
 )
 
 type FakeTokenExchanger struct {
@@ -17,6 +21,15 @@ func (f *FakeTokenExchanger) Exchange(ctx context.Context, req TokenExchangeRequ
 	return &TokenExchangeResponse{Token: f.token}, nil
 }
 
+type fakeTokenProvider struct {
+	audiences []string
+}
+
+func (f *fakeTokenProvider) Token(ctx context.Context, audience string) (string, error) {
+	f.audiences = append(f.audiences, audience)
+	return "provided-token", nil
+}
+
 func setupGrpcClientInterceptor(t *testing.T) (*GrpcClientInterceptor, *FakeTokenExchanger) {
 	tokenClient := &FakeTokenExchanger{token: "some-token"}
 
@@ -92,3 +105,43 @@ func TestGrpcClientInterceptor_wrapContextNoAccessToken(t *testing.T) {
 	idToken := mdIdKey[0]
 	require.Equal(t, idToken, "some-id-token")
 }
+
+func TestGrpcClientInterceptor_TokenProvider(t *testing.T) {
+	provider := &fakeTokenProvider{}
+	gci, err := NewGrpcClientInterceptor(
+		&GrpcClientConfig{},
+		WithTokenProviderOption(provider, "some-service"),
+		WithCallerIDTokenOption(),
+	)
+	require.NoError(t, err)
+
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+
+	t.Run("should attach access token and caller ID token", func(t *testing.T) {
+		ctx := claims.WithAuthInfo(context.Background(), &AuthInfo{
+			IdentityClaims: NewIdentityClaims(Claims[IDTokenClaims]{Claims: &jwt.Claims{Subject: "user:1"}, token: "caller-id-token"}),
+		})
+
+		err := gci.UnaryClientInterceptor(ctx, "/my.Service/Method", nil, nil, nil, invoker)
+		require.NoError(t, err)
+		require.Equal(t, []string{"provided-token"}, outgoing.Get(DefaultAccessTokenMetadataKey))
+		require.Equal(t, []string{"caller-id-token"}, outgoing.Get(DefaultIdTokenMetadataKey))
+		require.Equal(t, []string{"some-service"}, provider.audiences)
+	})
+
+	t.Run("should only attach access token without caller", func(t *testing.T) {
+		err := gci.UnaryClientInterceptor(context.Background(), "/my.Service/Method", nil, nil, nil, invoker)
+		require.NoError(t, err)
+		require.Equal(t, []string{"provided-token"}, outgoing.Get(DefaultAccessTokenMetadataKey))
+		require.Empty(t, outgoing.Get(DefaultIdTokenMetadataKey))
+	})
+
+	t.Run("should require an audience", func(t *testing.T) {
+		_, err := NewGrpcClientInterceptor(&GrpcClientConfig{}, WithTokenProviderOption(provider, ""))
+		require.ErrorIs(t, err, ErrMissingConfig)
+	})
+}
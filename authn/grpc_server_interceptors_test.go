@@ -0,0 +1,135 @@
+package authn
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/grafana/authlib/claims"
+)
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *fakeServerStream) Context() context.Context {
+	return f.ctx
+}
+
+func setupServerInterceptorEnv() *testEnv {
+	env := setupGrpcAuthenticator()
+	WithUnauthenticatedMethodsOption("/grpc.health.v1.Health/", "/my.Service/Public")(env.authenticator)
+	env.atVerifier.expectedClaims = &Claims[AccessTokenClaims]{
+		Claims: &jwt.Claims{Subject: claims.NewTypeID(claims.TypeAccessPolicy, "3")},
+		Rest:   AccessTokenClaims{Namespace: "*"},
+	}
+	env.idVerifier.expectedClaims = &Claims[IDTokenClaims]{
+		Claims: &jwt.Claims{Subject: claims.NewTypeID(claims.TypeUser, "3")},
+		Rest:   IDTokenClaims{Namespace: "stacks-12"},
+	}
+	return env
+}
+
+func TestGrpcAuthenticator_UnaryServerInterceptor(t *testing.T) {
+	env := setupServerInterceptorEnv()
+	interceptor := env.authenticator.UnaryServerInterceptor()
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		info, ok := claims.AuthInfoFrom(ctx)
+		return ok && info != nil, nil
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		md         metadata.MD
+		wantClaims bool
+		wantErr    error
+	}{
+		{
+			name:    "should reject request without tokens",
+			method:  "/my.Service/Private",
+			md:      metadata.Pairs(),
+			wantErr: ErrorMissingAccessToken,
+		},
+		{
+			name:       "should authenticate request",
+			method:     "/my.Service/Private",
+			md:         metadata.Pairs(DefaultAccessTokenMetadataKey, "access-token", DefaultIdTokenMetadataKey, "id-token"),
+			wantClaims: true,
+		},
+		{
+			name:   "should skip authentication for allowed method",
+			method: "/my.Service/Public",
+			md:     metadata.Pairs(),
+		},
+		{
+			name:   "should skip authentication for allowed service",
+			method: "/grpc.health.v1.Health/Check",
+			md:     metadata.Pairs(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			res, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantClaims, res)
+		})
+	}
+}
+
+func TestGrpcAuthenticator_StreamServerInterceptor(t *testing.T) {
+	env := setupServerInterceptorEnv()
+	interceptor := env.authenticator.StreamServerInterceptor()
+
+	var gotClaims bool
+	handler := func(srv any, stream grpc.ServerStream) error {
+		_, gotClaims = claims.AuthInfoFrom(stream.Context())
+		return nil
+	}
+
+	t.Run("should reject stream without tokens", func(t *testing.T) {
+		stream := &fakeServerStream{ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs())}
+		err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/my.Service/Private"}, handler)
+		require.ErrorIs(t, err, ErrorMissingAccessToken)
+	})
+
+	t.Run("should authenticate stream", func(t *testing.T) {
+		md := metadata.Pairs(DefaultAccessTokenMetadataKey, "access-token", DefaultIdTokenMetadataKey, "id-token")
+		stream := &fakeServerStream{ctx: metadata.NewIncomingContext(context.Background(), md)}
+		err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/my.Service/Private"}, handler)
+		require.NoError(t, err)
+		require.True(t, gotClaims)
+	})
+
+	t.Run("should skip authentication for allowed method", func(t *testing.T) {
+		stream := &fakeServerStream{ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs())}
+		err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch"}, handler)
+		require.NoError(t, err)
+		require.False(t, gotClaims)
+	})
+}
+
+func TestGrpcAuthInfoFrom(t *testing.T) {
+	_, err := GrpcAuthInfoFrom(context.Background())
+	require.ErrorIs(t, err, ErrorMissingAuthInfo)
+
+	env := setupServerInterceptorEnv()
+	md := metadata.Pairs(DefaultAccessTokenMetadataKey, "access-token", DefaultIdTokenMetadataKey, "id-token")
+	ctx, err := env.authenticator.Authenticate(metadata.NewIncomingContext(context.Background(), md))
+	require.NoError(t, err)
+
+	info, err := GrpcAuthInfoFrom(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "user:3", info.GetIdentity().Subject())
+}
@@ -0,0 +1,106 @@
+package authn
+
+import (
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/authlib/errs"
+)
+
+const (
+	DefaultAccessTokenHeader = "Authorization"
+	DefaultIDTokenHeader     = "X-Grafana-Id"
+)
+
+// HTTPErrorHandler writes the response of a request that failed authentication.
+type HTTPErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+type HTTPMiddlewareOption func(*HTTPMiddleware)
+
+// WithHTTPHeadersOption sets the headers the access token and the ID token are extracted from.
+// Defaults to "Authorization" and "X-Grafana-Id".
+func WithHTTPHeadersOption(accessTokenHeader, idTokenHeader string) HTTPMiddlewareOption {
+	return func(m *HTTPMiddleware) {
+		m.extractor = NewTokenExtractor(WithTokenHeadersOption(accessTokenHeader, idTokenHeader))
+	}
+}
+
+// WithHTTPTokenExtractorOption sets the extractor of the tokens of the requests, ex: to read them from cookies.
+func WithHTTPTokenExtractorOption(extractor *TokenExtractor) HTTPMiddlewareOption {
+	return func(m *HTTPMiddleware) {
+		m.extractor = extractor
+	}
+}
+
+// WithHTTPErrorHandlerOption sets the handler called when a request fails authentication.
+// By default, the request is rejected with a 401 or 403 status without details.
+func WithHTTPErrorHandlerOption(handler HTTPErrorHandler) HTTPMiddlewareOption {
+	return func(m *HTTPMiddleware) {
+		m.errorHandler = handler
+	}
+}
+
+// HTTPMiddleware authenticates HTTP requests with the same rules as the GrpcAuthenticator,
+// and stores the caller claims in the request context (see claims.AuthInfoFrom).
+// Tokens can be sent as is or using the Bearer scheme.
+type HTTPMiddleware struct {
+	authenticator *GrpcAuthenticator
+	extractor     *TokenExtractor
+	errorHandler  HTTPErrorHandler
+}
+
+// NewHTTPMiddleware creates a new HTTP middleware verifying tokens with the authenticator.
+func NewHTTPMiddleware(authenticator *GrpcAuthenticator, opts ...HTTPMiddlewareOption) *HTTPMiddleware {
+	m := &HTTPMiddleware{
+		authenticator: authenticator,
+		extractor:     NewTokenExtractor(),
+		errorHandler:  defaultHTTPErrorHandler,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Wrap returns a handler authenticating requests before calling next.
+func (m *HTTPMiddleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Reuse the gRPC authentication flow by exposing the tokens as incoming metadata
+		md := metadata.MD{}
+		tokens := m.extractor.FromRequest(r)
+		if tokens.AccessToken != "" {
+			md.Set(m.authenticator.cfg.AccessTokenMetadataKey, tokens.AccessToken)
+		}
+		if tokens.IDToken != "" {
+			md.Set(m.authenticator.cfg.IDTokenMetadataKey, tokens.IDToken)
+		}
+
+		ctx, err := m.authenticator.Authenticate(metadata.NewIncomingContext(r.Context(), md))
+		if err != nil {
+			m.errorHandler(w, r, err)
+			return
+		}
+
+		info, _ := claims.AuthInfoFrom(ctx)
+		next.ServeHTTP(w, r.WithContext(claims.WithAuthInfo(r.Context(), info)))
+	})
+}
+
+func bearerToken(value string) string {
+	const prefix = "Bearer "
+	if len(value) >= len(prefix) && strings.EqualFold(value[:len(prefix)], prefix) {
+		return strings.TrimSpace(value[len(prefix):])
+	}
+	return strings.TrimSpace(value)
+}
+
+func defaultHTTPErrorHandler(w http.ResponseWriter, _ *http.Request, err error) {
+	code := http.StatusUnauthorized
+	if errs.KindOf(err) == errs.KindForbidden {
+		code = http.StatusForbidden
+	}
+	http.Error(w, http.StatusText(code), code)
+}
@@ -0,0 +1,72 @@
+package authn
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/claims"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	env := setupServerInterceptorEnv()
+	middleware := NewHTTPMiddleware(env.authenticator)
+
+	var caller claims.AuthInfo
+	handler := middleware.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, _ = claims.AuthInfoFrom(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		wantCode int
+	}{
+		{
+			name:     "should reject request without access token",
+			headers:  map[string]string{DefaultIDTokenHeader: "id-token"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "should reject request with invalid access token",
+			headers:  map[string]string{DefaultAccessTokenHeader: "Bearer invalid"},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "should authenticate request",
+			headers:  map[string]string{DefaultAccessTokenHeader: "Bearer access-token", DefaultIDTokenHeader: "id-token"},
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller = nil
+			req := httptest.NewRequest(http.MethodGet, "/api/dashboards", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode != http.StatusOK {
+				require.Nil(t, caller)
+				return
+			}
+			require.NotNil(t, caller)
+			require.Equal(t, "access-policy:3", caller.GetAccess().Subject())
+			require.Equal(t, "user:3", caller.GetIdentity().Subject())
+		})
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	require.Equal(t, "token", bearerToken("Bearer token"))
+	require.Equal(t, "token", bearerToken("bearer token"))
+	require.Equal(t, "token", bearerToken("token"))
+	require.Equal(t, "", bearerToken(""))
+}
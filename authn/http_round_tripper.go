@@ -0,0 +1,132 @@
+package authn
+
+import (
+	"net/http"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/authlib/internal/logger"
+)
+
+var _ http.RoundTripper = &TokenRoundTripper{}
+
+type TokenRoundTripperOption func(*TokenRoundTripper)
+
+// WithTransportOption sets the round tripper performing the requests. Defaults to http.DefaultTransport.
+func WithTransportOption(transport http.RoundTripper) TokenRoundTripperOption {
+	return func(rt *TokenRoundTripper) {
+		rt.transport = transport
+	}
+}
+
+// WithRoundTripperHeadersOption sets the headers the access token and the ID token are attached to.
+// The access token uses the Bearer scheme when sent in the Authorization header.
+// Defaults to "Authorization" and "X-Grafana-Id".
+func WithRoundTripperHeadersOption(accessTokenHeader, idTokenHeader string) TokenRoundTripperOption {
+	return func(rt *TokenRoundTripper) {
+		rt.accessTokenHeader = accessTokenHeader
+		rt.idTokenHeader = idTokenHeader
+	}
+}
+
+// WithRoundTripperLoggerOption sets the logger of the requests retried with a new access token.
+func WithRoundTripperLoggerOption(logger Logger) TokenRoundTripperOption {
+	return func(rt *TokenRoundTripper) {
+		rt.logger = logger
+	}
+}
+
+// WithForwardCallerIDTokenOption forwards the ID token of the caller found in the request context (see claims.WithAuthInfo).
+func WithForwardCallerIDTokenOption() TokenRoundTripperOption {
+	return func(rt *TokenRoundTripper) {
+		rt.forwardIDToken = true
+	}
+}
+
+// TokenRoundTripper is an http.RoundTripper attaching an access token to every request.
+// Tokens are requested from the TokenProvider for the audience of the called service,
+// use a CachedTokenProvider to avoid issuing a new token per request.
+type TokenRoundTripper struct {
+	provider          TokenProvider
+	audience          string
+	transport         http.RoundTripper
+	accessTokenHeader string
+	idTokenHeader     string
+	forwardIDToken    bool
+	logger            Logger
+}
+
+// NewTokenRoundTripper creates a new round tripper attaching access tokens for the audience.
+func NewTokenRoundTripper(provider TokenProvider, audience string, opts ...TokenRoundTripperOption) *TokenRoundTripper {
+	rt := &TokenRoundTripper{
+		provider:          provider,
+		audience:          audience,
+		transport:         http.DefaultTransport,
+		accessTokenHeader: DefaultAccessTokenHeader,
+		idTokenHeader:     DefaultIDTokenHeader,
+		logger:            logger.Nop{},
+	}
+	for _, opt := range opts {
+		opt(rt)
+	}
+	return rt
+}
+
+// RoundTrip attaches the tokens to the request. Requests rejected as Unauthorized because the access token
+// expired in flight are retried once with a new token, when the token provider is refreshable and the body can be replayed.
+func (rt *TokenRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := rt.provider.Token(req.Context(), rt.audience)
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+
+	resp, err := rt.roundTrip(req, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !rt.canRetry(req, token) {
+		return resp, err
+	}
+
+	// The access token expired in flight, retry once with a new one
+	rt.logger.Debug("retrying with a new access token", "url", req.URL.Redacted())
+	rt.provider.(RefreshableTokenProvider).Invalidate(req.Context(), rt.audience, token)
+	token, err = rt.provider.Token(req.Context(), rt.audience)
+	if err != nil {
+		return resp, nil
+	}
+	req = req.Clone(req.Context())
+	if req.GetBody != nil {
+		if req.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	_ = resp.Body.Close()
+	return rt.roundTrip(req, token)
+}
+
+func (rt *TokenRoundTripper) roundTrip(req *http.Request, token string) (*http.Response, error) {
+	// RoundTrippers must not modify the request
+	req = req.Clone(req.Context())
+	if rt.accessTokenHeader == DefaultAccessTokenHeader {
+		token = "Bearer " + token
+	}
+	req.Header.Set(rt.accessTokenHeader, token)
+
+	if rt.forwardIDToken {
+		if caller, ok := claims.AuthInfoFrom(req.Context()); ok {
+			if idToken := caller.GetExtra()["id-token"]; len(idToken) > 0 {
+				req.Header.Set(rt.idTokenHeader, idToken[0])
+			}
+		}
+	}
+
+	return rt.transport.RoundTrip(req)
+}
+
+// canRetry returns whether the request rejected with the token can be retried with a new token.
+func (rt *TokenRoundTripper) canRetry(req *http.Request, token string) bool {
+	if _, ok := rt.provider.(RefreshableTokenProvider); !ok || !tokenExpiring(token) {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
@@ -0,0 +1,64 @@
+package authn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/claims"
+)
+
+func TestTokenRoundTripper(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	provider := &fakeTokenProvider{}
+	client := &http.Client{Transport: NewTokenRoundTripper(provider, "some-service", WithForwardCallerIDTokenOption())}
+
+	t.Run("should attach access token", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		require.Equal(t, "Bearer provided-token", headers.Get(DefaultAccessTokenHeader))
+		require.Empty(t, headers.Get(DefaultIDTokenHeader))
+		require.Empty(t, req.Header.Get(DefaultAccessTokenHeader))
+		require.Equal(t, "some-service", provider.audiences[0])
+	})
+
+	t.Run("should forward caller ID token", func(t *testing.T) {
+		ctx := claims.WithAuthInfo(context.Background(), &AuthInfo{
+			IdentityClaims: NewIdentityClaims(Claims[IDTokenClaims]{Claims: &jwt.Claims{Subject: "user:1"}, token: "caller-id-token"}),
+		})
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		require.Equal(t, "Bearer provided-token", headers.Get(DefaultAccessTokenHeader))
+		require.Equal(t, "caller-id-token", headers.Get(DefaultIDTokenHeader))
+	})
+
+	t.Run("should use custom headers", func(t *testing.T) {
+		client := &http.Client{Transport: NewTokenRoundTripper(provider, "some-service", WithRoundTripperHeadersOption("X-Access-Token", "X-Id-Token"))}
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+
+		require.Equal(t, "provided-token", headers.Get("X-Access-Token"))
+	})
+}
@@ -0,0 +1,104 @@
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/grafana/authlib/internal/httpclient"
+)
+
+// Introspector returns the state of opaque tokens, see IntrospectionClient.
+type Introspector interface {
+	Introspect(ctx context.Context, token string) (*IntrospectionResponse, error)
+}
+
+// IntrospectionResponse is the response of an OAuth 2.0 token introspection endpoint (RFC 7662).
+type IntrospectionResponse struct {
+	// Active is whether the token is valid. The other fields are only set for the active tokens.
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	// The registered claims of the token: iss, sub, aud, exp, nbf, iat and jti.
+	jwt.Claims
+
+	// raw is the response, to decode the custom claims of the token
+	raw json.RawMessage
+}
+
+var _ Introspector = &IntrospectionClient{}
+
+// IntrospectionClientOpts allows setting custom parameters during construction.
+type IntrospectionClientOpts func(c *IntrospectionClient)
+
+// WithIntrospectionHTTPClient allows setting the HTTP client to be used by the introspection client.
+func WithIntrospectionHTTPClient(client *http.Client) IntrospectionClientOpts {
+	return func(c *IntrospectionClient) {
+		c.client = client
+	}
+}
+
+func NewIntrospectionClient(cfg IntrospectionConfig, opts ...IntrospectionClientOpts) (*IntrospectionClient, error) {
+	if cfg.IntrospectionURL == "" {
+		return nil, fmt.Errorf("missing required introspection url")
+	}
+
+	c := &IntrospectionClient{cfg: cfg}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.client == nil {
+		c.client = httpclient.New()
+	}
+
+	return c, nil
+}
+
+// IntrospectionClient calls an OAuth 2.0 token introspection endpoint (RFC 7662), authenticated with the client
+// credentials, if any.
+type IntrospectionClient struct {
+	cfg    IntrospectionConfig
+	client *http.Client
+}
+
+func (c *IntrospectionClient) Introspect(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.IntrospectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIntrospection, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrIntrospection, res.Status)
+	}
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIntrospection, err)
+	}
+	response := IntrospectionResponse{raw: raw}
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIntrospection, err)
+	}
+	return &response, nil
+}
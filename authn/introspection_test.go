@@ -0,0 +1,95 @@
+package authn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_Introspection(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "client", user)
+		require.Equal(t, "secret", pass)
+		require.NoError(t, r.ParseForm())
+
+		switch r.PostForm.Get("token") {
+		case "opaque":
+			_, _ = w.Write([]byte(`{"active": true, "sub": "access-policy:1", "aud": "stack:1", "exp": ` + strconv.FormatInt(exp, 10) +
+				`, "namespace": "stacks-1", "scopes": ["folders:read"]}`))
+		case "opaque-id":
+			_, _ = w.Write([]byte(`{"active": true, "token_type": "id_token", "sub": "user:1", "aud": "stack:1", "exp": ` + strconv.FormatInt(exp, 10) +
+				`, "namespace": "stacks-1"}`))
+		case "inactive":
+			_, _ = w.Write([]byte(`{"active": false}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	introspector, err := NewIntrospectionClient(IntrospectionConfig{
+		IntrospectionURL: server.URL,
+		ClientID:         "client",
+		ClientSecret:     "secret",
+	}, WithIntrospectionHTTPClient(server.Client()))
+	require.NoError(t, err)
+
+	verifier := NewAccessTokenVerifier(VerifierConfig{AllowedAudiences: jwt.Audience{"stack:1"}}, nil, WithIntrospection(introspector))
+	ctx := context.Background()
+
+	t.Run("should map the introspection response into the claims", func(t *testing.T) {
+		claims, err := verifier.Verify(ctx, "opaque")
+		require.NoError(t, err)
+		require.Equal(t, "access-policy:1", claims.Subject)
+		require.Equal(t, "stacks-1", claims.Rest.Namespace)
+		require.Equal(t, []string{"folders:read"}, claims.Rest.Scopes)
+	})
+
+	t.Run("should reject the inactive tokens", func(t *testing.T) {
+		_, err := verifier.Verify(ctx, "inactive")
+		require.ErrorIs(t, err, ErrInactiveToken)
+		require.True(t, IsInvalidTokenErr(err))
+	})
+
+	t.Run("should validate the audience", func(t *testing.T) {
+		other := NewAccessTokenVerifier(VerifierConfig{AllowedAudiences: jwt.Audience{"stack:2"}}, nil, WithIntrospection(introspector))
+		_, err := other.Verify(ctx, "opaque")
+		require.ErrorIs(t, err, ErrInvalidAudience)
+	})
+
+	t.Run("should fail when the introspection fails", func(t *testing.T) {
+		_, err := verifier.Verify(ctx, "unknown")
+		require.ErrorIs(t, err, ErrIntrospection)
+	})
+
+	t.Run("should validate the token type", func(t *testing.T) {
+		_, err := verifier.Verify(ctx, "opaque-id")
+		require.ErrorIs(t, err, ErrInvalidTokenType)
+
+		idVerifier := NewIDTokenVerifier(VerifierConfig{AllowedAudiences: jwt.Audience{"stack:1"}}, nil, WithIntrospection(introspector))
+		claims, err := idVerifier.Verify(ctx, "opaque-id")
+		require.NoError(t, err)
+		require.Equal(t, "user:1", claims.Subject)
+		_, err = idVerifier.Verify(ctx, "opaque")
+		require.ErrorIs(t, err, ErrInvalidTokenType)
+	})
+
+	t.Run("should not introspect the malformed JWTs", func(t *testing.T) {
+		_, err := verifier.Verify(ctx, "not.a.jwt")
+		require.ErrorIs(t, err, ErrParseToken)
+	})
+
+	t.Run("should reject the opaque tokens without introspection", func(t *testing.T) {
+		_, err := NewAccessTokenVerifier(VerifierConfig{}, nil).Verify(ctx, "opaque")
+		require.ErrorIs(t, err, ErrParseToken)
+	})
+}
@@ -4,14 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/go-jose/go-jose/v3"
 	"golang.org/x/sync/singleflight"
 
 	"github.com/grafana/authlib/cache"
+	"github.com/grafana/authlib/internal/logger"
 )
 
 type KeyRetriever interface {
@@ -24,6 +27,14 @@ type DefaultKeyRetrieverOption func(*DefaultKeyRetriever)
 func WithHTTPClientKeyRetrieverOpt(client *http.Client) DefaultKeyRetrieverOption {
 	return func(c *DefaultKeyRetriever) {
 		c.client = client
+		c.customClient = true
+	}
+}
+
+// WithLoggerKeyRetrieverOpt sets the logger of the failures to decode or cache the signing keys.
+func WithLoggerKeyRetrieverOpt(logger Logger) DefaultKeyRetrieverOption {
+	return func(c *DefaultKeyRetriever) {
+		c.logger = logger
 	}
 }
 
@@ -34,57 +45,126 @@ const (
 
 func NewKeyRetriever(cfg KeyRetrieverConfig, opt ...DefaultKeyRetrieverOption) *DefaultKeyRetriever {
 	s := &DefaultKeyRetriever{
-		cfg: cfg,
-		c: cache.NewLocalCache(cache.Config{
-			Expiry:          cacheTTL,
-			CleanupInterval: cacheCleanupInterval,
-		}),
-		client: http.DefaultClient,
+		cfg:    cfg,
+		c:      newKeyCache(),
 		s:      &singleflight.Group{},
+		logger: logger.Nop{},
 	}
 
 	for _, o := range opt {
 		o(s)
 	}
+	if !s.customClient {
+		s.client, s.clientErr = keyRetrieverClient(cfg.TLS)
+	}
 	return s
 }
 
 type DefaultKeyRetriever struct {
-	cfg    KeyRetrieverConfig
-	client *http.Client
 	s      *singleflight.Group
-	c      cache.Cache
+	logger Logger
+
+	// mtx protects the configuration and the keys which are swapped on reload
+	mtx sync.RWMutex
+	cfg KeyRetrieverConfig
+	c   cache.LoaderCache
+	// client is built from the TLS configuration, unless customClient is set.
+	// clientErr is returned by the requests when the TLS configuration is invalid.
+	client       *http.Client
+	customClient bool
+	clientErr    error
+	// discovered is the jwks_uri of the discovery document of the issuer, until discoveredExpiry
+	discovered       string
+	discoveredExpiry time.Time
+}
+
+// errUnknownKey is returned by the loader of the keys missing from the signing keys.
+var errUnknownKey = errors.New("unknown signing key")
+
+// keyCacheVersion is the version of the encoding of the cached signing keys.
+const keyCacheVersion = 1
+
+func newKeyCache() cache.LoaderCache {
+	return cache.Loading(cache.Versioned(cache.NewLocalCache(cache.Config{
+		Expiry:          cacheTTL,
+		CleanupInterval: cacheCleanupInterval,
+	}), keyCacheVersion))
+}
+
+// Reload atomically replaces the key retriever configuration.
+// When the signing keys URL or the issuer changes, the keys fetched from the previous URL are dropped.
+func (s *DefaultKeyRetriever) Reload(cfg KeyRetrieverConfig) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if cfg.SigningKeysURL != s.cfg.SigningKeysURL || cfg.Issuer != s.cfg.Issuer {
+		s.c = newKeyCache()
+		s.discovered, s.discoveredExpiry = "", time.Time{}
+	}
+	if !s.customClient && cfg.TLS != s.cfg.TLS {
+		s.client, s.clientErr = keyRetrieverClient(cfg.TLS)
+	}
+	s.cfg = cfg
+}
+
+func keyRetrieverClient(cfg TLSConfig) (*http.Client, error) {
+	if cfg.IsZero() {
+		return http.DefaultClient, nil
+	}
+	return cfg.HTTPClient()
+}
+
+// httpClient returns the client of the requests, or the error of the TLS configuration.
+func (s *DefaultKeyRetriever) httpClient() (*http.Client, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.client, s.clientErr
 }
 
 func (s *DefaultKeyRetriever) Get(ctx context.Context, keyID string) (*jose.JSONWebKey, error) {
-	jwk, ok := s.getCachedItem(ctx, keyID)
-	if !ok {
-		_, err, _ := s.s.Do("fetch", func() (interface{}, error) {
-			jwks, err := s.fetchJWKS(ctx)
+	url, c, err := s.signingKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.GetOrSet(ctx, keyID, cache.NoExpiration, func() ([]byte, error) {
+		_, err, _ := s.s.Do("fetch-"+url, func() (interface{}, error) {
+			jwks, err := s.fetchJWKS(ctx, url)
 			if err != nil {
 				return nil, err
 			}
 
 			for i := range jwks.Keys {
-				s.setCachedItem(ctx, jwks.Keys[i])
+				s.setCachedItem(ctx, c, jwks.Keys[i])
 			}
 
 			return nil, nil
 		})
-
 		if err != nil {
 			return nil, err
 		}
 
-		jwk, ok = s.getCachedItem(ctx, keyID)
-		if !ok {
-			// Key still don't exist after a re-fetch.
-			// Cache the invalid key to prevent re-fetch
-			// for known invalid keys.
-			s.setEmptyCacheItem(ctx, keyID)
+		data, err := c.Get(ctx, keyID)
+		if err != nil {
+			return nil, errUnknownKey
 		}
+		return data, nil
+	})
+
+	if errors.Is(err, errUnknownKey) {
+		// Key still don't exist after a re-fetch.
+		// Cache the invalid key to prevent re-fetch
+		// for known invalid keys.
+		s.setEmptyCacheItem(ctx, c, keyID)
+		return nil, ErrInvalidSigningKey
+	}
+	if errors.Is(err, cache.ErrWrite) {
+		s.logger.Warn("failed to cache the signing key", "kid", keyID, "error", err)
+	} else if err != nil {
+		return nil, err
 	}
 
+	jwk := s.decodeItem(keyID, data)
 	if jwk == nil {
 		return nil, ErrInvalidSigningKey
 	}
@@ -92,13 +172,18 @@ func (s *DefaultKeyRetriever) Get(ctx context.Context, keyID string) (*jose.JSON
 	return jwk, nil
 }
 
-func (s *DefaultKeyRetriever) fetchJWKS(ctx context.Context) (*jose.JSONWebKeySet, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", s.cfg.SigningKeysURL, nil)
+func (s *DefaultKeyRetriever) fetchJWKS(ctx context.Context, url string) (*jose.JSONWebKeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := s.client.Do(req)
+	client, err := s.httpClient()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFetchingSigningKey, err)
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("%w: request error", ErrFetchingSigningKey)
 	}
@@ -110,44 +195,44 @@ func (s *DefaultKeyRetriever) fetchJWKS(ctx context.Context) (*jose.JSONWebKeySe
 
 	var jwks jose.JSONWebKeySet
 	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		s.logger.Warn("failed to decode the signing keys", "url", url, "error", err)
 		return nil, fmt.Errorf("%w: unable to decode response", ErrFetchingSigningKey)
 	}
 
 	return &jwks, nil
 }
 
-func (s *DefaultKeyRetriever) getCachedItem(ctx context.Context, keyID string) (*jose.JSONWebKey, bool) {
-	data, err := s.c.Get(ctx, keyID)
-	// error is a noop for local cache
-	if err != nil {
-		return nil, false
-	}
-
+// decodeItem decodes the cached signing key, or returns nil for the invalid keys.
+func (s *DefaultKeyRetriever) decodeItem(keyID string, data []byte) *jose.JSONWebKey {
 	// we cache invalid keys as a empty byte slice
 	if len(data) == 0 {
-		return nil, true
+		return nil
 	}
 
 	var jwk jose.JSONWebKey
-	// We should not fail to decode the jwk, all items in the cache are gob encoded [jose.JSONWebKey].
+	// We should not fail to decode the jwk, all items in the cache are json encoded [jose.JSONWebKey].
 	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&jwk); err != nil {
-		return nil, false
+		s.logger.Warn("failed to decode the cached signing key", "kid", keyID, "error", err)
+		return nil
 	}
 
-	return &jwk, true
+	return &jwk
 }
 
-func (s *DefaultKeyRetriever) setCachedItem(ctx context.Context, key jose.JSONWebKey) {
+func (s *DefaultKeyRetriever) setCachedItem(ctx context.Context, c cache.Cache, key jose.JSONWebKey) {
 	buf := bytes.Buffer{}
 	if err := json.NewEncoder(&buf).Encode(&key); err != nil {
+		s.logger.Warn("failed to encode the signing key", "kid", key.KeyID, "error", err)
 		return
 	}
 
-	// Set cannot fail when using local cache
-	_ = s.c.Set(ctx, key.KeyID, buf.Bytes(), cache.NoExpiration)
+	if err := c.Set(ctx, key.KeyID, buf.Bytes(), cache.NoExpiration); err != nil {
+		s.logger.Warn("failed to cache the signing key", "kid", key.KeyID, "error", err)
+	}
 }
 
-func (s *DefaultKeyRetriever) setEmptyCacheItem(ctx context.Context, keyID string) {
-	// Set cannot fail when using local cache
-	_ = s.c.Set(ctx, keyID, []byte{}, cacheTTL)
+func (s *DefaultKeyRetriever) setEmptyCacheItem(ctx context.Context, c cache.Cache, keyID string) {
+	if err := c.Set(ctx, keyID, []byte{}, cacheTTL); err != nil {
+		s.logger.Warn("failed to cache the unknown signing key", "kid", keyID, "error", err)
+	}
 }
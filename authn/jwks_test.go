@@ -1,9 +1,11 @@
 package authn
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -66,3 +68,92 @@ func TestDefaultKeyRetriever_Get(t *testing.T) {
 		}
 	})
 }
+
+func TestDefaultKeyRetriever_Reload(t *testing.T) {
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{KeyID: firstKeyID, Key: firstKey.Public(), Algorithm: string(jose.ES256)},
+		}})
+	}))
+	second := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{KeyID: secondKeyId, Key: secondKey.Public(), Algorithm: string(jose.ES256)},
+		}})
+	}))
+
+	service := NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: first.URL})
+
+	key, err := service.Get(context.Background(), firstKeyID)
+	require.NoError(t, err)
+	require.NotNil(t, key)
+
+	_, err = service.Get(context.Background(), secondKeyId)
+	require.ErrorIs(t, err, ErrInvalidSigningKey)
+
+	service.Reload(KeyRetrieverConfig{SigningKeysURL: second.URL})
+
+	t.Run("should fetch keys from the new URL", func(t *testing.T) {
+		key, err := service.Get(context.Background(), secondKeyId)
+		require.NoError(t, err)
+		assert.Equal(t, secondKeyId, key.KeyID)
+	})
+
+	t.Run("should drop keys from the previous URL", func(t *testing.T) {
+		_, err := service.Get(context.Background(), firstKeyID)
+		require.ErrorIs(t, err, ErrInvalidSigningKey)
+	})
+}
+
+func TestDefaultKeyRetriever_Logger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+
+	buf := &bytes.Buffer{}
+	service := NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL},
+		WithLoggerKeyRetrieverOpt(slog.New(slog.NewTextHandler(buf, nil))))
+
+	_, err := service.Get(context.Background(), firstKeyID)
+	require.ErrorIs(t, err, ErrFetchingSigningKey)
+	require.Contains(t, buf.String(), "failed to decode the signing keys")
+}
+
+func TestDefaultKeyRetriever_Discovery(t *testing.T) {
+	var discoveries, fetches int
+	var issuer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case discoveryPath:
+			discoveries++
+			_, _ = fmt.Fprintf(w, `{"issuer": %q, "jwks_uri": %q}`, issuer, issuer+"/keys")
+		case "/keys":
+			fetches++
+			_, _ = w.Write(keys())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	issuer = server.URL
+
+	service := NewKeyRetriever(KeyRetrieverConfig{Issuer: server.URL + "/"})
+
+	key, err := service.Get(context.Background(), firstKeyID)
+	require.NoError(t, err)
+	require.Equal(t, firstKeyID, key.KeyID)
+	_, err = service.Get(context.Background(), "invalid")
+	require.ErrorIs(t, err, ErrInvalidSigningKey)
+	require.Equal(t, 1, discoveries, "should cache the discovery document")
+	require.Equal(t, 2, fetches)
+
+	t.Run("should reject the discovery document of another issuer", func(t *testing.T) {
+		issuer = "https://other.example.com"
+		service := NewKeyRetriever(KeyRetrieverConfig{Issuer: server.URL})
+		_, err := service.Get(context.Background(), firstKeyID)
+		require.ErrorIs(t, err, ErrFetchingSigningKey)
+	})
+}
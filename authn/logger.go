@@ -0,0 +1,7 @@
+package authn
+
+import "github.com/grafana/authlib/internal/logger"
+
+// Logger surfaces the internal failures that do not fail the requests (ex: cache errors or token retries)
+// to the logging of the application. *slog.Logger implements it. Logs are discarded by default.
+type Logger = logger.Logger
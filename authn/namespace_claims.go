@@ -0,0 +1,39 @@
+package authn
+
+import (
+	"context"
+
+	"github.com/grafana/authlib/claims"
+)
+
+// namespaceClaims are the custom claims carrying the namespace of the token.
+type namespaceClaims interface {
+	namespace() string
+}
+
+func (c AccessTokenClaims) namespace() string { return c.Namespace }
+
+func (c IDTokenClaims) namespace() string { return c.Namespace }
+
+// namespaced adapts a namespace claim to claims.Namespaced.
+type namespaced string
+
+func (n namespaced) Namespace() string { return string(n) }
+
+// validateNamespace rejects the tokens whose namespace does not match the expected namespace, if any.
+// The tokens without namespace claim are rejected when a namespace is expected.
+func validateNamespace(ctx context.Context, cfg *VerifierConfig, rest any) error {
+	expected := cfg.ExpectedNamespace
+	if cfg.ExpectedNamespaceFunc != nil {
+		expected = cfg.ExpectedNamespaceFunc(ctx)
+	}
+	if expected == "" {
+		return nil
+	}
+
+	c, ok := rest.(namespaceClaims)
+	if !ok || !claims.NamespaceMatches(namespaced(c.namespace()), expected) {
+		return ErrNamespaceMismatch
+	}
+	return nil
+}
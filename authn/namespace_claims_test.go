@@ -0,0 +1,64 @@
+package authn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_ExpectedNamespace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(keys())
+	}))
+	defer server.Close()
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: firstKey}, &jose.SignerOptions{
+		ExtraHeaders: map[jose.HeaderKey]interface{}{"kid": firstKeyID, "typ": TokenTypeAccess},
+	})
+	require.NoError(t, err)
+	token, err := jwt.Signed(signer).
+		Claims(jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Minute))}).
+		Claims(map[string]any{"namespace": "stacks-1"}).
+		CompactSerialize()
+	require.NoError(t, err)
+
+	retriever := NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL})
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		cfg     VerifierConfig
+		wantErr error
+	}{
+		{name: "no expected namespace", cfg: VerifierConfig{}},
+		{name: "matching namespace", cfg: VerifierConfig{ExpectedNamespace: "stacks-1"}},
+		{name: "other namespace", cfg: VerifierConfig{ExpectedNamespace: "stacks-2"}, wantErr: ErrNamespaceMismatch},
+		{
+			name:    "namespace of the request",
+			cfg:     VerifierConfig{ExpectedNamespace: "stacks-1", ExpectedNamespaceFunc: func(context.Context) string { return "stacks-2" }},
+			wantErr: ErrNamespaceMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAccessTokenVerifier(tt.cfg, retriever).Verify(ctx, token)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+
+	t.Run("should reject the tokens without namespace claim", func(t *testing.T) {
+		type CustomClaims struct{}
+		_, err := NewVerifier[CustomClaims](VerifierConfig{ExpectedNamespace: "stacks-1"}, TokenTypeAccess, retriever).Verify(ctx, token)
+		require.ErrorIs(t, err, ErrNamespaceMismatch)
+	})
+}
@@ -0,0 +1,97 @@
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grafana/authlib/cache"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	discoveryTTL  = time.Hour
+)
+
+// discoveryDocument is the subset of the OpenID Provider metadata used to retrieve the signing keys.
+type discoveryDocument struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// signingKeys returns the URL of the signing keys with their cache. The URL is the configured signing keys URL,
+// or the jwks_uri of the discovery document of the issuer, which is cached for an hour. The previous jwks_uri is
+// still used when the discovery document cannot be refreshed.
+func (s *DefaultKeyRetriever) signingKeys(ctx context.Context) (string, cache.LoaderCache, error) {
+	s.mtx.RLock()
+	cfg, c, discovered, expiry := s.cfg, s.c, s.discovered, s.discoveredExpiry
+	s.mtx.RUnlock()
+
+	if cfg.SigningKeysURL != "" || cfg.Issuer == "" {
+		return cfg.SigningKeysURL, c, nil
+	}
+	if discovered != "" && time.Now().Before(expiry) {
+		return discovered, c, nil
+	}
+
+	url, err, _ := s.s.Do("discover-"+cfg.Issuer, func() (interface{}, error) {
+		return s.discover(ctx, cfg.Issuer)
+	})
+	if err != nil {
+		if discovered != "" {
+			s.logger.Warn("failed to refresh the discovery document", "issuer", cfg.Issuer, "error", err)
+			return discovered, c, nil
+		}
+		return "", nil, err
+	}
+
+	s.mtx.Lock()
+	if s.cfg.Issuer == cfg.Issuer {
+		s.discovered, s.discoveredExpiry = url.(string), time.Now().Add(discoveryTTL)
+	}
+	s.mtx.Unlock()
+
+	return url.(string), c, nil
+}
+
+// discover returns the jwks_uri of the discovery document of the issuer.
+func (s *DefaultKeyRetriever) discover(ctx context.Context, issuer string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(issuer, "/")+discoveryPath, nil)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := s.httpClient()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrFetchingSigningKey, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: discovery request error", ErrFetchingSigningKey)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: discovery returned %s", ErrFetchingSigningKey, resp.Status)
+	}
+
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		s.logger.Warn("failed to decode the discovery document", "issuer", issuer, "error", err)
+		return "", fmt.Errorf("%w: unable to decode discovery document", ErrFetchingSigningKey)
+	}
+
+	// The issuer of the discovery document must be the configured issuer (OpenID Connect Discovery 1.0, 4.3)
+	if strings.TrimRight(doc.Issuer, "/") != strings.TrimRight(issuer, "/") {
+		return "", fmt.Errorf("%w: discovery document issuer %q does not match", ErrFetchingSigningKey, doc.Issuer)
+	}
+	if doc.JWKSURI == "" {
+		return "", fmt.Errorf("%w: discovery document without jwks_uri", ErrFetchingSigningKey)
+	}
+
+	return doc.JWKSURI, nil
+}
@@ -0,0 +1,35 @@
+package authn
+
+// reloader is implemented by the components whose configuration can be replaced at runtime.
+type reloader[C any] interface {
+	Reload(cfg C)
+}
+
+var (
+	_ reloader[VerifierConfig]     = &VerifierBase[any]{}
+	_ reloader[VerifierConfig]     = &UnsafeVerifierBase[any]{}
+	_ reloader[VerifierConfig]     = &AccessTokenVerifier{}
+	_ reloader[VerifierConfig]     = &IDTokenVerifier{}
+	_ reloader[KeyRetrieverConfig] = &DefaultKeyRetriever{}
+)
+
+// Reload replaces the key retriever and verifiers configuration at runtime,
+// allowing to rotate the signing keys URL or the allowed audiences without a restart.
+// The metadata keys and the enabled authentication methods are not reloaded.
+// Key retrievers provided with WithKeyRetrieverOption are only reloaded if they implement Reload(KeyRetrieverConfig).
+func (ga *GrpcAuthenticator) Reload(cfg GrpcAuthenticatorConfig) {
+	if r, ok := ga.keyRetriever.(reloader[KeyRetrieverConfig]); ok {
+		r.Reload(cfg.KeyRetrieverConfig)
+	}
+
+	if r, ok := ga.atVerifier.(reloader[VerifierConfig]); ok {
+		r.Reload(cfg.VerifierConfig)
+	}
+
+	if r, ok := ga.idVerifier.(reloader[VerifierConfig]); ok {
+		// Skip audience checks for ID tokens (reset AllowedAudiences)
+		verifierConfig := cfg.VerifierConfig
+		verifierConfig.AllowedAudiences = []string{}
+		r.Reload(verifierConfig)
+	}
+}
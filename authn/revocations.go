@@ -0,0 +1,188 @@
+package authn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/grafana/authlib/internal/logger"
+)
+
+// Revocations are consulted by the verifier after validating the tokens, to reject the compromised tokens
+// before they expire. See WithRevocations.
+type Revocations interface {
+	// IsRevoked returns whether the token, with the claims and signed with the key, was revoked.
+	// The key ID is empty for the opaque tokens verified by introspection.
+	IsRevoked(ctx context.Context, keyID string, claims *jwt.Claims) (bool, error)
+}
+
+// WithRevocations rejects the tokens revoked by the revocations with ErrRevokedToken, including the tokens
+// cached by WithVerifiedTokenCache.
+func WithRevocations(revocations Revocations) VerifierOption {
+	return func(o *verifierOptions) {
+		o.revocations = revocations
+	}
+}
+
+const DefaultRevocationPollInterval = 30 * time.Second
+
+// revocationList is the list served by the revocation list URL.
+type revocationList struct {
+	// Tokens are the IDs (jti) of the revoked tokens.
+	Tokens []string `json:"tokens"`
+	// Keys are the IDs (kid) of the purged signing keys, revoking all the tokens they signed.
+	Keys []string `json:"keys"`
+	// Subjects revokes the tokens of the subjects issued before the time, in seconds since the epoch.
+	Subjects map[string]int64 `json:"subjects"`
+}
+
+type RevocationListOption func(*RevocationList)
+
+// WithHTTPClientRevocationListOpt allows setting the HTTP client to be used by the revocation list.
+func WithHTTPClientRevocationListOpt(client *http.Client) RevocationListOption {
+	return func(r *RevocationList) {
+		r.client = client
+	}
+}
+
+// WithLoggerRevocationListOpt sets the logger of the failures to refresh the revocation list.
+func WithLoggerRevocationListOpt(logger Logger) RevocationListOption {
+	return func(r *RevocationList) {
+		r.logger = logger
+	}
+}
+
+var _ Revocations = &RevocationList{}
+
+func NewRevocationList(cfg RevocationListConfig, opts ...RevocationListOption) (*RevocationList, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("missing required revocation list url")
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultRevocationPollInterval
+	}
+
+	r := &RevocationList{
+		cfg:    cfg,
+		client: http.DefaultClient,
+		logger: logger.Nop{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// RevocationList polls a revocation list of token IDs, signing key IDs and subjects. The list is served as JSON:
+//
+//	{"tokens": ["<jti>"], "keys": ["<kid>"], "subjects": {"user:1": <revoked before, in seconds>}}
+//
+// Run polls the list, the previous list is used while the list cannot be refreshed.
+// Until the list is fetched for the first time, no token is revoked (fail open), unless FailClosed is set,
+// in which case IsRevoked returns ErrRevocationsNotReady. Ready reports whether the list was fetched.
+type RevocationList struct {
+	cfg    RevocationListConfig
+	client *http.Client
+	logger Logger
+
+	mtx      sync.RWMutex
+	ready    bool
+	tokens   map[string]bool
+	keys     map[string]bool
+	subjects map[string]time.Time
+}
+
+// Run refreshes the revocation list every poll interval, until the context is canceled.
+func (r *RevocationList) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Refresh(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Warn("failed to refresh the revocation list", "url", r.cfg.URL, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh fetches the revocation list.
+func (r *RevocationList) Refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.cfg.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: request error", ErrFetchingRevocations)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s", ErrFetchingRevocations, resp.Status)
+	}
+
+	var list revocationList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return fmt.Errorf("%w: unable to decode response", ErrFetchingRevocations)
+	}
+
+	tokens := make(map[string]bool, len(list.Tokens))
+	for _, id := range list.Tokens {
+		tokens[id] = true
+	}
+	keys := make(map[string]bool, len(list.Keys))
+	for _, id := range list.Keys {
+		keys[id] = true
+	}
+	subjects := make(map[string]time.Time, len(list.Subjects))
+	for subject, before := range list.Subjects {
+		subjects[subject] = time.Unix(before, 0)
+	}
+
+	r.mtx.Lock()
+	r.tokens, r.keys, r.subjects, r.ready = tokens, keys, subjects, true
+	r.mtx.Unlock()
+	return nil
+}
+
+// Ready reports whether the revocation list was fetched at least once, ex: for a readiness probe.
+func (r *RevocationList) Ready() bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.ready
+}
+
+func (r *RevocationList) IsRevoked(_ context.Context, keyID string, claims *jwt.Claims) (bool, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	if !r.ready && r.cfg.FailClosed {
+		return false, ErrRevocationsNotReady
+	}
+
+	if keyID != "" && r.keys[keyID] {
+		return true, nil
+	}
+	if claims == nil {
+		return false, nil
+	}
+	if claims.ID != "" && r.tokens[claims.ID] {
+		return true, nil
+	}
+	if before, ok := r.subjects[claims.Subject]; ok && claims.Subject != "" {
+		// The tokens without issue time cannot be told apart, and are revoked
+		return claims.IssuedAt == nil || claims.IssuedAt.Time().Before(before), nil
+	}
+	return false, nil
+}
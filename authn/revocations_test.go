@@ -0,0 +1,103 @@
+package authn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationList(t *testing.T) {
+	list := `{}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/keys" {
+			_, _ = w.Write(keys())
+			return
+		}
+		_, _ = w.Write([]byte(list))
+	}))
+	defer server.Close()
+
+	revocations, err := NewRevocationList(RevocationListConfig{URL: server.URL}, WithHTTPClientRevocationListOpt(server.Client()))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("should fail open before the first refresh by default", func(t *testing.T) {
+		require.False(t, revocations.Ready())
+		ok, err := revocations.IsRevoked(ctx, firstKeyID, &jwt.Claims{ID: "jti-1"})
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("should fail closed before the first refresh when configured", func(t *testing.T) {
+		closed, err := NewRevocationList(RevocationListConfig{URL: server.URL, FailClosed: true}, WithHTTPClientRevocationListOpt(server.Client()))
+		require.NoError(t, err)
+		_, err = closed.IsRevoked(ctx, firstKeyID, &jwt.Claims{ID: "jti-1"})
+		require.ErrorIs(t, err, ErrRevocationsNotReady)
+
+		require.NoError(t, closed.Refresh(ctx))
+		require.True(t, closed.Ready())
+		ok, err := closed.IsRevoked(ctx, firstKeyID, &jwt.Claims{ID: "jti-1"})
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	type CustomClaims struct{}
+	verifier := NewVerifier[CustomClaims](VerifierConfig{}, TokenTypeID,
+		NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL + "/keys"}),
+		WithRevocations(revocations), WithVerifiedTokenCache(0))
+
+	t.Run("should revoke the tokens signed with the purged keys", func(t *testing.T) {
+		token := signFirst(t)
+		require.NoError(t, revocations.Refresh(ctx))
+		_, err := verifier.Verify(ctx, token)
+		require.NoError(t, err)
+
+		list = `{"keys": ["` + firstKeyID + `"]}`
+		require.NoError(t, revocations.Refresh(ctx))
+		_, err = verifier.Verify(ctx, token)
+		require.ErrorIs(t, err, ErrRevokedToken, "should revoke the cached tokens")
+		require.True(t, IsInvalidTokenErr(err))
+
+		_, err = verifier.Verify(ctx, signSecond(t))
+		require.NoError(t, err)
+	})
+
+	t.Run("should revoke the tokens by id and subject", func(t *testing.T) {
+		list = `{"tokens": ["jti-1"], "subjects": {"user:1": 1700000000}}`
+		require.NoError(t, revocations.Refresh(ctx))
+
+		revoked := func(claims jwt.Claims) bool {
+			ok, err := revocations.IsRevoked(ctx, secondKeyId, &claims)
+			require.NoError(t, err)
+			return ok
+		}
+		require.True(t, revoked(jwt.Claims{ID: "jti-1"}))
+		require.False(t, revoked(jwt.Claims{ID: "jti-2"}))
+		require.True(t, revoked(jwt.Claims{Subject: "user:1", IssuedAt: jwt.NewNumericDate(time.Unix(1600000000, 0))}))
+		require.True(t, revoked(jwt.Claims{Subject: "user:1"}))
+		require.False(t, revoked(jwt.Claims{Subject: "user:1", IssuedAt: jwt.NewNumericDate(time.Unix(1800000000, 0))}))
+		require.False(t, revoked(jwt.Claims{Subject: "user:2"}))
+	})
+
+	t.Run("should keep the previous list on failures", func(t *testing.T) {
+		list = `{`
+		require.ErrorIs(t, revocations.Refresh(ctx), ErrFetchingRevocations)
+		ok, err := revocations.IsRevoked(ctx, "", &jwt.Claims{ID: "jti-1"})
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("should poll until canceled", func(t *testing.T) {
+		list = `{"tokens": ["jti-3"]}`
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		revocations.Run(ctx)
+		ok, _ := revocations.IsRevoked(ctx, "", &jwt.Claims{ID: "jti-3"})
+		require.False(t, ok, "should not refresh with a canceled context")
+	})
+}
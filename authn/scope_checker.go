@@ -0,0 +1,96 @@
+package authn
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/authlib/errs"
+)
+
+var ErrMissingScope = errs.New(errs.KindForbidden, "missing required scope")
+
+// SpaceDelimited is a list of values encoded either as a JSON list or as a space-delimited string.
+type SpaceDelimited []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *SpaceDelimited) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*s = strings.Fields(str)
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+// ScopeChecker enforces the OAuth-style scopes of the access token of the caller, for services which
+// do not need the authz service. The scopes are read from the "scopes", "scope" and "scp" claims.
+//
+// A granted scope ending with "*" grants all the scopes it prefixes: "dashboards:*" grants "dashboards:read",
+// and "*" grants any scope.
+type ScopeChecker struct {
+	required []string
+}
+
+// NewScopeChecker returns a checker requiring all the scopes.
+func NewScopeChecker(required ...string) *ScopeChecker {
+	return &ScopeChecker{required: required}
+}
+
+// oauthScoped is implemented by the access claims carrying OAuth scopes, see Access.OAuthScopes.
+type oauthScoped interface {
+	OAuthScopes() []string
+}
+
+// Check returns ErrMissingScope if the access token of the caller is not granted all the required scopes.
+func (c *ScopeChecker) Check(caller claims.AuthInfo) error {
+	var granted []string
+	if caller != nil {
+		if access := caller.GetAccess(); access != nil && !access.IsNil() {
+			granted = access.Scopes()
+			if oauth, ok := access.(oauthScoped); ok {
+				granted = append(slices.Clone(granted), oauth.OAuthScopes()...)
+			}
+		}
+	}
+	for _, scope := range c.required {
+		if !HasScope(granted, scope) {
+			return fmt.Errorf("%w: %s", ErrMissingScope, scope)
+		}
+	}
+	return nil
+}
+
+// Wrap returns a handler rejecting with a 403 status the requests which caller is not granted all the
+// required scopes. The caller is read from the request context, see HTTPMiddleware.
+func (c *ScopeChecker) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, _ := claims.AuthInfoFromRequest(r)
+		if err := c.Check(caller); err != nil {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HasScope returns whether the scope is granted, directly or by a wildcard scope.
+func HasScope(granted []string, scope string) bool {
+	for _, g := range granted {
+		if g == scope {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(g, "*"); ok && strings.HasPrefix(scope, prefix) {
+			return true
+		}
+	}
+	return false
+}
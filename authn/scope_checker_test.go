@@ -0,0 +1,81 @@
+package authn
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/claims"
+)
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []string
+		scope   string
+		want    bool
+	}{
+		{name: "exact match", granted: []string{"dashboards:read"}, scope: "dashboards:read", want: true},
+		{name: "wildcard suffix", granted: []string{"dashboards:*"}, scope: "dashboards:write", want: true},
+		{name: "wildcard", granted: []string{"*"}, scope: "folders:read", want: true},
+		{name: "other scope", granted: []string{"dashboards:*"}, scope: "folders:read"},
+		{name: "no scope", scope: "folders:read"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HasScope(tt.granted, tt.scope))
+		})
+	}
+}
+
+func TestScopeChecker(t *testing.T) {
+	var rest AccessTokenClaims
+	require.NoError(t, json.Unmarshal([]byte(`{"scopes":["plugins:read"],"scope":"dashboards:read folders:*","scp":["users:read"]}`), &rest))
+	caller := &AuthInfo{AccessClaims: NewAccessClaims(Claims[AccessTokenClaims]{Claims: &jwt.Claims{Subject: "access-policy:1"}, Rest: rest})}
+
+	t.Run("should read the scopes of all the claims", func(t *testing.T) {
+		require.NoError(t, NewScopeChecker("plugins:read", "dashboards:read", "folders:write", "users:read").Check(caller))
+	})
+
+	t.Run("should keep the access policy scopes apart", func(t *testing.T) {
+		require.Equal(t, []string{"plugins:read"}, caller.AccessClaims.Scopes())
+		require.Equal(t, []string{"dashboards:read", "folders:*", "users:read"}, caller.AccessClaims.OAuthScopes())
+	})
+
+	t.Run("should read space-delimited scp claims", func(t *testing.T) {
+		var rest AccessTokenClaims
+		require.NoError(t, json.Unmarshal([]byte(`{"scp":"users:read users:write"}`), &rest))
+		require.Equal(t, SpaceDelimited{"users:read", "users:write"}, rest.Scp)
+	})
+
+	t.Run("should reject a missing scope", func(t *testing.T) {
+		err := NewScopeChecker("dashboards:read", "dashboards:write").Check(caller)
+		require.ErrorIs(t, err, ErrMissingScope)
+		require.ErrorContains(t, err, "dashboards:write")
+	})
+
+	t.Run("should reject a caller without access token", func(t *testing.T) {
+		require.ErrorIs(t, NewScopeChecker("dashboards:read").Check(&AuthInfo{}), ErrMissingScope)
+		require.ErrorIs(t, NewScopeChecker("dashboards:read").Check(nil), ErrMissingScope)
+	})
+
+	t.Run("should enforce the scopes of HTTP requests", func(t *testing.T) {
+		handler := NewScopeChecker("folders:read").Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		handler.ServeHTTP(rec, req.WithContext(claims.WithAuthInfo(req.Context(), caller)))
+		require.Equal(t, http.StatusNoContent, rec.Code)
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
@@ -0,0 +1,166 @@
+package authn
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+const defaultSignerTokenTTL = 10 * time.Minute
+
+var _ KeyRetriever = &Signer{}
+
+// SignerOption allows setting custom parameters during construction.
+type SignerOption func(*Signer)
+
+// WithSigningKeyIDOption selects the key used to sign tokens.
+// Defaults to the first key of the set.
+func WithSigningKeyIDOption(keyID string) SignerOption {
+	return func(s *Signer) {
+		s.keyID = keyID
+	}
+}
+
+// WithNowSignerOption overrides the clock used to compute token timestamps. Useful for tests.
+func WithNowSignerOption(now func() time.Time) SignerOption {
+	return func(s *Signer) {
+		s.now = now
+	}
+}
+
+// NewSigner creates a Signer from a set of private JSON web keys.
+// All keys must have a key id and an algorithm set.
+func NewSigner(cfg SignerConfig, keys jose.JSONWebKeySet, opts ...SignerOption) (*Signer, error) {
+	if len(keys.Keys) == 0 {
+		return nil, fmt.Errorf("missing signing keys: %w", ErrMissingConfig)
+	}
+
+	s := &Signer{
+		cfg:  cfg,
+		keys: keys,
+		now:  time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.cfg.TokenTTL <= 0 {
+		s.cfg.TokenTTL = defaultSignerTokenTTL
+	}
+
+	for _, k := range keys.Keys {
+		if k.KeyID == "" {
+			return nil, fmt.Errorf("%w: missing key id", ErrInvalidSigningKey)
+		}
+		if k.Algorithm == "" {
+			return nil, fmt.Errorf("%w: missing algorithm for key %s", ErrInvalidSigningKey, k.KeyID)
+		}
+		if k.IsPublic() {
+			return nil, fmt.Errorf("%w: key %s is not a private key", ErrInvalidSigningKey, k.KeyID)
+		}
+	}
+
+	if s.keyID == "" {
+		s.keyID = keys.Keys[0].KeyID
+	}
+
+	if len(s.keys.Key(s.keyID)) == 0 {
+		return nil, fmt.Errorf("%w: unknown key id %s", ErrInvalidSigningKey, s.keyID)
+	}
+
+	return s, nil
+}
+
+// Signer mints ID and access tokens that can be verified by VerifierBase.
+// It also implements KeyRetriever and will serve the public part of its keys
+// so in-process services can verify the tokens it issued.
+type Signer struct {
+	cfg   SignerConfig
+	keys  jose.JSONWebKeySet
+	keyID string
+	now   func() time.Time
+}
+
+// SignIDToken mints an ID token for the provided claims.
+func (s *Signer) SignIDToken(ctx context.Context, claims *jwt.Claims, idClaims IDTokenClaims) (string, error) {
+	return s.Sign(ctx, TokenTypeID, claims, idClaims)
+}
+
+// SignAccessToken mints an access token for the provided claims.
+func (s *Signer) SignAccessToken(ctx context.Context, claims *jwt.Claims, atClaims AccessTokenClaims) (string, error) {
+	return s.Sign(ctx, TokenTypeAccess, claims, atClaims)
+}
+
+// Sign mints a token of the given type. Issuer, issued at, not before and expiry
+// are populated from the configuration when not set on the provided claims.
+// Custom claims are merged with the standard claims in the token payload.
+func (s *Signer) Sign(ctx context.Context, typ TokenType, claims *jwt.Claims, custom any) (string, error) {
+	key := s.keys.Key(s.keyID)[0]
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.SignatureAlgorithm(key.Algorithm),
+		Key:       key,
+	}, &jose.SignerOptions{
+		ExtraHeaders: map[jose.HeaderKey]interface{}{
+			"typ": typ,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create signer: %w", err)
+	}
+
+	std := jwt.Claims{}
+	if claims != nil {
+		std = *claims
+	}
+
+	now := s.now()
+	if std.Issuer == "" {
+		std.Issuer = s.cfg.Issuer
+	}
+	if std.IssuedAt == nil {
+		std.IssuedAt = jwt.NewNumericDate(now)
+	}
+	if std.NotBefore == nil {
+		std.NotBefore = jwt.NewNumericDate(now)
+	}
+	if std.Expiry == nil {
+		std.Expiry = jwt.NewNumericDate(now.Add(s.cfg.TokenTTL))
+	}
+
+	builder := jwt.Signed(signer).Claims(std)
+	if custom != nil {
+		builder = builder.Claims(custom)
+	}
+
+	token, err := builder.CompactSerialize()
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return token, nil
+}
+
+// Get implements KeyRetriever and returns the public key matching keyID.
+func (s *Signer) Get(ctx context.Context, keyID string) (*jose.JSONWebKey, error) {
+	keys := s.keys.Key(keyID)
+	if len(keys) == 0 {
+		return nil, ErrInvalidSigningKey
+	}
+
+	public := keys[0].Public()
+	return &public, nil
+}
+
+// PublicKeys returns the public part of the signing keys, as served by a jwks endpoint.
+func (s *Signer) PublicKeys() jose.JSONWebKeySet {
+	set := jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0, len(s.keys.Keys))}
+	for _, k := range s.keys.Keys {
+		set.Keys = append(set.Keys, k.Public())
+	}
+	return set
+}
@@ -0,0 +1,115 @@
+package authn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/claims"
+)
+
+func signingKeys() jose.JSONWebKeySet {
+	return jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{KeyID: firstKeyID, Key: firstKey, Algorithm: string(jose.ES256)},
+		{KeyID: secondKeyId, Key: secondKey, Algorithm: string(jose.ES256)},
+	}}
+}
+
+func TestNewSigner(t *testing.T) {
+	t.Run("should not be able to create signer without keys", func(t *testing.T) {
+		_, err := NewSigner(SignerConfig{}, jose.JSONWebKeySet{})
+		require.ErrorIs(t, err, ErrMissingConfig)
+	})
+
+	t.Run("should not be able to create signer with public keys", func(t *testing.T) {
+		_, err := NewSigner(SignerConfig{}, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{KeyID: firstKeyID, Key: firstKey.Public(), Algorithm: string(jose.ES256)},
+		}})
+		require.ErrorIs(t, err, ErrInvalidSigningKey)
+	})
+
+	t.Run("should not be able to create signer with unknown key id", func(t *testing.T) {
+		_, err := NewSigner(SignerConfig{}, signingKeys(), WithSigningKeyIDOption("unknown"))
+		require.ErrorIs(t, err, ErrInvalidSigningKey)
+	})
+
+	t.Run("should default to the first key", func(t *testing.T) {
+		s, err := NewSigner(SignerConfig{}, signingKeys())
+		require.NoError(t, err)
+		require.Equal(t, firstKeyID, s.keyID)
+		require.Equal(t, defaultSignerTokenTTL, s.cfg.TokenTTL)
+	})
+}
+
+func TestSigner_Sign(t *testing.T) {
+	signer, err := NewSigner(SignerConfig{Issuer: "signer", TokenTTL: time.Minute}, signingKeys(), WithSigningKeyIDOption(secondKeyId))
+	require.NoError(t, err)
+
+	t.Run("should sign access token verifiable by access token verifier", func(t *testing.T) {
+		token, err := signer.SignAccessToken(context.Background(), &jwt.Claims{
+			Subject:  "access-policy:1",
+			Audience: jwt.Audience{"some-service"},
+		}, AccessTokenClaims{Namespace: "stacks-1", Permissions: []string{"dashboards:read"}})
+		require.NoError(t, err)
+
+		verifier := NewAccessTokenVerifier(VerifierConfig{AllowedAudiences: []string{"some-service"}}, signer)
+		c, err := verifier.Verify(context.Background(), token)
+		require.NoError(t, err)
+		assert.Equal(t, "signer", c.Issuer)
+		assert.Equal(t, "access-policy:1", c.Subject)
+		assert.Equal(t, "stacks-1", c.Rest.Namespace)
+		assert.Equal(t, []string{"dashboards:read"}, c.Rest.Permissions)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), c.Expiry.Time(), 5*time.Second)
+
+		parsed, err := jwt.ParseSigned(token)
+		require.NoError(t, err)
+		assert.Equal(t, secondKeyId, parsed.Headers[0].KeyID)
+	})
+
+	t.Run("should sign id token verifiable by id token verifier", func(t *testing.T) {
+		token, err := signer.SignIDToken(context.Background(), &jwt.Claims{Subject: "user:1"}, IDTokenClaims{
+			Identifier: "1",
+			Type:       claims.TypeUser,
+			Namespace:  "stacks-1",
+		})
+		require.NoError(t, err)
+
+		c, err := NewIDTokenVerifier(VerifierConfig{}, signer).Verify(context.Background(), token)
+		require.NoError(t, err)
+		assert.Equal(t, "user:1", c.Rest.asTypedUID())
+
+		_, err = NewAccessTokenVerifier(VerifierConfig{}, signer).Verify(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidTokenType)
+	})
+
+	t.Run("should keep provided expiry", func(t *testing.T) {
+		token, err := signer.SignIDToken(context.Background(), &jwt.Claims{
+			Expiry: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		}, IDTokenClaims{})
+		require.NoError(t, err)
+
+		_, err = NewIDTokenVerifier(VerifierConfig{}, signer).Verify(context.Background(), token)
+		assert.ErrorIs(t, err, ErrExpiredToken)
+	})
+}
+
+func TestSigner_Get(t *testing.T) {
+	signer, err := NewSigner(SignerConfig{}, signingKeys())
+	require.NoError(t, err)
+
+	key, err := signer.Get(context.Background(), secondKeyId)
+	require.NoError(t, err)
+	assert.True(t, key.IsPublic())
+
+	_, err = signer.Get(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrInvalidSigningKey)
+
+	for _, k := range signer.PublicKeys().Keys {
+		assert.True(t, k.IsPublic())
+	}
+}
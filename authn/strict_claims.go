@@ -0,0 +1,73 @@
+package authn
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// registeredClaims are the claims decoded in jwt.Claims.
+var registeredClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"}
+
+// knownClaims returns the top-level claims a token of type T is expected to carry:
+// the registered claims and the JSON fields of T.
+func knownClaims[T any]() map[string]bool {
+	known := make(map[string]bool, len(registeredClaims))
+	for _, c := range registeredClaims {
+		known[c] = true
+	}
+	addJSONFields(reflect.TypeOf((*T)(nil)).Elem(), known)
+	return known
+}
+
+func addJSONFields(typ reflect.Type, known map[string]bool) {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		// Fields of embedded structs are promoted to the top-level
+		if field.Anonymous && name == "" {
+			addJSONFields(field.Type, known)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		known[name] = true
+	}
+}
+
+// validateClaims returns ErrUnknownClaim if the token carries a top-level claim
+// which is neither known nor allowed by the configuration.
+func validateClaims(cfg *VerifierConfig, known map[string]bool, raw map[string]any) error {
+	for name := range raw {
+		if known[name] {
+			continue
+		}
+		allowed := false
+		for _, c := range cfg.AllowedClaims {
+			if c == name {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %s", ErrUnknownClaim, name)
+		}
+	}
+	return nil
+}
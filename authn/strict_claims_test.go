@@ -0,0 +1,106 @@
+package authn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKnownClaims(t *testing.T) {
+	type Embedded struct {
+		Team string `json:"team"`
+	}
+	type CustomClaims struct {
+		Embedded
+		Namespace string `json:"namespace,omitempty"`
+		Ignored   string `json:"-"`
+		Untagged  string
+		private   string
+	}
+
+	known := knownClaims[CustomClaims]()
+	for _, c := range []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "team", "namespace", "Untagged"} {
+		require.True(t, known[c], c)
+	}
+	require.False(t, known["Ignored"])
+	require.False(t, known["private"])
+
+	require.True(t, knownClaims[AccessTokenClaims]()["delegatedPermissions"])
+	require.True(t, knownClaims[IDTokenClaims]()["act"])
+}
+
+func TestVerifier_StrictClaims(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(keys())
+	}))
+
+	type CustomClaims struct {
+		Namespace string `json:"namespace"`
+	}
+
+	token := signWithClaims(t, map[string]any{"namespace": "stacks-1", "role": "admin"})
+
+	verifier := NewVerifier[CustomClaims](
+		VerifierConfig{},
+		TokenTypeID,
+		NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL}),
+	)
+
+	t.Run("valid: unknown claims are ignored by default", func(t *testing.T) {
+		claims, err := verifier.Verify(context.Background(), token)
+		require.NoError(t, err)
+		require.Equal(t, "stacks-1", claims.Rest.Namespace)
+	})
+
+	t.Run("invalid: unknown claim in strict mode", func(t *testing.T) {
+		verifier.Reload(VerifierConfig{StrictClaims: true})
+		claims, err := verifier.Verify(context.Background(), token)
+		require.ErrorIs(t, err, ErrUnknownClaim)
+		require.True(t, IsInvalidTokenErr(err))
+		require.Nil(t, claims)
+	})
+
+	t.Run("valid: allowed claim in strict mode", func(t *testing.T) {
+		verifier.Reload(VerifierConfig{StrictClaims: true, AllowedClaims: []string{"role"}})
+		claims, err := verifier.Verify(context.Background(), token)
+		require.NoError(t, err)
+		require.NotNil(t, claims)
+	})
+
+	t.Run("invalid: unknown claim in strict mode without verification", func(t *testing.T) {
+		verifier := NewUnsafeVerifier[CustomClaims](VerifierConfig{StrictClaims: true}, TokenTypeID)
+		claims, err := verifier.Verify(context.Background(), token)
+		require.ErrorIs(t, err, ErrUnknownClaim)
+		require.Nil(t, claims)
+	})
+}
+
+func signWithClaims(t *testing.T, rest map[string]any) string {
+	t.Helper()
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key:       firstKey,
+	}, &jose.SignerOptions{
+		ExtraHeaders: map[jose.HeaderKey]interface{}{
+			"kid": firstKeyID,
+			"typ": TokenTypeID,
+		},
+	})
+	require.NoError(t, err)
+
+	token, err := jwt.Signed(signer).
+		Claims(jwt.Claims{Subject: "user:1", Expiry: jwt.NewNumericDate(time.Now().Add(time.Minute))}).
+		Claims(rest).
+		CompactSerialize()
+	require.NoError(t, err)
+
+	return token
+}
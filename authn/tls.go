@@ -0,0 +1,77 @@
+package authn
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/grafana/authlib/internal/tlsconfig"
+)
+
+// TLSConfig configures the TLS of the requests to endpoints served with private authorities or requiring mTLS.
+type TLSConfig struct {
+	// CAFile is the PEM encoded bundle of the authorities of the server certificate, trusted instead of
+	// the system ones. The system authorities are used when empty.
+	CAFile string `yaml:"caFile"`
+	// CertFile and KeyFile are the PEM encoded client certificate and key, for mTLS.
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+	// InsecureSkipVerify disables the verification of the server certificate. Only use it in development.
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify"`
+	// ReloadInterval is how often the files are checked for changes, on new connections,
+	// so that rotated certificates are used without a restart. Defaults to 1 minute.
+	ReloadInterval time.Duration `yaml:"reloadInterval"`
+}
+
+func (c *TLSConfig) RegisterFlags(prefix string, fs *flag.FlagSet) {
+	fs.StringVar(&c.CAFile, prefix+".ca-file", "", "Path to the PEM bundle of the trusted authorities, instead of the system ones.")
+	fs.StringVar(&c.CertFile, prefix+".cert-file", "", "Path to the PEM client certificate, for mTLS.")
+	fs.StringVar(&c.KeyFile, prefix+".key-file", "", "Path to the PEM client key, for mTLS.")
+	fs.BoolVar(&c.InsecureSkipVerify, prefix+".insecure-skip-verify", false, "Skip the verification of the server certificate.")
+	fs.DurationVar(&c.ReloadInterval, prefix+".reload-interval", tlsconfig.DefaultReloadInterval, "How often the certificate files are checked for changes.")
+}
+
+// IsZero reports whether the configuration is empty, in which case the defaults of the HTTP client apply.
+func (c TLSConfig) IsZero() bool {
+	return c.CAFile == "" && c.CertFile == "" && c.KeyFile == "" && !c.InsecureSkipVerify
+}
+
+// HTTPClient returns a client with the TLS configuration, and the settings of http.DefaultTransport otherwise.
+// The files are loaded right away, so that a misconfiguration fails at startup, and reloaded when they change.
+func (c TLSConfig) HTTPClient() (*http.Client, error) {
+	files, err := tlsconfig.Load(tlsconfig.Config{
+		CAFile:             c.CAFile,
+		CertFile:           c.CertFile,
+		KeyFile:            c.KeyFile,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		ReloadInterval:     c.ReloadInterval,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Used for the connections through a proxy, which verify the server name of the handshake
+	transport.TLSClientConfig = files.ClientConfig("")
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, files.ClientConfig(host))
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+	return &http.Client{Transport: transport}, nil
+}
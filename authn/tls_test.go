@@ -0,0 +1,131 @@
+package authn
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeSelfSigned writes a self-signed client certificate and its key in the directory.
+func writeSelfSigned(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "grafana"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestDefaultKeyRetriever_TLS(t *testing.T) {
+	dir := t.TempDir()
+
+	var clientCerts int
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientCerts = len(r.TLS.PeerCertificates)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(keys())
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+	certFile, keyFile := writeSelfSigned(t, dir)
+
+	get := func(cfg TLSConfig) error {
+		_, err := NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL, TLS: cfg}).Get(context.Background(), firstKeyID)
+		return err
+	}
+
+	t.Run("should trust the private authority", func(t *testing.T) {
+		require.NoError(t, get(TLSConfig{CAFile: caFile}))
+		require.Equal(t, 0, clientCerts)
+	})
+
+	t.Run("should reject the unknown authority", func(t *testing.T) {
+		require.ErrorIs(t, get(TLSConfig{}), ErrFetchingSigningKey)
+	})
+
+	t.Run("should skip the verification", func(t *testing.T) {
+		require.NoError(t, get(TLSConfig{InsecureSkipVerify: true}))
+	})
+
+	t.Run("should send the client certificate", func(t *testing.T) {
+		require.NoError(t, get(TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}))
+		require.Equal(t, 1, clientCerts)
+	})
+
+	t.Run("should fail on invalid configuration", func(t *testing.T) {
+		err := get(TLSConfig{CertFile: certFile})
+		require.ErrorIs(t, err, ErrFetchingSigningKey)
+		require.ErrorIs(t, err, ErrInvalidTLSConfig)
+
+		require.ErrorIs(t, get(TLSConfig{CAFile: filepath.Join(dir, "missing.crt")}), ErrInvalidTLSConfig)
+	})
+
+	t.Run("should reload the rotated authorities", func(t *testing.T) {
+		rotated := filepath.Join(dir, "rotated.crt")
+		other, err := os.ReadFile(certFile)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(rotated, other, 0o600))
+		kr := NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL, TLS: TLSConfig{CAFile: rotated, ReloadInterval: time.Millisecond}})
+		_, err = kr.Get(context.Background(), firstKeyID)
+		require.ErrorIs(t, err, ErrFetchingSigningKey)
+
+		ca, err := os.ReadFile(caFile)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(rotated, ca, 0o600))
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(rotated, later, later))
+		require.Eventually(t, func() bool {
+			_, err := kr.Get(context.Background(), firstKeyID)
+			return err == nil
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("should apply the TLS configuration on reload", func(t *testing.T) {
+		kr := NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL})
+		_, err := kr.Get(context.Background(), firstKeyID)
+		require.ErrorIs(t, err, ErrFetchingSigningKey)
+
+		kr.Reload(KeyRetrieverConfig{SigningKeysURL: server.URL, TLS: TLSConfig{CAFile: caFile}})
+		_, err = kr.Get(context.Background(), firstKeyID)
+		require.NoError(t, err)
+	})
+
+	t.Run("should keep the custom HTTP client", func(t *testing.T) {
+		kr := NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL, TLS: TLSConfig{CertFile: certFile}},
+			WithHTTPClientKeyRetrieverOpt(server.Client()))
+		_, err := kr.Get(context.Background(), firstKeyID)
+		require.NoError(t, err)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 
 	"github.com/grafana/authlib/cache"
 	"github.com/grafana/authlib/internal/httpclient"
+	"github.com/grafana/authlib/internal/logger"
 )
 
 // Provided for mockability of client
@@ -22,7 +24,7 @@ type TokenExchanger interface {
 	Exchange(ctx context.Context, r TokenExchangeRequest) (*TokenExchangeResponse, error)
 }
 
-var _ TokenExchanger = &TokenExchangeClient{}
+var _ RefreshableTokenExchanger = &TokenExchangeClient{}
 
 // ExchangeClientOpts allows setting custom parameters during construction.
 type ExchangeClientOpts func(c *TokenExchangeClient)
@@ -34,6 +36,22 @@ func WithHTTPClient(client *http.Client) ExchangeClientOpts {
 	}
 }
 
+// WithRefreshBeforeExpiry enables the proactive refresh of the cached tokens, d before their expiry.
+// The cached token is still returned while the refresh happens in the background.
+// Proactive refresh is disabled by default, or with a zero or negative value.
+func WithRefreshBeforeExpiry(d time.Duration) ExchangeClientOpts {
+	return func(c *TokenExchangeClient) {
+		c.refreshBefore = d
+	}
+}
+
+// WithTokenExchangeLogger sets the logger of the failures to refresh or cache the exchanged tokens.
+func WithTokenExchangeLogger(logger Logger) ExchangeClientOpts {
+	return func(c *TokenExchangeClient) {
+		c.logger = logger
+	}
+}
+
 func NewTokenExchangeClient(cfg TokenExchangeConfig, opts ...ExchangeClientOpts) (*TokenExchangeClient, error) {
 	if cfg.Token == "" {
 		return nil, fmt.Errorf("missing required token")
@@ -44,11 +62,12 @@ func NewTokenExchangeClient(cfg TokenExchangeConfig, opts ...ExchangeClientOpts)
 	}
 
 	c := &TokenExchangeClient{
-		cache: cache.NewLocalCache(cache.Config{
+		cache: cache.Versioned(cache.NewLocalCache(cache.Config{
 			CleanupInterval: 5 * time.Minute,
-		}),
+		}), tokenCacheVersion),
 		cfg:     cfg,
 		singlef: singleflight.Group{},
+		logger:  logger.Nop{},
 	}
 
 	for _, opt := range opts {
@@ -63,11 +82,19 @@ func NewTokenExchangeClient(cfg TokenExchangeConfig, opts ...ExchangeClientOpts)
 
 }
 
+const (
+	cacheLeeway = 15 * time.Second
+	// tokenCacheVersion is the version of the encoding of the cached tokens, see encodeCachedToken.
+	tokenCacheVersion = 2
+)
+
 type TokenExchangeClient struct {
-	cache   cache.Cache
-	cfg     TokenExchangeConfig
-	client  *http.Client
-	singlef singleflight.Group
+	cache         cache.Cache
+	cfg           TokenExchangeConfig
+	client        *http.Client
+	singlef       singleflight.Group
+	refreshBefore time.Duration
+	logger        Logger
 }
 
 type TokenExchangeRequest struct {
@@ -76,6 +103,9 @@ type TokenExchangeRequest struct {
 	Namespace string `json:"namespace"`
 	// Audiences token should be signed with.
 	Audiences []string `json:"audiences"`
+	// DelegatedPermissions are the permissions the token should be allowed to use on behalf of a user.
+	// Leave empty to use the permissions of the access policy.
+	DelegatedPermissions []string `json:"delegatedPermissions,omitempty"`
 }
 
 type TokenExchangeResponse struct {
@@ -83,13 +113,18 @@ type TokenExchangeResponse struct {
 }
 
 func (r TokenExchangeRequest) hash() string {
-	br := strings.Builder{}
-	br.WriteString(r.Namespace)
-	br.WriteByte('-')
-	sort.Strings(r.Audiences)
-	br.WriteString(strings.Join(r.Audiences, "-"))
-
-	return br.String()
+	audiences := append([]string{}, r.Audiences...)
+	sort.Strings(audiences)
+	perms := append([]string{}, r.DelegatedPermissions...)
+	sort.Strings(perms)
+
+	// The number of audiences delimits the audiences from the delegated permissions
+	parts := make([]string, 0, len(audiences)+len(perms)+2)
+	parts = append(parts, r.Namespace, strconv.Itoa(len(audiences)))
+	parts = append(parts, audiences...)
+	parts = append(parts, perms...)
+
+	return cache.NewCacheKey("exchange", parts...).String()
 }
 
 type tokenExchangeResponse struct {
@@ -112,11 +147,36 @@ func (c *TokenExchangeClient) Exchange(ctx context.Context, r TokenExchangeReque
 	}
 
 	key := r.hash()
-	token, ok := c.getCache(ctx, key)
+	token, exp, ok := c.getCache(ctx, key)
 	if ok {
+		if c.shouldRefresh(exp) {
+			// Refresh in the background, the cached token is still valid.
+			go func() {
+				if _, err := c.exchange(context.WithoutCancel(ctx), key, r); err != nil {
+					c.logger.Warn("failed to refresh the exchanged token", "namespace", r.Namespace, "error", err)
+				}
+			}()
+		}
 		return &TokenExchangeResponse{Token: token}, nil
 	}
 
+	token, err := c.exchange(ctx, key, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenExchangeResponse{Token: token}, nil
+}
+
+// Invalidate drops the cached token of the request, if it was not refreshed already, so that the next exchange issues a new one.
+func (c *TokenExchangeClient) Invalidate(ctx context.Context, r TokenExchangeRequest, token string) {
+	key := r.hash()
+	if cached, _, ok := c.getCache(ctx, key); ok && cached == token {
+		_ = c.cache.Delete(ctx, key)
+	}
+}
+
+func (c *TokenExchangeClient) exchange(ctx context.Context, key string, r TokenExchangeRequest) (string, error) {
 	resp, err, _ := c.singlef.Do(key, func() (interface{}, error) {
 		data, err := json.Marshal(&r)
 		if err != nil {
@@ -150,18 +210,20 @@ func (c *TokenExchangeClient) Exchange(ctx context.Context, r TokenExchangeReque
 			return nil, fmt.Errorf("%w: %s", ErrInvalidExchangeResponse, res.Status)
 		}
 
-		// FIXME: for now we ignore errors when updating the cache becasue we still
+		// Errors when updating the cache are only logged because we still
 		// have a valid response to return.
-		_ = c.setCache(ctx, response.Data.Token, key)
+		if err := c.setCache(ctx, response.Data.Token, key); err != nil {
+			c.logger.Warn("failed to cache the exchanged token", "namespace", r.Namespace, "error", err)
+		}
 		return response, nil
 	})
 
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	response := resp.(tokenExchangeResponse)
-	return &TokenExchangeResponse{Token: response.Data.Token}, nil
+	return response.Data.Token, nil
 }
 
 func (c *TokenExchangeClient) withHeaders(r *http.Request) *http.Request {
@@ -177,25 +239,63 @@ func (c *TokenExchangeClient) withHeaders(r *http.Request) *http.Request {
 	return r
 }
 
-func (c *TokenExchangeClient) getCache(ctx context.Context, key string) (string, bool) {
-	if token, err := c.cache.Get(ctx, key); err == nil {
-		return string(token), true
+func (c *TokenExchangeClient) getCache(ctx context.Context, key string) (string, time.Time, bool) {
+	data, err := c.cache.Get(ctx, key)
+	if err != nil {
+		return "", time.Time{}, false
 	}
-	return "", false
+	return decodeCachedToken(data)
 }
 
 func (c *TokenExchangeClient) setCache(ctx context.Context, token string, key string) error {
-	const cacheLeeway = 15 * time.Second
+	exp, err := tokenExpiry(token)
+	if err != nil {
+		return err
+	}
 
+	return c.cache.Set(ctx, key, encodeCachedToken(token, exp), time.Until(exp)-cacheLeeway)
+}
+
+// encodeCachedToken stores the expiry of the token with it, so that the token is not parsed again on cache hits.
+func encodeCachedToken(token string, exp time.Time) []byte {
+	return []byte(strconv.FormatInt(exp.Unix(), 10) + " " + token)
+}
+
+func decodeCachedToken(data []byte) (string, time.Time, bool) {
+	unix, token, ok := strings.Cut(string(data), " ")
+	if !ok {
+		return "", time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return token, time.Unix(sec, 0), true
+}
+
+// shouldRefresh returns true when proactive refresh is enabled and the token expiring at exp is about to expire.
+func (c *TokenExchangeClient) shouldRefresh(exp time.Time) bool {
+	if c.refreshBefore <= 0 {
+		return false
+	}
+
+	return time.Until(exp)-cacheLeeway < c.refreshBefore
+}
+
+func tokenExpiry(token string) (time.Time, error) {
 	parsed, err := jwt.ParseSigned(token)
 	if err != nil {
-		return fmt.Errorf("failed to parse token: %v", err)
+		return time.Time{}, fmt.Errorf("failed to parse token: %v", err)
 	}
 
 	var claims jwt.Claims
 	if err = parsed.UnsafeClaimsWithoutVerification(&claims); err != nil {
-		return fmt.Errorf("failed to extract claims from the token: %v", err)
+		return time.Time{}, fmt.Errorf("failed to extract claims from the token: %v", err)
+	}
+
+	if claims.Expiry == nil {
+		return time.Time{}, fmt.Errorf("missing expiry in token")
 	}
 
-	return c.cache.Set(ctx, key, []byte(token), time.Until(claims.Expiry.Time())-cacheLeeway)
+	return claims.Expiry.Time(), nil
 }
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -111,7 +112,85 @@ func Test_TokenExchangeClient_Exchange(t *testing.T) {
 	})
 }
 
+func Test_TokenExchangeClient_DelegatedPermissions(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req TokenExchangeRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, []string{"teams:read"}, req.DelegatedPermissions)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": {"token": "` + signAccessToken(t) + `"}}`))
+	}))
+
+	c, err := NewTokenExchangeClient(TokenExchangeConfig{Token: "some-token", TokenExchangeURL: srv.URL})
+	require.NoError(t, err)
+
+	req := TokenExchangeRequest{Namespace: "*", Audiences: []string{"some-service"}, DelegatedPermissions: []string{"teams:read"}}
+	_, err = c.Exchange(context.Background(), req)
+	require.NoError(t, err)
+	_, err = c.Exchange(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+
+	// delegated permissions are part of the cache key
+	require.NotEqual(t, req.hash(), TokenExchangeRequest{Namespace: "*", Audiences: []string{"some-service"}}.hash())
+	// audiences and delegated permissions do not collide
+	require.NotEqual(t, req.hash(), TokenExchangeRequest{Namespace: "*", Audiences: []string{"some-service", "teams:read"}}.hash())
+	require.NotEqual(t,
+		TokenExchangeRequest{Namespace: "*", Audiences: []string{"a-b"}}.hash(),
+		TokenExchangeRequest{Namespace: "*", Audiences: []string{"a", "b"}}.hash(),
+	)
+	// order does not matter
+	require.Equal(t,
+		TokenExchangeRequest{Namespace: "*", Audiences: []string{"a", "b"}}.hash(),
+		TokenExchangeRequest{Namespace: "*", Audiences: []string{"b", "a"}}.hash(),
+	)
+}
+
+func Test_TokenExchangeClient_ProactiveRefresh(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data": {"token": "` + signAccessTokenWithExpiry(t, time.Now().Add(1*time.Minute)) + `"}}`))
+	}))
+
+	c, err := NewTokenExchangeClient(
+		TokenExchangeConfig{Token: "some-token", TokenExchangeURL: srv.URL},
+		WithRefreshBeforeExpiry(2*time.Minute),
+	)
+	require.NoError(t, err)
+
+	req := TokenExchangeRequest{Namespace: "*", Audiences: []string{"some-service"}}
+	_, err = c.Exchange(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), calls.Load())
+
+	// token is about to expire, the cached token is returned and refreshed in the background
+	res, err := c.Exchange(context.Background(), req)
+	require.NoError(t, err)
+	require.NotEmpty(t, res.Token)
+	require.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, 10*time.Millisecond)
+
+	t.Run("should not refresh by default", func(t *testing.T) {
+		calls.Store(0)
+		c, err := NewTokenExchangeClient(TokenExchangeConfig{Token: "some-token", TokenExchangeURL: srv.URL})
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err = c.Exchange(context.Background(), req)
+			require.NoError(t, err)
+		}
+		require.Never(t, func() bool { return calls.Load() > 1 }, 50*time.Millisecond, 10*time.Millisecond)
+	})
+}
+
 func signAccessToken(t *testing.T) string {
+	return signAccessTokenWithExpiry(t, time.Now().Add(10*time.Minute))
+}
+
+func signAccessTokenWithExpiry(t *testing.T, exp time.Time) string {
 	signer, err := jose.NewSigner(jose.SigningKey{
 		Algorithm: jose.HS256,
 		Key:       []byte("key"),
@@ -119,7 +198,7 @@ func signAccessToken(t *testing.T) string {
 	require.NoError(t, err)
 
 	token, err := jwt.Signed(signer).
-		Claims(&jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(10 * time.Minute))}).
+		Claims(&jwt.Claims{Expiry: jwt.NewNumericDate(exp)}).
 		CompactSerialize()
 
 	require.NoError(t, err)
@@ -0,0 +1,93 @@
+package authn
+
+import (
+	"context"
+	"net/http"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// TokenPair are the access token and the ID token of a request. The missing tokens are empty.
+type TokenPair struct {
+	AccessToken string
+	IDToken     string
+}
+
+type TokenExtractorOption func(*TokenExtractor)
+
+// WithTokenHeadersOption sets the headers the access token and the ID token are extracted from.
+// Defaults to "Authorization" and "X-Grafana-Id".
+func WithTokenHeadersOption(accessTokenHeader, idTokenHeader string) TokenExtractorOption {
+	return func(e *TokenExtractor) {
+		e.accessTokenHeader = accessTokenHeader
+		e.idTokenHeader = idTokenHeader
+	}
+}
+
+// WithTokenCookiesOption sets the cookies the access token and the ID token are extracted from, when they are
+// missing from the headers. An empty name disables the cookie.
+func WithTokenCookiesOption(accessTokenCookie, idTokenCookie string) TokenExtractorOption {
+	return func(e *TokenExtractor) {
+		e.accessTokenCookie = accessTokenCookie
+		e.idTokenCookie = idTokenCookie
+	}
+}
+
+// TokenExtractor extracts the tokens of the HTTP requests, from the headers, then from the cookies, if any.
+// The tokens in the headers can be sent as is or using the Bearer scheme.
+type TokenExtractor struct {
+	accessTokenHeader string
+	idTokenHeader     string
+	accessTokenCookie string
+	idTokenCookie     string
+}
+
+func NewTokenExtractor(opts ...TokenExtractorOption) *TokenExtractor {
+	e := &TokenExtractor{
+		accessTokenHeader: DefaultAccessTokenHeader,
+		idTokenHeader:     DefaultIDTokenHeader,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// FromRequest returns the tokens of the request.
+func (e *TokenExtractor) FromRequest(r *http.Request) TokenPair {
+	return TokenPair{
+		AccessToken: requestToken(r, e.accessTokenHeader, e.accessTokenCookie),
+		IDToken:     requestToken(r, e.idTokenHeader, e.idTokenCookie),
+	}
+}
+
+func requestToken(r *http.Request, header, cookie string) string {
+	if token := bearerToken(r.Header.Get(header)); token != "" {
+		return token
+	}
+	if cookie == "" {
+		return ""
+	}
+	if c, err := r.Cookie(cookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// TokensFromRequest returns the tokens of the request, from the "Authorization" and "X-Grafana-Id" headers.
+func TokensFromRequest(r *http.Request) TokenPair {
+	return NewTokenExtractor().FromRequest(r)
+}
+
+// TokensFromMetadata returns the tokens of the gRPC metadata, from the "X-Access-Token" and "X-Id-Token" keys.
+func TokensFromMetadata(md metadata.MD) TokenPair {
+	at, _ := getFirstMetadataValue(md, DefaultAccessTokenMetadataKey)
+	id, _ := getFirstMetadataValue(md, DefaultIdTokenMetadataKey)
+	return TokenPair{AccessToken: at, IDToken: id}
+}
+
+// TokensFromIncomingContext returns the tokens of the incoming gRPC metadata of the context, see TokensFromMetadata.
+func TokensFromIncomingContext(ctx context.Context) TokenPair {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return TokensFromMetadata(md)
+}
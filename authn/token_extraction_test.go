@@ -0,0 +1,44 @@
+package authn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTokenExtractor(t *testing.T) {
+	t.Run("should extract the tokens from the headers", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer access")
+		r.Header.Set("X-Grafana-Id", "id")
+		require.Equal(t, TokenPair{AccessToken: "access", IDToken: "id"}, TokensFromRequest(r))
+	})
+
+	t.Run("should extract the tokens from the configured headers", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Access-Token", "access")
+		r.Header.Set("X-Grafana-Id", "id")
+		extractor := NewTokenExtractor(WithTokenHeadersOption("X-Access-Token", "X-Id-Token"))
+		require.Equal(t, TokenPair{AccessToken: "access"}, extractor.FromRequest(r))
+	})
+
+	t.Run("should fall back to the cookies", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer access")
+		r.AddCookie(&http.Cookie{Name: "access_token", Value: "cookie-access"})
+		r.AddCookie(&http.Cookie{Name: "id_token", Value: "cookie-id"})
+		extractor := NewTokenExtractor(WithTokenCookiesOption("access_token", "id_token"))
+		require.Equal(t, TokenPair{AccessToken: "access", IDToken: "cookie-id"}, extractor.FromRequest(r))
+	})
+
+	t.Run("should extract the tokens from the gRPC metadata", func(t *testing.T) {
+		md := metadata.Pairs(DefaultAccessTokenMetadataKey, "access", DefaultIdTokenMetadataKey, "id")
+		require.Equal(t, TokenPair{AccessToken: "access", IDToken: "id"}, TokensFromMetadata(md))
+		require.Equal(t, TokenPair{AccessToken: "access", IDToken: "id"}, TokensFromIncomingContext(metadata.NewIncomingContext(context.Background(), md)))
+		require.Equal(t, TokenPair{}, TokensFromIncomingContext(context.Background()))
+	})
+}
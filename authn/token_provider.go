@@ -0,0 +1,184 @@
+package authn
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"golang.org/x/sync/singleflight"
+)
+
+const defaultTokenRefreshRatio = 0.8
+
+// TokenProvider provides access tokens to authenticate outgoing requests.
+type TokenProvider interface {
+	// Token returns a valid access token for the given audience.
+	Token(ctx context.Context, audience string) (string, error)
+}
+
+// TokenSource issues a new access token for the given audience.
+type TokenSource func(ctx context.Context, audience string) (string, error)
+
+// ExchangeTokenSource returns a TokenSource issuing tokens for the namespace through the token exchanger.
+// When the exchanger caches the tokens (see RefreshableTokenExchanger), the token previously issued for the
+// audience is invalidated first, so that refreshes get a new token rather than the cached one.
+func ExchangeTokenSource(exchanger TokenExchanger, namespace string) TokenSource {
+	refreshable, _ := exchanger.(RefreshableTokenExchanger)
+
+	var mtx sync.Mutex
+	issued := map[string]string{}
+	return func(ctx context.Context, audience string) (string, error) {
+		req := TokenExchangeRequest{
+			Namespace: namespace,
+			Audiences: []string{audience},
+		}
+
+		mtx.Lock()
+		previous, ok := issued[audience]
+		mtx.Unlock()
+		if ok && refreshable != nil {
+			refreshable.Invalidate(ctx, req, previous)
+		}
+
+		res, err := exchanger.Exchange(ctx, req)
+		if err != nil {
+			return "", err
+		}
+
+		mtx.Lock()
+		issued[audience] = res.Token
+		mtx.Unlock()
+		return res.Token, nil
+	}
+}
+
+// SignerTokenSource returns a TokenSource signing access tokens with the provided claims.
+// The audience of the claims is overridden by the requested audience.
+func SignerTokenSource(signer *Signer, claims jwt.Claims, atClaims AccessTokenClaims) TokenSource {
+	return func(ctx context.Context, audience string) (string, error) {
+		c := claims
+		c.Audience = jwt.Audience{audience}
+		return signer.SignAccessToken(ctx, &c, atClaims)
+	}
+}
+
+var _ RefreshableTokenProvider = &CachedTokenProvider{}
+
+// CachedTokenProviderOption allows setting custom parameters during construction.
+type CachedTokenProviderOption func(*CachedTokenProvider)
+
+// WithTokenRefreshRatioOption sets the fraction of a token lifetime after which it is refreshed in the background.
+// Defaults to 0.8.
+func WithTokenRefreshRatioOption(ratio float64) CachedTokenProviderOption {
+	return func(p *CachedTokenProvider) {
+		p.refreshRatio = ratio
+	}
+}
+
+// NewCachedTokenProvider creates a TokenProvider that caches tokens issued by source per audience.
+func NewCachedTokenProvider(source TokenSource, opts ...CachedTokenProviderOption) *CachedTokenProvider {
+	p := &CachedTokenProvider{
+		source:       source,
+		refreshRatio: defaultTokenRefreshRatio,
+		tokens:       map[string]*cachedToken{},
+		now:          time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.refreshRatio <= 0 || p.refreshRatio > 1 {
+		p.refreshRatio = defaultTokenRefreshRatio
+	}
+
+	return p
+}
+
+// CachedTokenProvider caches access tokens per audience and refreshes them
+// in the background before they expire, so callers only block on the first acquisition.
+type CachedTokenProvider struct {
+	source       TokenSource
+	refreshRatio float64
+	now          func() time.Time
+
+	mtx     sync.Mutex
+	tokens  map[string]*cachedToken
+	singlef singleflight.Group
+}
+
+type cachedToken struct {
+	token      string
+	expiry     time.Time
+	refreshAt  time.Time
+	refreshing bool
+}
+
+// Token returns the cached token for the audience or issues a new one.
+// Once a token reached its refresh point, it is still returned while a new one is issued in the background.
+func (p *CachedTokenProvider) Token(ctx context.Context, audience string) (string, error) {
+	now := p.now()
+
+	p.mtx.Lock()
+	entry, ok := p.tokens[audience]
+	if ok && now.Before(entry.expiry.Add(-cacheLeeway)) {
+		token := entry.token
+		if !entry.refreshing && !now.Before(entry.refreshAt) {
+			entry.refreshing = true
+			go p.refresh(context.WithoutCancel(ctx), audience)
+		}
+		p.mtx.Unlock()
+		return token, nil
+	}
+	p.mtx.Unlock()
+
+	return p.refresh(ctx, audience)
+}
+
+// Invalidate drops the token of the audience, if it was not refreshed already, so that the next call issues a new one.
+func (p *CachedTokenProvider) Invalidate(_ context.Context, audience, token string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if entry, ok := p.tokens[audience]; ok && entry.token == token {
+		delete(p.tokens, audience)
+	}
+}
+
+func (p *CachedTokenProvider) refresh(ctx context.Context, audience string) (string, error) {
+	res, err, _ := p.singlef.Do(audience, func() (interface{}, error) {
+		issuedAt := p.now()
+		token, err := p.source(ctx, audience)
+		if err != nil {
+			return nil, err
+		}
+
+		exp, err := tokenExpiry(token)
+		if err != nil {
+			return nil, err
+		}
+
+		p.mtx.Lock()
+		p.tokens[audience] = &cachedToken{
+			token:     token,
+			expiry:    exp,
+			refreshAt: issuedAt.Add(time.Duration(float64(exp.Sub(issuedAt)) * p.refreshRatio)),
+		}
+		p.mtx.Unlock()
+
+		return token, nil
+	})
+
+	if err != nil {
+		// Allow the next call to retry the refresh, the previous token is kept until it expires.
+		p.mtx.Lock()
+		if entry, ok := p.tokens[audience]; ok {
+			entry.refreshing = false
+		}
+		p.mtx.Unlock()
+		return "", err
+	}
+
+	return res.(string), nil
+}
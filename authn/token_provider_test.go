@@ -0,0 +1,149 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedTokenProvider_Token(t *testing.T) {
+	var calls atomic.Int32
+	var fail atomic.Bool
+	source := func(ctx context.Context, audience string) (string, error) {
+		calls.Add(1)
+		if fail.Load() {
+			return "", errors.New("source failure")
+		}
+		return signAccessTokenWithExpiry(t, time.Now().Add(10*time.Minute)), nil
+	}
+
+	var now atomic.Int64
+	now.Store(time.Now().UnixNano())
+	p := NewCachedTokenProvider(source)
+	p.now = func() time.Time { return time.Unix(0, now.Load()) }
+
+	t.Run("should issue token on first call", func(t *testing.T) {
+		token, err := p.Token(context.Background(), "some-service")
+		require.NoError(t, err)
+		require.NotEmpty(t, token)
+		require.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("should return cached token", func(t *testing.T) {
+		_, err := p.Token(context.Background(), "some-service")
+		require.NoError(t, err)
+		require.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("should cache tokens per audience", func(t *testing.T) {
+		_, err := p.Token(context.Background(), "some-other-service")
+		require.NoError(t, err)
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("should keep token when background refresh fails", func(t *testing.T) {
+		fail.Store(true)
+		now.Add(int64(9 * time.Minute))
+
+		token, err := p.Token(context.Background(), "some-service")
+		require.NoError(t, err)
+		require.NotEmpty(t, token)
+		require.Eventually(t, func() bool { return calls.Load() == 3 }, time.Second, 10*time.Millisecond)
+
+		// refresh is retried on the next call
+		require.Eventually(t, func() bool {
+			_, err := p.Token(context.Background(), "some-service")
+			require.NoError(t, err)
+			return calls.Load() > 3
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should refresh token in the background", func(t *testing.T) {
+		fail.Store(false)
+		require.Eventually(t, func() bool {
+			_, err := p.Token(context.Background(), "some-service")
+			require.NoError(t, err)
+
+			p.mtx.Lock()
+			defer p.mtx.Unlock()
+			entry := p.tokens["some-service"]
+			return !entry.refreshing && entry.refreshAt.After(p.now())
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should block when token is expired", func(t *testing.T) {
+		fail.Store(true)
+		now.Add(int64(time.Hour))
+
+		_, err := p.Token(context.Background(), "some-service")
+		require.Error(t, err)
+	})
+}
+
+func TestSignerTokenSource(t *testing.T) {
+	signer, err := NewSigner(SignerConfig{}, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{KeyID: firstKeyID, Key: firstKey, Algorithm: string(jose.ES256)},
+	}})
+	require.NoError(t, err)
+
+	source := SignerTokenSource(signer, jwt.Claims{Subject: "access-policy:1"}, AccessTokenClaims{Namespace: "*"})
+	token, err := source(context.Background(), "some-service")
+	require.NoError(t, err)
+
+	claims, err := NewAccessTokenVerifier(VerifierConfig{AllowedAudiences: []string{"some-service"}}, signer).Verify(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "access-policy:1", claims.Subject)
+}
+
+func TestExchangeTokenSource(t *testing.T) {
+	source := ExchangeTokenSource(&FakeTokenExchanger{token: "some-token"}, "stacks-1")
+	token, err := source(context.Background(), "some-service")
+	require.NoError(t, err)
+	assert.Equal(t, "some-token", token)
+}
+
+func TestExchangeTokenSource_Refresh(t *testing.T) {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: []byte("key")}, nil)
+	require.NoError(t, err)
+	var issued atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := jwt.Signed(signer).Claims(&jwt.Claims{
+			ID:     strconv.Itoa(int(issued.Add(1))),
+			Expiry: jwt.NewNumericDate(time.Now().Add(10 * time.Minute)),
+		}).CompactSerialize()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"token": "` + token + `"}}`))
+	}))
+	defer srv.Close()
+
+	exchanger, err := NewTokenExchangeClient(TokenExchangeConfig{Token: "some-token", TokenExchangeURL: srv.URL})
+	require.NoError(t, err)
+
+	var now atomic.Int64
+	now.Store(time.Now().UnixNano())
+	p := NewCachedTokenProvider(ExchangeTokenSource(exchanger, "stacks-1"))
+	p.now = func() time.Time { return time.Unix(0, now.Load()) }
+
+	original, err := p.Token(context.Background(), "some-service")
+	require.NoError(t, err)
+
+	now.Add(int64(9 * time.Minute))
+	require.Eventually(t, func() bool {
+		token, err := p.Token(context.Background(), "some-service")
+		require.NoError(t, err)
+		return token != original
+	}, time.Second, 10*time.Millisecond, "the refresh should not return the token cached by the exchanger")
+}
@@ -0,0 +1,28 @@
+package authn
+
+import (
+	"context"
+	"time"
+)
+
+// tokenRetryWindow is how long before their expiry the rejected access tokens are considered expired,
+// to account for the clock skew with the called services.
+const tokenRetryWindow = time.Minute
+
+// RefreshableTokenProvider is a TokenProvider able to drop a rejected token, so that the next call issues a new one.
+type RefreshableTokenProvider interface {
+	TokenProvider
+	Invalidate(ctx context.Context, audience, token string)
+}
+
+// RefreshableTokenExchanger is a TokenExchanger able to drop a rejected token, so that the next exchange issues a new one.
+type RefreshableTokenExchanger interface {
+	TokenExchanger
+	Invalidate(ctx context.Context, r TokenExchangeRequest, token string)
+}
+
+// tokenExpiring returns whether the rejected token has expired, or is about to.
+func tokenExpiring(token string) bool {
+	exp, err := tokenExpiry(token)
+	return err == nil && time.Until(exp) < tokenRetryWindow
+}
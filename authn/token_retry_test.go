@@ -0,0 +1,94 @@
+package authn
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// rotatingTokenSource issues a token about to expire, then fresh tokens.
+func rotatingTokenSource(t *testing.T) (TokenSource, string) {
+	expiring := signAccessTokenWithExpiry(t, time.Now().Add(30*time.Second))
+	fresh := signAccessToken(t)
+	issued := 0
+	return func(ctx context.Context, audience string) (string, error) {
+		issued++
+		if issued == 1 {
+			return expiring, nil
+		}
+		return fresh, nil
+	}, expiring
+}
+
+func TestGrpcClientInterceptor_TokenRetry(t *testing.T) {
+	t.Run("should retry once with a new token", func(t *testing.T) {
+		source, expiring := rotatingTokenSource(t)
+		gci, err := NewGrpcClientInterceptor(&GrpcClientConfig{}, WithTokenProviderOption(NewCachedTokenProvider(source), "some-service"))
+		require.NoError(t, err)
+
+		var tokens []string
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			tokens = append(tokens, md.Get(DefaultAccessTokenMetadataKey)[0])
+			if md.Get(DefaultAccessTokenMetadataKey)[0] == expiring {
+				return status.Error(codes.Unauthenticated, "token expired")
+			}
+			return nil
+		}
+
+		err = gci.UnaryClientInterceptor(context.Background(), "/my.Service/Method", nil, nil, nil, invoker)
+		require.NoError(t, err)
+		require.Len(t, tokens, 2)
+		require.NotEqual(t, tokens[0], tokens[1])
+	})
+
+	t.Run("should not retry valid tokens", func(t *testing.T) {
+		gci, tokenClient := setupGrpcClientInterceptor(t)
+		tokenClient.token = signAccessToken(t)
+
+		calls := 0
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls++
+			return status.Error(codes.Unauthenticated, "invalid id token")
+		}
+
+		err := gci.UnaryClientInterceptor(context.Background(), "/my.Service/Method", nil, nil, nil, invoker)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+		require.Equal(t, 1, calls)
+	})
+}
+
+func TestTokenRoundTripper_TokenRetry(t *testing.T) {
+	source, expiring := rotatingTokenSource(t)
+
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.Header.Get(DefaultAccessTokenHeader) == "Bearer "+expiring {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTokenRoundTripper(NewCachedTokenProvider(source), "some-service")}
+
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"payload", "payload"}, bodies)
+}
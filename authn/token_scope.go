@@ -0,0 +1,62 @@
+package authn
+
+import (
+	"slices"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/authlib/errs"
+)
+
+var ErrNoDelegatedPermissions = errs.New(errs.KindForbidden, "none of the required permissions can be delegated")
+
+// NarrowDelegatedPermissions returns the minimal delegated permissions to request when exchanging a token
+// for a plugin: the actions required by the plugin that the caller can delegate.
+// Missing are the required actions the caller cannot delegate, the plugin host can refuse to call the plugin.
+//
+// On behalf of a user, the caller can delegate the delegated permissions of its access token,
+// and of the impersonator if any. Without user, it can delegate the permissions of its access token.
+// A caller without access token (ex: access token checks disabled) can delegate any action.
+func NarrowDelegatedPermissions(caller claims.AuthInfo, required []string) (granted, missing []string) {
+	allows := func(string) bool { return true }
+
+	access, id := caller.GetAccess(), caller.GetIdentity()
+	if access != nil && !access.IsNil() {
+		perms := access.Permissions()
+		if id != nil && !id.IsNil() {
+			perms = access.DelegatedPermissions()
+		}
+		allows = func(action string) bool { return slices.Contains(perms, action) }
+	}
+
+	if actor, ok := claims.GetActor(id); ok {
+		serviceAllows := allows
+		allows = func(action string) bool {
+			return serviceAllows(action) && slices.Contains(actor.DelegatedPermissions(), action)
+		}
+	}
+
+	for _, action := range required {
+		if slices.Contains(granted, action) || slices.Contains(missing, action) {
+			continue
+		}
+		if allows(action) {
+			granted = append(granted, action)
+		} else {
+			missing = append(missing, action)
+		}
+	}
+	return granted, missing
+}
+
+// NarrowTokenExchangeRequest returns the exchange request restricted to the delegated permissions
+// the plugin requires and the caller can delegate (see NarrowDelegatedPermissions).
+// It returns ErrNoDelegatedPermissions when none can be delegated: an exchange request
+// without delegated permissions would get all the permissions of the access policy.
+func NarrowTokenExchangeRequest(r TokenExchangeRequest, caller claims.AuthInfo, required []string) (TokenExchangeRequest, error) {
+	granted, _ := NarrowDelegatedPermissions(caller, required)
+	if len(granted) == 0 {
+		return r, ErrNoDelegatedPermissions
+	}
+	r.DelegatedPermissions = granted
+	return r, nil
+}
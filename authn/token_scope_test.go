@@ -0,0 +1,81 @@
+package authn
+
+import (
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNarrowDelegatedPermissions(t *testing.T) {
+	access := NewAccessClaims(Claims[AccessTokenClaims]{
+		Claims: &jwt.Claims{Subject: "access-policy:grafana"},
+		Rest: AccessTokenClaims{
+			Permissions:          []string{"plugins:read"},
+			DelegatedPermissions: []string{"dashboards:read", "dashboards:write", "folders:read"},
+		},
+	})
+	user := NewIdentityClaims(Claims[IDTokenClaims]{Claims: &jwt.Claims{Subject: "user:1"}})
+	impersonated := NewIdentityClaims(Claims[IDTokenClaims]{
+		Claims: &jwt.Claims{Subject: "user:1"},
+		Rest:   IDTokenClaims{Actor: &ActorClaims{Subject: "user:2", DelegatedPermissions: []string{"dashboards:read"}}},
+	})
+
+	tests := []struct {
+		name        string
+		caller      *AuthInfo
+		required    []string
+		wantGranted []string
+		wantMissing []string
+	}{
+		{
+			name:        "should keep the delegated permissions required by the plugin",
+			caller:      &AuthInfo{AccessClaims: access, IdentityClaims: user},
+			required:    []string{"dashboards:read", "folders:read", "dashboards:read", "users:read"},
+			wantGranted: []string{"dashboards:read", "folders:read"},
+			wantMissing: []string{"users:read"},
+		},
+		{
+			name:        "should keep the permissions delegated by the impersonator",
+			caller:      &AuthInfo{AccessClaims: access, IdentityClaims: impersonated},
+			required:    []string{"dashboards:read", "dashboards:write"},
+			wantGranted: []string{"dashboards:read"},
+			wantMissing: []string{"dashboards:write"},
+		},
+		{
+			name:        "should keep the permissions of the service without user",
+			caller:      &AuthInfo{AccessClaims: access},
+			required:    []string{"plugins:read", "dashboards:read"},
+			wantGranted: []string{"plugins:read"},
+			wantMissing: []string{"dashboards:read"},
+		},
+		{
+			name:        "should keep all the required actions without access token",
+			caller:      &AuthInfo{IdentityClaims: user},
+			required:    []string{"users:read"},
+			wantGranted: []string{"users:read"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			granted, missing := NarrowDelegatedPermissions(tt.caller, tt.required)
+			require.Equal(t, tt.wantGranted, granted)
+			require.Equal(t, tt.wantMissing, missing)
+		})
+	}
+
+	t.Run("should narrow the exchange request", func(t *testing.T) {
+		req, err := NarrowTokenExchangeRequest(TokenExchangeRequest{Namespace: "stacks-12", Audiences: []string{"plugin"}},
+			&AuthInfo{AccessClaims: access, IdentityClaims: user}, []string{"dashboards:read"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"dashboards:read"}, req.DelegatedPermissions)
+		require.Equal(t, "stacks-12", req.Namespace)
+	})
+
+	t.Run("should refuse to exchange without delegated permissions", func(t *testing.T) {
+		_, err := NarrowTokenExchangeRequest(TokenExchangeRequest{Namespace: "stacks-12"},
+			&AuthInfo{AccessClaims: access, IdentityClaims: user}, []string{"users:read"})
+		require.ErrorIs(t, err, ErrNoDelegatedPermissions)
+	})
+}
@@ -2,22 +2,33 @@ package authn
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/grafana/authlib/internal/redact"
 )
 
 func NewUnsafeVerifier[T any](cfg VerifierConfig, typ TokenType) *UnsafeVerifierBase[T] {
 	v := &UnsafeVerifierBase[T]{
-		cfg:       cfg,
-		tokenType: typ,
+		tokenType:   typ,
+		knownClaims: knownClaims[T](),
 	}
+	v.cfg.Store(&cfg)
 	return v
 }
 
 type UnsafeVerifierBase[T any] struct {
-	cfg       VerifierConfig
+	cfg       atomic.Pointer[VerifierConfig]
 	tokenType TokenType
+	// knownClaims are the top-level claims accepted in strict mode
+	knownClaims map[string]bool
+}
+
+// Reload atomically replaces the verifier configuration (ex: the allowed audiences).
+func (v *UnsafeVerifierBase[T]) Reload(cfg VerifierConfig) {
+	v.cfg.Store(&cfg)
 }
 
 // Verify will parse and verify provided token, if `AllowedAudiences` was configured those will be validated as well.
@@ -31,20 +42,41 @@ func (v *UnsafeVerifierBase[T]) Verify(ctx context.Context, token string) (*Clai
 		return nil, ErrInvalidTokenType
 	}
 
+	cfg := v.cfg.Load()
+
+	if !validAlgorithm(parsed, cfg.AllowedAlgorithms) {
+		return nil, ErrInvalidAlgorithm
+	}
+
 	claims := Claims[T]{
-		token: token, // hold on to the original token
+		token: redact.Secret(token), // hold on to the original token
 	}
 
-	if err := parsed.UnsafeClaimsWithoutVerification(&claims.Claims, &claims.Rest); err != nil {
+	var raw map[string]any
+	out := []any{&claims.Claims, &claims.Rest}
+	if cfg.StrictClaims {
+		out = append(out, &raw)
+	}
+	if err := parsed.UnsafeClaimsWithoutVerification(out...); err != nil {
 		return nil, err
 	}
 
 	if err := claims.Validate(jwt.Expected{
-		Audience: v.cfg.AllowedAudiences,
+		Audience: cfg.AllowedAudiences,
 		Time:     time.Now(),
 	}); err != nil {
 		return nil, mapErr(err)
 	}
 
+	if cfg.StrictClaims {
+		if err := validateClaims(cfg, v.knownClaims, raw); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateNamespace(ctx, cfg, claims.Rest); err != nil {
+		return nil, err
+	}
+
 	return &claims, nil
 }
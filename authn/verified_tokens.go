@@ -0,0 +1,119 @@
+package authn
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+const DefaultVerifiedTokenCacheSize = 10000
+
+// WithVerifiedTokenCache caches the claims of the verified tokens until they expire, so that the hot paths
+// verifying the same token repeatedly skip the signature verification. At most size tokens are cached, defaults
+// to DefaultVerifiedTokenCacheSize, and the least recently used are evicted first. The tokens are cached by hash,
+// the tokens without expiry and the opaque tokens verified by introspection are not cached. The cache is dropped
+// when the verifier is reloaded.
+func WithVerifiedTokenCache(size int) VerifierOption {
+	return func(o *verifierOptions) {
+		if size <= 0 {
+			size = DefaultVerifiedTokenCacheSize
+		}
+		o.verifiedTokenCacheSize = size
+	}
+}
+
+// verifiedTokens are the claims of the verified tokens, by hash of the token, until they expire.
+// The least recently used tokens are evicted when the cache is full.
+// It is safe for concurrent use, and nil-safe: a nil cache caches nothing.
+type verifiedTokens[T any] struct {
+	size int
+	now  func() time.Time
+
+	mtx    sync.Mutex
+	tokens map[[sha256.Size]byte]*list.Element
+	// lru holds the verifiedToken entries, most recently used first.
+	lru *list.List
+}
+
+type verifiedToken[T any] struct {
+	key    [sha256.Size]byte
+	claims *Claims[T]
+	keyID  string
+	expiry time.Time
+}
+
+func newVerifiedTokens[T any](size int) *verifiedTokens[T] {
+	if size <= 0 {
+		return nil
+	}
+	return &verifiedTokens[T]{size: size, now: time.Now, tokens: map[[sha256.Size]byte]*list.Element{}, lru: list.New()}
+}
+
+// get returns a copy of the claims of the token with the ID of its signing key, if it was verified and has not
+// expired.
+func (v *verifiedTokens[T]) get(token string) (*Claims[T], string, bool) {
+	if v == nil {
+		return nil, "", false
+	}
+
+	key := sha256.Sum256([]byte(token))
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	elem, ok := v.tokens[key]
+	if !ok {
+		return nil, "", false
+	}
+	entry := elem.Value.(*verifiedToken[T])
+	if !v.now().Before(entry.expiry) {
+		v.remove(elem)
+		return nil, "", false
+	}
+	v.lru.MoveToFront(elem)
+	claims := *entry.claims
+	return &claims, entry.keyID, true
+}
+
+// set caches the claims of the verified token until it expires. When the cache is full, the least recently
+// used token is evicted.
+func (v *verifiedTokens[T]) set(token, keyID string, claims *Claims[T]) {
+	if v == nil || claims.Claims == nil || claims.Expiry == nil {
+		return
+	}
+
+	key := sha256.Sum256([]byte(token))
+	stored := *claims
+	entry := &verifiedToken[T]{key: key, claims: &stored, keyID: keyID, expiry: claims.Expiry.Time()}
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	if elem, ok := v.tokens[key]; ok {
+		elem.Value = entry
+		v.lru.MoveToFront(elem)
+		return
+	}
+
+	v.tokens[key] = v.lru.PushFront(entry)
+	if v.lru.Len() > v.size {
+		v.remove(v.lru.Back())
+	}
+}
+
+func (v *verifiedTokens[T]) remove(elem *list.Element) {
+	v.lru.Remove(elem)
+	delete(v.tokens, elem.Value.(*verifiedToken[T]).key)
+}
+
+// reset drops the cached tokens.
+func (v *verifiedTokens[T]) reset() {
+	if v == nil {
+		return
+	}
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	v.tokens = map[[sha256.Size]byte]*list.Element{}
+	v.lru.Init()
+}
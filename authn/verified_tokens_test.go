@@ -0,0 +1,101 @@
+package authn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRetriever counts the retrievals of the signing keys, one per verified signature.
+type countingRetriever struct {
+	KeyRetriever
+	calls int
+}
+
+func (r *countingRetriever) Get(ctx context.Context, keyID string) (*jose.JSONWebKey, error) {
+	r.calls++
+	return r.KeyRetriever.Get(ctx, keyID)
+}
+
+func TestVerifier_VerifiedTokenCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(keys())
+	}))
+	defer server.Close()
+
+	type CustomClaims struct{}
+	retriever := &countingRetriever{KeyRetriever: NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL})}
+	verifier := NewVerifier[CustomClaims](VerifierConfig{}, TokenTypeID, retriever, WithVerifiedTokenCache(1))
+	ctx := context.Background()
+	token := signFirst(t)
+
+	for i := 0; i < 3; i++ {
+		claims, err := verifier.Verify(ctx, token)
+		require.NoError(t, err)
+		require.Equal(t, jwt.Audience{"stack:1"}, claims.Audience)
+	}
+	require.Equal(t, 1, retriever.calls, "should skip the verification of the cached token")
+
+	t.Run("should evict the tokens when full", func(t *testing.T) {
+		_, err := verifier.Verify(ctx, signSecond(t))
+		require.NoError(t, err)
+
+		calls := retriever.calls
+		_, err = verifier.Verify(ctx, token)
+		require.NoError(t, err)
+		require.Equal(t, calls+1, retriever.calls)
+	})
+
+	t.Run("should expire the tokens", func(t *testing.T) {
+		calls := retriever.calls
+		verifier.verified.now = func() time.Time { return time.Now().Add(time.Minute) }
+		defer func() { verifier.verified.now = time.Now }()
+		_, err := verifier.Verify(ctx, token)
+		require.NoError(t, err)
+		require.Equal(t, calls+1, retriever.calls)
+	})
+
+	t.Run("should not cache the failed verifications", func(t *testing.T) {
+		expired := signExpired(t)
+		calls := retriever.calls
+		for i := 0; i < 2; i++ {
+			_, err := verifier.Verify(ctx, expired)
+			require.ErrorIs(t, err, ErrExpiredToken)
+		}
+		require.Equal(t, calls+2, retriever.calls, "should verify the failed token again")
+	})
+
+	t.Run("should drop the tokens on reload", func(t *testing.T) {
+		_, err := verifier.Verify(ctx, token)
+		require.NoError(t, err)
+		verifier.Reload(VerifierConfig{AllowedAudiences: jwt.Audience{"stack:2"}})
+		_, err = verifier.Verify(ctx, token)
+		require.ErrorIs(t, err, ErrInvalidAudience)
+	})
+}
+
+func TestVerifiedTokens_Evict(t *testing.T) {
+	tokens := newVerifiedTokens[struct{}](2)
+	claims := &Claims[struct{}]{Claims: &jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Minute))}}
+
+	tokens.set("a", "key", claims)
+	tokens.set("b", "key", claims)
+	_, _, ok := tokens.get("a")
+	require.True(t, ok)
+
+	tokens.set("c", "key", claims)
+	_, _, ok = tokens.get("b")
+	require.False(t, ok, "should evict the least recently used token")
+	for _, token := range []string{"a", "c"} {
+		_, _, ok = tokens.get(token)
+		require.True(t, ok)
+	}
+	require.Len(t, tokens.tokens, 2)
+	require.Equal(t, 2, tokens.lru.Len())
+}
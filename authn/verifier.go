@@ -2,11 +2,18 @@ package authn
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-jose/go-jose/v3"
 	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/grafana/authlib/internal/redact"
 )
 
 type TokenType = string
@@ -21,46 +28,204 @@ type Verifier[T any] interface {
 	Verify(ctx context.Context, token string) (*Claims[T], error)
 }
 
-func NewVerifier[T any](cfg VerifierConfig, typ TokenType, keys KeyRetriever) *VerifierBase[T] {
-	return &VerifierBase[T]{cfg, typ, keys}
+// VerifierOption allows setting custom parameters during construction.
+type VerifierOption func(o *verifierOptions)
+
+type verifierOptions struct {
+	introspector           Introspector
+	decryptionKey          interface{}
+	verifiedTokenCacheSize int
+	revocations            Revocations
+}
+
+// WithIntrospection verifies the opaque (non-JWT) tokens with the introspector, instead of rejecting them.
+// The registered claims and the custom claims of the active tokens are read from the introspection response,
+// and validated like the claims of the JWTs, except for StrictClaims. The token_type of the response must match
+// the type of the verifier: access token verifiers accept the access tokens ("Bearer", "access_token"...) and the
+// responses without token_type, ID token verifiers only accept "id_token".
+// The malformed JWTs are rejected without being introspected.
+func WithIntrospection(introspector Introspector) VerifierOption {
+	return func(o *verifierOptions) {
+		o.introspector = introspector
+	}
+}
+
+// WithDecryptionKey decrypts the nested JWE tokens (signed then encrypted JWTs) with the key, before verifying
+// their signature, for the tokens carrying PII through intermediaries. The key is the private key, or the shared
+// key, of the key management algorithm of the tokens (ex: *ecdsa.PrivateKey for ECDH-ES).
+// The signed tokens which are not encrypted are still accepted.
+func WithDecryptionKey(key interface{}) VerifierOption {
+	return func(o *verifierOptions) {
+		o.decryptionKey = key
+	}
+}
+
+func NewVerifier[T any](cfg VerifierConfig, typ TokenType, keys KeyRetriever, opts ...VerifierOption) *VerifierBase[T] {
+	v := &VerifierBase[T]{tokenType: typ, keys: keys, knownClaims: knownClaims[T]()}
+	for _, opt := range opts {
+		opt(&v.opts)
+	}
+	v.verified = newVerifiedTokens[T](v.opts.verifiedTokenCacheSize)
+	v.cfg.Store(&cfg)
+	return v
 }
 
 type VerifierBase[T any] struct {
-	cfg       VerifierConfig
+	cfg       atomic.Pointer[VerifierConfig]
 	tokenType TokenType
 	keys      KeyRetriever
+	// knownClaims are the top-level claims accepted in strict mode
+	knownClaims map[string]bool
+	opts        verifierOptions
+	// verified caches the claims of the verified tokens, if enabled
+	verified *verifiedTokens[T]
+}
+
+// Reload atomically replaces the verifier configuration (ex: the allowed audiences).
+func (v *VerifierBase[T]) Reload(cfg VerifierConfig) {
+	v.cfg.Store(&cfg)
+	v.verified.reset()
 }
 
 // Verify will parse and verify provided token, if `AllowedAudiences` was configured those will be validated as well.
 func (v *VerifierBase[T]) Verify(ctx context.Context, token string) (*Claims[T], error) {
-	parsed, err := jwt.ParseSigned(token)
+	claims, keyID, cached := v.verified.get(token)
+	if !cached {
+		var err error
+		if claims, keyID, err = v.verify(ctx, token); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate the namespace of the cached tokens too, it can depend on the request
+	if err := validateNamespace(ctx, v.cfg.Load(), claims.Rest); err != nil {
+		return nil, err
+	}
+
+	if v.opts.revocations != nil {
+		revoked, err := v.opts.revocations.IsRevoked(ctx, keyID, claims.Claims)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
+
+	// Only cache the signed tokens, the opaque tokens have no signing key
+	if !cached && keyID != "" {
+		v.verified.set(token, keyID, claims)
+	}
+	return claims, nil
+}
+
+// verify verifies the token, and returns its claims with the ID of its signing key.
+func (v *VerifierBase[T]) verify(ctx context.Context, token string) (*Claims[T], string, error) {
+	parsed, err := v.parse(token)
+	if errors.Is(err, ErrParseToken) && v.opts.introspector != nil && !isCompactJWT(token) {
+		claims, err := v.introspect(ctx, token)
+		return claims, "", err
+	}
 	if err != nil {
-		return nil, ErrParseToken
+		return nil, "", err
 	}
 
 	if !validType(parsed, v.tokenType) {
-		return nil, ErrInvalidTokenType
+		return nil, "", ErrInvalidTokenType
+	}
+
+	cfg := v.cfg.Load()
+
+	if !validAlgorithm(parsed, cfg.AllowedAlgorithms) {
+		return nil, "", ErrInvalidAlgorithm
 	}
 
 	keyID, err := getKeyID(parsed.Headers)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	jwk, err := v.keys.Get(ctx, keyID)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	claims := Claims[T]{
-		token: token, // hold on to the original token
+		token: redact.Secret(token), // hold on to the original token
+	}
+	var raw map[string]any
+	out := []any{&claims.Claims, &claims.Rest}
+	if cfg.StrictClaims {
+		out = append(out, &raw)
+	}
+	if err := parsed.Claims(jwk, out...); err != nil {
+		return nil, "", err
+	}
+
+	if err := claims.Validate(jwt.Expected{
+		Audience: cfg.AllowedAudiences,
+		Time:     time.Now(),
+	}); err != nil {
+		return nil, "", mapErr(err)
 	}
-	if err := parsed.Claims(jwk, &claims.Claims, &claims.Rest); err != nil {
+
+	if cfg.StrictClaims {
+		if err := validateClaims(cfg, v.knownClaims, raw); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return &claims, keyID, nil
+}
+
+// parse parses the signed token, decrypting it first when it is a JWE token and a decryption key is configured.
+func (v *VerifierBase[T]) parse(token string) (*jwt.JSONWebToken, error) {
+	// The compact serialization of the JWE tokens has five parts
+	if v.opts.decryptionKey != nil && strings.Count(token, ".") == 4 {
+		nested, err := jwt.ParseSignedAndEncrypted(token)
+		if err != nil {
+			return nil, ErrParseToken
+		}
+		parsed, err := nested.Decrypt(v.opts.decryptionKey)
+		if err != nil {
+			return nil, ErrDecryptToken
+		}
+		return parsed, nil
+	}
+
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, ErrParseToken
+	}
+	return parsed, nil
+}
+
+// introspect verifies the opaque token with the introspector.
+func (v *VerifierBase[T]) introspect(ctx context.Context, token string) (*Claims[T], error) {
+	res, err := v.opts.introspector.Introspect(ctx, token)
+	if err != nil {
 		return nil, err
 	}
+	if !res.Active {
+		return nil, ErrInactiveToken
+	}
+	if !validIntrospectedType(res.TokenType, v.tokenType) {
+		return nil, ErrInvalidTokenType
+	}
+
+	registered := res.Claims
+	claims := Claims[T]{
+		Claims: &registered,
+		token:  redact.Secret(token),
+	}
+	if len(res.raw) > 0 {
+		if err := json.Unmarshal(res.raw, &claims.Rest); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrIntrospection, err)
+		}
+	}
 
 	if err := claims.Validate(jwt.Expected{
-		Audience: v.cfg.AllowedAudiences,
+		Audience: v.cfg.Load().AllowedAudiences,
 		Time:     time.Now(),
 	}); err != nil {
 		return nil, mapErr(err)
@@ -69,6 +234,30 @@ func (v *VerifierBase[T]) Verify(ctx context.Context, token string) (*Claims[T],
 	return &claims, nil
 }
 
+// isCompactJWT returns whether the token has the shape of a compact JWS (3 parts) or JWE (5 parts) token.
+func isCompactJWT(token string) bool {
+	parts := strings.Count(token, ".")
+	return parts == 2 || parts == 4
+}
+
+// validIntrospectedType returns whether the token_type of an introspection response matches the verifier type.
+func validIntrospectedType(tokenType string, typ TokenType) bool {
+	tokenType = strings.ToLower(tokenType)
+	switch typ {
+	case "":
+		return true
+	case TokenTypeAccess:
+		switch tokenType {
+		case "", "bearer", "access_token", "urn:ietf:params:oauth:token-type:access_token", TokenTypeAccess:
+			return true
+		}
+		return false
+	case TokenTypeID:
+		return tokenType == "id_token" || tokenType == "urn:ietf:params:oauth:token-type:id_token"
+	}
+	return tokenType == strings.ToLower(typ)
+}
+
 func validType(token *jwt.JSONWebToken, typ string) bool {
 	if typ == "" {
 		return true
@@ -82,6 +271,20 @@ func validType(token *jwt.JSONWebToken, typ string) bool {
 	return false
 }
 
+// validAlgorithm returns whether the token is signed with one of the allowed algorithms, if any.
+func validAlgorithm(token *jwt.JSONWebToken, allowed []jose.SignatureAlgorithm) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, h := range token.Headers {
+		if !slices.Contains(allowed, jose.SignatureAlgorithm(h.Algorithm)) {
+			return false
+		}
+	}
+	return len(token.Headers) > 0
+}
+
 func mapErr(err error) error {
 	if errors.Is(err, jwt.ErrExpired) {
 		return ErrExpiredToken
@@ -7,18 +7,23 @@ import (
 type AccessTokenClaims struct {
 	// Namespace takes the form of '<type>-<id>', '*' means all namespaces.
 	// Type can be either org or stack.
+	// Fleet-wide tokens can use a pattern ('stacks-*') or a comma-separated list of namespaces.
 	Namespace string `json:"namespace"`
 	// Access policy scopes
 	Scopes []string `json:"scopes"`
+	// OAuth scopes, space-delimited [RFC 9068 §2.2.3]: https://datatracker.ietf.org/doc/html/rfc9068#section-2.2.3
+	Scope string `json:"scope,omitempty"`
+	// OAuth scopes as issued by some providers (ex: Okta or Entra ID), either a list or space-delimited
+	Scp SpaceDelimited `json:"scp,omitempty"`
 	// Grafana roles
 	Permissions []string `json:"permissions"`
 	// On-behalf-of user
 	DelegatedPermissions []string `json:"delegatedPermissions"`
 }
 
-func NewAccessTokenVerifier(cfg VerifierConfig, keys KeyRetriever) *AccessTokenVerifier {
+func NewAccessTokenVerifier(cfg VerifierConfig, keys KeyRetriever, opts ...VerifierOption) *AccessTokenVerifier {
 	return &AccessTokenVerifier{
-		v: NewVerifier[AccessTokenClaims](cfg, TokenTypeAccess, keys),
+		v: NewVerifier[AccessTokenClaims](cfg, TokenTypeAccess, keys, opts...),
 	}
 }
 
@@ -37,3 +42,10 @@ type AccessTokenVerifier struct {
 func (e *AccessTokenVerifier) Verify(ctx context.Context, token string) (*Claims[AccessTokenClaims], error) {
 	return e.v.Verify(ctx, token)
 }
+
+// Reload replaces the configuration of the underlying verifier.
+func (e *AccessTokenVerifier) Reload(cfg VerifierConfig) {
+	if r, ok := e.v.(reloader[VerifierConfig]); ok {
+		r.Reload(cfg)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/go-jose/go-jose/v3/jwt"
+
 	"github.com/grafana/authlib/claims"
 )
 
@@ -23,6 +25,30 @@ type IDTokenClaims struct {
 	Username string `json:"username,omitempty"`
 	// Display name of the user (name attribute if it is set, otherwise the login or email)
 	DisplayName string `json:"name,omitempty"`
+	// Actor is the identity impersonating the subject, if any.
+	Actor *ActorClaims `json:"act,omitempty"`
+	// BreakGlass is the emergency access grant of the identity, if any.
+	BreakGlass *BreakGlassClaims `json:"breakGlass,omitempty"`
+	// Groups are the names of the identity provider groups the entity is a member of.
+	Groups []string `json:"groups,omitempty"`
+	// Teams are the UIDs of the teams the entity is a member of.
+	Teams []string `json:"teams,omitempty"`
+}
+
+// BreakGlassClaims is an emergency access grant, see claims.BreakGlass.
+type BreakGlassClaims struct {
+	// Reason of the emergency access (ex: an incident reference).
+	Reason string `json:"reason"`
+	// IssuedAt is when the emergency access was granted.
+	IssuedAt *jwt.NumericDate `json:"iat"`
+}
+
+// ActorClaims identifies the impersonator of an ID token.
+type ActorClaims struct {
+	// Subject of the impersonator, in the form: <IdentityType>:<Identifier>
+	Subject string `json:"sub"`
+	// DelegatedPermissions are the actions the impersonator delegates to the impersonation.
+	DelegatedPermissions []string `json:"delegatedPermissions,omitempty"`
 }
 
 // Helper for the id
@@ -43,9 +69,9 @@ func (c IDTokenClaims) getK8sName() string {
 	return c.Identifier
 }
 
-func NewIDTokenVerifier(cfg VerifierConfig, keys KeyRetriever) *IDTokenVerifier {
+func NewIDTokenVerifier(cfg VerifierConfig, keys KeyRetriever, opts ...VerifierOption) *IDTokenVerifier {
 	return &IDTokenVerifier{
-		v: NewVerifier[IDTokenClaims](cfg, TokenTypeID, keys),
+		v: NewVerifier[IDTokenClaims](cfg, TokenTypeID, keys, opts...),
 	}
 }
 
@@ -64,3 +90,10 @@ type IDTokenVerifier struct {
 func (e *IDTokenVerifier) Verify(ctx context.Context, token string) (*Claims[IDTokenClaims], error) {
 	return e.v.Verify(ctx, token)
 }
+
+// Reload replaces the configuration of the underlying verifier.
+func (e *IDTokenVerifier) Reload(cfg VerifierConfig) {
+	if r, ok := e.v.(reloader[VerifierConfig]); ok {
+		r.Reload(cfg)
+	}
+}
@@ -16,6 +16,8 @@ import (
 	"github.com/go-jose/go-jose/v3/jwt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/internal/redact"
 )
 
 var firstKeyID = "key-1"
@@ -167,6 +169,28 @@ func TestVerifier_Verify(t *testing.T) {
 		assert.Nil(t, claims)
 	})
 
+	t.Run("invalid: signature algorithm not allowed", func(t *testing.T) {
+		verifier := NewVerifier[CustomClaims](
+			VerifierConfig{AllowedAlgorithms: []jose.SignatureAlgorithm{jose.RS256}},
+			TokenTypeID,
+			NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL}),
+		)
+		claims, err := verifier.Verify(context.Background(), signFirst(t))
+		assert.ErrorIs(t, err, ErrInvalidAlgorithm)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("valid: signature algorithm allowed", func(t *testing.T) {
+		verifier := NewVerifier[CustomClaims](
+			VerifierConfig{AllowedAlgorithms: []jose.SignatureAlgorithm{jose.RS256, jose.ES256}},
+			TokenTypeID,
+			NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL}),
+		)
+		claims, err := verifier.Verify(context.Background(), signFirst(t))
+		assert.NoError(t, err)
+		assert.NotNil(t, claims)
+	})
+
 	t.Run("valid: token audience allowed", func(t *testing.T) {
 		verifier := NewVerifier[CustomClaims](
 			VerifierConfig{AllowedAudiences: []string{"stack:1"}},
@@ -216,3 +240,85 @@ func signToken(t *testing.T, keyID string, key *ecdsa.PrivateKey, exp time.Time)
 
 	return token
 }
+
+func TestVerifier_Reload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(keys())
+	}))
+
+	verifier := NewIDTokenVerifier(
+		VerifierConfig{AllowedAudiences: jwt.Audience{"stack:2"}},
+		NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL}),
+	)
+
+	_, err := verifier.Verify(context.Background(), signFirst(t))
+	require.ErrorIs(t, err, ErrInvalidAudience)
+
+	verifier.Reload(VerifierConfig{AllowedAudiences: jwt.Audience{"stack:1"}})
+
+	claims, err := verifier.Verify(context.Background(), signFirst(t))
+	require.NoError(t, err)
+	require.NotNil(t, claims)
+}
+
+func TestClaims_RedactToken(t *testing.T) {
+	token := signFirst(t)
+	claims := Claims[IDTokenClaims]{Claims: &jwt.Claims{}, token: redact.Secret(token)}
+
+	require.NotContains(t, fmt.Sprintf("%v", claims), token)
+	require.NotContains(t, fmt.Sprintf("%+v", claims), token)
+	require.Equal(t, token, (&AuthInfo{IdentityClaims: NewIdentityClaims(claims)}).GetExtra()["id-token"][0])
+}
+
+func TestVerifier_Verify_Encrypted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(keys())
+	}))
+	defer server.Close()
+
+	type CustomClaims struct{}
+	retriever := NewKeyRetriever(KeyRetrieverConfig{SigningKeysURL: server.URL})
+	verifier := NewVerifier[CustomClaims](VerifierConfig{}, TokenTypeID, retriever, WithDecryptionKey(secondKey))
+
+	t.Run("valid: encrypted token", func(t *testing.T) {
+		claims, err := verifier.Verify(context.Background(), signEncrypted(t, &secondKey.PublicKey))
+		require.NoError(t, err)
+		require.Equal(t, jwt.Audience{"stack:1"}, claims.Audience)
+	})
+
+	t.Run("valid: signed token", func(t *testing.T) {
+		_, err := verifier.Verify(context.Background(), signFirst(t))
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid: encrypted for another key", func(t *testing.T) {
+		_, err := verifier.Verify(context.Background(), signEncrypted(t, &firstKey.PublicKey))
+		require.ErrorIs(t, err, ErrDecryptToken)
+	})
+
+	t.Run("invalid: encrypted token without decryption key", func(t *testing.T) {
+		verifier := NewVerifier[CustomClaims](VerifierConfig{}, TokenTypeID, retriever)
+		_, err := verifier.Verify(context.Background(), signEncrypted(t, &secondKey.PublicKey))
+		require.ErrorIs(t, err, ErrParseToken)
+	})
+}
+
+func signEncrypted(t *testing.T, key *ecdsa.PublicKey) string {
+	t.Helper()
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: firstKey}, &jose.SignerOptions{
+		ExtraHeaders: map[jose.HeaderKey]interface{}{"kid": firstKeyID, "typ": TokenTypeID},
+	})
+	require.NoError(t, err)
+	encrypter, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{Algorithm: jose.ECDH_ES_A256KW, Key: key},
+		(&jose.EncrypterOptions{}).WithContentType("JWT"))
+	require.NoError(t, err)
+
+	token, err := jwt.SignedAndEncrypted(signer, encrypter).
+		Claims(jwt.Claims{Audience: jwt.Audience{"stack:1"}, Expiry: jwt.NewNumericDate(time.Now().Add(time.Minute))}).
+		CompactSerialize()
+	require.NoError(t, err)
+
+	return token
+}
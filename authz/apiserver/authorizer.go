@@ -0,0 +1,187 @@
+// Package apiserver checks the requests of an API server with authz.MultiTenantClient,
+// for Grafana apps embedding an aggregated API server.
+//
+// The package does not depend on k8s.io/apiserver: Attributes is the subset of authorizer.Attributes
+// used by the checks, and Decision has the values of authorizer.Decision. The authorizer.Authorizer
+// of k8s.io/apiserver is implemented by the Authorizer of the github.com/grafana/authlib/k8s module.
+package apiserver
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/grafana/authlib/authz"
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/authlib/errs"
+)
+
+var (
+	ErrMissingCaller    = errs.New(errs.KindUnauthenticated, "missing caller")
+	ErrInvalidNamespace = errs.New(errs.KindBadRequest, "invalid namespace")
+)
+
+// Decision is the decision of the authorizer, with the values of k8s.io/apiserver authorizer.Decision.
+type Decision int
+
+const (
+	DecisionDeny Decision = iota
+	DecisionAllow
+	DecisionNoOpinion
+)
+
+// Attributes are the attributes of the request to authorize.
+// They are implemented by k8s.io/apiserver authorizer.Attributes.
+type Attributes interface {
+	GetVerb() string
+	GetNamespace() string
+	GetResource() string
+	GetSubresource() string
+	GetName() string
+	GetAPIGroup() string
+	IsResourceRequest() bool
+}
+
+// StackIDFunc returns the stack ID the permissions are checked against for the namespace of the request.
+type StackIDFunc func(namespace string) (int64, error)
+
+// DefaultStackID returns the stack ID of cloud namespaces (ex: "stacks-12") and the org ID of
+// on-prem namespaces (ex: "default", "org-2").
+func DefaultStackID(namespace string) (int64, error) {
+	info, err := claims.ParseNamespace(namespace)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidNamespace, err)
+	}
+	if info.StackID > 0 {
+		return info.StackID, nil
+	}
+	if info.OrgID > 0 {
+		return info.OrgID, nil
+	}
+	return 0, ErrInvalidNamespace
+}
+
+// CallerFunc returns the caller of the request, when it is not in the context (see claims.WithAuthInfo),
+// ex: from the user of the k8s.io/apiserver attributes.
+type CallerFunc func(ctx context.Context, attrs Attributes) (claims.AuthInfo, bool)
+
+// DetailedChecker is implemented by the clients explaining their decisions (ex: authz.LegacyClientImpl).
+// The reason of the decision is then returned by the authorizer, and shows in the API server audit logs.
+type DetailedChecker interface {
+	CheckDetailed(ctx context.Context, req *authz.CheckRequest) (authz.CheckResult, error)
+}
+
+var _ DetailedChecker = (*authz.LegacyClientImpl)(nil)
+
+type Option func(*Authorizer)
+
+func WithTracerOption(tracer trace.Tracer) Option {
+	return func(a *Authorizer) {
+		a.tracer = tracer
+	}
+}
+
+// WithStackIDOption sets how namespaces are mapped to stack IDs. Defaults to DefaultStackID.
+func WithStackIDOption(fn StackIDFunc) Option {
+	return func(a *Authorizer) {
+		a.stackID = fn
+	}
+}
+
+// WithCallerOption sets how the caller is resolved when it is not in the context.
+func WithCallerOption(fn CallerFunc) Option {
+	return func(a *Authorizer) {
+		a.caller = fn
+	}
+}
+
+// Authorizer authorizes the resource requests of an API server with a MultiTenantClient.
+// The caller is read from the context (see claims.WithAuthInfo), the attributes are
+// forwarded as authz.ResourceAttributes and mapped to actions by the client.
+type Authorizer struct {
+	client  authz.MultiTenantClient
+	stackID StackIDFunc
+	caller  CallerFunc
+	tracer  trace.Tracer
+}
+
+func New(client authz.MultiTenantClient, opts ...Option) *Authorizer {
+	a := &Authorizer{client: client}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.stackID == nil {
+		a.stackID = DefaultStackID
+	}
+
+	if a.tracer == nil {
+		a.tracer = noop.Tracer{}
+	}
+
+	return a
+}
+
+// Authorize authorizes the request with its attributes.
+// Non-resource requests and cluster-scoped requests are left to the other authorizers.
+func (a *Authorizer) Authorize(ctx context.Context, attrs Attributes) (Decision, string, error) {
+	ctx, span := a.tracer.Start(ctx, "Authorizer.Authorize")
+	defer span.End()
+
+	if !attrs.IsResourceRequest() || attrs.GetNamespace() == "" {
+		return DecisionNoOpinion, "", nil
+	}
+
+	span.SetAttributes(
+		attribute.String("group", attrs.GetAPIGroup()),
+		attribute.String("resource", attrs.GetResource()),
+		attribute.String("verb", attrs.GetVerb()),
+	)
+
+	caller, ok := claims.AuthInfoFrom(ctx)
+	if !ok && a.caller != nil {
+		caller, ok = a.caller(ctx, attrs)
+	}
+	if !ok {
+		return DecisionDeny, "missing caller", ErrMissingCaller
+	}
+
+	stackID, err := a.stackID(attrs.GetNamespace())
+	if err != nil {
+		span.RecordError(err)
+		return DecisionDeny, "invalid namespace", err
+	}
+
+	req := &authz.CheckRequest{
+		Caller:  caller,
+		StackID: stackID,
+		Attributes: &authz.ResourceAttributes{
+			Group:       attrs.GetAPIGroup(),
+			Resource:    attrs.GetResource(),
+			Subresource: attrs.GetSubresource(),
+			Verb:        attrs.GetVerb(),
+			Name:        attrs.GetName(),
+		},
+	}
+
+	res, err := a.check(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		return DecisionDeny, "check failed", err
+	}
+	if !res.Allowed {
+		return DecisionDeny, string(res.Reason), nil
+	}
+	return DecisionAllow, string(res.Reason), nil
+}
+
+func (a *Authorizer) check(ctx context.Context, req *authz.CheckRequest) (authz.CheckResult, error) {
+	if dc, ok := a.client.(DetailedChecker); ok {
+		return dc.CheckDetailed(ctx, req)
+	}
+	allowed, err := a.client.Check(ctx, req)
+	return authz.CheckResult{Allowed: allowed}, err
+}
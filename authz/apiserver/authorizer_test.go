@@ -0,0 +1,142 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/authz"
+	"github.com/grafana/authlib/claims"
+)
+
+type fakeClient struct {
+	allowed bool
+	err     error
+	lastReq *authz.CheckRequest
+}
+
+func (f *fakeClient) Check(_ context.Context, req *authz.CheckRequest) (bool, error) {
+	f.lastReq = req
+	return f.allowed, f.err
+}
+
+type fakeAttributes struct {
+	verb, namespace, resource, subresource, name, group string
+	resourceRequest                                     bool
+}
+
+func (a fakeAttributes) GetVerb() string         { return a.verb }
+func (a fakeAttributes) GetNamespace() string    { return a.namespace }
+func (a fakeAttributes) GetResource() string     { return a.resource }
+func (a fakeAttributes) GetSubresource() string  { return a.subresource }
+func (a fakeAttributes) GetName() string         { return a.name }
+func (a fakeAttributes) GetAPIGroup() string     { return a.group }
+func (a fakeAttributes) IsResourceRequest() bool { return a.resourceRequest }
+
+func TestAuthorizer_Authorize(t *testing.T) {
+	caller := &authn.AuthInfo{
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: "user:1"},
+			Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+		}),
+	}
+	ctx := claims.WithAuthInfo(context.Background(), caller)
+	get := fakeAttributes{verb: "get", namespace: "stacks-12", resource: "dashboards", subresource: "status", name: "abc", group: "dashboard.grafana.app", resourceRequest: true}
+
+	t.Run("should translate the attributes", func(t *testing.T) {
+		client := &fakeClient{allowed: true}
+		decision, _, err := New(client).Authorize(ctx, get)
+		require.NoError(t, err)
+		require.Equal(t, DecisionAllow, decision)
+		require.Equal(t, &authz.CheckRequest{
+			Caller:  caller,
+			StackID: 12,
+			Attributes: &authz.ResourceAttributes{
+				Group:       "dashboard.grafana.app",
+				Resource:    "dashboards",
+				Subresource: "status",
+				Verb:        "get",
+				Name:        "abc",
+			},
+		}, client.lastReq)
+	})
+
+	t.Run("should deny", func(t *testing.T) {
+		decision, _, err := New(&fakeClient{}).Authorize(ctx, get)
+		require.NoError(t, err)
+		require.Equal(t, DecisionDeny, decision)
+	})
+
+	t.Run("should deny on check errors", func(t *testing.T) {
+		decision, _, err := New(&fakeClient{allowed: true, err: errors.New("unavailable")}).Authorize(ctx, get)
+		require.Error(t, err)
+		require.Equal(t, DecisionDeny, decision)
+	})
+
+	t.Run("should have no opinion on non-resource and cluster-scoped requests", func(t *testing.T) {
+		client := &fakeClient{allowed: true}
+		decision, _, err := New(client).Authorize(ctx, fakeAttributes{verb: "get"})
+		require.NoError(t, err)
+		require.Equal(t, DecisionNoOpinion, decision)
+
+		decision, _, err = New(client).Authorize(ctx, fakeAttributes{verb: "list", resource: "namespaces", resourceRequest: true})
+		require.NoError(t, err)
+		require.Equal(t, DecisionNoOpinion, decision)
+		require.Nil(t, client.lastReq)
+	})
+
+	t.Run("should require a caller", func(t *testing.T) {
+		decision, _, err := New(&fakeClient{allowed: true}).Authorize(context.Background(), get)
+		require.ErrorIs(t, err, ErrMissingCaller)
+		require.Equal(t, DecisionDeny, decision)
+	})
+
+	t.Run("should map the namespace", func(t *testing.T) {
+		client := &fakeClient{allowed: true}
+		attrs := get
+		attrs.namespace = "default"
+		_, _, err := New(client).Authorize(ctx, attrs)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), client.lastReq.StackID)
+
+		attrs.namespace = "stacks-x"
+		decision, _, err := New(client).Authorize(ctx, attrs)
+		require.ErrorIs(t, err, ErrInvalidNamespace)
+		require.Equal(t, DecisionDeny, decision)
+
+		_, _, err = New(client, WithStackIDOption(func(string) (int64, error) { return 42, nil })).Authorize(ctx, attrs)
+		require.NoError(t, err)
+		require.Equal(t, int64(42), client.lastReq.StackID)
+	})
+
+	t.Run("should resolve the caller from the attributes", func(t *testing.T) {
+		client := &fakeClient{allowed: true}
+		a := New(client, WithCallerOption(func(context.Context, Attributes) (claims.AuthInfo, bool) { return caller, true }))
+		decision, _, err := a.Authorize(context.Background(), get)
+		require.NoError(t, err)
+		require.Equal(t, DecisionAllow, decision)
+		require.Equal(t, caller, client.lastReq.Caller)
+	})
+
+	t.Run("should return the reason of detailed checks", func(t *testing.T) {
+		client := &fakeDetailedClient{res: authz.CheckResult{Reason: authz.ReasonNoScopeMatch}}
+		decision, reason, err := New(client).Authorize(ctx, get)
+		require.NoError(t, err)
+		require.Equal(t, DecisionDeny, decision)
+		require.Equal(t, "no_scope_match", reason)
+	})
+}
+
+type fakeDetailedClient struct {
+	fakeClient
+	res authz.CheckResult
+}
+
+func (f *fakeDetailedClient) CheckDetailed(_ context.Context, req *authz.CheckRequest) (authz.CheckResult, error) {
+	f.lastReq = req
+	return f.res, f.err
+}
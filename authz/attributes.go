@@ -0,0 +1,74 @@
+package authz
+
+// ResourceAttributes describes a check the way the Kubernetes apiserver authorizers do.
+type ResourceAttributes struct {
+	// Group is the API group of the resource. Ex: "dashboard.grafana.app"
+	Group string
+	// Resource is the type of resource. Ex: "dashboards"
+	Resource string
+	// Subresource is the subresource checked, if any. Ex: "status"
+	Subresource string
+	// Verb is the verb of the check. Ex: "get", "list", "create", "update", "patch", "delete"
+	Verb string
+	// Name is the name of the resource, empty for collections (ex: "list", "create").
+	Name string
+}
+
+// AttributesMapper maps the Kubernetes-style attributes of a check to the action
+// and the resource (nil for collections) the permissions are granted on.
+type AttributesMapper func(attrs ResourceAttributes) (action string, resource *Resource)
+
+// verbActions maps the Kubernetes verbs to the suffix of Grafana actions.
+var verbActions = map[string]string{
+	"get":              "read",
+	"list":             "read",
+	"watch":            "read",
+	"create":           "create",
+	"update":           "write",
+	"patch":            "write",
+	"delete":           "delete",
+	"deletecollection": "delete",
+}
+
+// DefaultAttributesMapper maps the verbs to the actions of the resource type (ex: "get" on "dashboards" => "dashboards:read")
+// and the name to the "uid" attribute of the resource (ex: "dashboards:uid:<name>").
+// Subresources are appended to the resource type of the action (ex: "update" on "dashboards/status" => "dashboards/status:write").
+// Unknown verbs are used as is.
+func DefaultAttributesMapper(attrs ResourceAttributes) (string, *Resource) {
+	if attrs.Resource == "" || attrs.Verb == "" {
+		return "", nil
+	}
+
+	kind := attrs.Resource
+	if attrs.Subresource != "" {
+		kind += "/" + attrs.Subresource
+	}
+	verb, ok := verbActions[attrs.Verb]
+	if !ok {
+		verb = attrs.Verb
+	}
+	action := kind + ":" + verb
+
+	if attrs.Name == "" {
+		return action, nil
+	}
+	return action, &Resource{Kind: attrs.Resource, Attr: "uid", ID: attrs.Name}
+}
+
+// withAttributes returns the request with the action and the resource mapped from its attributes,
+// when they are not set explicitly.
+func (c *LegacyClientImpl) withAttributes(req *CheckRequest) *CheckRequest {
+	if req == nil || req.Attributes == nil || (req.Action != "" && req.Resource != nil) {
+		return req
+	}
+
+	action, resource := c.attrsMapper(*req.Attributes)
+	mapped := *req
+	if mapped.Action == "" {
+		mapped.Action = action
+	}
+	if mapped.Resource == nil {
+		mapped.Resource = resource
+	}
+	return &mapped
+}
@@ -0,0 +1,124 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+func TestDefaultAttributesMapper(t *testing.T) {
+	tests := []struct {
+		name         string
+		attrs        ResourceAttributes
+		wantAction   string
+		wantResource *Resource
+	}{
+		{
+			name:         "get by name",
+			attrs:        ResourceAttributes{Group: "dashboard.grafana.app", Resource: "dashboards", Verb: "get", Name: "abc"},
+			wantAction:   "dashboards:read",
+			wantResource: &Resource{Kind: "dashboards", Attr: "uid", ID: "abc"},
+		},
+		{
+			name:       "list collection",
+			attrs:      ResourceAttributes{Resource: "dashboards", Verb: "list"},
+			wantAction: "dashboards:read",
+		},
+		{
+			name:         "patch subresource",
+			attrs:        ResourceAttributes{Resource: "dashboards", Subresource: "status", Verb: "patch", Name: "abc"},
+			wantAction:   "dashboards/status:write",
+			wantResource: &Resource{Kind: "dashboards", Attr: "uid", ID: "abc"},
+		},
+		{
+			name:       "unknown verb",
+			attrs:      ResourceAttributes{Resource: "dashboards", Verb: "approve"},
+			wantAction: "dashboards:approve",
+		},
+		{
+			name:  "missing verb",
+			attrs: ResourceAttributes{Resource: "dashboards", Name: "abc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, resource := DefaultAttributesMapper(tt.attrs)
+			require.Equal(t, tt.wantAction, action)
+			require.Equal(t, tt.wantResource, resource)
+		})
+	}
+}
+
+func TestLegacyClientImpl_Check_Attributes(t *testing.T) {
+	caller := &authn.AuthInfo{
+		AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+			Claims: &jwt.Claims{Subject: "service"},
+			Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+		}),
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: "user:1"},
+			Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+		}),
+	}
+	attrs := &ResourceAttributes{Group: "dashboard.grafana.app", Resource: "dashboards", Verb: "get", Name: "1"}
+
+	t.Run("should map the attributes and send them to the service", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+
+		got, err := client.Check(context.Background(), &CheckRequest{Caller: caller, StackID: 12, Attributes: attrs})
+		require.NoError(t, err)
+		require.True(t, got)
+		require.Equal(t, "dashboards:read", authz.lastReq.Action)
+		require.Equal(t, "dashboard.grafana.app", authz.lastReq.Group)
+		require.Equal(t, "dashboards", authz.lastReq.Resource)
+		require.Equal(t, "get", authz.lastReq.Verb)
+		require.Equal(t, "1", authz.lastReq.Name)
+
+		// Other names are checked against the cached permissions of the action
+		other := *attrs
+		other.Name = "2"
+		got, err = client.Check(context.Background(), &CheckRequest{Caller: caller, StackID: 12, Attributes: &other})
+		require.NoError(t, err)
+		require.False(t, got)
+		require.Equal(t, 1, authz.reads)
+	})
+
+	t.Run("should prefer the explicit action and resource", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "folders:uid:1"}}}
+
+		got, err := client.Check(context.Background(), &CheckRequest{
+			Caller:     caller,
+			StackID:    12,
+			Action:     "dashboards:read",
+			Resource:   &Resource{Kind: "folders", Attr: "uid", ID: "1"},
+			Attributes: attrs,
+		})
+		require.NoError(t, err)
+		require.True(t, got)
+	})
+
+	t.Run("should use the configured mapper", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		WithAttributesMapperLCOption(func(attrs ResourceAttributes) (string, *Resource) {
+			return attrs.Group + "/" + attrs.Resource + ":" + attrs.Verb, nil
+		})(client)
+		authz.res = &authzv1.ReadResponse{Found: true}
+
+		_, err := client.Check(context.Background(), &CheckRequest{Caller: caller, StackID: 12, Attributes: attrs})
+		require.NoError(t, err)
+		require.Equal(t, 0, authz.reads, "the service is not allowed the mapped action")
+	})
+
+	t.Run("should require an action or attributes", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		_, err := client.Check(context.Background(), &CheckRequest{Caller: caller, StackID: 12, Attributes: &ResourceAttributes{Resource: "dashboards"}})
+		require.ErrorIs(t, err, ErrMissingAction)
+	})
+}
@@ -0,0 +1,151 @@
+package authz
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/authlib/claims"
+)
+
+// Decision is the audit record of a check.
+type Decision struct {
+	Time    time.Time
+	StackID int64
+	// Namespace is the namespace of the stack, as formatted by the client.
+	Namespace string
+	// Service is the subject of the access token, if any.
+	Service string
+	// Subject is the subject of the identity, if any.
+	Subject string
+	// Actor is the subject of the impersonator, if any.
+	Actor  string
+	Action string
+	// Resource is the scope of the requested resource, empty for checks on the action only.
+	Resource string
+	Allowed  bool
+	// Reason is why the check was allowed or denied.
+	Reason CheckReason
+	Err    error
+	// SampleRate is the rate the decision was sampled at, to weight the audited decisions.
+	SampleRate float64
+}
+
+// DecisionLogger records the audited decisions.
+type DecisionLogger func(ctx context.Context, decision Decision)
+
+// AuditSampling is the rate, between 0 and 1, of the decisions audited per namespace.
+type AuditSampling struct {
+	// Default is the rate of the namespaces without specific rate. Ex: 0.01
+	Default float64
+	// Namespaces are the rates of specific namespaces (ex: 1 for the tenants under investigation).
+	// Keys are matched like the namespaces of the claims: "stacks-*" or "stacks-12,stacks-13" are supported.
+	// Explicit keys, without wildcard, take precedence over the wildcard patterns, even with a rate of 0 to
+	// exclude a namespace. When several keys of the same kind match, the highest rate applies.
+	Namespaces map[string]float64
+}
+
+// rate returns the sampling rate of the namespace.
+func (s *AuditSampling) rate(namespace string, matches claims.NamespaceMatcher) float64 {
+	rate, matched, explicit := s.Default, false, false
+	for pattern, r := range s.Namespaces {
+		if !matches(namespacePattern(pattern), namespace) {
+			continue
+		}
+		isExplicit := !strings.Contains(pattern, "*")
+		switch {
+		case !matched, isExplicit && !explicit, isExplicit == explicit && r > rate:
+			rate, matched, explicit = r, true, isExplicit
+		}
+	}
+	return rate
+}
+
+type namespacePattern string
+
+func (p namespacePattern) Namespace() string {
+	return string(p)
+}
+
+// DecisionAuditor logs a sample of the decisions of the checks, per namespace.
+// The sampling can be changed at runtime with SetSampling. It is safe for concurrent use.
+type DecisionAuditor struct {
+	log      DecisionLogger
+	sampling atomic.Pointer[AuditSampling]
+	now      func() time.Time
+	random   func() float64
+}
+
+func NewDecisionAuditor(log DecisionLogger, sampling AuditSampling) *DecisionAuditor {
+	a := &DecisionAuditor{log: log, now: time.Now, random: rand.Float64}
+	a.SetSampling(sampling)
+	return a
+}
+
+// SetSampling replaces the sampling of the auditor, for instance when the configuration is reloaded.
+func (a *DecisionAuditor) SetSampling(sampling AuditSampling) {
+	a.sampling.Store(&sampling)
+}
+
+// Audit logs the decision if it is sampled for its namespace.
+// The namespaces of the sampling are matched with claims.NamespaceMatches.
+func (a *DecisionAuditor) Audit(ctx context.Context, decision Decision) {
+	a.sample(ctx, decision, claims.NamespaceMatches)
+}
+
+func (a *DecisionAuditor) sample(ctx context.Context, decision Decision, matches claims.NamespaceMatcher) {
+	rate := a.sampling.Load().rate(decision.Namespace, matches)
+	if rate <= 0 || (rate < 1 && a.random() >= rate) {
+		return
+	}
+
+	decision.SampleRate = rate
+	if decision.Time.IsZero() {
+		decision.Time = a.now()
+	}
+	a.log(ctx, decision)
+}
+
+// audit samples the decision of the check, with the namespace formatter and matcher of the client.
+func (a *DecisionAuditor) audit(ctx context.Context, c *LegacyClientImpl, req *CheckRequest, res CheckResult, err error) {
+	if req == nil {
+		return
+	}
+	a.sample(ctx, c.newDecision(req, res, err), c.nsMatcher)
+}
+
+// newDecision returns the record of the decision of the check.
+func (c *LegacyClientImpl) newDecision(req *CheckRequest, res CheckResult, err error) Decision {
+	decision := Decision{
+		StackID:   req.StackID,
+		Namespace: c.namespaceFmt(req.StackID),
+		Action:    req.Action,
+		Allowed:   res.Allowed,
+		Reason:    res.Reason,
+		Err:       err,
+	}
+	if req.Resource != nil {
+		decision.Resource = req.Resource.Scope()
+	}
+	if req.Caller != nil {
+		if access := req.Caller.GetAccess(); access != nil && !access.IsNil() {
+			decision.Service = access.Subject()
+		}
+		if id := req.Caller.GetIdentity(); id != nil && !id.IsNil() {
+			decision.Subject = id.Subject()
+			if actor, ok := claims.GetActor(id); ok {
+				decision.Actor = actor.Subject()
+			}
+		}
+	}
+	return decision
+}
+
+// WithDecisionAuditorLCOption audits the decisions of the checks with the auditor.
+func WithDecisionAuditorLCOption(auditor *DecisionAuditor) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.auditor = auditor
+	}
+}
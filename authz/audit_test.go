@@ -0,0 +1,77 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+func TestDecisionAuditor(t *testing.T) {
+	var logged []Decision
+	auditor := NewDecisionAuditor(func(_ context.Context, d Decision) { logged = append(logged, d) }, AuditSampling{
+		Default:    0.01,
+		Namespaces: map[string]float64{"stacks-12": 1, "stacks-*": 0.5, "stacks-14,stacks-15": 0},
+	})
+	auditor.random = func() float64 { return 0.3 }
+
+	for _, ns := range []string{"stacks-12", "stack-12", "stacks-13", "stacks-14", "org-2"} {
+		auditor.Audit(context.Background(), Decision{Namespace: ns})
+	}
+	require.Len(t, logged, 3, "the explicit rate of stacks-14 takes precedence over stacks-*")
+	require.Equal(t, "stacks-12", logged[0].Namespace)
+	require.Equal(t, 1.0, logged[0].SampleRate)
+	require.False(t, logged[0].Time.IsZero())
+	require.Equal(t, "stack-12", logged[1].Namespace)
+	require.Equal(t, "stacks-13", logged[2].Namespace)
+	require.Equal(t, 0.5, logged[2].SampleRate)
+
+	t.Run("should apply the highest matching rate of the patterns", func(t *testing.T) {
+		logged = nil
+		auditor.SetSampling(AuditSampling{Namespaces: map[string]float64{"stacks-*": 0.5, "*": 0.8}})
+		auditor.Audit(context.Background(), Decision{Namespace: "stacks-13"})
+		require.Len(t, logged, 1)
+		require.Equal(t, 0.8, logged[0].SampleRate)
+	})
+
+	t.Run("should apply the reloaded sampling", func(t *testing.T) {
+		logged = nil
+		auditor.SetSampling(AuditSampling{Default: 1})
+		auditor.Audit(context.Background(), Decision{Namespace: "stacks-14"})
+		require.Len(t, logged, 1)
+	})
+}
+
+func TestLegacyClientImpl_Check_Audit(t *testing.T) {
+	var logged []Decision
+	client, authz := setupLegacyClient()
+	authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+	WithDecisionAuditorLCOption(NewDecisionAuditor(func(_ context.Context, d Decision) { logged = append(logged, d) }, AuditSampling{
+		Namespaces: map[string]float64{"stacks-12": 1},
+	}))(client)
+
+	allowed, err := client.Check(context.Background(), inProcessCheckRequest())
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	invalid := inProcessCheckRequest()
+	invalid.Action = ""
+	_, err = client.Check(context.Background(), invalid)
+	require.ErrorIs(t, err, ErrMissingAction)
+
+	other := inProcessCheckRequest()
+	other.StackID = 13
+	_, err = client.Check(context.Background(), other)
+	require.NoError(t, err)
+
+	require.Len(t, logged, 2)
+	require.Equal(t, "stack-12", logged[0].Namespace)
+	require.Equal(t, "service", logged[0].Service)
+	require.Equal(t, "user:1", logged[0].Subject)
+	require.Equal(t, "dashboards:read", logged[0].Action)
+	require.Equal(t, "dashboards:uid:1", logged[0].Resource)
+	require.True(t, logged[0].Allowed)
+	require.ErrorIs(t, logged[1].Err, ErrMissingAction)
+}
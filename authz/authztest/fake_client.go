@@ -0,0 +1,126 @@
+package authztest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grafana/authlib/authz"
+)
+
+var _ authz.MultiTenantClient = (*FakeClient)(nil)
+
+// Matcher selects the checks a response of the FakeClient applies to.
+type Matcher func(req *authz.CheckRequest) bool
+
+// MatchAny matches all the checks.
+func MatchAny() Matcher {
+	return func(*authz.CheckRequest) bool { return true }
+}
+
+// MatchAction matches the checks of the action.
+func MatchAction(action string) Matcher {
+	return func(req *authz.CheckRequest) bool { return req.Action == action }
+}
+
+// MatchSubject matches the checks of the identity, by the subject of its ID token (ex: "user:1").
+func MatchSubject(subject string) Matcher {
+	return func(req *authz.CheckRequest) bool {
+		if req.Caller == nil {
+			return false
+		}
+		id := req.Caller.GetIdentity()
+		return id != nil && !id.IsNil() && id.Subject() == subject
+	}
+}
+
+// MatchResource matches the checks of the resource, by its scope (ex: "dashboards:uid:1").
+func MatchResource(scope string) Matcher {
+	return func(req *authz.CheckRequest) bool { return req.Resource != nil && req.Resource.Scope() == scope }
+}
+
+// MatchAll matches the checks matched by all the matchers.
+func MatchAll(matchers ...Matcher) Matcher {
+	return func(req *authz.CheckRequest) bool {
+		for _, m := range matchers {
+			if !m(req) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+type fakeResponse struct {
+	match   Matcher
+	allowed bool
+	err     error
+}
+
+// FakeClient is a MultiTenantClient which responses are scripted by the tests, to unit test the calling code
+// without the authz service. The checks are recorded. It is safe for concurrent use.
+//
+//	client := authztest.NewFakeClient()
+//	client.Allow(authztest.MatchAction("dashboards:read"))
+//	client.Fail(authztest.MatchAction("dashboards:write"), authz.ErrReadPermission)
+type FakeClient struct {
+	mtx       sync.Mutex
+	responses []fakeResponse
+	calls     []*authz.CheckRequest
+}
+
+// NewFakeClient returns a FakeClient denying all the checks.
+func NewFakeClient() *FakeClient {
+	return &FakeClient{}
+}
+
+// Respond sets the response of the checks matched. The responses are matched in the order they were set,
+// the checks matching none of them are denied.
+func (c *FakeClient) Respond(match Matcher, allowed bool, err error) *FakeClient {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.responses = append(c.responses, fakeResponse{match: match, allowed: allowed, err: err})
+	return c
+}
+
+// Allow allows the checks matched.
+func (c *FakeClient) Allow(match Matcher) *FakeClient {
+	return c.Respond(match, true, nil)
+}
+
+// Deny denies the checks matched.
+func (c *FakeClient) Deny(match Matcher) *FakeClient {
+	return c.Respond(match, false, nil)
+}
+
+// Fail fails the checks matched with the error.
+func (c *FakeClient) Fail(match Matcher, err error) *FakeClient {
+	return c.Respond(match, false, err)
+}
+
+// Calls returns the checks made, in order.
+func (c *FakeClient) Calls() []*authz.CheckRequest {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return append([]*authz.CheckRequest(nil), c.calls...)
+}
+
+// Reset clears the responses and the checks made.
+func (c *FakeClient) Reset() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.responses = nil
+	c.calls = nil
+}
+
+func (c *FakeClient) Check(_ context.Context, req *authz.CheckRequest) (bool, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.calls = append(c.calls, req)
+	for _, res := range c.responses {
+		if res.match(req) {
+			return res.allowed, res.err
+		}
+	}
+	return false, nil
+}
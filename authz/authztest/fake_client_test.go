@@ -0,0 +1,58 @@
+package authztest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authz"
+)
+
+func TestFakeClient(t *testing.T) {
+	ctx := context.Background()
+	dashboard := &authz.Resource{Kind: "dashboards", Attr: "uid", ID: "1"}
+
+	t.Run("should deny the checks by default", func(t *testing.T) {
+		allowed, err := NewFakeClient().Check(ctx, checkRequest("dashboards:read", dashboard))
+		require.NoError(t, err)
+		require.False(t, allowed)
+	})
+
+	t.Run("should respond with the first matching response", func(t *testing.T) {
+		client := NewFakeClient().
+			Deny(MatchResource("dashboards:uid:2")).
+			Allow(MatchAll(MatchAction("dashboards:read"), MatchSubject("user:1"))).
+			Fail(MatchAny(), authz.ErrReadPermission)
+
+		allowed, err := client.Check(ctx, checkRequest("dashboards:read", dashboard))
+		require.NoError(t, err)
+		require.True(t, allowed)
+
+		allowed, err = client.Check(ctx, checkRequest("dashboards:read", &authz.Resource{Kind: "dashboards", Attr: "uid", ID: "2"}))
+		require.NoError(t, err)
+		require.False(t, allowed)
+
+		allowed, err = client.Check(ctx, checkRequest("dashboards:write", dashboard))
+		require.ErrorIs(t, err, authz.ErrReadPermission)
+		require.False(t, allowed)
+
+		allowed, err = client.Check(ctx, &authz.CheckRequest{Action: "dashboards:read"})
+		require.ErrorIs(t, err, authz.ErrReadPermission)
+		require.False(t, allowed)
+	})
+
+	t.Run("should record the checks", func(t *testing.T) {
+		client := NewFakeClient().Allow(MatchAny())
+		first, second := checkRequest("dashboards:read", dashboard), checkRequest("dashboards:write", nil)
+		_, _ = client.Check(ctx, first)
+		_, _ = client.Check(ctx, second)
+		require.Equal(t, []*authz.CheckRequest{first, second}, client.Calls())
+
+		client.Reset()
+		require.Empty(t, client.Calls())
+		allowed, err := client.Check(ctx, first)
+		require.NoError(t, err)
+		require.False(t, allowed)
+	})
+}
@@ -0,0 +1,34 @@
+// Package authztest provides test doubles of the authz clients and service, to test the services checking
+// permissions without mocks, and their resilience to authz failures.
+package authztest
+
+import (
+	"context"
+
+	"github.com/grafana/authlib/authz"
+	"github.com/grafana/authlib/internal/fault"
+)
+
+// Faults is the latency and the errors injected in the calls.
+type Faults = fault.Config
+
+var _ authz.MultiTenantClient = (*FlakyClient)(nil)
+
+// FlakyClient is a MultiTenantClient injecting latency and errors in the checks of the wrapped client.
+// The failing checks return authz.ErrReadPermission, like the client does when the authz service fails,
+// unless Faults.Err is set.
+type FlakyClient struct {
+	client authz.MultiTenantClient
+	faults *fault.Injector
+}
+
+func NewFlakyClient(client authz.MultiTenantClient, faults Faults) *FlakyClient {
+	return &FlakyClient{client: client, faults: fault.New(faults, authz.ErrReadPermission)}
+}
+
+func (c *FlakyClient) Check(ctx context.Context, req *authz.CheckRequest) (bool, error) {
+	if err := c.faults.Inject(ctx); err != nil {
+		return false, err
+	}
+	return c.client.Check(ctx, req)
+}
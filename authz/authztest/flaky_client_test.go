@@ -0,0 +1,30 @@
+package authztest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authz"
+)
+
+type allowAll struct{}
+
+func (allowAll) Check(context.Context, *authz.CheckRequest) (bool, error) {
+	return true, nil
+}
+
+func TestFlakyClient(t *testing.T) {
+	t.Run("should forward the checks without faults", func(t *testing.T) {
+		allowed, err := NewFlakyClient(allowAll{}, Faults{}).Check(context.Background(), &authz.CheckRequest{})
+		require.NoError(t, err)
+		require.True(t, allowed)
+	})
+
+	t.Run("should fail like the authz service", func(t *testing.T) {
+		allowed, err := NewFlakyClient(allowAll{}, Faults{ErrorRate: 1}).Check(context.Background(), &authz.CheckRequest{})
+		require.ErrorIs(t, err, authz.ErrReadPermission)
+		require.False(t, allowed)
+	})
+}
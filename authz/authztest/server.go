@@ -0,0 +1,138 @@
+package authztest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/authlib/authz"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+	"github.com/grafana/authlib/authz/server"
+	"github.com/grafana/authlib/cache"
+)
+
+var _ authzv1.AuthzServiceServer = (*Server)(nil)
+
+// Server is an in-memory authz service, which permissions are set by the tests.
+// It implements the reads of the authz/server package, multi-action reads included. It is safe for concurrent use.
+type Server struct {
+	*server.Server
+
+	mtx   sync.Mutex
+	perms map[permKey][]string
+	reads int
+	err   error
+}
+
+type permKey struct {
+	stackID int64
+	subject string
+	action  string
+}
+
+func NewServer(opts ...server.Option) *Server {
+	s := &Server{perms: map[permKey][]string{}}
+	s.Server = server.New(storeFunc(s.scopes), opts...)
+	return s
+}
+
+// Set grants the action to the subject of the stack on the scopes (ex: "dashboards:uid:1", "dashboards:*" or "*"),
+// replacing the scopes previously set. Without scopes, the action is granted without any resource.
+func (s *Server) Set(stackID int64, subject, action string, scopes ...string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.perms[permKey{stackID: stackID, subject: subject, action: action}] = append([]string{}, scopes...)
+}
+
+// Unset revokes the action from the subject of the stack.
+func (s *Server) Unset(stackID int64, subject, action string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.perms, permKey{stackID: stackID, subject: subject, action: action})
+}
+
+// Reset revokes all the permissions, and clears the reads and the error.
+func (s *Server) Reset() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.perms = map[permKey][]string{}
+	s.reads = 0
+	s.err = nil
+}
+
+// FailReads makes the reads return the error (ex: status.Error(codes.Unavailable, "down")), nil to stop failing.
+func (s *Server) FailReads(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.err = err
+}
+
+// Reads returns the number of reads served, failed ones included.
+func (s *Server) Reads() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.reads
+}
+
+func (s *Server) Read(ctx context.Context, req *authzv1.ReadRequest) (*authzv1.ReadResponse, error) {
+	s.mtx.Lock()
+	s.reads++
+	err := s.err
+	s.mtx.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	return s.Server.Read(ctx, req)
+}
+
+func (s *Server) scopes(_ context.Context, query server.Query) ([]string, bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	scopes, found := s.perms[permKey{stackID: query.StackID, subject: query.Subject, action: query.Action}]
+	if !found {
+		return nil, false, nil
+	}
+	return append([]string(nil), scopes...), true, nil
+}
+
+// storeFunc adapts a function to a server.PermissionStore.
+type storeFunc func(ctx context.Context, query server.Query) ([]string, bool, error)
+
+func (f storeFunc) Scopes(ctx context.Context, query server.Query) ([]string, bool, error) {
+	return f(ctx, query)
+}
+
+// NewLegacyClient returns a client connected to the server through the gRPC stack, over an in-memory listener.
+// The server is stopped when the test ends. The permissions are not cached, so that the permissions set
+// during the test apply to the next checks, unless a cache is set with authz.WithCacheLCOption.
+func NewLegacyClient(t testing.TB, srv *Server, opts ...authz.LegacyClientOption) *authz.LegacyClientImpl {
+	t.Helper()
+
+	opts = append([]authz.LegacyClientOption{authz.WithCacheLCOption(noCache{})}, opts...)
+	client, stop, err := authz.NewInMemoryLegacyClient(srv, nil, opts...)
+	if err != nil {
+		t.Fatalf("failed to create the authz client: %v", err)
+	}
+	t.Cleanup(stop)
+	return client
+}
+
+var _ cache.Cache = noCache{}
+
+// noCache is a cache that stores nothing.
+type noCache struct{}
+
+func (noCache) Get(context.Context, string) ([]byte, error) {
+	return nil, cache.ErrNotFound
+}
+
+func (noCache) Set(context.Context, string, []byte, time.Duration) error {
+	return nil
+}
+
+func (noCache) Delete(context.Context, string) error {
+	return nil
+}
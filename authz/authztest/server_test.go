@@ -0,0 +1,109 @@
+package authztest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/authz"
+	"github.com/grafana/authlib/cache"
+)
+
+func checkRequest(action string, resource *authz.Resource) *authz.CheckRequest {
+	return &authz.CheckRequest{
+		Caller: &authn.AuthInfo{
+			AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+				Claims: &jwt.Claims{Subject: "service"},
+				Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read", "dashboards:write"}},
+			}),
+			IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+				Claims: &jwt.Claims{Subject: "user:1"},
+				Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+			}),
+		},
+		StackID:  12,
+		Action:   action,
+		Resource: resource,
+	}
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	dashboard := &authz.Resource{Kind: "dashboards", Attr: "uid", ID: "1"}
+
+	t.Run("should check the permissions set", func(t *testing.T) {
+		srv := NewServer()
+		client := NewLegacyClient(t, srv)
+
+		allowed, err := client.Check(ctx, checkRequest("dashboards:read", dashboard))
+		require.NoError(t, err)
+		require.False(t, allowed)
+
+		srv.Set(12, "user:1", "dashboards:read", "dashboards:uid:1")
+		allowed, err = client.Check(ctx, checkRequest("dashboards:read", dashboard))
+		require.NoError(t, err)
+		require.True(t, allowed)
+
+		allowed, err = client.Check(ctx, checkRequest("dashboards:read", &authz.Resource{Kind: "dashboards", Attr: "uid", ID: "2"}))
+		require.NoError(t, err)
+		require.False(t, allowed)
+
+		srv.Set(12, "user:1", "dashboards:write")
+		allowed, err = client.Check(ctx, checkRequest("dashboards:write", nil))
+		require.NoError(t, err)
+		require.True(t, allowed)
+
+		srv.Unset(12, "user:1", "dashboards:read")
+		allowed, err = client.Check(ctx, checkRequest("dashboards:read", dashboard))
+		require.NoError(t, err)
+		require.False(t, allowed)
+		require.Equal(t, 5, srv.Reads())
+	})
+
+	t.Run("should scope the permissions to the stack and the subject", func(t *testing.T) {
+		srv := NewServer()
+		client := NewLegacyClient(t, srv)
+
+		srv.Set(13, "user:1", "dashboards:read", "*")
+		srv.Set(12, "user:2", "dashboards:read", "*")
+		allowed, err := client.Check(ctx, checkRequest("dashboards:read", dashboard))
+		require.NoError(t, err)
+		require.False(t, allowed)
+	})
+
+	t.Run("should fail the reads", func(t *testing.T) {
+		srv := NewServer()
+		client := NewLegacyClient(t, srv)
+		srv.Set(12, "user:1", "dashboards:read", "*")
+
+		srv.FailReads(status.Error(codes.Unavailable, "down"))
+		_, err := client.Check(ctx, checkRequest("dashboards:read", dashboard))
+		require.ErrorIs(t, err, authz.ErrReadPermission)
+
+		srv.FailReads(nil)
+		allowed, err := client.Check(ctx, checkRequest("dashboards:read", dashboard))
+		require.NoError(t, err)
+		require.True(t, allowed)
+
+		srv.Reset()
+		require.Zero(t, srv.Reads())
+	})
+
+	t.Run("should cache with the cache option", func(t *testing.T) {
+		srv := NewServer()
+		client := NewLegacyClient(t, srv, authz.WithCacheLCOption(cache.NewLocalCache(cache.Config{})))
+		srv.Set(12, "user:1", "dashboards:read", "dashboards:*")
+
+		for i := 0; i < 2; i++ {
+			allowed, err := client.Check(ctx, checkRequest("dashboards:read", dashboard))
+			require.NoError(t, err)
+			require.True(t, allowed)
+		}
+		require.Equal(t, 1, srv.Reads())
+	})
+}
@@ -0,0 +1,38 @@
+package authz
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/grafana/authlib/errs"
+)
+
+// BatchCheck checks the requests, in order. The result of a failed request is false,
+// and its error is reported in the returned *errs.Batch with the index of the request,
+// so that callers can retry only the failed requests.
+func (c *LegacyClientImpl) BatchCheck(ctx context.Context, reqs []*CheckRequest) ([]bool, error) {
+	ctx, span := c.tracer.Start(ctx, "LegacyClientImpl.BatchCheck")
+	defer span.End()
+	c.legacyUsage.report(ctx, SurfaceLegacyClient, "BatchCheck")
+
+	span.SetAttributes(attribute.Int("requests", len(reqs)))
+
+	res := make([]bool, len(reqs))
+	batch := &errs.Batch{}
+	for i, req := range reqs {
+		allowed, err := c.Check(ctx, req)
+		if err != nil {
+			batch.Add(i, err)
+			continue
+		}
+		res[i] = allowed
+	}
+
+	if err := batch.Err(); err != nil {
+		span.RecordError(err)
+		span.SetAttributes(attribute.Int("failed", batch.Len()))
+		return res, err
+	}
+	return res, nil
+}
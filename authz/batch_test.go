@@ -0,0 +1,35 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+	"github.com/grafana/authlib/errs"
+)
+
+func TestLegacyClientImpl_BatchCheck(t *testing.T) {
+	client, authz := setupLegacyClient()
+	authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+
+	allowed := inProcessCheckRequest()
+	denied := inProcessCheckRequest()
+	denied.Resource = &Resource{Kind: "dashboards", Attr: "uid", ID: "2"}
+	missingStack := inProcessCheckRequest()
+	missingStack.StackID = 0
+
+	got, err := client.BatchCheck(context.Background(), []*CheckRequest{allowed, missingStack, denied})
+	require.Equal(t, []bool{true, false, false}, got)
+
+	var batch *errs.Batch
+	require.True(t, errors.As(err, &batch))
+	require.Equal(t, []int{1}, batch.Failed())
+	require.ErrorIs(t, err, ErrMissingStackID)
+
+	got, err = client.BatchCheck(context.Background(), []*CheckRequest{allowed, denied})
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false}, got)
+}
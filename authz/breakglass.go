@@ -0,0 +1,110 @@
+package authz
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/authlib/claims"
+)
+
+// DefaultBreakGlassTTL is the default validity of break-glass grants.
+const DefaultBreakGlassTTL = time.Hour
+
+// BreakGlassEvent is the audit event of a check allowed by break glass.
+type BreakGlassEvent struct {
+	Time    time.Time
+	StackID int64
+	// Subject is the emergency identity (ex: "user:1").
+	Subject string
+	Action  string
+	// Resource is the scope of the requested resource, empty for checks on the action only.
+	Resource string
+	// Reason of the emergency access, as carried by the break-glass grant.
+	Reason string
+	// GrantedAt is when the break-glass grant was issued.
+	GrantedAt time.Time
+	// Err is the error of the authz backend that was bypassed.
+	Err error
+}
+
+// BreakGlassAuditor records the checks allowed by break glass.
+// It is called synchronously, before the check returns.
+type BreakGlassAuditor func(ctx context.Context, event BreakGlassEvent)
+
+// BreakGlassConfig configures the emergency identities allowed to bypass a failing authz backend.
+type BreakGlassConfig struct {
+	// Subjects are the emergency identities (ex: "user:1"). Their ID token must carry a break-glass grant.
+	Subjects []string
+	// TTL is how long a break-glass grant is honored after it was issued. Defaults to DefaultBreakGlassTTL.
+	TTL time.Duration
+	// Audit is called for every check allowed by break glass. Break glass is disabled without an auditor.
+	Audit BreakGlassAuditor
+}
+
+type breakGlass struct {
+	subjects map[string]bool
+	ttl      time.Duration
+	audit    BreakGlassAuditor
+	now      func() time.Time
+}
+
+// WithBreakGlassLCOption allows the configured emergency identities to bypass the authz backend when it fails.
+// Only the backend is bypassed: the namespace, the service and the impersonation checks still apply.
+// Impersonated identities never break glass.
+func WithBreakGlassLCOption(cfg BreakGlassConfig) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		if cfg.Audit == nil || len(cfg.Subjects) == 0 {
+			c.breakGlass = nil
+			return
+		}
+
+		bg := &breakGlass{
+			subjects: make(map[string]bool, len(cfg.Subjects)),
+			ttl:      cfg.TTL,
+			audit:    cfg.Audit,
+			now:      time.Now,
+		}
+		if bg.ttl <= 0 {
+			bg.ttl = DefaultBreakGlassTTL
+		}
+		for _, s := range cfg.Subjects {
+			bg.subjects[s] = true
+		}
+		c.breakGlass = bg
+	}
+}
+
+// allows returns true, and audits the check, if the identity can bypass the backend error.
+func (b *breakGlass) allows(ctx context.Context, req *CheckRequest, id claims.IdentityClaims, backendErr error) bool {
+	if b == nil || !b.subjects[id.Subject()] {
+		return false
+	}
+	if _, impersonated := claims.GetActor(id); impersonated {
+		return false
+	}
+
+	grant, ok := claims.GetBreakGlass(id)
+	if !ok {
+		return false
+	}
+	now := b.now()
+	grantedAt := grant.IssuedAt()
+	if grantedAt.IsZero() || grantedAt.After(now) || now.Sub(grantedAt) > b.ttl {
+		return false
+	}
+
+	event := BreakGlassEvent{
+		Time:      now,
+		StackID:   req.StackID,
+		Subject:   id.Subject(),
+		Action:    req.Action,
+		Reason:    grant.Reason(),
+		GrantedAt: grantedAt,
+		Err:       backendErr,
+	}
+	if req.Resource != nil {
+		event.Resource = req.Resource.Scope()
+	}
+	b.audit(ctx, event)
+	return true
+}
@@ -0,0 +1,113 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/grafana/authlib/authn"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+type failingAuthzServiceClient struct{}
+
+func (failingAuthzServiceClient) Read(context.Context, *authzv1.ReadRequest, ...grpc.CallOption) (*authzv1.ReadResponse, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestLegacyClientImpl_Check_BreakGlass(t *testing.T) {
+	now := time.Now()
+	caller := func(subject string, grant *authn.BreakGlassClaims, actor *authn.ActorClaims) *authn.AuthInfo {
+		return &authn.AuthInfo{
+			AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+				Claims: &jwt.Claims{Subject: "service"},
+				Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+			}),
+			IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+				Claims: &jwt.Claims{Subject: subject},
+				Rest:   authn.IDTokenClaims{Namespace: "stacks-12", BreakGlass: grant, Actor: actor},
+			}),
+		}
+	}
+	grant := func(age time.Duration) *authn.BreakGlassClaims {
+		return &authn.BreakGlassClaims{Reason: "INC-42", IssuedAt: jwt.NewNumericDate(now.Add(-age))}
+	}
+
+	tests := []struct {
+		name    string
+		caller  *authn.AuthInfo
+		allowed bool
+	}{
+		{name: "emergency identity with a grant", caller: caller("user:1", grant(time.Minute), nil), allowed: true},
+		{name: "emergency identity without grant", caller: caller("user:1", nil, nil)},
+		{name: "expired grant", caller: caller("user:1", grant(2*time.Hour), nil)},
+		{name: "grant issued in the future", caller: caller("user:1", grant(-time.Minute), nil)},
+		{name: "grant without issue time", caller: caller("user:1", &authn.BreakGlassClaims{Reason: "INC-42"}, nil)},
+		{name: "other identity with a grant", caller: caller("user:2", grant(time.Minute), nil)},
+		{
+			name:   "impersonated emergency identity",
+			caller: caller("user:1", grant(time.Minute), &authn.ActorClaims{Subject: "user:3", DelegatedPermissions: []string{"dashboards:read"}}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []BreakGlassEvent
+			client, _ := setupLegacyClient()
+			client.clientV1 = failingAuthzServiceClient{}
+			WithBreakGlassLCOption(BreakGlassConfig{
+				Subjects: []string{"user:1"},
+				Audit:    func(_ context.Context, e BreakGlassEvent) { events = append(events, e) },
+			})(client)
+
+			got, err := client.Check(context.Background(), &CheckRequest{
+				Caller:   tt.caller,
+				StackID:  12,
+				Action:   "dashboards:read",
+				Resource: &Resource{Kind: "dashboards", Attr: "uid", ID: "1"},
+			})
+			if !tt.allowed {
+				require.ErrorIs(t, err, ErrReadPermission)
+				require.False(t, got)
+				require.Empty(t, events)
+				return
+			}
+
+			require.NoError(t, err)
+			require.True(t, got)
+			require.Len(t, events, 1)
+			require.Equal(t, "user:1", events[0].Subject)
+			require.Equal(t, "dashboards:uid:1", events[0].Resource)
+			require.Equal(t, "INC-42", events[0].Reason)
+			require.ErrorIs(t, events[0].Err, ErrReadPermission)
+		})
+	}
+
+	t.Run("should require an auditor", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		client.clientV1 = failingAuthzServiceClient{}
+		WithBreakGlassLCOption(BreakGlassConfig{Subjects: []string{"user:1"}})(client)
+
+		_, err := client.Check(context.Background(), &CheckRequest{Caller: caller("user:1", grant(time.Minute), nil), StackID: 12, Action: "dashboards:read"})
+		require.ErrorIs(t, err, ErrReadPermission)
+	})
+
+	t.Run("should not bypass a working backend", func(t *testing.T) {
+		var events []BreakGlassEvent
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: false}
+		WithBreakGlassLCOption(BreakGlassConfig{
+			Subjects: []string{"user:1"},
+			Audit:    func(_ context.Context, e BreakGlassEvent) { events = append(events, e) },
+		})(client)
+
+		got, err := client.Check(context.Background(), &CheckRequest{Caller: caller("user:1", grant(time.Minute), nil), StackID: 12, Action: "dashboards:read"})
+		require.NoError(t, err)
+		require.False(t, got)
+		require.Empty(t, events)
+	})
+}
@@ -0,0 +1,178 @@
+// Package canary provides a MultiTenantClient routing a share of the checks to a new authz backend,
+// to de-risk the upgrades of the authz service.
+//
+//	client := canary.NewClient(stable, next, canary.Config{Percent: 5, ByStack: true})
+//
+// The checks failing on the canary are retried on the stable backend, and the canary is disabled
+// for a cooldown when its error rate exceeds the threshold.
+package canary
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/grafana/authlib/authz"
+)
+
+const (
+	DefaultErrorRateThreshold = 0.05
+	DefaultMinRequests        = 20
+	DefaultWindow             = time.Minute
+	DefaultCooldown           = 5 * time.Minute
+)
+
+// Config configures the share of the checks routed to the canary backend.
+type Config struct {
+	// Percent is the percentage, from 0 to 100, of the checks routed to the canary.
+	Percent float64
+	// ByStack routes all the checks of a stack to the same backend, instead of sampling the checks randomly.
+	ByStack bool
+	// ErrorRateThreshold is the error rate of the canary, between 0 and 1, disabling it. Defaults to 0.05.
+	ErrorRateThreshold float64
+	// MinRequests is the number of canary checks in the window before the error rate is considered. Defaults to 20.
+	MinRequests int
+	// Window is the period the error rate of the canary is measured over. Defaults to 1m.
+	Window time.Duration
+	// Cooldown is how long the canary is disabled once its error rate exceeded the threshold. Defaults to 5m.
+	Cooldown time.Duration
+	// OnFallback is called when the canary is disabled, with its error rate.
+	OnFallback func(errorRate float64)
+	// Seed seeds the random sampling.
+	Seed int64
+}
+
+type Option func(*Client)
+
+func WithTracerOption(tracer trace.Tracer) Option {
+	return func(c *Client) {
+		c.tracer = tracer
+	}
+}
+
+var _ authz.MultiTenantClient = (*Client)(nil)
+
+// Client is a MultiTenantClient routing the checks between a stable and a canary backend.
+type Client struct {
+	stable authz.MultiTenantClient
+	canary authz.MultiTenantClient
+	cfg    Config
+	tracer trace.Tracer
+	now    func() time.Time
+
+	mtx           sync.Mutex
+	rand          *rand.Rand
+	windowStart   time.Time
+	requests      int
+	errors        int
+	disabledUntil time.Time
+}
+
+func NewClient(stable, canary authz.MultiTenantClient, cfg Config, opts ...Option) *Client {
+	if cfg.ErrorRateThreshold <= 0 {
+		cfg.ErrorRateThreshold = DefaultErrorRateThreshold
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = DefaultMinRequests
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultCooldown
+	}
+
+	c := &Client{
+		stable: stable,
+		canary: canary,
+		cfg:    cfg,
+		now:    time.Now,
+		rand:   rand.New(rand.NewSource(cfg.Seed)),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.tracer == nil {
+		c.tracer = noop.Tracer{}
+	}
+
+	return c
+}
+
+func (c *Client) Check(ctx context.Context, req *authz.CheckRequest) (bool, error) {
+	ctx, span := c.tracer.Start(ctx, "canary.Client.Check")
+	defer span.End()
+
+	if !c.routeToCanary(req) {
+		return c.stable.Check(ctx, req)
+	}
+
+	span.SetAttributes(attribute.Bool("canary", true))
+	allowed, err := c.canary.Check(ctx, req)
+	c.record(err)
+	if err != nil {
+		span.RecordError(err)
+		// Do not fail the caller on the errors of the canary
+		return c.stable.Check(ctx, req)
+	}
+	return allowed, nil
+}
+
+// Enabled returns whether the canary receives checks, or is disabled after an elevated error rate.
+func (c *Client) Enabled() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.cfg.Percent > 0 && !c.now().Before(c.disabledUntil)
+}
+
+func (c *Client) routeToCanary(req *authz.CheckRequest) bool {
+	if !c.Enabled() {
+		return false
+	}
+	if c.cfg.Percent >= 100 {
+		return true
+	}
+
+	if c.cfg.ByStack && req != nil {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(strconv.FormatInt(req.StackID, 10)))
+		return float64(h.Sum32()%10000) < c.cfg.Percent*100
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.rand.Float64()*100 < c.cfg.Percent
+}
+
+// record records the outcome of a canary check, and disables the canary when its error rate is elevated.
+func (c *Client) record(err error) {
+	c.mtx.Lock()
+	now := c.now()
+	if now.Sub(c.windowStart) >= c.cfg.Window {
+		c.windowStart, c.requests, c.errors = now, 0, 0
+	}
+	c.requests++
+	if err != nil {
+		c.errors++
+	}
+
+	rate := float64(c.errors) / float64(c.requests)
+	fallback := c.requests >= c.cfg.MinRequests && rate > c.cfg.ErrorRateThreshold
+	if fallback {
+		c.disabledUntil = now.Add(c.cfg.Cooldown)
+		c.windowStart, c.requests, c.errors = now, 0, 0
+	}
+	c.mtx.Unlock()
+
+	if fallback && c.cfg.OnFallback != nil {
+		c.cfg.OnFallback(rate)
+	}
+}
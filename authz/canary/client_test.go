@@ -0,0 +1,83 @@
+package canary
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authz"
+)
+
+type fakeClient struct {
+	allowed bool
+	err     error
+	checks  int
+}
+
+func (f *fakeClient) Check(context.Context, *authz.CheckRequest) (bool, error) {
+	f.checks++
+	return f.allowed, f.err
+}
+
+func TestClient_Check(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should sample the checks", func(t *testing.T) {
+		stable, next := &fakeClient{}, &fakeClient{allowed: true}
+		c := NewClient(stable, next, Config{Percent: 20, Seed: 1})
+		for i := 0; i < 1000; i++ {
+			_, err := c.Check(ctx, &authz.CheckRequest{StackID: int64(i)})
+			require.NoError(t, err)
+		}
+		require.InDelta(t, 200, next.checks, 50)
+		require.Equal(t, 1000, stable.checks+next.checks)
+	})
+
+	t.Run("should route the stacks consistently", func(t *testing.T) {
+		stable, next := &fakeClient{}, &fakeClient{}
+		c := NewClient(stable, next, Config{Percent: 50, ByStack: true})
+		for i := 0; i < 10; i++ {
+			_, _ = c.Check(ctx, &authz.CheckRequest{StackID: 12})
+		}
+		require.Contains(t, []int{0, 10}, next.checks)
+	})
+
+	t.Run("should not route without percentage", func(t *testing.T) {
+		stable, next := &fakeClient{}, &fakeClient{}
+		c := NewClient(stable, next, Config{})
+		_, _ = c.Check(ctx, &authz.CheckRequest{})
+		require.Equal(t, 0, next.checks)
+		require.False(t, c.Enabled())
+	})
+
+	t.Run("should fall back on elevated error rates", func(t *testing.T) {
+		stable, next := &fakeClient{allowed: true}, &fakeClient{err: errors.New("unavailable")}
+		var rates []float64
+		c := NewClient(stable, next, Config{Percent: 100, MinRequests: 5, Cooldown: time.Minute, OnFallback: func(rate float64) {
+			rates = append(rates, rate)
+		}})
+		now := time.Now()
+		c.now = func() time.Time { return now }
+
+		for i := 0; i < 10; i++ {
+			allowed, err := c.Check(ctx, &authz.CheckRequest{})
+			require.NoError(t, err, "the stable backend should answer the failed checks")
+			require.True(t, allowed)
+		}
+		require.Equal(t, 5, next.checks)
+		require.Equal(t, 10, stable.checks)
+		require.False(t, c.Enabled())
+		require.Equal(t, []float64{1}, rates)
+
+		// Re-enable after the cooldown
+		now = now.Add(time.Minute)
+		next.err, next.allowed = nil, true
+		require.True(t, c.Enabled())
+		_, err := c.Check(ctx, &authz.CheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, 6, next.checks)
+	})
+}
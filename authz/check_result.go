@@ -0,0 +1,90 @@
+package authz
+
+import (
+	"context"
+	"strings"
+)
+
+// CheckReason is the machine-readable reason of a check decision.
+type CheckReason string
+
+const (
+	// ReasonInvalidRequest denies requests that failed the validation.
+	ReasonInvalidRequest CheckReason = "invalid_request"
+	// ReasonNamespaceMismatch denies callers from another namespace than the stack.
+	ReasonNamespaceMismatch CheckReason = "namespace_mismatch"
+	// ReasonStaticPolicy allows bootstrap identities granted the action by the static policy.
+	ReasonStaticPolicy CheckReason = "static_policy"
+	// ReasonAnonymous denies anonymous identities.
+	ReasonAnonymous CheckReason = "anonymous"
+	// ReasonServicePermission allows services granted the action, or any service when access tokens are disabled.
+	ReasonServicePermission CheckReason = "service_permission"
+	// ReasonMissingServicePermission denies services not granted the action.
+	ReasonMissingServicePermission CheckReason = "missing_service_permission"
+	// ReasonImpersonatedAPIKey denies impersonated API keys.
+	ReasonImpersonatedAPIKey CheckReason = "impersonated_api_key"
+	// ReasonMissingDelegatedPermission denies identities whose service, or impersonator, was not delegated the action.
+	ReasonMissingDelegatedPermission CheckReason = "missing_delegated_permission"
+	// ReasonNoPermission denies identities not granted the action.
+	ReasonNoPermission CheckReason = "no_permission"
+	// ReasonAction allows identities granted the action, for checks on the action only.
+	ReasonAction CheckReason = "action"
+	// ReasonScopeMatch allows identities granted the scope of the resource, or of a contextual resource.
+	ReasonScopeMatch CheckReason = "scope_match"
+	// ReasonWildcard allows identities granted a wildcard scope on the kind of the resource.
+	ReasonWildcard CheckReason = "wildcard"
+	// ReasonNoScopeMatch denies identities granted the action, but not on the requested resources.
+	ReasonNoScopeMatch CheckReason = "no_scope_match"
+	// ReasonBreakGlass allows emergency identities while the authz service is failing.
+	ReasonBreakGlass CheckReason = "break_glass"
+	// ReasonError denies the checks that failed.
+	ReasonError CheckReason = "error"
+)
+
+// CheckResult is the detailed decision of a check.
+type CheckResult struct {
+	Allowed bool
+	Reason  CheckReason
+	// Scope is the granted scope that matched the requested resources, as granted
+	// (ex: "dashboards:uid:1", "dashboards:*" or "dashboards:uid:*").
+	// It is empty when no scope was involved in the decision.
+	Scope string
+}
+
+func allow(reason CheckReason) CheckResult {
+	return CheckResult{Allowed: true, Reason: reason}
+}
+
+func deny(reason CheckReason) CheckResult {
+	return CheckResult{Reason: reason}
+}
+
+// scopeResult returns the result of the match of the permissions against the requested resources.
+func scopeResult(found bool, scope string, allowed bool, resources []Resource) CheckResult {
+	switch {
+	case !found:
+		return deny(ReasonNoPermission)
+	case !allowed:
+		return deny(ReasonNoScopeMatch)
+	case len(resources) == 0:
+		return allow(ReasonAction)
+	case scope == "*" || strings.HasSuffix(scope, ":*"):
+		return CheckResult{Allowed: true, Reason: ReasonWildcard, Scope: scope}
+	default:
+		return CheckResult{Allowed: true, Reason: ReasonScopeMatch, Scope: scope}
+	}
+}
+
+// CheckDetailed checks the request like Check, and returns why it was allowed or denied.
+// The reason is ReasonError, or ReasonInvalidRequest, when an error is returned.
+func (c *LegacyClientImpl) CheckDetailed(ctx context.Context, req *CheckRequest) (CheckResult, error) {
+	ctx, span := c.tracer.Start(ctx, "LegacyClientImpl.CheckDetailed")
+	defer span.End()
+	c.legacyUsage.report(ctx, SurfaceLegacyClient, "CheckDetailed")
+
+	if req.multiAction() {
+		return c.checkActions(ctx, span, req)
+	}
+	req = c.withAttributes(req)
+	return c.check(ctx, span, req)
+}
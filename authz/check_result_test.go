@@ -0,0 +1,107 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+func TestLegacyClientImpl_CheckDetailed(t *testing.T) {
+	tests := []struct {
+		name   string
+		res    *authzv1.ReadResponse
+		modify func(req *CheckRequest)
+		want   CheckResult
+	}{
+		{
+			name: "should match the scope of the resource",
+			res:  &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}},
+			want: CheckResult{Allowed: true, Reason: ReasonScopeMatch, Scope: "dashboards:uid:1"},
+		},
+		{
+			name: "should match the scope of a contextual resource",
+			res:  &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "folders:uid:f"}}},
+			modify: func(req *CheckRequest) {
+				req.Contextual = []Resource{{Kind: "folders", Attr: "uid", ID: "f"}}
+			},
+			want: CheckResult{Allowed: true, Reason: ReasonScopeMatch, Scope: "folders:uid:f"},
+		},
+		{
+			name: "should match a wildcard",
+			res:  &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:*"}}},
+			want: CheckResult{Allowed: true, Reason: ReasonWildcard, Scope: "dashboards:*"},
+		},
+		{
+			name: "should match a wildcard as granted",
+			res:  &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:*"}}},
+			want: CheckResult{Allowed: true, Reason: ReasonWildcard, Scope: "dashboards:uid:*"},
+		},
+		{
+			name: "should match the wildcard of a contextual resource as granted",
+			res:  &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "folders:uid:*"}}},
+			modify: func(req *CheckRequest) {
+				req.Contextual = []Resource{{Kind: "folders", Attr: "uid", ID: "f"}}
+			},
+			want: CheckResult{Allowed: true, Reason: ReasonWildcard, Scope: "folders:uid:*"},
+		},
+		{
+			name:   "should allow action checks",
+			res:    &authzv1.ReadResponse{Found: true},
+			modify: func(req *CheckRequest) { req.Resource = nil },
+			want:   CheckResult{Allowed: true, Reason: ReasonAction},
+		},
+		{
+			name: "should deny without scope match",
+			res:  &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:2"}}},
+			want: CheckResult{Reason: ReasonNoScopeMatch},
+		},
+		{
+			name: "should deny without permission",
+			res:  &authzv1.ReadResponse{Found: false},
+			want: CheckResult{Reason: ReasonNoPermission},
+		},
+		{
+			name:   "should deny on namespace mismatch",
+			modify: func(req *CheckRequest) { req.StackID = 13 },
+			want:   CheckResult{Reason: ReasonNamespaceMismatch},
+		},
+		{
+			name:   "should deny without delegated permission",
+			modify: func(req *CheckRequest) { req.Action = "dashboards:write" },
+			want:   CheckResult{Reason: ReasonMissingDelegatedPermission},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, authz := setupLegacyClient()
+			authz.res = tt.res
+
+			req := inProcessCheckRequest()
+			if tt.modify != nil {
+				tt.modify(req)
+			}
+
+			got, err := client.CheckDetailed(context.Background(), req)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+
+			allowed, err := client.Check(context.Background(), req)
+			require.NoError(t, err)
+			require.Equal(t, tt.want.Allowed, allowed)
+		})
+	}
+
+	t.Run("should report invalid requests", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+
+		req := inProcessCheckRequest()
+		req.Action = ""
+		got, err := client.CheckDetailed(context.Background(), req)
+		require.ErrorIs(t, err, ErrMissingAction)
+		require.Equal(t, CheckResult{Reason: ReasonInvalidRequest}, got)
+	})
+}
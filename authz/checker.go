@@ -1,5 +1,7 @@
 package authz
 
+import "github.com/grafana/authlib/authz/scope"
+
 var (
 	noAccessChecker   Checker = func(resources ...Resource) bool { return false }
 	fullAccessChecker Checker = func(resources ...Resource) bool { return true }
@@ -51,14 +53,7 @@ func wildcardDetector(kinds ...string) func(scope string) bool {
 	if len(kinds) == 0 {
 		return func(scope string) bool { return false }
 	}
-	return func(scope string) bool {
-		// split the scope into its parts
-		kind, _, id := splitScope(scope)
-		for i := range kinds {
-			if (kind == kinds[i] || kind == "*") && id == "*" {
-				return true
-			}
-		}
-		return false
+	return func(s string) bool {
+		return scope.IsWildcard(s, kinds...)
 	}
 }
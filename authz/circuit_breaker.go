@@ -0,0 +1,141 @@
+package authz
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrCircuitOpen = status.Errorf(codes.Unavailable, "authz service circuit open")
+
+const (
+	DefaultCircuitFailureThreshold = 5
+	DefaultCircuitOpenTimeout      = 10 * time.Second
+	DefaultCircuitHalfOpenProbes   = 1
+)
+
+// CircuitBreakerConfig configures the circuit breaker around the reads of the authz service.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed reads opening the circuit. Defaults to 5.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open, failing the reads with ErrCircuitOpen,
+	// before probing the authz service again. Defaults to 10s.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of reads probing the authz service once the circuit is half-open.
+	// The circuit closes when they all succeed, and opens again on the first failure. Defaults to 1.
+	HalfOpenProbes int
+}
+
+// WithCircuitBreakerLCOption fails the reads fast, with ErrCircuitOpen, while the authz service is failing,
+// instead of piling up timeouts. Only the service failures (ex: Unavailable or DeadlineExceeded) are counted.
+// Cached permissions are still used, and break glass identities still bypass the failing reads.
+func WithCircuitBreakerLCOption(cfg CircuitBreakerConfig) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.breaker = newCircuitBreaker(cfg)
+	}
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+type circuitBreaker struct {
+	threshold   int
+	openTimeout time.Duration
+	probes      int
+	now         func() time.Time
+
+	mtx      sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	// inflight and succeeded are the probes of the half-open circuit
+	inflight  int
+	succeeded int
+}
+
+func newCircuitBreaker(cfg CircuitBreakerConfig) *circuitBreaker {
+	b := &circuitBreaker{
+		threshold:   cfg.FailureThreshold,
+		openTimeout: cfg.OpenTimeout,
+		probes:      cfg.HalfOpenProbes,
+		now:         time.Now,
+	}
+	if b.threshold <= 0 {
+		b.threshold = DefaultCircuitFailureThreshold
+	}
+	if b.openTimeout <= 0 {
+		b.openTimeout = DefaultCircuitOpenTimeout
+	}
+	if b.probes <= 0 {
+		b.probes = DefaultCircuitHalfOpenProbes
+	}
+	return b
+}
+
+// allow returns ErrCircuitOpen if the read must fail fast. Otherwise, the read outcome must be recorded.
+func (b *circuitBreaker) allow() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.state == circuitOpen {
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return ErrCircuitOpen
+		}
+		b.state, b.inflight, b.succeeded = circuitHalfOpen, 0, 0
+	}
+	if b.state == circuitHalfOpen {
+		if b.inflight >= b.probes {
+			return ErrCircuitOpen
+		}
+		b.inflight++
+	}
+	return nil
+}
+
+// record records the outcome of an allowed read, and returns whether it opened the circuit.
+func (b *circuitBreaker) record(err error) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if !isServiceFailure(err) {
+		switch b.state {
+		case circuitClosed:
+			b.failures = 0
+		case circuitHalfOpen:
+			b.succeeded++
+			if b.succeeded >= b.probes {
+				b.state, b.failures = circuitClosed, 0
+			}
+		}
+		return false
+	}
+
+	switch b.state {
+	case circuitClosed:
+		b.failures++
+		if b.failures < b.threshold {
+			return false
+		}
+	case circuitOpen:
+		// a read allowed before the circuit opened
+		return false
+	}
+	b.state, b.openedAt = circuitOpen, b.now()
+	return true
+}
+
+// isServiceFailure returns whether the error is a failure of the authz service, rather than of the request.
+func isServiceFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+// switchableAuthzServiceClient fails the reads with err, if any, and counts them.
+type switchableAuthzServiceClient struct {
+	err   error
+	reads int
+}
+
+func (f *switchableAuthzServiceClient) Read(context.Context, *authzv1.ReadRequest, ...grpc.CallOption) (*authzv1.ReadResponse, error) {
+	f.reads++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}, nil
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute, HalfOpenProbes: 2})
+	b.now = func() time.Time { return now }
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	require.NoError(t, b.allow())
+	require.False(t, b.record(status.Error(codes.InvalidArgument, "invalid")), "request errors are not counted")
+	require.NoError(t, b.allow())
+	require.False(t, b.record(unavailable))
+	require.NoError(t, b.allow())
+	require.True(t, b.record(unavailable))
+	require.ErrorIs(t, b.allow(), ErrCircuitOpen)
+
+	t.Run("should reopen when a probe fails", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		require.NoError(t, b.allow())
+		require.NoError(t, b.allow())
+		require.ErrorIs(t, b.allow(), ErrCircuitOpen, "only the probes are allowed")
+		require.True(t, b.record(unavailable))
+		require.ErrorIs(t, b.allow(), ErrCircuitOpen)
+	})
+
+	t.Run("should close when the probes succeed", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		require.NoError(t, b.allow())
+		require.NoError(t, b.allow())
+		b.record(nil)
+		require.ErrorIs(t, b.allow(), ErrCircuitOpen)
+		b.record(nil)
+		require.NoError(t, b.allow())
+		require.NoError(t, b.allow())
+	})
+}
+
+func TestLegacyClientImpl_Check_CircuitBreaker(t *testing.T) {
+	client, _ := setupLegacyClient()
+	authz := &switchableAuthzServiceClient{err: errors.New("unavailable")}
+	client.clientV1 = authz
+	WithCircuitBreakerLCOption(CircuitBreakerConfig{FailureThreshold: 2})(client)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Check(context.Background(), inProcessCheckRequest())
+		require.ErrorIs(t, err, ErrReadPermission)
+	}
+
+	_, err := client.Check(context.Background(), inProcessCheckRequest())
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, 2, authz.reads, "the open circuit fails fast")
+
+	client.breaker.now = func() time.Time { return time.Now().Add(DefaultCircuitOpenTimeout) }
+	authz.err = nil
+	allowed, err := client.Check(context.Background(), inProcessCheckRequest())
+	require.NoError(t, err)
+	require.True(t, allowed)
+	require.Equal(t, 3, authz.reads)
+}
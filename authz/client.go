@@ -7,32 +7,41 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	goquery "github.com/google/go-querystring/query"
-	"golang.org/x/sync/singleflight"
 
 	"github.com/grafana/authlib/authn"
 	"github.com/grafana/authlib/cache"
+	"github.com/grafana/authlib/errs"
 	"github.com/grafana/authlib/internal/httpclient"
 )
 
 var _ client = &clientImpl{}
 
 var (
-	ErrInvalidQuery     = errors.New("invalid query")
-	ErrInvalidIDToken   = errors.New("invalid id token: cannot extract namespaced ID")
-	ErrInvalidToken     = errors.New("invalid token: cannot query server")
+	ErrInvalidQuery     = errs.New(errs.KindBadRequest, "invalid query")
+	ErrInvalidIDToken   = errs.New(errs.KindUnauthenticated, "invalid id token: cannot extract namespaced ID")
+	ErrInvalidToken     = errs.New(errs.KindUnauthenticated, "invalid token: cannot query server")
 	ErrInvalidResponse  = errors.New("invalid response from server")
 	ErrUnexpectedStatus = errors.New("unexpected response status")
-	ErrInvalidNamespace = errors.New("invalid token: can only query server for users and service-accounts")
+	ErrInvalidNamespace = errs.New(errs.KindForbidden, "invalid token: can only query server for users and service-accounts")
 )
 
+// unexpectedStatus returns the error of the response status, of the kind of the status (ex: 503 is unavailable).
+func unexpectedStatus(res *http.Response) error {
+	return errs.Wrap(errs.KindFromHTTPStatus(res.StatusCode), fmt.Errorf("%w: %s", ErrUnexpectedStatus, res.Status))
+}
+
 const (
 	cacheExp                = 5 * time.Minute
+	validatorCacheExp       = 24 * time.Hour
 	searchPath              = "/api/access-control/users/permissions/search"
+	teamSearchPath          = "/api/access-control/teams/permissions/search"
 	NamespaceServiceAccount = "service-account"
 	NamespaceUser           = "user"
 )
@@ -48,9 +57,19 @@ func withHTTPClient(doer HTTPRequestDoer) clientOption {
 }
 
 // withCache allows overriding the default cache, which is a local cache.
-func withCache(cache cache.Cache) clientOption {
+func withCache(c cache.Cache) clientOption {
+	return func(client *clientImpl) error {
+		client.cache = cache.Loading(cache.Versioned(c, searchCacheVersion))
+
+		return nil
+	}
+}
+
+// withRetry retries the throttled or failing searches.
+func withRetry(policy RetryPolicy) clientOption {
 	return func(c *clientImpl) error {
-		c.cache = cache
+		policy = policy.withDefaults()
+		c.retry = &policy
 
 		return nil
 	}
@@ -58,10 +77,9 @@ func withCache(cache cache.Cache) clientOption {
 
 func newClient(cfg Config, opts ...clientOption) (*clientImpl, error) {
 	client := &clientImpl{
-		singlef: singleflight.Group{},
-		client:  nil,
-		cache:   nil,
-		cfg:     cfg,
+		client: nil,
+		cache:  nil,
+		cfg:    cfg,
 	}
 
 	for _, opt := range opts {
@@ -71,10 +89,10 @@ func newClient(cfg Config, opts ...clientOption) (*clientImpl, error) {
 	}
 
 	if client.cache == nil {
-		client.cache = cache.NewLocalCache(cache.Config{
+		client.cache = cache.Loading(cache.Versioned(cache.NewLocalCache(cache.Config{
 			Expiry:          cacheExp,
 			CleanupInterval: 1 * time.Minute,
-		})
+		}), searchCacheVersion))
 	}
 
 	client.verifier = authn.NewVerifier[customClaims](
@@ -92,13 +110,19 @@ func newClient(cfg Config, opts ...clientOption) (*clientImpl, error) {
 }
 
 type clientImpl struct {
-	cache    cache.Cache
+	cache    cache.LoaderCache
 	cfg      Config
 	client   HTTPRequestDoer
 	verifier authn.Verifier[customClaims]
-	singlef  singleflight.Group
+	// retry retries the throttled or failing searches, if set
+	retry *RetryPolicy
+	// generations version the cached searches, to invalidate them on writes
+	generations generations
 }
 
+// searchCacheVersion is the version of the encoding of the cached search responses.
+const searchCacheVersion = 1
+
 func searchCacheKey(query searchQuery) string {
 	// TODO : safe to ignore the error completely?
 	data, _ := json.Marshal(query)
@@ -116,10 +140,10 @@ func (query *searchQuery) processIDToken(c *clientImpl) error {
 	if query.IdToken != "" {
 		claims, err := c.verifier.Verify(context.Background(), query.IdToken)
 		if err != nil {
-			return fmt.Errorf("%v: %w", ErrInvalidIDToken, err)
+			return fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
 		}
 		if claims.Subject == "" {
-			return fmt.Errorf("%v: %w", ErrInvalidIDToken, errors.New("missing subject (namespacedID) in id token"))
+			return fmt.Errorf("%w: missing subject (namespacedID) in id token", ErrInvalidIDToken)
 		}
 		query.NamespacedID = claims.Subject
 		if !(strings.HasPrefix(query.NamespacedID, NamespaceServiceAccount) || strings.HasPrefix(query.NamespacedID, NamespaceUser)) {
@@ -144,91 +168,300 @@ func (query *searchQuery) validateQuery() error {
 	return nil
 }
 
-// Search returns the permissions for the given query.
-func (c *clientImpl) Search(ctx context.Context, query searchQuery) (*searchResponse, error) {
+// prepareQuery sets the scope and the namespaced ID of the query, and validates it.
+func (c *clientImpl) prepareQuery(query *searchQuery) error {
 	// set scope if resource is provided
 	query.processResource()
 
 	// set namespaced ID if id token is provided
 	if err := query.processIDToken(c); err != nil {
-		return nil, err
+		return err
 	}
 
 	// validate query
-	if err := query.validateQuery(); err != nil {
+	return query.validateQuery()
+}
+
+// Search returns the permissions for the given query, following the pages of the search, if any.
+func (c *clientImpl) Search(ctx context.Context, query searchQuery) (*searchResponse, error) {
+	if err := c.prepareQuery(&query); err != nil {
 		return nil, err
 	}
 
-	key := searchCacheKey(query)
+	// Version the key with the generation of the subject, bumped when its permissions are written
+	key := c.orgCacheKey(ctx, searchCacheKey(query)+"@"+c.generations.key(query.NamespacedID))
+
+	item, err := c.cache.GetOrSet(ctx, key, cache.DefaultExpiration, func() ([]byte, error) {
+		if c.cfg.SearchPageSize <= 0 {
+			perms, err := c.searchConditional(ctx, query, key)
+			if err != nil {
+				return nil, err
+			}
+			return encodePermissions(perms)
+		}
 
-	item, err := c.cache.Get(ctx, key)
-	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		all := permissionsByID{}
+		err := c.searchPages(ctx, query, func(page permissionsByID) bool {
+			all.merge(page)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		return encodePermissions(all)
+	})
+	if errors.Is(err, cache.ErrWrite) {
+		return nil, fmt.Errorf("failed to cache response: %w", err)
+	}
+	if err != nil {
 		return nil, err
 	}
 
-	if err == nil {
-		perms := permissionsByID{}
-		err := gob.NewDecoder(bytes.NewReader(item)).Decode(&perms)
+	perms := permissionsByID{}
+	if err := gob.NewDecoder(bytes.NewReader(item)).Decode(&perms); err != nil {
+		return nil, fmt.Errorf("failed to decode cache entry: %w", err)
+	}
+	return &searchResponse{Data: &perms}, nil
+}
+
+// SearchTeams returns the permissions of the teams for the given query.
+func (c *clientImpl) SearchTeams(ctx context.Context, query TeamPermissionsQuery) (permissionsByID, error) {
+	if query.TeamID == 0 && query.ActionPrefix == "" && query.Action == "" {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, "at least one search option must be provided")
+	}
+	if query.ActionPrefix != "" && query.Action != "" {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, "'action' and 'actionPrefix' are mutually exclusive")
+	}
+
+	data, _ := json.Marshal(query)
+	key := c.orgCacheKey(ctx, "teams"+string(data))
+
+	item, err := c.cache.GetOrSet(ctx, key, cache.DefaultExpiration, func() ([]byte, error) {
+		perms, err := c.searchPage(ctx, teamSearchPath, query, string(data))
 		if err != nil {
-			return nil, fmt.Errorf("failed to decode cache entry: %w", err)
-		} else {
-			return &searchResponse{Data: &perms}, nil
+			return nil, err
 		}
+		return encodePermissions(perms)
+	})
+	if errors.Is(err, cache.ErrWrite) {
+		return nil, fmt.Errorf("failed to cache response: %w", err)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	res, err, _ := c.singlef.Do(key, func() (interface{}, error) {
-		v, _ := goquery.Values(query)
-		url := strings.TrimRight(c.cfg.APIURL, "/") + searchPath + "?" + v.Encode()
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, strings.NewReader(key))
+	perms := permissionsByID{}
+	if err := gob.NewDecoder(bytes.NewReader(item)).Decode(&perms); err != nil {
+		return nil, fmt.Errorf("failed to decode cache entry: %w", err)
+	}
+	return perms, nil
+}
+
+// SearchPages calls yield with the pages of the permissions for the given query, until it returns false.
+// The pages are not cached.
+func (c *clientImpl) SearchPages(ctx context.Context, query searchQuery, yield func(permissionsByID) bool) error {
+	if err := c.prepareQuery(&query); err != nil {
+		return err
+	}
+	return c.searchPages(ctx, query, yield)
+}
+
+// maxSearchPages bounds the pages followed by a search.
+const maxSearchPages = 10000
+
+// searchPages follows the pages of the search, when the page size is configured.
+// The last page is the first one with less users than the page size, or without new users,
+// from servers ignoring the pagination.
+func (c *clientImpl) searchPages(ctx context.Context, query searchQuery, yield func(permissionsByID) bool) error {
+	if c.cfg.SearchPageSize <= 0 {
+		perms, err := c.searchPage(ctx, searchPath, query, searchCacheKey(query))
 		if err != nil {
-			return nil, err
+			return err
+		}
+		yield(perms)
+		return nil
+	}
+
+	query.PerPage = c.cfg.SearchPageSize
+	seen := map[int64]struct{}{}
+	for query.Page = 1; query.Page <= maxSearchPages; query.Page++ {
+		perms, err := c.searchPage(ctx, searchPath, query, searchCacheKey(query))
+		if err != nil {
+			return err
 		}
+		added := 0
+		for id := range perms {
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				added++
+			}
+		}
+		if added == 0 {
+			return nil
+		}
+		if !yield(perms) || len(perms) < query.PerPage {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: more than %d pages", ErrInvalidResponse, maxSearchPages)
+}
+
+// searchValidator is the last response of a search with its ETag. It outlives the cached search, to revalidate
+// the search with a conditional request once the cached search expired.
+type searchValidator struct {
+	ETag        string
+	Permissions permissionsByID
+}
 
-		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept", "application/json")
+// searchConditional searches the permissions in a single request, conditional on the ETag of the last response,
+// if any. On 304 (Not Modified), the permissions of the last response are reused, without transferring them.
+func (c *clientImpl) searchConditional(ctx context.Context, query searchQuery, key string) (permissionsByID, error) {
+	validatorKey := key + "#etag"
+	var validator searchValidator
+	if item, err := c.cache.Get(ctx, validatorKey); err == nil {
+		if err := gob.NewDecoder(bytes.NewReader(item)).Decode(&validator); err != nil {
+			validator = searchValidator{}
+		}
+	}
+
+	header := http.Header{}
+	if validator.ETag != "" {
+		header.Set("If-None-Match", validator.ETag)
+	}
+	res, err := c.send(ctx, http.MethodGet, searchPath, query, searchCacheKey(query), header)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
 
-		res, err := c.client.Do(req)
+	if res.StatusCode != http.StatusNotModified || validator.ETag == "" {
+		perms, err := decodeSearch(res)
 		if err != nil {
 			return nil, err
 		}
+		validator = searchValidator{ETag: res.Header.Get("ETag"), Permissions: perms}
+	}
 
-		defer res.Body.Close()
+	if validator.ETag != "" {
+		// Refresh the validator, the failures only cost a full response on the next search
+		buf := bytes.Buffer{}
+		if err := gob.NewEncoder(&buf).Encode(validator); err == nil {
+			_ = c.cache.Set(ctx, validatorKey, buf.Bytes(), validatorCacheExp)
+		}
+	}
+	return validator.Permissions, nil
+}
+
+// searchPage searches the permissions at the path, with the query encoded in the URL.
+func (c *clientImpl) searchPage(ctx context.Context, path string, query interface{}, body string) (permissionsByID, error) {
+	res, err := c.send(ctx, http.MethodGet, path, query, body, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return decodeSearch(res)
+}
 
-		if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
-			return nil, ErrInvalidToken
+// send sends the request to Grafana, with the query encoded in the URL and the additional headers, if any, and
+// retries it when it is throttled or fails on the server (see withRetry). The caller must close the body of the
+// response.
+func (c *clientImpl) send(ctx context.Context, method, path string, query interface{}, body string, header http.Header) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := c.do(ctx, method, path, query, body, header)
+		if err != nil {
+			return nil, err
 		}
 
-		if res.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("%w: %s", ErrUnexpectedStatus, res.Status)
+		delay, retry := c.retryDelay(res, attempt)
+		if !retry {
+			return res, nil
 		}
 
-		response := permissionsByID{}
-		if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
-			return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+		_, _ = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		if !wait(ctx, delay) {
+			return nil, ctx.Err()
 		}
-		return response, nil
-	})
+	}
+}
 
+func (c *clientImpl) do(ctx context.Context, method, path string, query interface{}, body string, header http.Header) (*http.Response, error) {
+	url := strings.TrimRight(c.cfg.APIURL, "/") + path
+	if query != nil {
+		v, _ := goquery.Values(query)
+		url += "?" + v.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 
-	perms := res.(permissionsByID)
-	if err := c.cacheValue(ctx, perms, key); err != nil {
-		return nil, fmt.Errorf("failed to cache response: %w", err)
+	for name, values := range header {
+		req.Header[name] = values
 	}
+	req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	c.setOrg(req)
 
-	return &searchResponse{Data: &perms}, nil
+	return c.client.Do(req)
 }
 
-func (c *clientImpl) cacheValue(ctx context.Context, perms permissionsByID, key string) error {
-	buf := bytes.Buffer{}
-	err := gob.NewEncoder(&buf).Encode(perms)
-	if err != nil {
-		return err
+// retryDelay returns the delay before retrying the request following the attempt, and whether to retry it.
+// The requests throttled (429) or failing on the server (5xx) are retried, after the delay of the Retry-After
+// header, if any. Retry-After delays longer than the maximum backoff are not waited for.
+func (c *clientImpl) retryDelay(res *http.Response, attempt int) (time.Duration, bool) {
+	if c.retry == nil || attempt >= c.retry.MaxAttempts {
+		return 0, false
+	}
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode < http.StatusInternalServerError {
+		return 0, false
 	}
 
-	// Cache with default expiry
-	return c.cache.Set(ctx, key, buf.Bytes(), cache.DefaultExpiration)
+	delay := c.retry.backoff(attempt)
+	if after, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+		if after > c.retry.MaxBackoff {
+			return 0, false
+		}
+		delay = max(delay, after)
+	}
+	return delay, true
+}
+
+// parseRetryAfter parses the Retry-After header, in seconds or as an HTTP date.
+func parseRetryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+func decodeSearch(res *http.Response) (permissionsByID, error) {
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return nil, ErrInvalidToken
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus(res)
+	}
+
+	response := permissionsByID{}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+	}
+	return response, nil
+}
+
+func encodePermissions(perms permissionsByID) ([]byte, error) {
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(perms); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
@@ -74,3 +74,191 @@ func TestClientImpl_Search(t *testing.T) {
 		})
 	}
 }
+
+func TestClientImpl_SearchPages(t *testing.T) {
+	// 5 users, served by pages
+	users := map[int]map[string][]string{}
+	for id := 1; id <= 5; id++ {
+		users[id] = map[string][]string{"teams:read": {fmt.Sprintf("teams:id:%d", id)}}
+	}
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, perPage := r.URL.Query().Get("page"), r.URL.Query().Get("perpage")
+		pages = append(pages, page)
+
+		var p, pp int
+		_, _ = fmt.Sscan(page, &p)
+		_, _ = fmt.Sscan(perPage, &pp)
+		res := map[string]map[string][]string{}
+		for id := (p-1)*pp + 1; id <= p*pp && id <= len(users); id++ {
+			res[fmt.Sprint(id)] = users[id]
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+
+	c, err := newClient(Config{APIURL: server.URL, Token: "aabbcc", SearchPageSize: 2})
+	require.NoError(t, err)
+	c.client = server.Client()
+
+	t.Run("should follow the pages", func(t *testing.T) {
+		pages = nil
+		got, err := c.Search(context.Background(), searchQuery{ActionPrefix: "teams:"})
+		require.NoError(t, err)
+		require.Len(t, *got.Data, 5)
+		require.Equal(t, []string{"1", "2", "3"}, pages)
+	})
+
+	t.Run("should stop following the pages", func(t *testing.T) {
+		pages = nil
+		err := c.SearchPages(context.Background(), searchQuery{ActionPrefix: "teams:"}, func(permissionsByID) bool { return false })
+		require.NoError(t, err)
+		require.Equal(t, []string{"1"}, pages)
+	})
+}
+
+func TestClientImpl_Search_PaginationIgnored(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"1": {"teams:read": ["teams:id:1"]}, "2": {"teams:read": ["teams:id:2"]}}`))
+	}))
+	defer server.Close()
+
+	c, err := newClient(Config{APIURL: server.URL, Token: "aabbcc", SearchPageSize: 2})
+	require.NoError(t, err)
+	c.client = server.Client()
+
+	got, err := c.Search(context.Background(), searchQuery{ActionPrefix: "teams:"})
+	require.NoError(t, err)
+	require.Len(t, *got.Data, 2)
+	require.Equal(t, 2, calls)
+}
+
+func TestClientImpl_Search_Retry(t *testing.T) {
+	var statuses []int
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[calls]
+		calls++
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"1": {"teams:read": ["teams:id:1"]}}`))
+	}))
+	defer server.Close()
+
+	newRetryClient := func(policy RetryPolicy) *clientImpl {
+		c, err := newClient(Config{APIURL: server.URL, Token: "aabbcc"}, withRetry(policy))
+		require.NoError(t, err)
+		c.client = server.Client()
+		return c
+	}
+
+	t.Run("should retry the throttled and failing searches", func(t *testing.T) {
+		statuses, calls = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusOK}, 0
+		c := newRetryClient(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+		got, err := c.Search(context.Background(), searchQuery{Action: "teams:read"})
+		require.NoError(t, err)
+		require.Len(t, *got.Data, 1)
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("should fail after the last attempt", func(t *testing.T) {
+		statuses, calls = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}, 0
+		c := newRetryClient(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+		_, err := c.Search(context.Background(), searchQuery{Action: "teams:read"})
+		require.ErrorIs(t, err, ErrUnexpectedStatus)
+		require.Equal(t, 2, calls)
+	})
+
+	t.Run("should not retry the client errors", func(t *testing.T) {
+		statuses, calls = []int{http.StatusBadRequest}, 0
+		c := newRetryClient(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+		_, err := c.Search(context.Background(), searchQuery{Action: "teams:read"})
+		require.ErrorIs(t, err, ErrUnexpectedStatus)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("should return the error of the context cancelled while waiting", func(t *testing.T) {
+		statuses, calls = []int{http.StatusServiceUnavailable, http.StatusOK}, 0
+		c := newRetryClient(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Minute, MaxBackoff: time.Minute})
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := c.Search(ctx, searchQuery{Action: "teams:read"})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NotErrorIs(t, err, ErrUnexpectedStatus)
+		require.Equal(t, 1, calls)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	d, ok := parseRetryAfter("2")
+	require.True(t, ok)
+	require.Equal(t, 2*time.Second, d)
+
+	d, ok = parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	require.True(t, ok)
+	require.InDelta(t, time.Hour, d, float64(2*time.Second))
+
+	_, ok = parseRetryAfter("soon")
+	require.False(t, ok)
+}
+
+func TestClientImpl_SearchTeams(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		require.Equal(t, teamSearchPath, r.URL.Path)
+		require.Equal(t, "2", r.URL.Query().Get("teamId"))
+		_, _ = w.Write([]byte(`{"2": {"teams:read": ["teams:id:2"]}}`))
+	}))
+	defer server.Close()
+
+	testCache := &cacheWrap{cache: cache.NewLocalCache(cache.Config{})}
+	c, err := newClient(Config{APIURL: server.URL, Token: "aabbcc"}, withCache(testCache))
+	require.NoError(t, err)
+	c.client = server.Client()
+
+	for i := 0; i < 2; i++ {
+		got, err := c.SearchTeams(context.Background(), TeamPermissionsQuery{TeamID: 2})
+		require.NoError(t, err)
+		require.Equal(t, permissionsByID{2: {"teams:read": {"teams:id:2"}}}, got)
+	}
+	require.Equal(t, 1, calls, "should read from cache")
+	require.Equal(t, 1, testCache.successWriteCnt)
+
+	_, err = c.SearchTeams(context.Background(), TeamPermissionsQuery{})
+	require.ErrorIs(t, err, ErrInvalidQuery)
+}
+
+func TestClientImpl_Search_ETag(t *testing.T) {
+	var calls, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(`{"1": {"users:read": ["org.users:*"]}}`))
+	}))
+	defer server.Close()
+
+	testCache := &cacheWrap{cache: cache.NewLocalCache(cache.Config{Expiry: time.Millisecond})}
+	c, err := newClient(Config{APIURL: server.URL, Token: "aabbcc"}, withCache(testCache))
+	require.NoError(t, err)
+	c.client = server.Client()
+
+	for i := 0; i < 2; i++ {
+		got, err := c.Search(context.Background(), searchQuery{NamespacedID: "user:1"})
+		require.NoError(t, err)
+		require.Equal(t, &permissionsByID{1: {"users:read": {"org.users:*"}}}, got.Data)
+		// Expire the cached search, but not its validator
+		time.Sleep(5 * time.Millisecond)
+	}
+	require.Equal(t, 2, calls)
+	require.Equal(t, 1, notModified, "should revalidate the expired search")
+}
@@ -37,10 +37,68 @@ func (_m *MockClient) Search(ctx context.Context, query searchQuery) (*searchRes
 	return r0, r1
 }
 
+func (_m *MockClient) SearchTeams(ctx context.Context, query TeamPermissionsQuery) (permissionsByID, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 permissionsByID
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(permissionsByID)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockClient) ListRoles(ctx context.Context, query ListRolesQuery) ([]Role, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 []Role
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]Role)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockClient) GetRole(ctx context.Context, uid string) (*Role, error) {
+	ret := _m.Called(ctx, uid)
+
+	var r0 *Role
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*Role)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockClient) ListRoleAssignments(ctx context.Context, uid string) (*RoleAssignments, error) {
+	ret := _m.Called(ctx, uid)
+
+	var r0 *RoleAssignments
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*RoleAssignments)
+	}
+	return r0, ret.Error(1)
+}
+
+func (_m *MockClient) AssignRole(ctx context.Context, userID int64, roleUID string) error {
+	return _m.Called(ctx, userID, roleUID).Error(0)
+}
+
+func (_m *MockClient) RevokeRole(ctx context.Context, userID int64, roleUID string) error {
+	return _m.Called(ctx, userID, roleUID).Error(0)
+}
+
+func (_m *MockClient) SetUserPermissions(ctx context.Context, perm ResourcePermission) error {
+	return _m.Called(ctx, perm).Error(0)
+}
+
+func (_m *MockClient) SearchPages(ctx context.Context, query searchQuery, yield func(permissionsByID) bool) error {
+	ret := _m.Called(ctx, query, yield)
+	return ret.Error(0)
+}
+
 type cacheWrap struct {
 	successReadCnt   int
 	successWriteCnt  int
 	successDeleteCnt int
+	lastExpiry       time.Duration
 	cache            cache.Cache
 }
 
@@ -58,6 +116,7 @@ func (c *cacheWrap) Set(ctx context.Context, key string, data []byte, exp time.D
 	err := c.cache.Set(ctx, key, data, exp)
 	if err == nil {
 		c.successWriteCnt++
+		c.lastExpiry = exp
 	}
 	return err
 }
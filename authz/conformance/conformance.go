@@ -0,0 +1,85 @@
+// Package conformance provides the test vectors all authz.MultiTenantClient implementations must pass.
+//
+// Implementations run the suite from their own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, func(t *testing.T, perms []conformance.Permission) authz.MultiTenantClient {
+//			return newClientServing(perms)
+//		})
+//	}
+package conformance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/authlib/authz"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+// Permission is a permission granted to a user or service account of a stack.
+type Permission struct {
+	StackID int64
+	// Subject is the typed identifier of the identity (ex: "user:1").
+	Subject string
+	Action  string
+	// Scope is the resource the action applies to (ex: "dashboards:uid:1", "dashboards:uid:*" or "*").
+	// An empty scope grants the action only.
+	Scope string
+}
+
+// TestCase is a check request with its expected outcome.
+type TestCase struct {
+	Name    string
+	Request authz.CheckRequest
+	// Want is the expected result of the check.
+	Want bool
+	// WantCode is the expected gRPC status code of the error. codes.OK means no error is expected.
+	WantCode codes.Code
+}
+
+// ClientFactory creates the client under test, backed by the given permissions.
+type ClientFactory func(t *testing.T, perms []Permission) authz.MultiTenantClient
+
+// Run checks the client created by newClient against all the test vectors.
+// A new client is created for each test case.
+func Run(t *testing.T, newClient ClientFactory) {
+	t.Helper()
+
+	perms := Permissions()
+	for _, tc := range TestCases() {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			client := newClient(t, perms)
+
+			got, err := client.Check(context.Background(), &tc.Request)
+			if tc.WantCode != codes.OK {
+				require.Error(t, err)
+				require.Equal(t, tc.WantCode, status.Code(err), "unexpected error: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.Want, got)
+		})
+	}
+}
+
+// ReadResponse answers a read request from the permissions.
+// It can be used to implement test authz services backing the clients under test.
+func ReadResponse(perms []Permission, req *authzv1.ReadRequest) *authzv1.ReadResponse {
+	res := &authzv1.ReadResponse{}
+	for _, p := range perms {
+		if p.StackID != req.GetStackId() || p.Subject != req.GetSubject() || p.Action != req.GetAction() {
+			continue
+		}
+		res.Found = true
+		if p.Scope != "" {
+			res.Data = append(res.Data, &authzv1.ReadResponse_Data{Object: p.Scope})
+		}
+	}
+	return res
+}
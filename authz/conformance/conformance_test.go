@@ -0,0 +1,39 @@
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/grafana/authlib/authz"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+// fakeConn serves the authz service from the permissions.
+type fakeConn struct {
+	perms []Permission
+}
+
+func (c *fakeConn) Invoke(ctx context.Context, method string, args any, reply any, opts ...grpc.CallOption) error {
+	if method != authzv1.AuthzService_Read_FullMethodName {
+		return fmt.Errorf("unexpected method %s", method)
+	}
+	proto.Merge(reply.(*authzv1.ReadResponse), ReadResponse(c.perms, args.(*authzv1.ReadRequest)))
+	return nil
+}
+
+func (c *fakeConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, fmt.Errorf("unexpected stream %s", method)
+}
+
+func TestLegacyClientConformance(t *testing.T) {
+	Run(t, func(t *testing.T, perms []Permission) authz.MultiTenantClient {
+		client, err := authz.NewLegacyClient(&authz.MultiTenantClientConfig{}, authz.WithGrpcConnectionLCOption(&fakeConn{perms: perms}))
+		require.NoError(t, err)
+		return client
+	})
+}
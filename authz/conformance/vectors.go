@@ -0,0 +1,251 @@
+package conformance
+
+import (
+	"github.com/go-jose/go-jose/v3/jwt"
+	"google.golang.org/grpc/codes"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/authz"
+)
+
+const (
+	stackID        = 12
+	namespace      = "stacks-12"
+	otherNamespace = "stacks-13"
+
+	serviceSubject = "access-policy:service"
+	readAction     = "dashboards:read"
+	writeAction    = "dashboards:write"
+)
+
+// Permissions returns the permissions backing the test vectors.
+func Permissions() []Permission {
+	return []Permission{
+		{StackID: stackID, Subject: "user:1", Action: readAction, Scope: "dashboards:uid:1"},
+		{StackID: stackID, Subject: "user:1", Action: readAction, Scope: "folders:uid:1"},
+		{StackID: stackID, Subject: "user:1", Action: writeAction},
+		{StackID: stackID, Subject: "user:2", Action: readAction, Scope: "dashboards:uid:*"},
+		{StackID: stackID, Subject: "user:3", Action: readAction, Scope: "*"},
+		{StackID: stackID, Subject: "service-account:1", Action: readAction, Scope: "dashboards:uid:2"},
+		// Same user in another stack
+		{StackID: 13, Subject: "user:4", Action: readAction, Scope: "*"},
+	}
+}
+
+// TestCases returns the test vectors.
+func TestCases() []TestCase {
+	delegated := service(namespace, nil, []string{readAction, writeAction})
+
+	return []TestCase{
+		// Validation
+		{
+			Name:     "missing caller",
+			Request:  authz.CheckRequest{Caller: &authn.AuthInfo{}, StackID: stackID, Action: readAction},
+			WantCode: codes.Unauthenticated,
+		},
+		{
+			Name:     "missing stack",
+			Request:  authz.CheckRequest{Caller: caller(delegated, nil), Action: readAction},
+			WantCode: codes.InvalidArgument,
+		},
+		{
+			Name:     "missing action",
+			Request:  authz.CheckRequest{Caller: caller(delegated, nil), StackID: stackID},
+			WantCode: codes.InvalidArgument,
+		},
+		{
+			Name:     "empty identity subject",
+			Request:  authz.CheckRequest{Caller: caller(delegated, user("", namespace)), StackID: stackID, Action: readAction},
+			WantCode: codes.Unauthenticated,
+		},
+
+		// Service only
+		{
+			Name:    "service has the action",
+			Request: authz.CheckRequest{Caller: caller(service(namespace, []string{readAction}, nil), nil), StackID: stackID, Action: readAction},
+			Want:    true,
+		},
+		{
+			Name:    "service does not have the action",
+			Request: authz.CheckRequest{Caller: caller(service(namespace, []string{writeAction}, nil), nil), StackID: stackID, Action: readAction},
+			Want:    false,
+		},
+		{
+			Name:    "service only has the action delegated",
+			Request: authz.CheckRequest{Caller: caller(delegated, nil), StackID: stackID, Action: readAction},
+			Want:    false,
+		},
+		{
+			Name:    "service has the action in another namespace",
+			Request: authz.CheckRequest{Caller: caller(service(otherNamespace, []string{readAction}, nil), nil), StackID: stackID, Action: readAction},
+			Want:    false,
+		},
+		{
+			Name:    "service has the action in all namespaces",
+			Request: authz.CheckRequest{Caller: caller(service("*", []string{readAction}, nil), nil), StackID: stackID, Action: readAction},
+			Want:    true,
+		},
+		{
+			Name:    "service has the action in all stacks",
+			Request: authz.CheckRequest{Caller: caller(service("stacks-*", []string{readAction}, nil), nil), StackID: stackID, Action: readAction},
+			Want:    true,
+		},
+		{
+			Name:    "service has the action in a list of namespaces",
+			Request: authz.CheckRequest{Caller: caller(service(otherNamespace+","+namespace, []string{readAction}, nil), nil), StackID: stackID, Action: readAction},
+			Want:    true,
+		},
+		{
+			Name:    "service has the action in a list of other namespaces",
+			Request: authz.CheckRequest{Caller: caller(service(otherNamespace+",stacks-14", []string{readAction}, nil), nil), StackID: stackID, Action: readAction},
+			Want:    false,
+		},
+
+		// On behalf of
+		{
+			Name:    "service cannot act on behalf of the user",
+			Request: authz.CheckRequest{Caller: caller(service(namespace, []string{readAction}, nil), user("user:1", namespace)), StackID: stackID, Action: readAction},
+			Want:    false,
+		},
+		{
+			Name:    "user has the action",
+			Request: authz.CheckRequest{Caller: caller(delegated, user("user:1", namespace)), StackID: stackID, Action: writeAction},
+			Want:    true,
+		},
+		{
+			Name:    "user does not have the action",
+			Request: authz.CheckRequest{Caller: caller(delegated, user("user:5", namespace)), StackID: stackID, Action: readAction},
+			Want:    false,
+		},
+		{
+			Name:    "user has the action on the resource",
+			Request: dashboardCheck(caller(delegated, user("user:1", namespace)), "1"),
+			Want:    true,
+		},
+		{
+			Name:    "user has the action on another resource",
+			Request: dashboardCheck(caller(delegated, user("user:1", namespace)), "2"),
+			Want:    false,
+		},
+		{
+			Name:    "user has the action without scope",
+			Request: authz.CheckRequest{Caller: caller(delegated, user("user:1", namespace)), StackID: stackID, Action: writeAction, Resource: dashboard("1")},
+			Want:    false,
+		},
+		{
+			Name: "user has the action on the contextual resource",
+			Request: authz.CheckRequest{
+				Caller:     caller(delegated, user("user:1", namespace)),
+				StackID:    stackID,
+				Action:     readAction,
+				Resource:   dashboard("3"),
+				Contextual: []authz.Resource{{Kind: "folders", Attr: "uid", ID: "2"}, {Kind: "folders", Attr: "uid", ID: "1"}},
+			},
+			Want: true,
+		},
+		{
+			Name:    "user has the action on all resources of the kind",
+			Request: dashboardCheck(caller(delegated, user("user:2", namespace)), "42"),
+			Want:    true,
+		},
+		{
+			Name: "user has the action on the resources of the kind only",
+			Request: authz.CheckRequest{
+				Caller:   caller(delegated, user("user:2", namespace)),
+				StackID:  stackID,
+				Action:   readAction,
+				Resource: &authz.Resource{Kind: "folders", Attr: "uid", ID: "1"},
+			},
+			Want: false,
+		},
+		{
+			Name:    "user has the action on all resources",
+			Request: dashboardCheck(caller(delegated, user("user:3", namespace)), "42"),
+			Want:    true,
+		},
+		{
+			Name:    "service account has the action on the resource",
+			Request: dashboardCheck(caller(delegated, user("service-account:1", namespace)), "2"),
+			Want:    true,
+		},
+
+		// Impersonation
+		{
+			Name:    "impersonator delegated the action",
+			Request: dashboardCheck(caller(delegated, impersonated("user:1", "user:3", []string{readAction})), "1"),
+			Want:    true,
+		},
+		{
+			Name:    "impersonator did not delegate the action",
+			Request: dashboardCheck(caller(delegated, impersonated("user:1", "user:3", []string{writeAction})), "1"),
+			Want:    false,
+		},
+		{
+			Name:    "impersonator delegated the action the subject does not have",
+			Request: dashboardCheck(caller(delegated, impersonated("user:5", "user:3", []string{readAction})), "1"),
+			Want:    false,
+		},
+		{
+			Name:     "impersonator without subject",
+			Request:  dashboardCheck(caller(delegated, impersonated("user:1", "", []string{readAction})), "1"),
+			WantCode: codes.Unauthenticated,
+		},
+
+		// Namespaces
+		{
+			Name:    "user is in another namespace",
+			Request: dashboardCheck(caller(service("*", nil, []string{readAction}), user("user:4", otherNamespace)), "1"),
+			Want:    false,
+		},
+		{
+			Name:    "user of the stack with a fleet-wide service",
+			Request: dashboardCheck(caller(service("stacks-*", nil, []string{readAction}), user("user:1", namespace)), "1"),
+			Want:    true,
+		},
+		{
+			Name:    "user and service namespaces differ from the stack",
+			Request: dashboardCheck(caller(service(otherNamespace, nil, []string{readAction}), user("user:3", otherNamespace)), "1"),
+			Want:    false,
+		},
+	}
+}
+
+func caller(access *authn.Access, identity *authn.Identity) *authn.AuthInfo {
+	return &authn.AuthInfo{AccessClaims: access, IdentityClaims: identity}
+}
+
+func service(namespace string, perms, delegatedPerms []string) *authn.Access {
+	return authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+		Claims: &jwt.Claims{Subject: serviceSubject},
+		Rest: authn.AccessTokenClaims{
+			Namespace:            namespace,
+			Permissions:          perms,
+			DelegatedPermissions: delegatedPerms,
+		},
+	})
+}
+
+func user(subject, namespace string) *authn.Identity {
+	return authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+		Claims: &jwt.Claims{Subject: subject},
+		Rest:   authn.IDTokenClaims{Namespace: namespace},
+	})
+}
+
+func impersonated(subject, actor string, delegatedPerms []string) *authn.Identity {
+	return authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+		Claims: &jwt.Claims{Subject: subject},
+		Rest: authn.IDTokenClaims{
+			Namespace: namespace,
+			Actor:     &authn.ActorClaims{Subject: actor, DelegatedPermissions: delegatedPerms},
+		},
+	})
+}
+
+func dashboard(uid string) *authz.Resource {
+	return &authz.Resource{Kind: "dashboards", Attr: "uid", ID: uid}
+}
+
+func dashboardCheck(caller *authn.AuthInfo, uid string) authz.CheckRequest {
+	return authz.CheckRequest{Caller: caller, StackID: stackID, Action: readAction, Resource: dashboard(uid)}
+}
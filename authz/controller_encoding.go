@@ -0,0 +1,89 @@
+package authz
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+// controllerEncodingV1 is the version of the encoding of the cached controllers:
+//
+//	version (1 byte) | fetched at, in unix milliseconds (8 bytes, big endian) | authzv1.ReadResponse (protobuf)
+//
+// It is still decoded, for the values cached by the previous versions of the client.
+const controllerEncodingV1 byte = 1
+
+// controllerEncodingV2 is the previous version of controllerEncodingV3, which wildcards are the kinds they grant
+// ("*" or "dashboards") rather than the granted scopes. It is still decoded.
+const controllerEncodingV2 byte = 2
+
+// controllerEncodingV3 is the version of the encoding of the cached controllers:
+//
+//	version (1 byte) | fetched at, in unix milliseconds (8 bytes, big endian) | found (1 byte) |
+//	TTL, in milliseconds (uvarint) | granted scopes, when found (see appendGrants)
+//
+// The scopes are prefix-compressed, and only decoded by the checks of resources.
+// It can be decoded by the clients in other languages sharing the cache.
+const controllerEncodingV3 byte = 3
+
+const controllerHeaderLen = 1 + 8
+
+var errControllerEncoding = errors.New("unsupported encoding of the cached permissions")
+
+// encodeController encodes the controller with the latest encoding.
+func encodeController(ctrl *controller) ([]byte, error) {
+	data := make([]byte, controllerHeaderLen, 64)
+	data[0] = controllerEncodingV3
+	binary.BigEndian.PutUint64(data[1:], uint64(ctrl.FetchedAt.UnixMilli()))
+
+	if !ctrl.Found {
+		data = append(data, 0)
+		return binary.AppendUvarint(data, uint64(ctrl.TTL.Milliseconds())), nil
+	}
+	data = append(data, 1)
+	data = binary.AppendUvarint(data, uint64(ctrl.TTL.Milliseconds()))
+	return appendGrants(data, ctrl.grants), nil
+}
+
+// decodeController decodes the cached controller. It returns errControllerEncoding for the values of another
+// version (ex: encoded by another version of the client), which must be treated as cache misses.
+// The granted scopes are decoded on first use, and deny the checks if they cannot be decoded.
+func decodeController(data []byte) (*controller, error) {
+	if len(data) < controllerHeaderLen {
+		return nil, errControllerEncoding
+	}
+	fetchedAt := time.UnixMilli(int64(binary.BigEndian.Uint64(data[1:controllerHeaderLen])))
+
+	switch version := data[0]; version {
+	case controllerEncodingV1:
+		var resp authzv1.ReadResponse
+		if err := proto.Unmarshal(data[controllerHeaderLen:], &resp); err != nil {
+			return nil, errors.Join(errControllerEncoding, err)
+		}
+		ctrl := newController(&resp)
+		ctrl.FetchedAt = fetchedAt
+		return ctrl, nil
+
+	case controllerEncodingV2, controllerEncodingV3:
+		data = data[controllerHeaderLen:]
+		if len(data) < 1 || data[0] > 1 {
+			return nil, errControllerEncoding
+		}
+		found := data[0] == 1
+		ttl, n := binary.Uvarint(data[1:])
+		if n <= 0 {
+			return nil, errControllerEncoding
+		}
+
+		ctrl := &controller{Found: found, TTL: time.Duration(ttl) * time.Millisecond, FetchedAt: fetchedAt}
+		if found {
+			ctrl.grants = &grants{raw: data[1+n:], legacy: version == controllerEncodingV2}
+		}
+		return ctrl, nil
+	}
+	return nil, errControllerEncoding
+}
@@ -0,0 +1,118 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+func TestControllerEncoding(t *testing.T) {
+	fetchedAt := time.UnixMilli(time.Now().UnixMilli())
+
+	t.Run("should round trip the controllers", func(t *testing.T) {
+		for _, ctrl := range []*controller{
+			{Found: false, TTL: time.Minute},
+			{
+				Found:  true,
+				grants: newGrants("dashboards:uid:1", "folders:uid:f", "teams:*", "*"),
+				TTL:    time.Second,
+			},
+		} {
+			ctrl.FetchedAt = fetchedAt
+			data, err := encodeController(ctrl)
+			require.NoError(t, err)
+
+			got, err := decodeController(data)
+			require.NoError(t, err)
+			require.Equal(t, ctrl.Found, got.Found)
+			require.Equal(t, ctrl.TTL, got.TTL)
+			require.True(t, ctrl.FetchedAt.Equal(got.FetchedAt))
+			require.Equal(t, grantedScopes(ctrl), grantedScopes(got))
+		}
+	})
+
+	t.Run("should encode with the latest encoding", func(t *testing.T) {
+		data, err := encodeController(newController(&authzv1.ReadResponse{
+			Found: true,
+			Data:  []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}, {Object: "folders:*"}},
+		}))
+		require.NoError(t, err)
+		require.Equal(t, controllerEncodingV3, data[0])
+	})
+
+	t.Run("should decode the read responses cached by the previous versions", func(t *testing.T) {
+		resp, err := proto.Marshal(&authzv1.ReadResponse{
+			Found: true,
+			TtlMs: 1000,
+			Data:  []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}, {Object: "folders:*"}},
+		})
+		require.NoError(t, err)
+		data := make([]byte, controllerHeaderLen, controllerHeaderLen+len(resp))
+		data[0] = controllerEncodingV1
+		binary.BigEndian.PutUint64(data[1:], uint64(fetchedAt.UnixMilli()))
+		data = append(data, resp...)
+
+		got, err := decodeController(data)
+		require.NoError(t, err)
+		require.True(t, got.Found)
+		require.Equal(t, time.Second, got.TTL)
+		require.True(t, fetchedAt.Equal(got.FetchedAt))
+		require.Equal(t, []string{"dashboards:uid:1", "folders:*"}, grantedScopes(got))
+	})
+
+	t.Run("should decode the wildcard kinds cached by the previous version", func(t *testing.T) {
+		data := make([]byte, controllerHeaderLen, 32)
+		data[0] = controllerEncodingV2
+		binary.BigEndian.PutUint64(data[1:], uint64(fetchedAt.UnixMilli()))
+		// found, no TTL, the "folders" wildcard, no prefixes nor scopes
+		data = append(data, 1, 0, 1, 7, 'f', 'o', 'l', 'd', 'e', 'r', 's', 0, 0)
+
+		got, err := decodeController(data)
+		require.NoError(t, err)
+		require.Equal(t, []string{"folders:*"}, grantedScopes(got))
+		require.True(t, got.Check(Resource{Kind: "folders", Attr: "uid", ID: "f"}))
+	})
+
+	t.Run("should deny the checks of corrupted scopes", func(t *testing.T) {
+		data, err := encodeController(&controller{Found: true, grants: newGrants("dashboards:uid:1")})
+		require.NoError(t, err)
+
+		got, err := decodeController(data[:len(data)-2])
+		require.NoError(t, err)
+		require.True(t, got.Check())
+		require.False(t, got.Check(Resource{Kind: "dashboards", Attr: "uid", ID: "1"}))
+	})
+
+	t.Run("should reject the unknown encodings", func(t *testing.T) {
+		_, err := decodeController([]byte{4, 0, 0, 0, 0, 0, 0, 0, 0, 1})
+		require.ErrorIs(t, err, errControllerEncoding)
+	})
+
+	t.Run("should treat the gob encoded controllers as cache misses", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+
+		buf := bytes.Buffer{}
+		require.NoError(t, gob.NewEncoder(&buf).Encode(controller{Found: false}))
+		key := controllerCacheKey(12, "user:1", "dashboards:read")
+		require.NoError(t, client.cache.Set(context.Background(), key, buf.Bytes(), 0))
+
+		allowed, err := client.Check(context.Background(), inProcessCheckRequest())
+		require.NoError(t, err)
+		require.True(t, allowed)
+		require.Equal(t, 1, authz.reads)
+
+		// The permissions are cached again with the new encoding
+		ctrl, err := client.getCachedController(context.Background(), key)
+		require.NoError(t, err)
+		require.True(t, ctrl.Found)
+	})
+}
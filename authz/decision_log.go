@@ -0,0 +1,47 @@
+package authz
+
+import (
+	"context"
+	"time"
+)
+
+// DecisionSource is where the permissions of the identity were read from to decide.
+type DecisionSource string
+
+const (
+	// SourceNone is the source of the decisions made without the permissions of the identity
+	// (ex: service checks, static policy or invalid requests).
+	SourceNone DecisionSource = "none"
+	// SourceCache is the source of the decisions made with cached permissions.
+	SourceCache DecisionSource = "cache"
+	// SourceService is the source of the decisions made with permissions read from the authz service.
+	SourceService DecisionSource = "service"
+	// SourceMemo is the source of the decisions answered from the memo of the request (see WithRequestMemo).
+	SourceMemo DecisionSource = "memo"
+	// SourceLease is the source of the decisions answered by a DecisionLease.
+	SourceLease DecisionSource = "lease"
+)
+
+// DecisionLog is the log of a check decision. Unlike the audited decisions, every decision is logged.
+type DecisionLog struct {
+	Decision
+	// Latency is the duration of the check.
+	Latency time.Duration
+	// Source is where the permissions of the identity were read from.
+	Source DecisionSource
+}
+
+// WithDecisionLoggerLCOption logs every decision of the checks, for compliance audit trails.
+// The logger is called synchronously: it must not block.
+func WithDecisionLoggerLCOption(log func(ctx context.Context, log DecisionLog)) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.decisionLog = log
+	}
+}
+
+func (c *LegacyClientImpl) logDecision(ctx context.Context, req *CheckRequest, res CheckResult, err error, start time.Time, source DecisionSource) {
+	decision := c.newDecision(req, res, err)
+	decision.Time = start
+	decision.SampleRate = 1
+	c.decisionLog(ctx, DecisionLog{Decision: decision, Latency: time.Since(start), Source: source})
+}
@@ -0,0 +1,45 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+func TestLegacyClientImpl_Check_DecisionLogger(t *testing.T) {
+	var logged []DecisionLog
+	client, authz := setupLegacyClient()
+	authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+	WithDecisionLoggerLCOption(func(_ context.Context, log DecisionLog) { logged = append(logged, log) })(client)
+
+	for i := 0; i < 2; i++ {
+		allowed, err := client.Check(context.Background(), inProcessCheckRequest())
+		require.NoError(t, err)
+		require.True(t, allowed)
+	}
+
+	denied := inProcessCheckRequest()
+	denied.Action = "dashboards:write"
+	allowed, err := client.Check(context.Background(), denied)
+	require.NoError(t, err)
+	require.False(t, allowed)
+
+	require.Len(t, logged, 3)
+	require.Equal(t, SourceService, logged[0].Source)
+	require.Equal(t, SourceCache, logged[1].Source)
+	require.Equal(t, SourceNone, logged[2].Source)
+
+	require.Equal(t, "service", logged[0].Service)
+	require.Equal(t, "user:1", logged[0].Subject)
+	require.Equal(t, "dashboards:uid:1", logged[0].Resource)
+	require.True(t, logged[0].Allowed)
+	require.Equal(t, ReasonScopeMatch, logged[0].Reason)
+	require.False(t, logged[0].Time.IsZero())
+	require.Positive(t, logged[0].Latency)
+
+	require.False(t, logged[2].Allowed)
+	require.Equal(t, ReasonMissingDelegatedPermission, logged[2].Reason)
+}
@@ -3,8 +3,10 @@ package authz
 import (
 	"context"
 	"errors"
+	"sort"
 	"strings"
 
+	"github.com/grafana/authlib/authz/scope"
 	"github.com/grafana/authlib/cache"
 )
 
@@ -18,6 +20,7 @@ type EnforcementClientImpl struct {
 	client        client
 	queryTemplate *searchQuery
 	clientOpts    []clientOption
+	legacyUsage   *LegacyUsageReporter
 }
 
 func WithHTTPClient(doer HTTPRequestDoer) ClientOption {
@@ -96,6 +99,7 @@ func (s *EnforcementClientImpl) fetchPermissions(ctx context.Context,
 
 func (s *EnforcementClientImpl) Compile(ctx context.Context, idToken string,
 	action string, kinds ...string) (Checker, error) {
+	s.legacyUsage.report(ctx, SurfaceEnforcementClient, "Compile")
 	permissions, err := s.fetchPermissions(ctx, idToken, action)
 	if err != nil {
 		return noAccessChecker, err
@@ -118,6 +122,7 @@ func resourcesKind(resources ...Resource) []string {
 
 func (s *EnforcementClientImpl) HasAccess(ctx context.Context, idToken string,
 	action string, resources ...Resource) (bool, error) {
+	s.legacyUsage.report(ctx, SurfaceEnforcementClient, "HasAccess")
 	permissions, err := s.fetchPermissions(ctx, idToken, action, resources...)
 	if err != nil {
 		return false, err
@@ -126,43 +131,50 @@ func (s *EnforcementClientImpl) HasAccess(ctx context.Context, idToken string,
 	return compileChecker(permissions, action, kinds...)(resources...), nil
 }
 
+// SearchTeamPermissions returns the permissions of the teams matching the query, sorted by team ID.
+// The results are cached like the searches of the permissions of the users.
+func (s *EnforcementClientImpl) SearchTeamPermissions(ctx context.Context, query TeamPermissionsQuery) ([]TeamPermissions, error) {
+	s.legacyUsage.report(ctx, SurfaceEnforcementClient, "SearchTeamPermissions")
+
+	perms, err := s.client.SearchTeams(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := make([]TeamPermissions, 0, len(perms))
+	for id, p := range perms {
+		teams = append(teams, TeamPermissions{ID: id, Permissions: p})
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].ID < teams[j].ID })
+	return teams, nil
+}
+
 // Experimental: LookupResources returns the resources that the user has access to for the given action.
 // Resource expansion is still not supported in this method.
 func (s *EnforcementClientImpl) LookupResources(ctx context.Context, idToken string, action string) ([]Resource, error) {
+	s.legacyUsage.report(ctx, SurfaceEnforcementClient, "LookupResources")
 	permissions, err := s.fetchPermissions(ctx, idToken, action)
 	if err != nil {
 		return nil, err
 	}
 
 	resources := make([]Resource, 0, len(permissions))
-	for gotAction, scopes := range permissions {
-		if gotAction != action {
-			continue
-		}
-		for _, scope := range scopes {
-			if scope == "" {
-				continue
-			}
-			kind, attribute, id := splitScope(scope)
-			resources = append(resources, Resource{
-				Kind: kind,
-				Attr: attribute,
-				ID:   id,
-			})
-		}
-	}
+	lookupResources(permissions, action, func(r Resource) bool {
+		resources = append(resources, r)
+		return true
+	})
 	return resources, nil
 }
 
-// splitScope returns kind, attribute and Identifier
-func splitScope(scope string) (kind string, attribute string, id string) {
-	fragments := strings.Split(scope, ":")
-	switch l := len(fragments); l {
-	case 1: // Splitting a wildcard scope "*" -> kind: "*"; attribute: "*"; identifier: "*"
-		return fragments[0], fragments[0], fragments[0]
-	case 2: // Splitting a wildcard scope with specified kind "dashboards:*" -> kind: "dashboards"; attribute: "*"; identifier: "*"
-		return fragments[0], fragments[1], fragments[1]
-	default: // Splitting a scope with all fields specified "dashboards:uid:my_dash" -> kind: "dashboards"; attribute: "uid"; identifier: "my_dash"
-		return fragments[0], fragments[1], strings.Join(fragments[2:], ":")
+// lookupResources yields the resources of the action, until yield returns false.
+func lookupResources(permissions permissions, action string, yield func(Resource) bool) {
+	for _, s := range permissions[action] {
+		if s == "" {
+			continue
+		}
+		kind, attribute, id := scope.Split(s)
+		if !yield(Resource{Kind: kind, Attr: attribute, ID: id}) {
+			return
+		}
 	}
 }
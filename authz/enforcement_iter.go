@@ -0,0 +1,30 @@
+//go:build go1.23
+
+package authz
+
+import (
+	"context"
+	"iter"
+)
+
+// SearchPermissions returns an iterator over the permissions of the users and service accounts for the actions
+// with the prefix, following the pages of the search (see Config.SearchPageSize). The permissions are not cached:
+// it is meant to stream over the permissions of very large orgs without loading them at once.
+// The iteration stops after yielding the error of a failed search.
+func (s *EnforcementClientImpl) SearchPermissions(ctx context.Context, actionPrefix string) iter.Seq2[UserPermissions, error] {
+	s.legacyUsage.report(ctx, SurfaceEnforcementClient, "SearchPermissions")
+
+	return func(yield func(UserPermissions, error) bool) {
+		err := s.client.SearchPages(ctx, searchQuery{ActionPrefix: actionPrefix}, func(page permissionsByID) bool {
+			for _, id := range sortedIDs(page) {
+				if !yield(UserPermissions{ID: id, Permissions: page[id]}, nil) {
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			yield(UserPermissions{}, err)
+		}
+	}
+}
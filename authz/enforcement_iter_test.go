@@ -0,0 +1,53 @@
+//go:build go1.23
+
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnforcementClientImpl_SearchPermissions(t *testing.T) {
+	pages := []permissionsByID{
+		{2: {"teams:read": {"teams:id:2"}}, 1: {"teams:read": {"teams:id:1"}}},
+		{3: {"teams:write": {"teams:id:3"}}},
+	}
+
+	t.Run("should stream the permissions of the pages", func(t *testing.T) {
+		mockClient := &MockClient{}
+		mockClient.On("SearchPages", mock.Anything, searchQuery{ActionPrefix: "teams:"}, mock.Anything).
+			Run(func(args mock.Arguments) {
+				yield := args.Get(2).(func(permissionsByID) bool)
+				for _, p := range pages {
+					if !yield(p) {
+						return
+					}
+				}
+			}).Return(nil)
+		s := EnforcementClientImpl{client: mockClient}
+
+		var ids []int64
+		for perms, err := range s.SearchPermissions(context.Background(), "teams:") {
+			require.NoError(t, err)
+			ids = append(ids, perms.ID)
+			if perms.ID == 2 {
+				break
+			}
+		}
+		require.Equal(t, []int64{1, 2}, ids)
+	})
+
+	t.Run("should yield the search errors", func(t *testing.T) {
+		mockClient := &MockClient{}
+		mockClient.On("SearchPages", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("unavailable"))
+		s := EnforcementClientImpl{client: mockClient}
+
+		for _, err := range s.SearchPermissions(context.Background(), "teams:") {
+			require.Error(t, err)
+		}
+	})
+}
@@ -255,43 +255,19 @@ func TestEnforcementClientImpl_LookupResources(t *testing.T) {
 	}
 }
 
-func TestSplitScope(t *testing.T) {
-	tests := []struct {
-		name          string
-		scope         string
-		wantKind      string
-		wantAttribute string
-		wantID        string
-	}{
-		{
-			name:          "wildcard scope",
-			scope:         "*",
-			wantKind:      "*",
-			wantAttribute: "*",
-			wantID:        "*",
-		},
-		{
-			name:          "wildcard scope with specified kind",
-			scope:         "dashboards:*",
-			wantKind:      "dashboards",
-			wantAttribute: "*",
-			wantID:        "*",
-		},
-		{
-			name:          "scope with all fields specified",
-			scope:         "dashboards:uid:my_dash",
-			wantKind:      "dashboards",
-			wantAttribute: "uid",
-			wantID:        "my_dash",
-		},
-	}
+func TestEnforcementClientImpl_SearchTeamPermissions(t *testing.T) {
+	mockClient := &MockClient{}
+	query := TeamPermissionsQuery{ActionPrefix: "teams:"}
+	mockClient.On("SearchTeams", mock.Anything, query).Return(permissionsByID{
+		3: {"teams:write": {"teams:id:3"}},
+		2: {"teams:read": {"teams:id:2"}},
+	}, nil)
+	s := EnforcementClientImpl{client: mockClient}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			gotKind, gotAttribute, gotID := splitScope(tt.scope)
-			assert.Equal(t, tt.wantKind, gotKind, "they should be equal")
-			assert.Equal(t, tt.wantAttribute, gotAttribute, "they should be equal")
-			assert.Equal(t, tt.wantID, gotID, "they should be equal")
-		})
-	}
+	got, err := s.SearchTeamPermissions(context.Background(), query)
+	require.NoError(t, err)
+	require.Equal(t, []TeamPermissions{
+		{ID: 2, Permissions: map[string][]string{"teams:read": {"teams:id:2"}}},
+		{ID: 3, Permissions: map[string][]string{"teams:write": {"teams:id:3"}}},
+	}, got)
 }
@@ -0,0 +1,200 @@
+package fga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/authz"
+	"github.com/grafana/authlib/authz/conformance"
+	"github.com/grafana/authlib/claims"
+)
+
+// fakeStore holds relationship tuples. Its model grants the objects of a kind through the
+// "<kind>:<namespace>/*" object, and the objects of all kinds through the "*:<namespace>/*" object.
+type fakeStore struct {
+	tuples  map[TupleKey]bool
+	checked []TupleKey
+}
+
+func newFakeStore(perms []conformance.Permission) *fakeStore {
+	s := &fakeStore{tuples: map[TupleKey]bool{}}
+	for _, p := range perms {
+		ns := claims.CloudNamespaceFormatter(p.StackID)
+
+		// Any grant allows the action
+		s.tuples[DefaultTupleMapper(ns, p.Subject, p.Action, nil)] = true
+		if p.Scope == "" {
+			continue
+		}
+
+		var r *authz.Resource
+		switch parts := strings.SplitN(p.Scope, ":", 3); {
+		case p.Scope == "*":
+			r = &authz.Resource{Kind: "*", ID: "*"}
+		case len(parts) == 3:
+			r = &authz.Resource{Kind: parts[0], Attr: parts[1], ID: parts[2]}
+		}
+		s.tuples[DefaultTupleMapper(ns, p.Subject, p.Action, r)] = true
+	}
+	return s
+}
+
+func (s *fakeStore) Check(_ context.Context, tuple TupleKey) (bool, error) {
+	s.checked = append(s.checked, tuple)
+	if s.tuples[tuple] {
+		return true, nil
+	}
+
+	kind, rest, _ := strings.Cut(tuple.Object, ":")
+	ns, _, _ := strings.Cut(rest, "/")
+	for _, parent := range []string{kind + ":" + ns + "/*", "*:" + ns + "/*"} {
+		if s.tuples[TupleKey{User: tuple.User, Relation: tuple.Relation, Object: parent}] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T, perms []conformance.Permission) authz.MultiTenantClient {
+		return NewClient(newFakeStore(perms).Check)
+	})
+}
+
+func TestDefaultTupleMapper(t *testing.T) {
+	require.Equal(t,
+		TupleKey{User: "user:stack-12/1", Relation: "dashboards_read", Object: "dashboards:stack-12/abc"},
+		DefaultTupleMapper("stack-12", "user:1", "dashboards:read", &authz.Resource{Kind: "dashboards", Attr: "uid", ID: "abc"}),
+	)
+	require.Equal(t,
+		TupleKey{User: "service-account:stack-12/2", Relation: "users_write", Object: "namespace:stack-12"},
+		DefaultTupleMapper("stack-12", "service-account:2", "users:write", nil),
+	)
+}
+
+func TestClient_Check(t *testing.T) {
+	caller := &authn.AuthInfo{
+		AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+			Claims: &jwt.Claims{Subject: "access-policy:1"},
+			Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+		}),
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: "user:1"},
+			Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+		}),
+	}
+	req := &authz.CheckRequest{
+		Caller:     caller,
+		StackID:    12,
+		Action:     "dashboards:read",
+		Resource:   &authz.Resource{Kind: "dashboards", Attr: "uid", ID: "1"},
+		Contextual: []authz.Resource{{Kind: "folders", Attr: "uid", ID: "2"}},
+	}
+
+	t.Run("should check the resource and the contextual resources", func(t *testing.T) {
+		store := &fakeStore{tuples: map[TupleKey]bool{}}
+		allowed, err := NewClient(store.Check).Check(context.Background(), req)
+		require.NoError(t, err)
+		require.False(t, allowed)
+		require.Equal(t, []TupleKey{
+			{User: "user:stack-12/1", Relation: "dashboards_read", Object: "dashboards:stack-12/1"},
+			{User: "user:stack-12/1", Relation: "dashboards_read", Object: "folders:stack-12/2"},
+		}, store.checked)
+	})
+
+	t.Run("should use the tuple mapper", func(t *testing.T) {
+		var checked TupleKey
+		client := NewClient(func(_ context.Context, tuple TupleKey) (bool, error) {
+			checked = tuple
+			return true, nil
+		}, WithTupleMapperOption(func(namespace, subject, action string, resource *authz.Resource) TupleKey {
+			return TupleKey{User: subject, Relation: "viewer", Object: "dashboard:" + resource.ID}
+		}))
+
+		allowed, err := client.Check(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, allowed)
+		require.Equal(t, TupleKey{User: "user:1", Relation: "viewer", Object: "dashboard:1"}, checked)
+	})
+
+	t.Run("should hide check errors", func(t *testing.T) {
+		client := NewClient(func(context.Context, TupleKey) (bool, error) {
+			return false, errors.New("store not found")
+		})
+		_, err := client.Check(context.Background(), req)
+		require.ErrorIs(t, err, ErrCheckTuple)
+	})
+}
+
+// storeServer serves the check endpoint of an OpenFGA store backed by the fake store.
+func storeServer(t *testing.T, store *fakeStore) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/stores/store-1/check" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code": "store_id_not_found", "message": "store not found"}`))
+			return
+		}
+
+		var req checkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AuthorizationModelID != "model-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		allowed, _ := store.Check(r.Context(), TupleKey{User: req.TupleKey.User, Relation: req.TupleKey.Relation, Object: req.TupleKey.Object})
+		_ = json.NewEncoder(w).Encode(checkResponse{Allowed: allowed})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestConformance_Store(t *testing.T) {
+	conformance.Run(t, func(t *testing.T, perms []conformance.Permission) authz.MultiTenantClient {
+		server := storeServer(t, newFakeStore(perms))
+		client, err := NewStoreClient(StoreConfig{APIURL: server.URL, StoreID: "store-1", AuthorizationModelID: "model-1", Token: "token"})
+		require.NoError(t, err)
+		return client
+	})
+}
+
+func TestStore_Check(t *testing.T) {
+	server := storeServer(t, &fakeStore{tuples: map[TupleKey]bool{
+		{User: "user:stack-12/1", Relation: "dashboards_read", Object: "dashboards:stack-12/*"}: true,
+	}})
+
+	t.Run("should check the tuples against the store", func(t *testing.T) {
+		store, err := NewStore(StoreConfig{APIURL: server.URL, StoreID: "store-1", AuthorizationModelID: "model-1", Token: "token"}, server.Client())
+		require.NoError(t, err)
+
+		allowed, err := store.Check(context.Background(), TupleKey{User: "user:stack-12/1", Relation: "dashboards_read", Object: "dashboards:stack-12/abc"})
+		require.NoError(t, err)
+		require.True(t, allowed)
+
+		allowed, err = store.Check(context.Background(), TupleKey{User: "user:stack-12/2", Relation: "dashboards_read", Object: "dashboards:stack-12/abc"})
+		require.NoError(t, err)
+		require.False(t, allowed)
+	})
+
+	t.Run("should return the errors of the store", func(t *testing.T) {
+		store, err := NewStore(StoreConfig{APIURL: server.URL, StoreID: "store-2", Token: "token"}, server.Client())
+		require.NoError(t, err)
+
+		_, err = store.Check(context.Background(), TupleKey{User: "user:stack-12/1", Relation: "dashboards_read", Object: "dashboards:stack-12/abc"})
+		require.ErrorContains(t, err, "store not found")
+	})
+
+	t.Run("should validate the configuration", func(t *testing.T) {
+		_, err := NewStore(StoreConfig{APIURL: server.URL}, nil)
+		require.ErrorIs(t, err, ErrInvalidStoreConfig)
+		_, err = NewStoreClient(StoreConfig{StoreID: "store-1"})
+		require.ErrorIs(t, err, ErrInvalidStoreConfig)
+	})
+}
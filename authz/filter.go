@@ -0,0 +1,112 @@
+package authz
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/authlib/claims"
+)
+
+var ErrMissingKind = status.Errorf(codes.InvalidArgument, "missing kind")
+
+// FilterRequest asks for the resources of a kind the caller can perform the action on.
+type FilterRequest struct {
+	Caller  claims.AuthInfo
+	StackID int64
+	Action  string
+	// Kind of the filtered resources (ex: "dashboards").
+	Kind string
+	// Attr is the attribute identifying the resources (ex: "uid"), usually the filtered column.
+	Attr string
+	// MaxStaleness is the maximum age of the permissions tolerated, see CheckRequest.MaxStaleness.
+	MaxStaleness time.Duration
+}
+
+// Filter is the fragment of a query filtering the resources the caller has access to.
+// The zero Filter allows no resource.
+type Filter struct {
+	// All is true when the caller has access to all the resources of the kind.
+	All bool
+	// IDs are the sorted identifiers of the resources the caller has access to, when not All.
+	IDs []string
+}
+
+// None returns whether the caller has access to no resource: the query can be skipped.
+func (f Filter) None() bool {
+	return !f.All && len(f.IDs) == 0
+}
+
+// SQL returns the condition on the column, with "?" placeholders, and its arguments.
+// The condition is "1 = 1" when all the resources are allowed, and "1 = 0" when none is.
+func (f Filter) SQL(column string) (string, []any) {
+	if f.All {
+		return "1 = 1", nil
+	}
+	if len(f.IDs) == 0 {
+		return "1 = 0", nil
+	}
+
+	args := make([]any, len(f.IDs))
+	for i, id := range f.IDs {
+		args[i] = id
+	}
+	return column + " IN (?" + strings.Repeat(", ?", len(f.IDs)-1) + ")", args
+}
+
+// Filter returns the resources of the kind the caller can perform the action on, to filter them in
+// database queries instead of checking them one by one. The caller is checked like for an action check.
+// Permissions granted through contextual resources (ex: the folders of the dashboards) are not included:
+// filter the contextual resources with another Filter.
+func (c *LegacyClientImpl) Filter(ctx context.Context, req *FilterRequest) (Filter, error) {
+	ctx, span := c.tracer.Start(ctx, "LegacyClientImpl.Filter")
+	defer span.End()
+	c.legacyUsage.report(ctx, SurfaceLegacyClient, "Filter")
+
+	if req.Kind == "" {
+		return Filter{}, ErrMissingKind
+	}
+	span.SetAttributes(attribute.String("kind", req.Kind))
+
+	checkReq := &CheckRequest{Caller: req.Caller, StackID: req.StackID, Action: req.Action, MaxStaleness: req.MaxStaleness}
+	res, err := c.check(ctx, span, checkReq)
+	if err != nil || !res.Allowed {
+		return Filter{}, err
+	}
+	// Allowed regardless of the permissions of the identity (ex: service or static policy)
+	if res.Reason != ReasonAction {
+		return Filter{All: true}, nil
+	}
+
+	ctrl, err := c.retrievePermissions(ctx, checkReq, req.Caller.GetIdentity().Subject())
+	if err != nil {
+		span.RecordError(err)
+		return Filter{}, err
+	}
+
+	f := ctrl.filter(req.Kind, req.Attr)
+	span.SetAttributes(attribute.Bool("all", f.All), attribute.Int("ids", len(f.IDs)))
+	return f, nil
+}
+
+// filter returns the resources of the kind granted by the permissions, identified by the attribute.
+func (r *controller) filter(kind, attr string) Filter {
+	if !r.Found {
+		return Filter{}
+	}
+	if _, ok := r.grants.wildcard("*"); ok {
+		return Filter{All: true}
+	}
+	if _, ok := r.grants.wildcard(kind); ok {
+		return Filter{All: true}
+	}
+
+	f := Filter{IDs: r.grants.idsWithPrefix(kind + ":" + attr + ":")}
+	sort.Strings(f.IDs)
+	return f
+}
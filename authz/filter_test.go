@@ -0,0 +1,95 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+func TestController_Filter(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *authzv1.ReadResponse
+		want Filter
+	}{
+		{
+			name: "should allow none without permission",
+			resp: &authzv1.ReadResponse{Found: false},
+			want: Filter{},
+		},
+		{
+			name: "should allow all with a wildcard on the kind",
+			resp: &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:*"}}},
+			want: Filter{All: true},
+		},
+		{
+			name: "should allow all with a wildcard",
+			resp: &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "*"}}},
+			want: Filter{All: true},
+		},
+		{
+			name: "should list the identifiers of the kind",
+			resp: &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{
+				{Object: "dashboards:uid:2"}, {Object: "dashboards:uid:1"}, {Object: "folders:uid:1"}, {Object: "dashboards:id:3"},
+			}},
+			want: Filter{IDs: []string{"1", "2"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, newController(tt.resp).filter("dashboards", "uid"))
+		})
+	}
+}
+
+func TestFilter_SQL(t *testing.T) {
+	cond, args := Filter{All: true}.SQL("uid")
+	require.Equal(t, "1 = 1", cond)
+	require.Empty(t, args)
+
+	cond, args = Filter{}.SQL("uid")
+	require.Equal(t, "1 = 0", cond)
+	require.Empty(t, args)
+
+	cond, args = Filter{IDs: []string{"1", "2"}}.SQL("d.uid")
+	require.Equal(t, "d.uid IN (?, ?)", cond)
+	require.Equal(t, []any{"1", "2"}, args)
+}
+
+func TestLegacyClientImpl_Filter(t *testing.T) {
+	filterRequest := func() *FilterRequest {
+		req := inProcessCheckRequest()
+		return &FilterRequest{Caller: req.Caller, StackID: req.StackID, Action: req.Action, Kind: "dashboards", Attr: "uid"}
+	}
+
+	t.Run("should filter the granted resources", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+
+		f, err := client.Filter(context.Background(), filterRequest())
+		require.NoError(t, err)
+		require.Equal(t, Filter{IDs: []string{"1"}}, f)
+	})
+
+	t.Run("should allow none when the caller is denied", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+
+		req := filterRequest()
+		req.StackID = 13
+		f, err := client.Filter(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, f.None())
+	})
+
+	t.Run("should require the kind", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+
+		req := filterRequest()
+		req.Kind = ""
+		_, err := client.Filter(context.Background(), req)
+		require.ErrorIs(t, err, ErrMissingKind)
+	})
+}
@@ -0,0 +1,66 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/grafana/authlib/internal/logger"
+)
+
+var _ MultiTenantClient = (*FixedClient)(nil)
+
+// FixedClientOption allows setting custom parameters during construction.
+type FixedClientOption func(*FixedClient)
+
+// WithLoggerFixedClientOption logs the checks at debug level, to see which checks are made while they are not enforced.
+func WithLoggerFixedClientOption(logger Logger) FixedClientOption {
+	return func(c *FixedClient) {
+		c.logger = logger
+	}
+}
+
+// FixedClient is a MultiTenantClient making the same decision for all the checks, without the authz service.
+// It is meant for local development, demos, and rollouts where enforcement is staged: the calls to the client
+// can be deployed with NewAllowAllClient, before switching to a client enforcing the permissions.
+type FixedClient struct {
+	allowed bool
+	logger  Logger
+}
+
+// NewAllowAllClient returns a client allowing all the checks.
+func NewAllowAllClient(opts ...FixedClientOption) *FixedClient {
+	return newFixedClient(true, opts)
+}
+
+// NewDenyAllClient returns a client denying all the checks.
+func NewDenyAllClient(opts ...FixedClientOption) *FixedClient {
+	return newFixedClient(false, opts)
+}
+
+func newFixedClient(allowed bool, opts []FixedClientOption) *FixedClient {
+	c := &FixedClient{allowed: allowed}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.logger == nil {
+		c.logger = logger.Nop{}
+	}
+
+	return c
+}
+
+func (c *FixedClient) Check(_ context.Context, req *CheckRequest) (bool, error) {
+	args := []any{"allowed", c.allowed, "stack_id", req.StackID, "action", req.Action}
+	if req.Caller != nil {
+		if id := req.Caller.GetIdentity(); id != nil && !id.IsNil() {
+			args = append(args, "subject", id.Subject())
+		} else if access := req.Caller.GetAccess(); access != nil && !access.IsNil() {
+			args = append(args, "subject", access.Subject())
+		}
+	}
+	if req.Resource != nil {
+		args = append(args, "resource", req.Resource.Scope())
+	}
+	c.logger.Debug("fixed authz decision", args...)
+	return c.allowed, nil
+}
@@ -0,0 +1,39 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixedClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should allow all the checks", func(t *testing.T) {
+		for _, req := range []*CheckRequest{inProcessCheckRequest(), {}} {
+			allowed, err := NewAllowAllClient().Check(ctx, req)
+			require.NoError(t, err)
+			require.True(t, allowed)
+		}
+	})
+
+	t.Run("should deny all the checks", func(t *testing.T) {
+		for _, req := range []*CheckRequest{inProcessCheckRequest(), {}} {
+			allowed, err := NewDenyAllClient().Check(ctx, req)
+			require.NoError(t, err)
+			require.False(t, allowed)
+		}
+	})
+
+	t.Run("should log the checks", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		client := NewAllowAllClient(WithLoggerFixedClientOption(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+
+		_, err := client.Check(ctx, inProcessCheckRequest())
+		require.NoError(t, err)
+		require.Contains(t, buf.String(), "allowed=true stack_id=12 action=dashboards:read subject=user:1 resource=dashboards:uid:1")
+	})
+}
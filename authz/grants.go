@@ -0,0 +1,399 @@
+package authz
+
+import (
+	"encoding/binary"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/grafana/authlib/authz/scope"
+)
+
+// grants are the scopes granted for an action, in a compact representation for the subjects with tens of
+// thousands of scopes: the "<kind>:<attribute>:" prefixes are interned, the identifiers are concatenated in
+// a single string, and the scopes are looked up by their hash. The grants of the cached permissions are
+// decoded on first use, so that the action checks do not decode them.
+type grants struct {
+	once sync.Once
+	// raw are the encoded grants, decoded by once (see appendGrants)
+	raw []byte
+	// legacy is set when the raw wildcards are kinds, as encoded by controllerEncodingV2
+	legacy bool
+
+	// wildcards are the granted wildcard scopes (ex: "*", "dashboards:*" or "dashboards:uid:*"),
+	// wildcardKinds are the kinds they grant entirely, "*" for all kinds
+	wildcards     []string
+	wildcardKinds []string
+	prefixes      []string
+	// hashes are the sorted hashes of the scopes, entries are aligned with them
+	hashes  []uint64
+	entries []grantEntry
+	ids     string
+}
+
+// grantEntry is a scope: its prefix, and the end of its identifier in ids (starting at the end of the previous one).
+type grantEntry struct {
+	prefix uint32
+	end    uint32
+}
+
+var errGrantsEncoding = errors.New("invalid encoding of the granted scopes")
+
+// newGrants returns the grants of the scopes, wildcards included (ex: "dashboards:*" or "*").
+func newGrants(scopes ...string) *grants {
+	g := &grants{}
+	prefixes := map[string]uint32{}
+
+	type item struct {
+		hash   uint64
+		prefix uint32
+		id     string
+	}
+	items := make([]item, 0, len(scopes))
+	for _, s := range scopes {
+		if _, _, id := scope.Split(s); id == scope.Wildcard {
+			g.addWildcard(s)
+			continue
+		}
+
+		prefix, id := splitPrefix(s)
+		idx, ok := prefixes[prefix]
+		if !ok {
+			idx = uint32(len(g.prefixes))
+			prefixes[prefix] = idx
+			g.prefixes = append(g.prefixes, intern(prefix))
+		}
+		items = append(items, item{hash: hashScope(prefix, id), prefix: idx, id: id})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].hash != items[j].hash {
+			return items[i].hash < items[j].hash
+		}
+		return items[i].prefix < items[j].prefix || (items[i].prefix == items[j].prefix && items[i].id < items[j].id)
+	})
+
+	size := 0
+	for _, it := range items {
+		size += len(it.id)
+	}
+	ids := strings.Builder{}
+	ids.Grow(size)
+	g.hashes = make([]uint64, 0, len(items))
+	g.entries = make([]grantEntry, 0, len(items))
+	for i, it := range items {
+		// Skip the duplicates
+		if i > 0 && it.hash == items[i-1].hash && it.prefix == items[i-1].prefix && it.id == items[i-1].id {
+			continue
+		}
+		ids.WriteString(it.id)
+		g.hashes = append(g.hashes, it.hash)
+		g.entries = append(g.entries, grantEntry{prefix: it.prefix, end: uint32(ids.Len())})
+	}
+	g.ids = ids.String()
+
+	// Mark as decoded
+	g.once.Do(func() {})
+	return g
+}
+
+// decode decodes the raw grants, if any. Undecodable grants are empty, denying the checks.
+func (g *grants) decode() *grants {
+	if g == nil {
+		return nil
+	}
+	g.once.Do(func() {
+		if err := g.unmarshal(g.raw, g.legacy); err != nil {
+			g.wildcards, g.wildcardKinds, g.prefixes, g.hashes, g.entries, g.ids = nil, nil, nil, nil, nil, ""
+		}
+		g.raw = nil
+	})
+	return g
+}
+
+// addWildcard adds the wildcard scope, unless already granted.
+func (g *grants) addWildcard(s string) {
+	if slices.Contains(g.wildcards, s) {
+		return
+	}
+	kind, _, _ := scope.Split(s)
+	g.wildcards = append(g.wildcards, intern(s))
+	g.wildcardKinds = append(g.wildcardKinds, intern(kind))
+}
+
+// wildcardScope returns the first wildcard scope granting all the resources of the kind.
+func (g *grants) wildcardScope(kind string) (string, bool) {
+	for i, k := range g.wildcardKinds {
+		if k == kind {
+			return g.wildcards[i], true
+		}
+	}
+	return "", false
+}
+
+// empty returns whether no scope is granted.
+func (g *grants) empty() bool {
+	g = g.decode()
+	return g == nil || (len(g.wildcards) == 0 && len(g.entries) == 0)
+}
+
+// wildcard returns the wildcard scope, as granted, if all the resources of the kind are granted, "*" for all kinds.
+func (g *grants) wildcard(kind string) (string, bool) {
+	g = g.decode()
+	if g == nil {
+		return "", false
+	}
+	return g.wildcardScope(kind)
+}
+
+// contains returns whether the scope is granted, wildcards excluded.
+func (g *grants) contains(s string) bool {
+	g = g.decode()
+	if g == nil {
+		return false
+	}
+
+	h := hashScope(s, "")
+	for i := sort.Search(len(g.hashes), func(i int) bool { return g.hashes[i] >= h }); i < len(g.hashes) && g.hashes[i] == h; i++ {
+		prefix, id := g.prefixes[g.entries[i].prefix], g.id(i)
+		if len(s) == len(prefix)+len(id) && strings.HasPrefix(s, prefix) && strings.HasSuffix(s, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *grants) id(i int) string {
+	start := uint32(0)
+	if i > 0 {
+		start = g.entries[i-1].end
+	}
+	return g.ids[start:g.entries[i].end]
+}
+
+// idsWithPrefix returns the identifiers of the scopes with the prefix (ex: "dashboards:uid:").
+func (g *grants) idsWithPrefix(prefix string) []string {
+	g = g.decode()
+	if g == nil {
+		return nil
+	}
+
+	idx := -1
+	for i, p := range g.prefixes {
+		if p == prefix {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil
+	}
+
+	var ids []string
+	for i, e := range g.entries {
+		if e.prefix == uint32(idx) {
+			ids = append(ids, g.id(i))
+		}
+	}
+	return ids
+}
+
+// each yields the granted scopes, until yield returns false. Wildcards are yielded first, as granted.
+func (g *grants) each(yield func(string) bool) {
+	g = g.decode()
+	if g == nil {
+		return
+	}
+	for _, s := range g.wildcards {
+		if !yield(s) {
+			return
+		}
+	}
+	for i, e := range g.entries {
+		if !yield(g.prefixes[e.prefix] + g.id(i)) {
+			return
+		}
+	}
+}
+
+// appendGrants appends the encoding of the grants:
+//
+//	wildcards count (uvarint) | wildcards, each: length (uvarint) | wildcard scope
+//	prefixes count (uvarint)  | prefixes, each: length (uvarint) | prefix
+//	scopes count (uvarint)    | scopes, each: prefix index (uvarint) | length (uvarint) | identifier
+//
+// The scopes are encoded in the order of their hash, the FNV-1a 64 of "<prefix><identifier>".
+func appendGrants(data []byte, g *grants) []byte {
+	g = g.decode()
+	if g == nil {
+		g = &grants{}
+	}
+
+	data = binary.AppendUvarint(data, uint64(len(g.wildcards)))
+	for _, s := range g.wildcards {
+		data = appendString(data, s)
+	}
+	data = binary.AppendUvarint(data, uint64(len(g.prefixes)))
+	for _, prefix := range g.prefixes {
+		data = appendString(data, prefix)
+	}
+	data = binary.AppendUvarint(data, uint64(len(g.entries)))
+	for i, e := range g.entries {
+		data = binary.AppendUvarint(data, uint64(e.prefix))
+		data = appendString(data, g.id(i))
+	}
+	return data
+}
+
+// unmarshal decodes the grants. The wildcards of the legacy encoding are kinds rather than scopes,
+// they are decoded as "*" and "<kind>:*".
+func (g *grants) unmarshal(data []byte, legacy bool) error {
+	r := grantsReader{data: data}
+
+	n := r.count()
+	g.wildcards, g.wildcardKinds = make([]string, 0, n), make([]string, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		s := r.string()
+		if legacy && s != scope.Wildcard {
+			s += scope.Separator + scope.Wildcard
+		}
+		g.addWildcard(s)
+	}
+	g.prefixes = make([]string, r.count())
+	for i := range g.prefixes {
+		g.prefixes[i] = intern(r.string())
+	}
+
+	n = r.count()
+	g.entries = make([]grantEntry, 0, n)
+	ids := strings.Builder{}
+	ids.Grow(len(r.data))
+	for i := 0; i < n && r.err == nil; i++ {
+		prefix := r.uvarint()
+		if prefix >= uint64(len(g.prefixes)) {
+			return errGrantsEncoding
+		}
+		ids.Write(r.bytes())
+		g.entries = append(g.entries, grantEntry{prefix: uint32(prefix), end: uint32(ids.Len())})
+	}
+	if r.err != nil {
+		return r.err
+	}
+	g.ids = ids.String()
+
+	g.hashes = make([]uint64, len(g.entries))
+	for i, e := range g.entries {
+		g.hashes[i] = hashScope(g.prefixes[e.prefix], g.id(i))
+		if i > 0 && g.hashes[i] < g.hashes[i-1] {
+			return errGrantsEncoding
+		}
+	}
+	return nil
+}
+
+func appendString(data []byte, s string) []byte {
+	data = binary.AppendUvarint(data, uint64(len(s)))
+	return append(data, s...)
+}
+
+// grantsReader reads the encoded grants, recording the first error.
+type grantsReader struct {
+	data []byte
+	err  error
+}
+
+func (r *grantsReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errGrantsEncoding
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+// count reads a count, bounded by the remaining data to not allocate for corrupted counts.
+func (r *grantsReader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.data)) {
+		r.err = errGrantsEncoding
+		return 0
+	}
+	return int(n)
+}
+
+func (r *grantsReader) bytes() []byte {
+	n := r.count()
+	if r.err != nil {
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *grantsReader) string() string {
+	return string(r.bytes())
+}
+
+// splitPrefix splits the scope after its attribute: "dashboards:uid:1" is split in "dashboards:uid:" and "1".
+func splitPrefix(s string) (string, string) {
+	i := strings.Index(s, scope.Separator)
+	if i < 0 {
+		return "", s
+	}
+	j := strings.Index(s[i+1:], scope.Separator)
+	if j < 0 {
+		return "", s
+	}
+	return s[:i+j+2], s[i+j+2:]
+}
+
+// hashScope returns the FNV-1a 64 hash of the concatenation of the strings, without concatenating them.
+func hashScope(prefix, id string) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64)
+	for i := 0; i < len(prefix); i++ {
+		h ^= uint64(prefix[i])
+		h *= prime64
+	}
+	for i := 0; i < len(id); i++ {
+		h ^= uint64(id[i])
+		h *= prime64
+	}
+	return h
+}
+
+// maxInterned bounds the interned kinds and prefixes, which are expected to be few.
+const maxInterned = 4096
+
+var (
+	interned      sync.Map
+	internedCount atomic.Int64
+)
+
+// intern returns a shared copy of the string, so that the controllers share their kinds and prefixes
+// and do not retain the buffers they were decoded from.
+func intern(s string) string {
+	if v, ok := interned.Load(s); ok {
+		return v.(string)
+	}
+	s = strings.Clone(s)
+	if internedCount.Load() >= maxInterned {
+		return s
+	}
+	v, loaded := interned.LoadOrStore(s, s)
+	if !loaded {
+		internedCount.Add(1)
+	}
+	return v.(string)
+}
@@ -0,0 +1,170 @@
+package authz
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+// grantedScopes returns the sorted scopes granted by the controller.
+func grantedScopes(c *controller) []string {
+	var scopes []string
+	c.grants.each(func(s string) bool {
+		scopes = append(scopes, s)
+		return true
+	})
+	sort.Strings(scopes)
+	return scopes
+}
+
+func TestGrants(t *testing.T) {
+	g := newGrants("dashboards:uid:1", "dashboards:uid:10", "folders:uid:1", "dashboards:uid:1", "teams:*", "teams:*")
+
+	t.Run("should contain the granted scopes", func(t *testing.T) {
+		require.True(t, g.contains("dashboards:uid:1"))
+		require.True(t, g.contains("dashboards:uid:10"))
+		require.True(t, g.contains("folders:uid:1"))
+	})
+
+	t.Run("should not contain the other scopes", func(t *testing.T) {
+		for _, s := range []string{"dashboards:uid:2", "dashboards:uid:", "dashboards:uid:11", "folders:uid:10", "teams:id:1", "dashboards:uid"} {
+			require.False(t, g.contains(s), s)
+		}
+	})
+
+	t.Run("should deduplicate the scopes", func(t *testing.T) {
+		require.Equal(t, []string{"dashboards:uid:1", "dashboards:uid:10", "folders:uid:1", "teams:*"}, grantedScopes(&controller{grants: g}))
+	})
+
+	t.Run("should return the wildcards as granted", func(t *testing.T) {
+		wildcard := func(g *grants, kind string) string {
+			s, _ := g.wildcard(kind)
+			return s
+		}
+		require.Equal(t, "teams:*", wildcard(g, "teams"))
+		require.Empty(t, wildcard(g, "dashboards"))
+		require.Empty(t, wildcard(g, "*"))
+		require.Equal(t, "*", wildcard(newGrants("*"), "*"))
+		require.Equal(t, "folders:uid:*", wildcard(newGrants("folders:uid:*"), "folders"))
+	})
+
+	t.Run("should return the identifiers with the prefix", func(t *testing.T) {
+		ids := g.idsWithPrefix("dashboards:uid:")
+		sort.Strings(ids)
+		require.Equal(t, []string{"1", "10"}, ids)
+		require.Empty(t, g.idsWithPrefix("dashboards:id:"))
+	})
+
+	t.Run("should compare the scopes with the same hash", func(t *testing.T) {
+		// Forge a collision: the stored scope has the hash of another scope
+		c := newGrants("dashboards:uid:1")
+		c.hashes[0] = hashScope("dashboards:uid:2", "")
+		require.False(t, c.contains("dashboards:uid:2"))
+	})
+
+	t.Run("should round trip the grants", func(t *testing.T) {
+		got := &grants{raw: appendGrants(nil, g)}
+		require.Equal(t, grantedScopes(&controller{grants: g}), grantedScopes(&controller{grants: got}))
+		require.True(t, got.contains("dashboards:uid:10"))
+		s, ok := got.wildcard("teams")
+		require.True(t, ok)
+		require.Equal(t, "teams:*", s)
+	})
+
+	t.Run("should decode the wildcard kinds of the legacy encoding", func(t *testing.T) {
+		got := &grants{raw: []byte{2, 1, '*', 5, 't', 'e', 'a', 'm', 's', 0, 0}, legacy: true}
+		s, ok := got.wildcard("teams")
+		require.True(t, ok)
+		require.Equal(t, "teams:*", s)
+		s, ok = got.wildcard("*")
+		require.True(t, ok)
+		require.Equal(t, "*", s)
+	})
+
+	t.Run("should be empty when the grants are corrupted", func(t *testing.T) {
+		data := appendGrants(nil, g)
+		for _, raw := range [][]byte{data[:len(data)-1], {0, 0, 255}, {0, 1, 1, 'a', 1, 5, 1, 'x'}} {
+			got := &grants{raw: raw}
+			require.True(t, got.empty())
+			require.False(t, got.contains("dashboards:uid:1"))
+		}
+	})
+
+	t.Run("should reject the scopes out of the hash order", func(t *testing.T) {
+		b := newGrants("dashboards:uid:1", "dashboards:uid:2")
+		// Swap the identifiers, of the same length
+		b.ids = b.id(1) + b.id(0)
+		require.ErrorIs(t, (&grants{}).unmarshal(appendGrants(nil, b), false), errGrantsEncoding)
+	})
+
+	t.Run("should not decode the grants of the action checks", func(t *testing.T) {
+		ctrl := &controller{Found: true, grants: &grants{raw: appendGrants(nil, g)}}
+		require.True(t, ctrl.Check())
+		require.NotNil(t, ctrl.grants.raw)
+
+		require.True(t, ctrl.Check(Resource{Kind: "dashboards", Attr: "uid", ID: "10"}))
+		require.Nil(t, ctrl.grants.raw)
+	})
+
+	t.Run("nil grants should grant nothing", func(t *testing.T) {
+		var n *grants
+		require.True(t, n.empty())
+		_, ok := n.wildcard("*")
+		require.False(t, ok)
+		require.False(t, n.contains("dashboards:uid:1"))
+		require.Empty(t, n.idsWithPrefix("dashboards:uid:"))
+	})
+}
+
+func benchmarkResponse(n int) *authzv1.ReadResponse {
+	resp := &authzv1.ReadResponse{Found: true, Data: make([]*authzv1.ReadResponse_Data, 0, n)}
+	for i := 0; i < n; i++ {
+		resp.Data = append(resp.Data, &authzv1.ReadResponse_Data{Object: "dashboards:uid:" + strconv.Itoa(i)})
+	}
+	return resp
+}
+
+func BenchmarkControllerDecoding(b *testing.B) {
+	const scopes = 20_000
+	resp := benchmarkResponse(scopes)
+	res := Resource{Kind: "dashboards", Attr: "uid", ID: strconv.Itoa(scopes - 1)}
+
+	v1, err := proto.Marshal(resp)
+	require.NoError(b, err)
+	v1 = append(make([]byte, controllerHeaderLen), v1...)
+	v1[0] = controllerEncodingV1
+
+	ctrl := newController(resp)
+	ctrl.FetchedAt = time.Now()
+	v2, err := encodeController(ctrl)
+	require.NoError(b, err)
+
+	for _, bb := range []struct {
+		name      string
+		data      []byte
+		resources []Resource
+	}{
+		{name: "v1 action", data: v1},
+		{name: "v1 resource", data: v1, resources: []Resource{res}},
+		{name: "v2 action", data: v2},
+		{name: "v2 resource", data: v2, resources: []Resource{res}},
+	} {
+		bb := bb
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ReportMetric(float64(len(bb.data)), "payload-bytes")
+			for i := 0; i < b.N; i++ {
+				ctrl, err := decodeController(bb.data)
+				if err != nil || !ctrl.Check(bb.resources...) {
+					b.Fatal("unexpected denied check")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package authz
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/authlib/claims"
+)
+
+var (
+	ErrAccessDenied   = status.Errorf(codes.PermissionDenied, "access denied")
+	ErrUnmappedMethod = status.Errorf(codes.PermissionDenied, "method not mapped to an action")
+)
+
+// MethodActions maps the full gRPC method names (ex: "/dashboards.v1.DashboardService/GetDashboard")
+// to the actions checked for them (ex: "dashboards:read").
+type MethodActions map[string]string
+
+// ResourceFunc returns the resource checked for the request of the method, nil to check the action only.
+// The request is nil for the streams, as the interceptor runs before the messages are received.
+type ResourceFunc func(ctx context.Context, method string, req any) (*Resource, error)
+
+// CheckStackIDFunc returns the stack ID the permissions of the caller are checked against: the stack targeted
+// by the request, which is not necessarily the stack of the caller (ex: a service acting on several stacks).
+type CheckStackIDFunc func(ctx context.Context, caller claims.AuthInfo) (int64, error)
+
+type CheckInterceptorOption func(*checkInterceptor)
+
+// WithResourceCheckInterceptorOption sets how the resources are extracted from the requests.
+// Without it, the actions are checked without resource.
+func WithResourceCheckInterceptorOption(fn ResourceFunc) CheckInterceptorOption {
+	return func(c *checkInterceptor) {
+		c.resource = fn
+	}
+}
+
+// WithStackIDCheckInterceptorOption sets how the stack ID is resolved. Defaults to DefaultCheckStackID.
+func WithStackIDCheckInterceptorOption(fn CheckStackIDFunc) CheckInterceptorOption {
+	return func(c *checkInterceptor) {
+		c.stackID = fn
+	}
+}
+
+// WithSkipMethodsCheckInterceptorOption lets the methods through without check (ex: the health checks).
+// The other methods not mapped to an action are rejected with ErrUnmappedMethod.
+func WithSkipMethodsCheckInterceptorOption(methods ...string) CheckInterceptorOption {
+	return func(c *checkInterceptor) {
+		for _, m := range methods {
+			c.skip[m] = true
+		}
+	}
+}
+
+// DefaultCheckStackID returns the stack ID targeted by the request, from the DefaultStackIDMetadataKey metadata
+// (see MetadataCheckStackID). The namespace of the caller is then checked against it by the client.
+func DefaultCheckStackID(ctx context.Context, caller claims.AuthInfo) (int64, error) {
+	return MetadataCheckStackID(DefaultStackIDMetadataKey)(ctx, caller)
+}
+
+// MetadataCheckStackID returns a CheckStackIDFunc reading the stack ID targeted by the request from the key of the
+// incoming gRPC metadata. It returns ErrorMissingMetadata when the metadata is missing, and ErrorInvalidStackID
+// when it is not a stack ID.
+func MetadataCheckStackID(key string) CheckStackIDFunc {
+	return func(ctx context.Context, _ claims.AuthInfo) (int64, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return 0, ErrorMissingMetadata
+		}
+		value, ok := getFirstMetadataValue(md, key)
+		if !ok {
+			return 0, ErrorMissingMetadata
+		}
+		stackID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || stackID <= 0 {
+			return 0, ErrorInvalidStackID
+		}
+		return stackID, nil
+	}
+}
+
+// CallerCheckStackID returns the stack ID, or the org ID on-prem, of the namespace of the caller identity,
+// or of its access token when the caller has no identity (ex: a service). It assumes the requests target the
+// stack of their caller, so it is only meant for the services serving the callers of a single tenant.
+// It returns ErrMissingStackID for the namespaces of no stack nor org (ex: "*").
+func CallerCheckStackID(_ context.Context, caller claims.AuthInfo) (int64, error) {
+	namespace := ""
+	if id := caller.GetIdentity(); id != nil && !id.IsNil() {
+		namespace = id.Namespace()
+	} else if access := caller.GetAccess(); access != nil && !access.IsNil() {
+		namespace = access.Namespace()
+	}
+
+	info, err := claims.ParseNamespace(namespace)
+	if err != nil {
+		return 0, ErrMissingStackID
+	}
+	if info.StackID > 0 {
+		return info.StackID, nil
+	}
+	if info.OrgID > 0 {
+		return info.OrgID, nil
+	}
+	return 0, ErrMissingStackID
+}
+
+type checkInterceptor struct {
+	client   MultiTenantClient
+	actions  MethodActions
+	resource ResourceFunc
+	stackID  CheckStackIDFunc
+	skip     map[string]bool
+}
+
+func newCheckInterceptor(client MultiTenantClient, actions MethodActions, opts []CheckInterceptorOption) *checkInterceptor {
+	c := &checkInterceptor{client: client, actions: actions, skip: map[string]bool{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.stackID == nil {
+		c.stackID = DefaultCheckStackID
+	}
+
+	return c
+}
+
+// UnaryCheckInterceptor returns a new unary server interceptor checking that the caller is granted the action
+// of the method, with the client. The caller is read from the context (see claims.WithAuthInfo), so the interceptor
+// must run after the authentication (ex: authn.GrpcAuthenticator.UnaryServerInterceptor).
+// The denied requests are rejected with ErrAccessDenied, the errors of the checks are returned as is.
+func UnaryCheckInterceptor(client MultiTenantClient, actions MethodActions, opts ...CheckInterceptorOption) grpc.UnaryServerInterceptor {
+	c := newCheckInterceptor(client, actions, opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := c.check(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamCheckInterceptor returns a new stream server interceptor checking that the caller is granted the action
+// of the method, see UnaryCheckInterceptor. The resources are extracted without request.
+func StreamCheckInterceptor(client MultiTenantClient, actions MethodActions, opts ...CheckInterceptorOption) grpc.StreamServerInterceptor {
+	c := newCheckInterceptor(client, actions, opts)
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := c.check(stream.Context(), info.FullMethod, nil); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+func (c *checkInterceptor) check(ctx context.Context, method string, req any) error {
+	if c.skip[method] {
+		return nil
+	}
+	action, ok := c.actions[method]
+	if !ok {
+		return ErrUnmappedMethod
+	}
+
+	caller, ok := claims.AuthInfoFrom(ctx)
+	if !ok {
+		return ErrMissingCaller
+	}
+	stackID, err := c.stackID(ctx, caller)
+	if err != nil {
+		return err
+	}
+
+	checkReq := &CheckRequest{Caller: caller, StackID: stackID, Action: action}
+	if c.resource != nil {
+		if checkReq.Resource, err = c.resource(ctx, method, req); err != nil {
+			return err
+		}
+	}
+
+	allowed, err := c.client.Check(ctx, checkReq)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrAccessDenied
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/claims"
+)
+
+type recordingClient struct {
+	allowed bool
+	err     error
+	req     *CheckRequest
+}
+
+func (c *recordingClient) Check(_ context.Context, req *CheckRequest) (bool, error) {
+	c.req = req
+	return c.allowed, c.err
+}
+
+type getDashboardRequest struct {
+	uid string
+}
+
+const getDashboardMethod = "/dashboards.v1.DashboardService/GetDashboard"
+
+func TestUnaryCheckInterceptor(t *testing.T) {
+	caller := inProcessCheckRequest().Caller
+	ctx := claims.WithAuthInfo(metadata.NewIncomingContext(context.Background(), metadata.Pairs(DefaultStackIDMetadataKey, "12")), caller)
+	actions := MethodActions{getDashboardMethod: "dashboards:read"}
+	resource := func(_ context.Context, method string, req any) (*Resource, error) {
+		if r, ok := req.(*getDashboardRequest); ok {
+			return &Resource{Kind: "dashboards", Attr: "uid", ID: r.uid}, nil
+		}
+		return nil, status.Error(codes.InvalidArgument, "unexpected request")
+	}
+
+	call := func(ctx context.Context, interceptor grpc.UnaryServerInterceptor, method string, req any) (bool, error) {
+		called := false
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, any) (any, error) {
+			called = true
+			return nil, nil
+		})
+		return called, err
+	}
+
+	t.Run("should check the action and the resource of the method", func(t *testing.T) {
+		client := &recordingClient{allowed: true}
+		called, err := call(ctx, UnaryCheckInterceptor(client, actions, WithResourceCheckInterceptorOption(resource)), getDashboardMethod, &getDashboardRequest{uid: "1"})
+		require.NoError(t, err)
+		require.True(t, called)
+		require.Equal(t, &CheckRequest{
+			Caller:   caller,
+			StackID:  12,
+			Action:   "dashboards:read",
+			Resource: &Resource{Kind: "dashboards", Attr: "uid", ID: "1"},
+		}, client.req)
+	})
+
+	t.Run("should reject the denied requests", func(t *testing.T) {
+		called, err := call(ctx, UnaryCheckInterceptor(NewDenyAllClient(), actions), getDashboardMethod, nil)
+		require.ErrorIs(t, err, ErrAccessDenied)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+		require.False(t, called)
+	})
+
+	t.Run("should return the errors of the checks", func(t *testing.T) {
+		called, err := call(ctx, UnaryCheckInterceptor(&recordingClient{err: ErrReadPermission}, actions), getDashboardMethod, nil)
+		require.ErrorIs(t, err, ErrReadPermission)
+		require.False(t, called)
+
+		client := &recordingClient{allowed: true}
+		called, err = call(ctx, UnaryCheckInterceptor(client, actions, WithResourceCheckInterceptorOption(resource)), getDashboardMethod, "invalid")
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.False(t, called)
+		require.Nil(t, client.req)
+	})
+
+	t.Run("should reject the unmapped methods, unless skipped", func(t *testing.T) {
+		interceptor := UnaryCheckInterceptor(NewAllowAllClient(), actions, WithSkipMethodsCheckInterceptorOption("/grpc.health.v1.Health/Check"))
+
+		called, err := call(ctx, interceptor, "/dashboards.v1.DashboardService/DeleteDashboard", nil)
+		require.ErrorIs(t, err, ErrUnmappedMethod)
+		require.False(t, called)
+
+		called, err = call(context.Background(), interceptor, "/grpc.health.v1.Health/Check", nil)
+		require.NoError(t, err)
+		require.True(t, called)
+	})
+
+	t.Run("should reject the unauthenticated requests", func(t *testing.T) {
+		called, err := call(context.Background(), UnaryCheckInterceptor(NewAllowAllClient(), actions), getDashboardMethod, nil)
+		require.ErrorIs(t, err, ErrMissingCaller)
+		require.False(t, called)
+	})
+
+	t.Run("should resolve the stack ID", func(t *testing.T) {
+		errStack := errors.New("unknown stack")
+		interceptor := UnaryCheckInterceptor(NewAllowAllClient(), actions, WithStackIDCheckInterceptorOption(func(context.Context, claims.AuthInfo) (int64, error) {
+			return 0, errStack
+		}))
+		_, err := call(ctx, interceptor, getDashboardMethod, nil)
+		require.ErrorIs(t, err, errStack)
+
+		_, err = call(claims.WithAuthInfo(context.Background(), caller), UnaryCheckInterceptor(NewAllowAllClient(), actions), getDashboardMethod, nil)
+		require.ErrorIs(t, err, ErrorMissingMetadata)
+	})
+}
+
+func TestMetadataCheckStackID(t *testing.T) {
+	caller := inProcessCheckRequest().Caller
+	stackID := MetadataCheckStackID("X-Target-Stack")
+
+	t.Run("should read the stack targeted by the request, not the stack of the caller", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("X-Target-Stack", "13"))
+		id, err := stackID(ctx, caller)
+		require.NoError(t, err)
+		require.Equal(t, int64(13), id)
+	})
+
+	t.Run("should reject the missing and invalid stack IDs", func(t *testing.T) {
+		_, err := stackID(context.Background(), caller)
+		require.ErrorIs(t, err, ErrorMissingMetadata)
+
+		for _, value := range []string{"stacks-12", "0"} {
+			_, err = stackID(metadata.NewIncomingContext(context.Background(), metadata.Pairs("X-Target-Stack", value)), caller)
+			require.ErrorIs(t, err, ErrorInvalidStackID)
+		}
+	})
+}
+
+func TestCallerCheckStackID(t *testing.T) {
+	id, err := CallerCheckStackID(context.Background(), inProcessCheckRequest().Caller)
+	require.NoError(t, err)
+	require.Equal(t, int64(12), id)
+
+	wildcard := &authn.AuthInfo{IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+		Claims: &jwt.Claims{Subject: "user:1"},
+		Rest:   authn.IDTokenClaims{Namespace: "*"},
+	})}
+	_, err = CallerCheckStackID(context.Background(), wildcard)
+	require.ErrorIs(t, err, ErrMissingStackID)
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamCheckInterceptor(t *testing.T) {
+	ctx := claims.WithAuthInfo(metadata.NewIncomingContext(context.Background(), metadata.Pairs(DefaultStackIDMetadataKey, "12")), inProcessCheckRequest().Caller)
+	info := &grpc.StreamServerInfo{FullMethod: "/dashboards.v1.DashboardService/WatchDashboards"}
+	actions := MethodActions{info.FullMethod: "dashboards:read"}
+
+	client := &recordingClient{allowed: true}
+	called := false
+	err := StreamCheckInterceptor(client, actions)(nil, &fakeServerStream{ctx: ctx}, info, func(any, grpc.ServerStream) error {
+		called = true
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, called)
+	require.Equal(t, "dashboards:read", client.req.Action)
+
+	err = StreamCheckInterceptor(NewDenyAllClient(), actions)(nil, &fakeServerStream{ctx: ctx}, info, func(any, grpc.ServerStream) error {
+		return nil
+	})
+	require.ErrorIs(t, err, ErrAccessDenied)
+}
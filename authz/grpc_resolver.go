@@ -0,0 +1,81 @@
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+)
+
+var ErrUnknownResolver = errors.New("no resolver registered for the remote address scheme")
+
+// WithResolversLCOption registers resolvers for the client connection only,
+// for instance to resolve custom schemes from a service registry.
+// Resolvers for xds:/// targets are registered by importing google.golang.org/grpc/xds.
+func WithResolversLCOption(builders ...resolver.Builder) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.resolvers = append(c.resolvers, builders...)
+	}
+}
+
+// WithLoadBalancingPolicyLCOption sets the load balancing policy of the client connection
+// (ex: "round_robin", "weighted_round_robin" or a custom registered balancer).
+// The policy is ignored when the resolver provides a service config, which is the case of xDS.
+// It overrides the policy of WithServiceConfigLCOption, whatever the order of the options.
+func WithLoadBalancingPolicyLCOption(policy string) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.lbPolicy = policy
+	}
+}
+
+// connectionOptions returns the dial options derived from the resolver, service config and TLS options.
+func (c *LegacyClientImpl) connectionOptions(address string) ([]grpc.DialOption, error) {
+	if err := c.validateScheme(address); err != nil {
+		return nil, err
+	}
+
+	var opts []grpc.DialOption
+	if len(c.resolvers) > 0 {
+		opts = append(opts, grpc.WithResolvers(c.resolvers...))
+	}
+	if svcConfig := c.serviceConfig(); !svcConfig.isZero() {
+		sc, err := svcConfig.JSON()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithDefaultServiceConfig(sc))
+	}
+	if c.tlsConfig != nil {
+		creds, err := NewTLSCredentials(*c.tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(creds))
+	}
+	return opts, nil
+}
+
+// validateScheme makes sure the scheme of the remote address can be resolved.
+// Otherwise, gRPC silently falls back to the dns resolver.
+func (c *LegacyClientImpl) validateScheme(address string) error {
+	scheme, _, ok := strings.Cut(address, "://")
+	if !ok {
+		return nil
+	}
+
+	for _, b := range c.resolvers {
+		if b.Scheme() == scheme {
+			return nil
+		}
+	}
+	if resolver.Get(scheme) != nil {
+		return nil
+	}
+
+	if scheme == "xds" {
+		return fmt.Errorf("%w: %s, import google.golang.org/grpc/xds to enable xDS", ErrUnknownResolver, scheme)
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownResolver, scheme)
+}
@@ -0,0 +1,79 @@
+package authz
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+
+	"github.com/grafana/authlib/authn"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+type fakeAuthzServer struct {
+	authzv1.UnimplementedAuthzServiceServer
+	res *authzv1.ReadResponse
+}
+
+func (s *fakeAuthzServer) Read(ctx context.Context, req *authzv1.ReadRequest) (*authzv1.ReadResponse, error) {
+	return s.res, nil
+}
+
+func TestLegacyClientImpl_Resolvers(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	authzv1.RegisterAuthzServiceServer(server, &fakeAuthzServer{res: &authzv1.ReadResponse{Found: true}})
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	t.Run("should route requests with the custom resolver and balancer", func(t *testing.T) {
+		r := manual.NewBuilderWithScheme("authz-test")
+		r.InitialState(resolver.State{Addresses: []resolver.Address{{Addr: lis.Addr().String()}}})
+
+		client, err := NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: "authz-test:///authz"},
+			WithGrpcDialOptionsLCOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+			WithResolversLCOption(r),
+			WithLoadBalancingPolicyLCOption("round_robin"),
+		)
+		require.NoError(t, err)
+
+		got, err := client.Check(context.Background(), &CheckRequest{
+			Caller: &authn.AuthInfo{
+				AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+					Claims: &jwt.Claims{Subject: "service"},
+					Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+				}),
+				IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+					Claims: &jwt.Claims{Subject: "user:1"},
+					Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+				}),
+			},
+			StackID: 12,
+			Action:  "dashboards:read",
+		})
+		require.NoError(t, err)
+		require.True(t, got)
+	})
+
+	t.Run("should fail on unknown scheme", func(t *testing.T) {
+		_, err := NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: "xds:///authz"})
+		require.ErrorIs(t, err, ErrUnknownResolver)
+	})
+
+	t.Run("should accept registered schemes and plain addresses", func(t *testing.T) {
+		insecureOpt := WithGrpcDialOptionsLCOption(grpc.WithTransportCredentials(insecure.NewCredentials()))
+		_, err := NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: "dns:///authz:10000"}, insecureOpt)
+		require.NoError(t, err)
+
+		_, err = NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: "authz:10000"}, insecureOpt)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,65 @@
+package authz
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+var ErrUnhealthy = status.Errorf(codes.Unavailable, "authz service is not serving")
+
+// HealthService is the service name of the authz service in the gRPC health protocol.
+var HealthService = authzv1.AuthzService_ServiceDesc.ServiceName
+
+// WithWaitForReadyLCOption makes the requests wait for the connection to the authz service to be ready,
+// instead of failing fast, for at most maxWait (see WaitForReadyDialOptions).
+// The creation of the client fails if WithGrpcConnectionLCOption is also used.
+func WithWaitForReadyLCOption(maxWait time.Duration) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.grpcOptions = append(c.grpcOptions, WaitForReadyDialOptions(maxWait)...)
+	}
+}
+
+// WaitForReadyDialOptions are the dial options to wait for the connection to be ready rather than failing fast,
+// so that rolling restarts of the authz service do not show up as permission denials.
+// The unary calls without a deadline, or with a later one, are bounded by maxWait, streams are not.
+func WaitForReadyDialOptions(maxWait time.Duration) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.WaitForReady(true)),
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			if deadline, ok := ctx.Deadline(); maxWait > 0 && (!ok || time.Until(deadline) > maxWait) {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, maxWait)
+				defer cancel()
+			}
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+	}
+}
+
+// Health checks the authz service is serving with the gRPC health protocol.
+// ErrUnhealthy is returned when the service reports another status.
+func (c *LegacyClientImpl) Health(ctx context.Context) error {
+	ctx, span := c.tracer.Start(ctx, "LegacyClientImpl.Health")
+	defer span.End()
+
+	c.mtx.RLock()
+	conn := c.grpcConn
+	c.mtx.RUnlock()
+
+	res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: HealthService})
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return ErrUnhealthy
+	}
+	return nil
+}
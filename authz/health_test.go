@@ -0,0 +1,97 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/grafana/authlib/authn"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+func TestLegacyClientImpl_Health(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	authzv1.RegisterAuthzServiceServer(server, &fakeAuthzServer{res: &authzv1.ReadResponse{Found: true}})
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(server, hs)
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	// The service is unreachable until started
+	started := atomic.Bool{}
+	client, err := NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: "passthrough:///bufnet"},
+		WithGrpcDialOptionsLCOption(
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				if !started.Load() {
+					return nil, errors.New("connection refused")
+				}
+				return lis.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoff.Config{BaseDelay: 10 * time.Millisecond, MaxDelay: 10 * time.Millisecond}}),
+		),
+		WithWaitForReadyLCOption(5*time.Second),
+	)
+	require.NoError(t, err)
+
+	t.Run("should wait for the service to be ready", func(t *testing.T) {
+		time.AfterFunc(50*time.Millisecond, func() { started.Store(true) })
+
+		got, err := client.Check(context.Background(), &CheckRequest{
+			Caller: &authn.AuthInfo{
+				AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+					Claims: &jwt.Claims{Subject: "service"},
+					Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+				}),
+				IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+					Claims: &jwt.Claims{Subject: "user:1"},
+					Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+				}),
+			},
+			StackID: 12,
+			Action:  "dashboards:read",
+		})
+		require.NoError(t, err)
+		require.True(t, got)
+	})
+
+	t.Run("should report the health of the service", func(t *testing.T) {
+		hs.SetServingStatus(HealthService, healthpb.HealthCheckResponse_SERVING)
+		require.NoError(t, client.Health(context.Background()))
+
+		hs.SetServingStatus(HealthService, healthpb.HealthCheckResponse_NOT_SERVING)
+		require.ErrorIs(t, client.Health(context.Background()), ErrUnhealthy)
+	})
+}
+
+func TestWaitForReadyDialOptions(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	conn, err := grpc.NewClient("passthrough:///bufnet", append(WaitForReadyDialOptions(50*time.Millisecond),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return nil, errors.New("connection refused") }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)...)
+	require.NoError(t, err)
+	defer conn.Close()
+	defer lis.Close()
+
+	// The wait is bounded
+	start := time.Now()
+	_, err = authzv1.NewAuthzServiceClient(conn).Read(context.Background(), &authzv1.ReadRequest{})
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	require.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,128 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+const inMemoryBufferSize = 1024 * 1024
+
+var _ grpc.ClientConnInterface = (*inProcessConn)(nil)
+
+// inProcessConn calls the authz service server directly, without serialization nor network.
+type inProcessConn struct {
+	srv         authzv1.AuthzServiceServer
+	interceptor grpc.UnaryServerInterceptor
+}
+
+// NewInProcessConn returns a connection calling the authz service server directly.
+// The interceptors are run on the server side, in order, with the outgoing metadata of the client
+// exposed as incoming metadata (ex: to run authn.GrpcAuthenticator.UnaryServerInterceptor).
+// Client interceptors are not supported, use NewInMemoryLegacyClient to run them.
+func NewInProcessConn(srv authzv1.AuthzServiceServer, interceptors ...grpc.UnaryServerInterceptor) grpc.ClientConnInterface {
+	return &inProcessConn{srv: srv, interceptor: chainUnaryServer(interceptors)}
+}
+
+func (c *inProcessConn) Invoke(ctx context.Context, method string, args any, reply any, _ ...grpc.CallOption) error {
+	var desc *grpc.MethodDesc
+	for i, m := range authzv1.AuthzService_ServiceDesc.Methods {
+		if "/"+authzv1.AuthzService_ServiceDesc.ServiceName+"/"+m.MethodName == method {
+			desc = &authzv1.AuthzService_ServiceDesc.Methods[i]
+			break
+		}
+	}
+	if desc == nil {
+		return status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+
+	// Expose the client metadata to the server
+	md, _ := metadata.FromOutgoingContext(ctx)
+	ctx = metadata.NewIncomingContext(ctx, md.Copy())
+
+	dec := func(in any) error {
+		proto.Merge(in.(proto.Message), args.(proto.Message))
+		return nil
+	}
+	res, err := desc.Handler(c.srv, ctx, dec, c.interceptor)
+	if err != nil {
+		return err
+	}
+	proto.Merge(reply.(proto.Message), res.(proto.Message))
+	return nil
+}
+
+func (c *inProcessConn) NewStream(_ context.Context, _ *grpc.StreamDesc, method string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Errorf(codes.Unimplemented, "streams are not supported in process: %s", method)
+}
+
+func chainUnaryServer(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	if len(interceptors) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, h := interceptors[i], next
+			next = func(ctx context.Context, req any) (any, error) {
+				return interceptor(ctx, req, info, h)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// NewInProcessLegacyClient returns a client calling the authz service server directly.
+// It is meant for single-binary deployments, where the authz service runs in the same process.
+func NewInProcessLegacyClient(srv authzv1.AuthzServiceServer, opts ...LegacyClientOption) (*LegacyClientImpl, error) {
+	opts = append(opts, WithGrpcConnectionLCOption(NewInProcessConn(srv)))
+	return NewLegacyClient(&MultiTenantClientConfig{}, opts...)
+}
+
+// NewInMemoryLegacyClient serves the authz service server on an in-memory listener
+// and returns a client connected to it. Unlike NewInProcessLegacyClient, requests go through the gRPC stack,
+// so the server options and the client dial options (see WithGrpcDialOptionsLCOption) apply.
+// The returned function stops the server, it must be called once the client is no longer used.
+func NewInMemoryLegacyClient(srv authzv1.AuthzServiceServer, serverOpts []grpc.ServerOption, opts ...LegacyClientOption) (*LegacyClientImpl, func(), error) {
+	lis := bufconn.Listen(inMemoryBufferSize)
+	grpcServer := grpc.NewServer(serverOpts...)
+	authzv1.RegisterAuthzServiceServer(grpcServer, srv)
+	go func() {
+		_ = grpcServer.Serve(lis)
+	}()
+
+	opts = append(opts, func(c *LegacyClientImpl) {
+		c.grpcOptions = append(c.grpcOptions,
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+	})
+	client, err := NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: "passthrough:///in-memory"}, opts...)
+	if err != nil {
+		grpcServer.Stop()
+		return nil, nil, fmt.Errorf("failed to connect to the in-memory server: %w", err)
+	}
+
+	stop := func() {
+		client.mtx.RLock()
+		conn, ok := client.grpcConn.(*grpc.ClientConn)
+		client.mtx.RUnlock()
+		if ok {
+			_ = conn.Close()
+		}
+		grpcServer.Stop()
+	}
+	return client, stop, nil
+}
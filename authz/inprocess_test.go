@@ -0,0 +1,124 @@
+package authz
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/authlib/authn"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+type inProcessAuthzServer struct {
+	authzv1.UnimplementedAuthzServiceServer
+
+	mtx     sync.Mutex
+	res     *authzv1.ReadResponse
+	lastReq *authzv1.ReadRequest
+	lastMD  metadata.MD
+}
+
+func (f *inProcessAuthzServer) Read(ctx context.Context, req *authzv1.ReadRequest) (*authzv1.ReadResponse, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.lastReq = req
+	f.lastMD, _ = metadata.FromIncomingContext(ctx)
+	return f.res, nil
+}
+
+func inProcessCheckRequest() *CheckRequest {
+	return &CheckRequest{
+		Caller: &authn.AuthInfo{
+			AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+				Claims: &jwt.Claims{Subject: "service"},
+				Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+			}),
+			IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+				Claims: &jwt.Claims{Subject: "user:1"},
+				Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+			}),
+		},
+		StackID:  12,
+		Action:   "dashboards:read",
+		Resource: &Resource{Kind: "dashboards", Attr: "uid", ID: "1"},
+	}
+}
+
+func TestNewInProcessLegacyClient(t *testing.T) {
+	srv := &inProcessAuthzServer{res: &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}}
+
+	client, err := NewInProcessLegacyClient(srv)
+	require.NoError(t, err)
+
+	got, err := client.Check(context.Background(), inProcessCheckRequest())
+	require.NoError(t, err)
+	require.True(t, got)
+	require.Equal(t, "user:1", srv.lastReq.Subject)
+}
+
+func TestNewInProcessConn(t *testing.T) {
+	srv := &inProcessAuthzServer{res: &authzv1.ReadResponse{Found: true}}
+
+	var calls []string
+	interceptor := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			require.Equal(t, authzv1.AuthzService_Read_FullMethodName, info.FullMethod)
+			calls = append(calls, name)
+			return handler(ctx, req)
+		}
+	}
+	conn := NewInProcessConn(srv, interceptor("first"), interceptor("second"))
+	client := authzv1.NewAuthzServiceClient(conn)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-access-token", "token")
+	res, err := client.Read(ctx, &authzv1.ReadRequest{StackId: 12, Subject: "user:1", Action: "dashboards:read"})
+	require.NoError(t, err)
+	require.True(t, res.Found)
+	require.Equal(t, []string{"first", "second"}, calls)
+	require.Equal(t, []string{"token"}, srv.lastMD.Get("x-access-token"))
+
+	t.Run("should reject unknown methods", func(t *testing.T) {
+		err := conn.Invoke(context.Background(), "/authz.v1.AuthzService/Unknown", &authzv1.ReadRequest{}, &authzv1.ReadResponse{})
+		require.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+
+	t.Run("should reject streams", func(t *testing.T) {
+		_, err := conn.NewStream(context.Background(), &grpc.StreamDesc{}, "/authz.v1.AuthzService/Watch")
+		require.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+
+	t.Run("should return the interceptor errors", func(t *testing.T) {
+		deny := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			return nil, status.Error(codes.Unauthenticated, "denied")
+		}
+		client := authzv1.NewAuthzServiceClient(NewInProcessConn(srv, deny))
+		_, err := client.Read(context.Background(), &authzv1.ReadRequest{})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestNewInMemoryLegacyClient(t *testing.T) {
+	srv := &inProcessAuthzServer{res: &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}}
+
+	var intercepted bool
+	serverOpts := []grpc.ServerOption{grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		intercepted = true
+		return handler(ctx, req)
+	})}
+
+	client, stop, err := NewInMemoryLegacyClient(srv, serverOpts)
+	require.NoError(t, err)
+	defer stop()
+
+	got, err := client.Check(context.Background(), inProcessCheckRequest())
+	require.NoError(t, err)
+	require.True(t, got)
+	require.True(t, intercepted)
+}
@@ -0,0 +1,48 @@
+//go:build go1.23
+
+package authz
+
+import (
+	"context"
+	"iter"
+)
+
+// Scopes returns an iterator over the scopes granted to the subject (ex: "user:1") for the action.
+// Wildcards are yielded first, as granted (ex: "dashboards:uid:*"), then the other scopes in no particular order.
+// The permissions are read like for Check, from the cache or the authz service, but
+// neither the caller nor the delegated permissions are checked: it is meant for trusted callers,
+// to stream over large permission sets without materializing them.
+func (c *LegacyClientImpl) Scopes(ctx context.Context, stackID int64, subject, action string) (iter.Seq[string], error) {
+	ctx, span := c.tracer.Start(ctx, "LegacyClientImpl.Scopes")
+	defer span.End()
+	c.legacyUsage.report(ctx, SurfaceLegacyClient, "Scopes")
+
+	if stackID <= 0 {
+		return nil, ErrMissingStackID
+	}
+	if subject == "" {
+		return nil, ErrMissingSubject
+	}
+	if action == "" {
+		return nil, ErrMissingAction
+	}
+
+	ctrl, err := c.retrievePermissions(ctx, &CheckRequest{StackID: stackID, Action: action}, subject)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return ctrl.eachScope, nil
+}
+
+// LookupResourcesSeq is like LookupResources, but returns an iterator over the resources.
+func (s *EnforcementClientImpl) LookupResourcesSeq(ctx context.Context, idToken string, action string) (iter.Seq[Resource], error) {
+	permissions, err := s.fetchPermissions(ctx, idToken, action)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(yield func(Resource) bool) {
+		lookupResources(permissions, action, yield)
+	}, nil
+}
@@ -0,0 +1,80 @@
+//go:build go1.23
+
+package authz
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+func TestLegacyClientImpl_Scopes(t *testing.T) {
+	t.Run("should iterate over the scopes", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{
+			{Object: "dashboards:uid:1"},
+			{Object: "dashboards:uid:2"},
+			{Object: "folders:uid:*"},
+		}}
+
+		scopes, err := client.Scopes(context.Background(), 12, "user:1", "dashboards:read")
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"dashboards:uid:1", "dashboards:uid:2", "folders:uid:*"}, slices.Collect(scopes))
+		require.Equal(t, "folders:uid:*", slices.Collect(scopes)[0], "the wildcards are yielded first")
+		require.Equal(t, "user:1", authz.lastReq.Subject)
+
+		// The iteration can stop early
+		for range scopes {
+			break
+		}
+
+		// The permissions are cached
+		_, err = client.Scopes(context.Background(), 12, "user:1", "dashboards:read")
+		require.NoError(t, err)
+		require.Equal(t, 1, authz.reads)
+	})
+
+	t.Run("should not yield scopes when not found", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: false}
+
+		scopes, err := client.Scopes(context.Background(), 12, "user:1", "dashboards:read")
+		require.NoError(t, err)
+		require.Empty(t, slices.Collect(scopes))
+	})
+
+	t.Run("should validate the query", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		_, err := client.Scopes(context.Background(), 0, "user:1", "dashboards:read")
+		require.ErrorIs(t, err, ErrMissingStackID)
+		_, err = client.Scopes(context.Background(), 12, "", "dashboards:read")
+		require.ErrorIs(t, err, ErrMissingSubject)
+		_, err = client.Scopes(context.Background(), 12, "user:1", "")
+		require.ErrorIs(t, err, ErrMissingAction)
+	})
+}
+
+func TestEnforcementClientImpl_LookupResourcesSeq(t *testing.T) {
+	mockClient := &MockClient{}
+	s := EnforcementClientImpl{client: mockClient}
+	mockClient.On("Search", mock.Anything, mock.Anything).Return(&searchResponse{Data: &permissionsByID{
+		1: map[string][]string{
+			"teams:read":  {"teams:id:1", "teams:id:2"},
+			"teams:write": {"teams:id:1"},
+		},
+	}}, nil)
+
+	resources, err := s.LookupResourcesSeq(context.Background(), "jwt_id_token", "teams:read")
+	require.NoError(t, err)
+	require.Equal(t, []Resource{{Kind: "teams", Attr: "id", ID: "1"}, {Kind: "teams", Attr: "id", ID: "2"}}, slices.Collect(resources))
+
+	for r := range resources {
+		require.Equal(t, "1", r.ID)
+		break
+	}
+}
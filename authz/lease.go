@@ -0,0 +1,187 @@
+package authz
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/authlib/errs"
+)
+
+var ErrLeaseDenied = errs.New(errs.KindForbidden, "lease denied: initial check was not allowed")
+
+const (
+	defaultLeaseTTL     = 5 * time.Second
+	defaultLeaseMaxUses = 100
+)
+
+// LeaseOptions bounds a DecisionLease.
+type LeaseOptions struct {
+	// TTL is how long the lease can answer checks locally. Defaults to 5s.
+	TTL time.Duration
+	// MaxUses is the number of checks the lease can answer locally. Defaults to 100.
+	MaxUses int
+}
+
+// DecisionLease is a short-lived pre-authorization obtained after a positive Check.
+// It answers checks for the same caller, stack, action and resource kind locally,
+// until it expires or its budget is spent. Other checks are sent to the client.
+type DecisionLease struct {
+	client *LegacyClientImpl
+
+	stackID         int64
+	action          string
+	kind            string
+	accessSubject   string
+	identitySubject string
+	actorSubject    string
+	// decide evaluates the leased permissions against the requested resources
+	decide func(resources ...Resource) CheckResult
+
+	mtx       sync.Mutex
+	expiresAt time.Time
+	remaining int
+}
+
+// Lease checks the request and, when it is allowed, returns a DecisionLease answering identical checks
+// (same caller, stack, action and resource kind) locally. This is useful to avoid repeated checks in tight loops.
+// ErrLeaseDenied is returned when the initial check is not allowed. Multi-action requests (AllOf, AnyOf) cannot
+// be leased, ErrInvalidActions is returned for them.
+func (c *LegacyClientImpl) Lease(ctx context.Context, req *CheckRequest, opts LeaseOptions) (*DecisionLease, error) {
+	ctx, span := c.tracer.Start(ctx, "LegacyClientImpl.Lease")
+	defer span.End()
+
+	if req.multiAction() {
+		span.RecordError(ErrInvalidActions)
+		return nil, ErrInvalidActions
+	}
+
+	req = c.withAttributes(req)
+	res, err := c.CheckDetailed(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Allowed {
+		return nil, ErrLeaseDenied
+	}
+
+	if opts.TTL <= 0 {
+		opts.TTL = defaultLeaseTTL
+	}
+	if opts.MaxUses <= 0 {
+		opts.MaxUses = defaultLeaseMaxUses
+	}
+
+	lease := &DecisionLease{
+		client:          c,
+		stackID:         req.StackID,
+		action:          req.Action,
+		kind:            resourceKind(req.Resource),
+		accessSubject:   accessSubject(req.Caller),
+		identitySubject: identitySubject(req.Caller),
+		actorSubject:    actorSubject(req.Caller),
+		expiresAt:       time.Now().Add(opts.TTL),
+		remaining:       opts.MaxUses,
+	}
+
+	if lease.identitySubject == "" || claims.IsAnonymous(req.Caller.GetIdentity()) ||
+		res.Reason == ReasonStaticPolicy || res.Reason == ReasonServicePermission {
+		// Service only checks, including anonymous callers, and static rules do not depend on the requested resources
+		lease.decide = func(...Resource) CheckResult { return allow(res.Reason) }
+		return lease, nil
+	}
+
+	// The controller has just been cached by the check
+	ctrl, err := c.retrievePermissions(ctx, req, lease.identitySubject)
+	if err != nil {
+		return nil, err
+	}
+	lease.decide = func(resources ...Resource) CheckResult {
+		scope, allowed := ctrl.match(resources...)
+		return scopeResult(ctrl.Found, scope, allowed, resources)
+	}
+
+	return lease, nil
+}
+
+// Check answers the request locally when it is covered by the lease, otherwise it is sent to the client.
+// The decisions answered locally are audited and logged like the decisions of the client, from SourceLease.
+func (l *DecisionLease) Check(ctx context.Context, req *CheckRequest) (bool, error) {
+	req = l.client.withAttributes(req)
+	if !l.covers(req) || !l.use() {
+		return l.client.Check(ctx, req)
+	}
+
+	start := time.Now()
+	res := l.decide(req.resources()...)
+	l.client.recordDecision(ctx, req, res, nil, start, SourceLease)
+	return res.Allowed, nil
+}
+
+// Remaining returns the number of checks the lease can still answer locally.
+func (l *DecisionLease) Remaining() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if !time.Now().Before(l.expiresAt) {
+		return 0
+	}
+	return l.remaining
+}
+
+func (l *DecisionLease) covers(req *CheckRequest) bool {
+	if req == nil || req.Caller == nil || req.multiAction() {
+		return false
+	}
+
+	return req.StackID == l.stackID &&
+		req.Action == l.action &&
+		resourceKind(req.Resource) == l.kind &&
+		accessSubject(req.Caller) == l.accessSubject &&
+		identitySubject(req.Caller) == l.identitySubject &&
+		actorSubject(req.Caller) == l.actorSubject
+}
+
+// use consumes one check from the budget.
+func (l *DecisionLease) use() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.remaining <= 0 || !time.Now().Before(l.expiresAt) {
+		return false
+	}
+	l.remaining--
+	return true
+}
+
+func resourceKind(r *Resource) string {
+	if r == nil {
+		return ""
+	}
+	return r.Kind
+}
+
+func accessSubject(caller claims.AuthInfo) string {
+	accessClaims := caller.GetAccess()
+	if accessClaims == nil || accessClaims.IsNil() {
+		return ""
+	}
+	return accessClaims.Subject()
+}
+
+func actorSubject(caller claims.AuthInfo) string {
+	actor, ok := claims.GetActor(caller.GetIdentity())
+	if !ok {
+		return ""
+	}
+	return actor.Subject()
+}
+
+func identitySubject(caller claims.AuthInfo) string {
+	idClaims := caller.GetIdentity()
+	if idClaims == nil || idClaims.IsNil() {
+		return ""
+	}
+	return idClaims.Subject()
+}
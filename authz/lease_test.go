@@ -0,0 +1,164 @@
+package authz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+	"github.com/grafana/authlib/claims"
+)
+
+func TestLegacyClientImpl_Lease(t *testing.T) {
+	caller := &authn.AuthInfo{
+		AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+			Claims: &jwt.Claims{Subject: "service"},
+			Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+		}),
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: "user:1"},
+			Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+		}),
+	}
+	dashboard := func(id string) *CheckRequest {
+		return &CheckRequest{
+			Caller:   caller,
+			StackID:  12,
+			Action:   "dashboards:read",
+			Resource: &Resource{Kind: "dashboards", Attr: "uid", ID: id},
+		}
+	}
+
+	t.Run("should deny lease when check is not allowed", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: false}
+
+		lease, err := client.Lease(context.Background(), dashboard("1"), LeaseOptions{})
+		require.ErrorIs(t, err, ErrLeaseDenied)
+		require.Nil(t, lease)
+	})
+
+	t.Run("should answer covered checks locally until the budget is spent", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+
+		lease, err := client.Lease(context.Background(), dashboard("1"), LeaseOptions{TTL: time.Minute, MaxUses: 2})
+		require.NoError(t, err)
+		require.Equal(t, 2, lease.Remaining())
+
+		// Clear the cache to detect remote calls
+		require.NoError(t, client.cache.Delete(context.Background(), controllerCacheKey(12, "user:1", "dashboards:read")))
+		reads := authz.reads
+
+		got, err := lease.Check(context.Background(), dashboard("1"))
+		require.NoError(t, err)
+		require.True(t, got)
+
+		got, err = lease.Check(context.Background(), dashboard("2"))
+		require.NoError(t, err)
+		require.False(t, got)
+		require.Equal(t, reads, authz.reads)
+		require.Equal(t, 0, lease.Remaining())
+
+		// Budget spent, the check goes to the client
+		_, err = lease.Check(context.Background(), dashboard("1"))
+		require.NoError(t, err)
+		require.Equal(t, reads+1, authz.reads)
+	})
+
+	t.Run("should send checks not covered by the lease to the client", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+
+		lease, err := client.Lease(context.Background(), dashboard("1"), LeaseOptions{TTL: time.Minute})
+		require.NoError(t, err)
+
+		req := dashboard("1")
+		req.Resource.Kind = "folders"
+		got, err := lease.Check(context.Background(), req)
+		require.NoError(t, err)
+		require.False(t, got)
+		require.Equal(t, defaultLeaseMaxUses, lease.Remaining())
+	})
+
+	t.Run("should not lease nor answer the multi-action checks", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+
+		req := dashboard("1")
+		req.Action, req.AllOf = "", []string{"dashboards:read"}
+		_, err := client.Lease(context.Background(), req, LeaseOptions{TTL: time.Minute})
+		require.ErrorIs(t, err, ErrInvalidActions)
+
+		lease, err := client.Lease(context.Background(), dashboard("1"), LeaseOptions{TTL: time.Minute})
+		require.NoError(t, err)
+		req = dashboard("1")
+		req.Action, req.AllOf = "", []string{"dashboards:delete"}
+		got, err := lease.Check(context.Background(), req)
+		require.NoError(t, err)
+		require.False(t, got, "the delegated permissions do not include dashboards:delete")
+		require.Equal(t, defaultLeaseMaxUses, lease.Remaining())
+	})
+
+	t.Run("should lease the checks of anonymous callers on the service permissions", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		WithAnonymousServiceChecksLCOption()(client)
+		anonymous := &anonymousCaller{
+			AuthInfo: &authn.AuthInfo{
+				AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+					Claims: &jwt.Claims{Subject: "service"},
+					Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", Permissions: []string{"dashboards:read"}},
+				}),
+			},
+			identity: claims.NewAnonymousIdentity("stacks-12"),
+		}
+		req := dashboard("1")
+		req.Caller = anonymous
+
+		lease, err := client.Lease(context.Background(), req, LeaseOptions{TTL: time.Minute})
+		require.NoError(t, err)
+		got, err := lease.Check(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, got)
+		require.Equal(t, 0, authz.reads, "no permissions should be read for the anonymous subject")
+	})
+
+	t.Run("should not answer locally once expired", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+
+		lease, err := client.Lease(context.Background(), dashboard("1"), LeaseOptions{TTL: time.Minute})
+		require.NoError(t, err)
+		lease.expiresAt = time.Now().Add(-time.Second)
+		require.Equal(t, 0, lease.Remaining())
+
+		authz.res = &authzv1.ReadResponse{Found: false}
+		require.NoError(t, client.cache.Delete(context.Background(), controllerCacheKey(12, "user:1", "dashboards:read")))
+
+		got, err := lease.Check(context.Background(), dashboard("1"))
+		require.NoError(t, err)
+		require.False(t, got)
+	})
+
+	t.Run("should log the decisions answered locally", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+		var logged []DecisionLog
+		WithDecisionLoggerLCOption(func(_ context.Context, log DecisionLog) { logged = append(logged, log) })(client)
+
+		lease, err := client.Lease(context.Background(), dashboard("1"), LeaseOptions{TTL: time.Minute})
+		require.NoError(t, err)
+		got, err := lease.Check(context.Background(), dashboard("2"))
+		require.NoError(t, err)
+		require.False(t, got)
+
+		require.Len(t, logged, 2)
+		require.Equal(t, SourceLease, logged[1].Source)
+		require.Equal(t, ReasonNoScopeMatch, logged[1].Reason)
+		require.Equal(t, "dashboards:uid:2", logged[1].Resource)
+	})
+}
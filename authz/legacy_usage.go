@@ -0,0 +1,133 @@
+package authz
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Legacy surfaces reported by the LegacyUsageReporter.
+const (
+	SurfaceLegacyClient      = "LegacyClientImpl"
+	SurfaceEnforcementClient = "EnforcementClientImpl"
+)
+
+// UnknownCallSite is the call site of the calls without label, when it cannot be resolved from the stack.
+const UnknownCallSite = "unknown"
+
+// LegacyCall identifies calls into a legacy surface.
+type LegacyCall struct {
+	// Surface is the legacy API called (ex: SurfaceLegacyClient).
+	Surface string
+	// Method is the method called (ex: "Check").
+	Method string
+	// CallSite is the label of the caller (see WithCallSite), or the calling function (ex: "dashboards.(*API).Get").
+	CallSite string
+}
+
+// LegacyUsage is the number of calls into a legacy surface, from a call site.
+type LegacyUsage struct {
+	LegacyCall
+	Count int64
+}
+
+type callSiteKey struct{}
+
+// WithCallSite labels the calls into the legacy surfaces made with the context.
+// Without label, the call site is the calling function.
+func WithCallSite(ctx context.Context, label string) context.Context {
+	return context.WithValue(ctx, callSiteKey{}, label)
+}
+
+// LegacyUsageReporter counts the calls into the legacy surfaces, per call site, so that platform teams can
+// track the migration of the services to the v2 APIs. It is opt-in, see WithLegacyUsageReporterLCOption and
+// WithLegacyUsageReporter. It is safe for concurrent use.
+type LegacyUsageReporter struct {
+	inc func(call LegacyCall)
+
+	mtx    sync.Mutex
+	counts map[LegacyCall]int64
+}
+
+// NewLegacyUsageReporter returns a reporter keeping the counts in memory, and calling inc on each call, if set,
+// ex: to increment a Prometheus counter vector labelled by surface, method and call site.
+func NewLegacyUsageReporter(inc func(call LegacyCall)) *LegacyUsageReporter {
+	return &LegacyUsageReporter{inc: inc, counts: map[LegacyCall]int64{}}
+}
+
+// report counts a call into the surface. It is a no-op on a nil reporter.
+func (r *LegacyUsageReporter) report(ctx context.Context, surface, method string) {
+	if r == nil {
+		return
+	}
+
+	call := LegacyCall{Surface: surface, Method: method, CallSite: callSite(ctx)}
+	r.mtx.Lock()
+	r.counts[call]++
+	r.mtx.Unlock()
+
+	if r.inc != nil {
+		r.inc(call)
+	}
+}
+
+// Export returns the counts, sorted by surface, method and call site.
+func (r *LegacyUsageReporter) Export() []LegacyUsage {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	usage := make([]LegacyUsage, 0, len(r.counts))
+	for call, count := range r.counts {
+		usage = append(usage, LegacyUsage{LegacyCall: call, Count: count})
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if a.Surface != b.Surface {
+			return a.Surface < b.Surface
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.CallSite < b.CallSite
+	})
+	return usage
+}
+
+const authzPackage = "github.com/grafana/authlib/authz."
+
+// callSite returns the label of the context, or the first calling function outside of this package.
+func callSite(ctx context.Context) string {
+	if label, ok := ctx.Value(callSiteKey{}).(string); ok && label != "" {
+		return label
+	}
+
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" && !strings.HasPrefix(frame.Function, authzPackage) {
+			// Trim the module path of the function (ex: "github.com/org/svc/pkg.Func" -> "pkg.Func")
+			return frame.Function[strings.LastIndex(frame.Function, "/")+1:]
+		}
+		if !more {
+			return UnknownCallSite
+		}
+	}
+}
+
+// WithLegacyUsageReporterLCOption reports the calls into the client.
+func WithLegacyUsageReporterLCOption(r *LegacyUsageReporter) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.legacyUsage = r
+	}
+}
+
+// WithLegacyUsageReporter reports the calls into the client.
+func WithLegacyUsageReporter(r *LegacyUsageReporter) ClientOption {
+	return func(s *EnforcementClientImpl) error {
+		s.legacyUsage = r
+		return nil
+	}
+}
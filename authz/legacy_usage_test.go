@@ -0,0 +1,36 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLegacyUsageReporter(t *testing.T) {
+	var calls []LegacyCall
+	reporter := NewLegacyUsageReporter(func(call LegacyCall) { calls = append(calls, call) })
+
+	client, _ := setupLegacyClient()
+	WithLegacyUsageReporterLCOption(reporter)(client)
+
+	ctx := WithCallSite(context.Background(), "dashboards-api")
+	_, _ = client.Check(ctx, inProcessCheckRequest())
+	_, _ = client.Check(ctx, inProcessCheckRequest())
+	_, _ = client.CheckDetailed(ctx, inProcessCheckRequest())
+
+	// Without label, the call site is the first caller outside of the package
+	_, _ = client.Check(context.Background(), inProcessCheckRequest())
+
+	require.Equal(t, []LegacyUsage{
+		{LegacyCall: LegacyCall{Surface: SurfaceLegacyClient, Method: "Check", CallSite: "dashboards-api"}, Count: 2},
+		{LegacyCall: LegacyCall{Surface: SurfaceLegacyClient, Method: "Check", CallSite: "testing.tRunner"}, Count: 1},
+		{LegacyCall: LegacyCall{Surface: SurfaceLegacyClient, Method: "CheckDetailed", CallSite: "dashboards-api"}, Count: 1},
+	}, reporter.Export())
+	require.Len(t, calls, 4)
+
+	t.Run("should be a no-op without reporter", func(t *testing.T) {
+		var r *LegacyUsageReporter
+		require.NotPanics(t, func() { r.report(ctx, SurfaceEnforcementClient, "HasAccess") })
+	})
+}
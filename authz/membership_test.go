@@ -0,0 +1,64 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+func TestLegacyClientImpl_Check_Membership(t *testing.T) {
+	access := authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+		Claims: &jwt.Claims{Subject: "service"},
+		Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+	})
+
+	t.Run("should forward the groups and teams of the caller", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+
+		caller := &authn.AuthInfo{
+			AccessClaims: access,
+			IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+				Claims: &jwt.Claims{Subject: "user:1"},
+				Rest:   authn.IDTokenClaims{Namespace: "stacks-12", Groups: []string{"admins"}, Teams: []string{"team-1", "team-2"}},
+			}),
+		}
+		got, err := client.Check(context.Background(), &CheckRequest{
+			Caller:   caller,
+			StackID:  12,
+			Action:   "dashboards:read",
+			Resource: &Resource{Kind: "dashboards", Attr: "uid", ID: "1"},
+		})
+		require.NoError(t, err)
+		require.True(t, got)
+		require.Equal(t, []string{"admins"}, authz.lastReq.Groups)
+		require.Equal(t, []string{"team-1", "team-2"}, authz.lastReq.Teams)
+	})
+
+	t.Run("should not send memberships the caller does not have", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+
+		caller := &authn.AuthInfo{
+			AccessClaims: access,
+			IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+				Claims: &jwt.Claims{Subject: "user:1"},
+				Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+			}),
+		}
+		_, err := client.Check(context.Background(), &CheckRequest{
+			Caller:   caller,
+			StackID:  12,
+			Action:   "dashboards:read",
+			Resource: &Resource{Kind: "dashboards", Attr: "uid", ID: "1"},
+		})
+		require.NoError(t, err)
+		require.Empty(t, authz.lastReq.Groups)
+		require.Empty(t, authz.lastReq.Teams)
+	})
+}
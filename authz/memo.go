@@ -0,0 +1,98 @@
+package authz
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/authlib/cache"
+	"github.com/grafana/authlib/claims"
+)
+
+type requestMemoKey struct{}
+
+// requestMemo holds the decisions of the checks made while serving a request.
+type requestMemo struct {
+	mtx     sync.Mutex
+	results map[memoKey]CheckResult
+}
+
+type memoKey struct {
+	client *LegacyClientImpl
+	check  cache.CacheKey
+}
+
+// WithRequestMemo returns a context memoizing the decisions of the checks made with it, for the lifetime
+// of a request: checks with identical parameters, common in layered handlers, are answered from the memo
+// without reading the cache. Failed checks are not memoized. The context is returned as is if it already has a memo.
+func WithRequestMemo(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(requestMemoKey{}).(*requestMemo); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, requestMemoKey{}, &requestMemo{results: map[memoKey]CheckResult{}})
+}
+
+func requestMemoFrom(ctx context.Context) *requestMemo {
+	memo, _ := ctx.Value(requestMemoKey{}).(*requestMemo)
+	return memo
+}
+
+func (m *requestMemo) get(key memoKey) (CheckResult, bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	res, ok := m.results[key]
+	return res, ok
+}
+
+func (m *requestMemo) set(key memoKey, res CheckResult) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.results[key] = res
+}
+
+// decideMemoized returns the memoized decision on the request, if any, or decides and memoizes it.
+func (c *LegacyClientImpl) decideMemoized(ctx context.Context, span trace.Span, req *CheckRequest, source *DecisionSource) (CheckResult, error) {
+	memo := requestMemoFrom(ctx)
+	if memo == nil || req == nil || req.Caller == nil {
+		return c.decide(ctx, span, req, source)
+	}
+
+	key := c.memoKey(req)
+	if res, ok := memo.get(key); ok {
+		*source = SourceMemo
+		span.SetAttributes(attribute.Bool("memo", true))
+		return res, nil
+	}
+
+	res, err := c.decide(ctx, span, req, source)
+	if err == nil {
+		memo.set(key, res)
+	}
+	return res, err
+}
+
+// memoKey returns the key of the check in the memo, built from the subjects of the caller and the checked resources.
+func (c *LegacyClientImpl) memoKey(req *CheckRequest) memoKey {
+	parts := []string{strconv.FormatInt(req.StackID, 10), req.Action}
+	if access := req.Caller.GetAccess(); access != nil && !access.IsNil() {
+		parts = append(parts, access.Subject(), access.Namespace())
+	} else {
+		parts = append(parts, "", "")
+	}
+	if id := req.Caller.GetIdentity(); id != nil && !id.IsNil() {
+		actor := ""
+		if a, ok := claims.GetActor(id); ok {
+			actor = a.Subject()
+		}
+		parts = append(parts, id.Subject(), id.Namespace(), actor)
+	} else {
+		parts = append(parts, "", "", "")
+	}
+	for _, r := range req.resources() {
+		parts = append(parts, r.Scope())
+	}
+	return memoKey{client: c, check: cache.NewCacheKey("check", parts...)}
+}
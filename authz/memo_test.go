@@ -0,0 +1,48 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+	"github.com/grafana/authlib/cache"
+)
+
+func TestLegacyClientImpl_Check_RequestMemo(t *testing.T) {
+	client, authz := setupLegacyClient()
+	authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+	cacheWrap := &cacheWrap{cache: cache.NewLocalCache(cache.Config{})}
+	client.cache = cacheWrap
+
+	var sources []DecisionSource
+	WithDecisionLoggerLCOption(func(_ context.Context, log DecisionLog) { sources = append(sources, log.Source) })(client)
+
+	ctx := WithRequestMemo(context.Background())
+	require.Equal(t, ctx, WithRequestMemo(ctx), "the memo is reused")
+
+	for i := 0; i < 3; i++ {
+		allowed, err := client.Check(ctx, inProcessCheckRequest())
+		require.NoError(t, err)
+		require.True(t, allowed)
+	}
+	require.Equal(t, 1, authz.reads)
+	require.Equal(t, 0, cacheWrap.successReadCnt, "the memo answers without reading the cache")
+	require.Equal(t, []DecisionSource{SourceService, SourceMemo, SourceMemo}, sources)
+
+	t.Run("should not share the decisions of other parameters", func(t *testing.T) {
+		other := inProcessCheckRequest()
+		other.Resource = &Resource{Kind: "dashboards", Attr: "uid", ID: "2"}
+		allowed, err := client.Check(ctx, other)
+		require.NoError(t, err)
+		require.False(t, allowed)
+	})
+
+	t.Run("should not memoize without memo", func(t *testing.T) {
+		reads := cacheWrap.successReadCnt
+		_, err := client.Check(context.Background(), inProcessCheckRequest())
+		require.NoError(t, err)
+		require.Equal(t, reads+1, cacheWrap.successReadCnt)
+	})
+}
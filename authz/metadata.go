@@ -0,0 +1,67 @@
+package authz
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataPrefix prefixes the gRPC metadata keys of CheckRequest.Metadata,
+// so that they cannot override the metadata set by the client (ex: the access token).
+const MetadataPrefix = "x-authz-meta-"
+
+// MetadataFromIncomingContext returns the CheckRequest.Metadata forwarded by the client,
+// without MetadataPrefix. It is meant to be used by the authz service.
+func MetadataFromIncomingContext(ctx context.Context) map[string]string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var res map[string]string
+	for k, v := range md {
+		if !strings.HasPrefix(k, MetadataPrefix) || len(v) == 0 {
+			continue
+		}
+		if res == nil {
+			res = map[string]string{}
+		}
+		res[strings.TrimPrefix(k, MetadataPrefix)] = v[0]
+	}
+	return res
+}
+
+func validateMetadata(md map[string]string) error {
+	for k, v := range md {
+		if !validMetadataKey(k) || !validMetadataValue(v) {
+			return ErrInvalidMetadata
+		}
+	}
+	return nil
+}
+
+// validMetadataKey reports whether the key is a valid gRPC metadata key.
+// Binary keys ("-bin" suffix) are not supported as the values are strings.
+func validMetadataKey(k string) bool {
+	if k == "" || strings.HasSuffix(k, "-bin") {
+		return false
+	}
+	for i := 0; i < len(k); i++ {
+		c := k[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') && c != '-' && c != '_' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// validMetadataValue reports whether the value only contains printable ASCII characters.
+func validMetadataValue(v string) bool {
+	for i := 0; i < len(v); i++ {
+		if v[i] < 0x20 || v[i] > 0x7E {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+func TestLegacyClientImpl_Check_Metadata(t *testing.T) {
+	t.Run("should forward the metadata to the service", func(t *testing.T) {
+		srv := &inProcessAuthzServer{res: &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}}
+		client, err := NewInProcessLegacyClient(srv)
+		require.NoError(t, err)
+
+		req := inProcessCheckRequest()
+		req.Metadata = map[string]string{"correlation-id": "abc", "x-access-token": "forged"}
+		got, err := client.Check(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, got)
+
+		require.Equal(t, []string{"abc"}, srv.lastMD.Get(MetadataPrefix+"correlation-id"))
+		require.Empty(t, srv.lastMD.Get("x-access-token"), "should not override the client metadata")
+
+		ctx := metadata.NewIncomingContext(context.Background(), srv.lastMD)
+		require.Equal(t, req.Metadata, MetadataFromIncomingContext(ctx))
+	})
+
+	t.Run("should reject invalid metadata", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		for _, md := range []map[string]string{
+			{"": "value"},
+			{"Upper": "value"},
+			{"key with space": "value"},
+			{"key-bin": "value"},
+			{"key": "line\nbreak"},
+			{"key": "ünicode"},
+		} {
+			req := inProcessCheckRequest()
+			req.Metadata = md
+			_, err := client.Check(context.Background(), req)
+			require.ErrorIs(t, err, ErrInvalidMetadata, md)
+		}
+	})
+}
+
+func TestMetadataFromIncomingContext(t *testing.T) {
+	require.Nil(t, MetadataFromIncomingContext(context.Background()))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "token", MetadataPrefix+"flag", "on"))
+	require.Equal(t, map[string]string{"flag": "on"}, MetadataFromIncomingContext(ctx))
+}
@@ -15,6 +15,24 @@ type HTTPRequestDoer interface {
 type client interface {
 	// Search returns the permissions for the given query.
 	Search(ctx context.Context, query searchQuery) (*searchResponse, error)
+	// SearchPages calls yield with the pages of the permissions for the given query, until it returns false.
+	SearchPages(ctx context.Context, query searchQuery, yield func(permissionsByID) bool) error
+	// SearchTeams returns the permissions of the teams for the given query.
+	SearchTeams(ctx context.Context, query TeamPermissionsQuery) (permissionsByID, error)
+
+	// ListRoles returns the roles of the org of the token.
+	ListRoles(ctx context.Context, query ListRolesQuery) ([]Role, error)
+	// GetRole returns the role with the UID.
+	GetRole(ctx context.Context, uid string) (*Role, error)
+	// ListRoleAssignments returns the assignments of the role with the UID.
+	ListRoleAssignments(ctx context.Context, uid string) (*RoleAssignments, error)
+
+	// AssignRole assigns the role with the UID to the user.
+	AssignRole(ctx context.Context, userID int64, roleUID string) error
+	// RevokeRole revokes the role with the UID from the user.
+	RevokeRole(ctx context.Context, userID int64, roleUID string) error
+	// SetUserPermissions sets the permission of the user on the resource.
+	SetUserPermissions(ctx context.Context, perm ResourcePermission) error
 }
 
 type EnforcementClient interface {
@@ -49,6 +67,40 @@ type searchResponse response[permissionsByID]
 // ex: { 1: { "teams:read": ["teams:id:2", "teams:id:3"] }, 3: { "teams:read": ["teams:id:1", "teams:id:3"] } }
 type permissionsByID map[int64]permissions
 
+// merge adds the permissions of the page.
+func (p permissionsByID) merge(page permissionsByID) {
+	for id, perms := range page {
+		if p[id] == nil {
+			p[id] = permissions{}
+		}
+		for action, scopes := range perms {
+			p[id][action] = append(p[id][action], scopes...)
+		}
+	}
+}
+
+// TeamPermissionsQuery is the query to search for the permissions of teams.
+// Action and ActionPrefix are mutually exclusive.
+type TeamPermissionsQuery struct {
+	// TeamID restricts the search to the team. Ex: 2
+	TeamID       int64  `json:"teamId,omitempty" url:"teamId,omitempty"`
+	ActionPrefix string `json:"actionPrefix,omitempty" url:"actionPrefix,omitempty"`
+	Action       string `json:"action,omitempty" url:"action,omitempty"`
+	Scope        string `json:"scope,omitempty" url:"scope,omitempty"`
+}
+
+// TeamPermissions are the permissions of a team, with the scopes grouped by action.
+type TeamPermissions struct {
+	ID          int64
+	Permissions map[string][]string
+}
+
+// UserPermissions are the permissions of a user or service account, with the scopes grouped by action.
+type UserPermissions struct {
+	ID          int64
+	Permissions map[string][]string
+}
+
 // permissions maps actions to the scopes they can be applied to.
 // ex: { "pluginID.users:read": ["pluginID.users:uid:xHuuebS", "pluginID.users:uid:znbGGd"] }
 type permissions map[string][]string
@@ -57,6 +109,12 @@ type Config struct {
 	APIURL  string
 	Token   string
 	JWKsURL string
+	// SearchPageSize is the number of users per page of the permission searches, which are followed
+	// until the last page. Zero searches in a single request.
+	SearchPageSize int
+	// OrgID is the organization of the requests, in multi-organization Grafana instances (see also WithOrg).
+	// Zero uses the default organization of the token.
+	OrgID int64
 }
 
 // Resource represents a resource in Grafana.
@@ -80,6 +138,8 @@ type searchQuery struct {
 	Scope        string    `json:"scope,omitempty" url:"scope,omitempty"`
 	NamespacedID string    `json:"namespacedId" url:"namespacedId,omitempty"`
 	IdToken      string    `json:"-" url:"-"`
+	Page         int       `json:"page,omitempty" url:"page,omitempty"`
+	PerPage      int       `json:"perPage,omitempty" url:"perpage,omitempty"`
 	Resource     *Resource `json:"-" url:"-"`
 }
 
@@ -0,0 +1,135 @@
+package authz
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+	"github.com/grafana/authlib/claims"
+)
+
+var ErrInvalidActions = status.Errorf(codes.InvalidArgument, "only one of action, all of or any of actions can be set")
+
+// multiAction returns whether the request checks several actions.
+func (r *CheckRequest) multiAction() bool {
+	return r != nil && (len(r.AllOf) > 0 || len(r.AnyOf) > 0)
+}
+
+// checkActions checks the actions of a multi-action request, one by one, once their permissions are read.
+// The result is the one of the action which decided: the first denied of AllOf, or the first allowed of AnyOf.
+func (c *LegacyClientImpl) checkActions(ctx context.Context, span trace.Span, req *CheckRequest) (CheckResult, error) {
+	if req.Action != "" || req.Attributes != nil || (len(req.AllOf) > 0 && len(req.AnyOf) > 0) {
+		span.RecordError(ErrInvalidActions)
+		return deny(ReasonInvalidRequest), ErrInvalidActions
+	}
+
+	actions, all := req.AllOf, true
+	if len(req.AnyOf) > 0 {
+		actions, all = req.AnyOf, false
+	}
+	span.SetAttributes(attribute.StringSlice("actions", actions), attribute.Bool("all_of", all))
+
+	c.prefetchPermissions(ctx, req, actions)
+
+	var res CheckResult
+	for _, action := range actions {
+		single := *req
+		single.Action, single.AllOf, single.AnyOf = action, nil, nil
+
+		var err error
+		res, err = c.check(ctx, span, &single)
+		if err != nil || res.Allowed != all {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// prefetchPermissions reads in a single request the permissions of the actions missing from the cache,
+// so that the checks of the actions are served from the cache. Failures are left to the checks of the actions.
+func (c *LegacyClientImpl) prefetchPermissions(ctx context.Context, req *CheckRequest, actions []string) {
+	if req.Caller == nil || req.StackID <= 0 {
+		return
+	}
+	id := req.Caller.GetIdentity()
+	if id == nil || id.IsNil() || id.Subject() == "" || claims.IsAnonymous(id) {
+		return
+	}
+	subject := id.Subject()
+
+	missing := c.uncachedActions(ctx, req, subject, actions)
+	// A single action is read by its check
+	if len(missing) < 2 {
+		return
+	}
+	groups, teams := claims.GetMembership(id)
+	if _, err := c.readActions(ctx, req, subject, groups, teams, missing); err != nil {
+		c.logger.Debug("failed to read the permissions of the actions", "stack_id", req.StackID, "actions", missing, "error", err)
+	}
+}
+
+// uncachedActions returns the distinct actions which permissions are not cached, invalidated,
+// or older than the max staleness.
+func (c *LegacyClientImpl) uncachedActions(ctx context.Context, req *CheckRequest, subject string, actions []string) []string {
+	missing := make([]string, 0, len(actions))
+	for _, action := range actions {
+		if slices.Contains(missing, action) {
+			continue
+		}
+		ctrl, err := c.getCachedController(ctx, controllerCacheKey(req.StackID, subject, action))
+		if err == nil && ctrl != nil && !c.invalidations.stale(req.StackID, subject, action, ctrl.FetchedAt) && (req.MaxStaleness <= 0 || time.Since(ctrl.FetchedAt) <= req.MaxStaleness) {
+			continue
+		}
+		missing = append(missing, action)
+	}
+	return missing
+}
+
+// readActions reads the permissions of the subject, member of the groups and teams, for the actions in a single
+// request, and caches them.
+// It returns the actions which permissions were not returned, by services ignoring the additional actions.
+func (c *LegacyClientImpl) readActions(ctx context.Context, req *CheckRequest, subject string, groups, teams []string, actions []string) ([]string, error) {
+	first := *req
+	first.Action = actions[0]
+	readReq := c.readRequest(&first, subject)
+	readReq.Actions = actions[1:]
+	readReq.Groups, readReq.Teams = groups, teams
+
+	fetchedAt := time.Now()
+	outCtx, cancel := c.outgoingContext(ctx, req)
+	resp, err := c.read(outCtx, readReq)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	c.cachePrefetched(ctx, req.StackID, subject, actions[0], resp, fetchedAt)
+	unread := slices.Clone(readReq.Actions)
+	for _, a := range resp.GetActions() {
+		i := slices.Index(unread, a.GetAction())
+		if i < 0 {
+			continue
+		}
+		unread = slices.Delete(unread, i, i+1)
+		c.cachePrefetched(ctx, req.StackID, subject, a.GetAction(), &authzv1.ReadResponse{
+			Data:  a.GetData(),
+			Found: a.GetFound(),
+			TtlMs: resp.GetTtlMs(),
+		}, fetchedAt)
+	}
+	return unread, nil
+}
+
+func (c *LegacyClientImpl) cachePrefetched(ctx context.Context, stackID int64, subject, action string, resp *authzv1.ReadResponse, fetchedAt time.Time) {
+	ctrl := newController(resp)
+	ctrl.FetchedAt = fetchedAt
+	if err := c.cacheController(ctx, controllerCacheKey(stackID, subject, action), ctrl); err != nil {
+		c.logger.Debug("failed to cache the permissions of the action", "stack_id", stackID, "action", action, "error", err)
+	}
+}
@@ -0,0 +1,163 @@
+package authz
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/grafana/authlib/authn"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+// actionsAuthzClient serves the scopes of each action, and the additional actions of the reads when batched.
+type actionsAuthzClient struct {
+	scopes  map[string][]string
+	batched bool
+
+	mtx    sync.Mutex
+	reads  int
+	groups []string
+	teams  []string
+}
+
+func (f *actionsAuthzClient) Read(_ context.Context, in *authzv1.ReadRequest, _ ...grpc.CallOption) (*authzv1.ReadResponse, error) {
+	f.mtx.Lock()
+	f.reads++
+	f.groups, f.teams = in.Groups, in.Teams
+	f.mtx.Unlock()
+
+	data := func(action string) ([]*authzv1.ReadResponse_Data, bool) {
+		scopes, ok := f.scopes[action]
+		res := make([]*authzv1.ReadResponse_Data, 0, len(scopes))
+		for _, s := range scopes {
+			res = append(res, &authzv1.ReadResponse_Data{Object: s})
+		}
+		return res, ok
+	}
+
+	res := &authzv1.ReadResponse{}
+	res.Data, res.Found = data(in.Action)
+	if f.batched {
+		for _, action := range in.Actions {
+			d, found := data(action)
+			res.Actions = append(res.Actions, &authzv1.ReadResponse_Action{Action: action, Data: d, Found: found})
+		}
+	}
+	return res, nil
+}
+
+func TestLegacyClientImpl_Check_MultiAction(t *testing.T) {
+	caller := &authn.AuthInfo{
+		AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+			Claims: &jwt.Claims{Subject: "service"},
+			Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read", "folders:read", "users:read"}},
+		}),
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: "user:1"},
+			Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+		}),
+	}
+	scopes := map[string][]string{
+		"dashboards:read": {"dashboards:uid:1"},
+		"folders:read":    {"folders:uid:1"},
+	}
+	setup := func(batched bool) (*LegacyClientImpl, *actionsAuthzClient) {
+		client, _ := setupLegacyClient()
+		authz := &actionsAuthzClient{scopes: scopes, batched: batched}
+		client.clientV1 = authz
+		return client, authz
+	}
+
+	tests := []struct {
+		name      string
+		req       CheckRequest
+		want      bool
+		wantReads int
+	}{
+		{
+			name:      "all of the granted actions",
+			req:       CheckRequest{AllOf: []string{"dashboards:read", "folders:read"}},
+			want:      true,
+			wantReads: 1,
+		},
+		{
+			name:      "all of, with an action which is not granted",
+			req:       CheckRequest{AllOf: []string{"dashboards:read", "users:read"}},
+			wantReads: 1,
+		},
+		{
+			name:      "any of, with a granted action",
+			req:       CheckRequest{AnyOf: []string{"users:read", "folders:read"}},
+			want:      true,
+			wantReads: 1,
+		},
+		{
+			name:      "any of, without granted action",
+			req:       CheckRequest{AnyOf: []string{"users:read", "teams:read"}},
+			wantReads: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, authz := setup(true)
+			tt.req.Caller, tt.req.StackID = caller, 12
+
+			got, err := client.Check(context.Background(), &tt.req)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantReads, authz.reads)
+		})
+	}
+
+	t.Run("should check the resource with all the actions", func(t *testing.T) {
+		client, _ := setup(true)
+		req := &CheckRequest{
+			Caller:   caller,
+			StackID:  12,
+			AllOf:    []string{"dashboards:read", "folders:read"},
+			Resource: &Resource{Kind: "dashboards", Attr: "uid", ID: "1"},
+		}
+
+		got, err := client.CheckDetailed(context.Background(), req)
+		require.NoError(t, err)
+		require.False(t, got.Allowed)
+
+		req.Ancestors = []Resource{{Kind: "folders", Attr: "uid", ID: "1"}}
+		got, err = client.CheckDetailed(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, got.Allowed)
+	})
+
+	t.Run("should read the actions one by one from servers ignoring the batch", func(t *testing.T) {
+		client, authz := setup(false)
+
+		got, err := client.Check(context.Background(), &CheckRequest{Caller: caller, StackID: 12, AllOf: []string{"dashboards:read", "folders:read"}})
+		require.NoError(t, err)
+		require.True(t, got)
+		require.Equal(t, 2, authz.reads)
+	})
+
+	t.Run("should not read the cached permissions again", func(t *testing.T) {
+		client, authz := setup(true)
+
+		_, err := client.Check(context.Background(), &CheckRequest{Caller: caller, StackID: 12, Action: "dashboards:read"})
+		require.NoError(t, err)
+		got, err := client.Check(context.Background(), &CheckRequest{Caller: caller, StackID: 12, AllOf: []string{"dashboards:read", "folders:read"}})
+		require.NoError(t, err)
+		require.True(t, got)
+		require.Equal(t, 2, authz.reads)
+	})
+
+	t.Run("should reject ambiguous actions", func(t *testing.T) {
+		client, _ := setup(true)
+
+		_, err := client.Check(context.Background(), &CheckRequest{Caller: caller, StackID: 12, Action: "dashboards:read", AllOf: []string{"folders:read"}})
+		require.ErrorIs(t, err, ErrInvalidActions)
+		_, err = client.Check(context.Background(), &CheckRequest{Caller: caller, StackID: 12, AllOf: []string{"dashboards:read"}, AnyOf: []string{"folders:read"}})
+		require.ErrorIs(t, err, ErrInvalidActions)
+	})
+}
@@ -1,40 +1,108 @@
 package authz
 
 import (
-	"bytes"
 	"context"
-	"encoding/gob"
 	"errors"
 	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/sync/singleflight"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
 	"google.golang.org/grpc/status"
 
+	"github.com/grafana/authlib/actions"
 	authzv1 "github.com/grafana/authlib/authz/proto/v1"
 	"github.com/grafana/authlib/cache"
 	"github.com/grafana/authlib/claims"
+	"github.com/grafana/authlib/internal/logger"
 )
 
 var (
-	ErrMissingConfig  = errors.New("missing config")
-	ErrMissingStackID = status.Errorf(codes.InvalidArgument, "missing stack ID")
-	ErrMissingAction  = status.Errorf(codes.InvalidArgument, "missing action")
-	ErrMissingCaller  = status.Errorf(codes.Unauthenticated, "missing caller")
-	ErrMissingSubject = status.Errorf(codes.Unauthenticated, "missing subject")
-	ErrReadPermission = status.Errorf(codes.PermissionDenied, "read permission failed")
+	ErrMissingConfig   = errors.New("missing config")
+	ErrInvalidReload   = errors.New("invalid reload")
+	ErrConflictingConn = errors.New("dial options cannot be applied to a provided connection")
+	ErrMissingStackID  = status.Errorf(codes.InvalidArgument, "missing stack ID")
+	ErrMissingAction   = status.Errorf(codes.InvalidArgument, "missing action")
+	ErrMissingCaller   = status.Errorf(codes.Unauthenticated, "missing caller")
+	ErrMissingSubject  = status.Errorf(codes.Unauthenticated, "missing subject")
+	ErrInvalidAPIKey   = status.Errorf(codes.Unauthenticated, "invalid api key")
+	ErrReadPermission  = status.Errorf(codes.PermissionDenied, "read permission failed")
+	ErrInvalidMetadata = status.Errorf(codes.InvalidArgument, "invalid metadata")
 )
 
+// detachedReadTimeout bounds the reads of the permissions outliving the checks starting them
+// (shared by concurrent checks, or refreshing stale permissions).
+const detachedReadTimeout = 30 * time.Second
+
 type CheckRequest struct {
 	Caller     claims.AuthInfo
 	StackID    int64
 	Action     string
 	Resource   *Resource
 	Contextual []Resource
+	// Ancestors are the parents of the resource, from its direct parent up to the root
+	// (ex: the folders containing a dashboard). Permissions granted on any ancestor grant access to the resource,
+	// so callers do not have to flatten the inheritance into Contextual. Ignored when Resource is not set.
+	Ancestors []Resource
+	// AllOf and AnyOf check several actions in one call, instead of Action: the caller must be granted all
+	// the actions of AllOf, or any of the actions of AnyOf, on the resource. Only one of them can be set,
+	// without Action nor Attributes. The permissions missing from the cache are read in a single request.
+	AllOf []string
+	AnyOf []string
+	// MaxStaleness is the maximum age of the permissions tolerated for this check.
+	// Cached permissions older than this bound are refreshed, and the bound is sent to the authz service
+	// as a hint that it can serve the request from a read replica.
+	// Zero means the permissions are served from the cache until they expire.
+	MaxStaleness time.Duration
+	// Attributes describe the check the Kubernetes way, as an alternative to Action and Resource.
+	// When Action or Resource are not set, they are mapped from the attributes (see WithAttributesMapperLCOption).
+	// The attributes are also sent to the authz service.
+	Attributes *ResourceAttributes
+	// Metadata is forwarded to the authz service as gRPC metadata, with keys prefixed by MetadataPrefix
+	// (ex: correlation IDs, experiment flags or client hints used by server-side policies and logging).
+	// Keys must be valid gRPC metadata keys: lowercase letters, digits, "-", "_" and ".".
+	// The metadata only reaches the service when the permissions are not cached.
+	Metadata map[string]string
+}
+
+// detached returns a copy of what the reads of the permissions use of the request,
+// for the reads outliving the check (see detachedContext).
+func (req *CheckRequest) detached() *CheckRequest {
+	d := &CheckRequest{
+		Caller:       req.Caller,
+		StackID:      req.StackID,
+		Action:       req.Action,
+		MaxStaleness: req.MaxStaleness,
+		Metadata:     maps.Clone(req.Metadata),
+	}
+	if req.Attributes != nil {
+		attrs := *req.Attributes
+		d.Attributes = &attrs
+	}
+	return d
+}
+
+// resources returns the resources granting access to the requested resource: the resource itself,
+// its ancestors from the closest, then the contextual resources. It is empty for action checks only.
+func (req *CheckRequest) resources() []Resource {
+	if req.Resource == nil {
+		return nil
+	}
+	resources := make([]Resource, 0, 1+len(req.Ancestors)+len(req.Contextual))
+	resources = append(resources, *req.Resource)
+	resources = append(resources, req.Ancestors...)
+	return append(resources, req.Contextual...)
 }
 
 type MultiTenantClient interface {
@@ -42,9 +110,14 @@ type MultiTenantClient interface {
 }
 
 type MultiTenantClientConfig struct {
-	// RemoteAddress is the address of the authz service. It should be in the format "host:port".
+	// RemoteAddress is the address of the authz service. It should be in the format "host:port",
+	// or a gRPC target URI (ex: "xds:///authz") which scheme has a registered resolver.
 	RemoteAddress string
 
+	// CheckTimeout bounds each read of the permissions from the authz service, even when the context
+	// of the check has no deadline. With WithReadRetryLCOption, each attempt is bounded. Zero means no bound.
+	CheckTimeout time.Duration
+
 	// accessTokenAuthEnabled is a flag to enable access token authentication.
 	// If disabled, no service authentication will be performed. Defaults to true.
 	accessTokenAuthEnabled bool
@@ -56,12 +129,48 @@ type LegacyClientOption func(*LegacyClientImpl)
 
 type LegacyClientImpl struct {
 	authCfg      *MultiTenantClientConfig
-	clientV1     authzv1.AuthzServiceClient
 	cache        cache.Cache
-	grpcConn     grpc.ClientConnInterface
 	grpcOptions  []grpc.DialOption
+	resolvers    []resolver.Builder
+	svcConfig    ServiceConfig
+	lbPolicy     string
+	tlsConfig    *TLSConfig
 	namespaceFmt claims.NamespaceFormatter
+	nsMatcher    claims.NamespaceMatcher
 	tracer       trace.Tracer
+	logger       Logger
+	staticPolicy StaticPolicy
+	usage        *UsageCollector
+	attrsMapper  AttributesMapper
+	breakGlass   *breakGlass
+	breaker      *circuitBreaker
+	limiter      *rateLimiter
+	retry        *RetryPolicy
+	auditor      *DecisionAuditor
+	decisionLog  func(ctx context.Context, log DecisionLog)
+	// declaredActions are the actions the service checks, validated at construction
+	declaredActions []string
+	legacyUsage     *LegacyUsageReporter
+	// anonymousServiceChecks allows anonymous callers to pass the checks on the service permissions
+	anonymousServiceChecks bool
+
+	// singlef deduplicates the concurrent reads of the same permissions
+	singlef singleflight.Group
+	// swr serves the expired permissions while they are refreshed, if set
+	swr *StaleWhileRevalidateConfig
+	// revalidating are the cache keys being refreshed in the background
+	revalidating sync.Map
+	// invalidations are the permission changes received with Watch
+	invalidations invalidations
+
+	// mtx protects the connection which is swapped when the remote address is reloaded
+	mtx      sync.RWMutex
+	clientV1 authzv1.AuthzServiceClient
+	watchV1  authzv1.AuthzWatchServiceClient
+	grpcConn grpc.ClientConnInterface
+	// remoteAddress is the address of the connection dialed by the client.
+	// Empty when the connection was provided with WithGrpcConnectionLCOption.
+	remoteAddress string
 }
 
 type tracerProvider struct {
@@ -84,7 +193,7 @@ func WithCacheLCOption(cache cache.Cache) LegacyClientOption {
 }
 
 // WithGrpcDialOptionsLCOption sets the gRPC dial options for client connection setup.
-// Useful for adding client interceptors. These options are ignored if WithGrpcConnection is used.
+// Useful for adding client interceptors. The creation of the client fails if WithGrpcConnectionLCOption is also used.
 func WithGrpcDialOptionsLCOption(opts ...grpc.DialOption) LegacyClientOption {
 	return func(c *LegacyClientImpl) {
 		c.grpcOptions = opts
@@ -93,6 +202,8 @@ func WithGrpcDialOptionsLCOption(opts ...grpc.DialOption) LegacyClientOption {
 
 // WithGrpcConnectionLCOption sets the gRPC client connection directly.
 // Useful for running the client in the same process as the authorization service.
+// The options configuring the dial of the connection (dial options, resolvers, service config, TLS)
+// cannot be combined with it: the creation of the client fails with ErrConflictingConn.
 func WithGrpcConnectionLCOption(conn grpc.ClientConnInterface) LegacyClientOption {
 	return func(c *LegacyClientImpl) {
 		c.grpcConn = conn
@@ -105,12 +216,39 @@ func WithTracerLCOption(tracer trace.Tracer) LegacyClientOption {
 	}
 }
 
+// WithDeclaredActionsLCOption declares the actions the service checks. The construction of the client fails
+// when one of them is not a standard action (see actions.Validate), catching typos at startup.
+func WithDeclaredActionsLCOption(actions ...string) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.declaredActions = append(c.declaredActions, actions...)
+	}
+}
+
+// Logger surfaces the internal failures of the client (ex: the errors of the authz service behind ErrReadPermission)
+// to the logging of the application. *slog.Logger implements it. Logs are discarded by default.
+type Logger = logger.Logger
+
+func WithLoggerLCOption(logger Logger) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.logger = logger
+	}
+}
+
 func WithNamespaceFormatterLCOption(fmt claims.NamespaceFormatter) LegacyClientOption {
 	return func(c *LegacyClientImpl) {
 		c.namespaceFmt = fmt
 	}
 }
 
+// WithNamespaceMatcherLCOption sets how the namespace of the caller claims is matched
+// against the namespace formatted from the stack ID. Defaults to claims.NamespaceMatches.
+// Ex: single-tenant deployments, where namespaces are orgs, can use claims.OrgNamespaceMatches.
+func WithNamespaceMatcherLCOption(matcher claims.NamespaceMatcher) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.nsMatcher = matcher
+	}
+}
+
 // WithDisableAccessTokenLCOption is an option to disable access token authorization.
 // Warning: Using this option means there won't be any service authorization.
 func WithDisableAccessTokenLCOption() LegacyClientOption {
@@ -119,6 +257,23 @@ func WithDisableAccessTokenLCOption() LegacyClientOption {
 	}
 }
 
+// WithAnonymousServiceChecksLCOption allows anonymous callers (see claims.NewAnonymousIdentity)
+// to pass the checks on the service permissions, as if the service was calling on its own behalf.
+// By default, checks for anonymous callers are denied.
+func WithAnonymousServiceChecksLCOption() LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.anonymousServiceChecks = true
+	}
+}
+
+// WithAttributesMapperLCOption sets how the Kubernetes-style attributes of the checks
+// are mapped to actions and resources. Defaults to DefaultAttributesMapper.
+func WithAttributesMapperLCOption(mapper AttributesMapper) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.attrsMapper = mapper
+	}
+}
+
 // -----
 // Initialization
 // -----
@@ -136,6 +291,14 @@ func NewLegacyClient(cfg *MultiTenantClientConfig, opts ...LegacyClientOption) (
 		opt(client)
 	}
 
+	if err := actions.Validate(client.declaredActions...); err != nil {
+		return nil, err
+	}
+
+	if client.retry != nil && client.retry.MaxAttempts > 1 && client.svcConfig.retries() {
+		return nil, ErrConflictingRetries
+	}
+
 	// Instantiate the cache
 	if client.cache == nil {
 		client.cache = cache.NewLocalCache(cache.Config{
@@ -148,31 +311,133 @@ func NewLegacyClient(cfg *MultiTenantClientConfig, opts ...LegacyClientOption) (
 		client.tracer = noop.Tracer{}
 	}
 
+	if client.logger == nil {
+		client.logger = logger.Nop{}
+	}
+
 	// Instantiate the client
-	if client.grpcConn == nil {
+	if client.grpcConn != nil {
+		if err := client.validateProvidedConn(); err != nil {
+			return nil, err
+		}
+	} else {
 		if cfg.RemoteAddress == "" {
 			return nil, fmt.Errorf("missing remote address: %w", ErrMissingConfig)
 		}
 
-		tp := tracerProvider{tracer: client.tracer}
-		grpcOpts := client.grpcOptions
-		grpcOpts = append(grpcOpts, grpc.WithStatsHandler(otelgrpc.NewClientHandler(otelgrpc.WithTracerProvider(&tp))))
-
-		conn, err := grpc.NewClient(cfg.RemoteAddress, grpcOpts...)
+		conn, err := client.dial(cfg.RemoteAddress)
 		if err != nil {
 			return nil, err
 		}
 		client.grpcConn = conn
+		client.remoteAddress = cfg.RemoteAddress
 	}
 	client.clientV1 = authzv1.NewAuthzServiceClient(client.grpcConn)
+	client.watchV1 = authzv1.NewAuthzWatchServiceClient(client.grpcConn)
 
 	if client.namespaceFmt == nil {
 		client.namespaceFmt = claims.CloudNamespaceFormatter
 	}
 
+	if client.nsMatcher == nil {
+		client.nsMatcher = claims.NamespaceMatches
+	}
+
+	if client.attrsMapper == nil {
+		client.attrsMapper = DefaultAttributesMapper
+	}
+
 	return client, nil
 }
 
+// validateProvidedConn refuses the dial options when the connection is provided,
+// rather than silently connecting with other settings (ex: without TLS).
+func (c *LegacyClientImpl) validateProvidedConn() error {
+	var conflicting []string
+	if len(c.grpcOptions) > 0 {
+		conflicting = append(conflicting, "dial options")
+	}
+	if len(c.resolvers) > 0 {
+		conflicting = append(conflicting, "resolvers")
+	}
+	if !c.serviceConfig().isZero() {
+		conflicting = append(conflicting, "service config")
+	}
+	if c.tlsConfig != nil {
+		conflicting = append(conflicting, "tls config")
+	}
+	if len(conflicting) > 0 {
+		return fmt.Errorf("%w: %s", ErrConflictingConn, strings.Join(conflicting, ", "))
+	}
+	return nil
+}
+
+func (c *LegacyClientImpl) dial(address string) (*grpc.ClientConn, error) {
+	connOpts, err := c.connectionOptions(address)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := tracerProvider{tracer: c.tracer}
+	grpcOpts := make([]grpc.DialOption, 0, len(c.grpcOptions)+len(connOpts)+1)
+	grpcOpts = append(grpcOpts, c.grpcOptions...)
+	grpcOpts = append(grpcOpts, connOpts...)
+	grpcOpts = append(grpcOpts, grpc.WithStatsHandler(otelgrpc.NewClientHandler(otelgrpc.WithTracerProvider(&tp))))
+
+	return grpc.NewClient(address, grpcOpts...)
+}
+
+// Reload applies the configuration at runtime. When the remote address changes,
+// a new connection is established and atomically swapped with the previous one, which is then closed.
+// In-flight requests on the previous connection may fail.
+// The remote address cannot be reloaded when the connection was provided with WithGrpcConnectionLCOption.
+func (c *LegacyClientImpl) Reload(cfg *MultiTenantClientConfig) error {
+	if cfg == nil {
+		return ErrMissingConfig
+	}
+	if cfg.RemoteAddress == "" {
+		return fmt.Errorf("missing remote address: %w", ErrMissingConfig)
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.remoteAddress == "" {
+		return fmt.Errorf("cannot reload the remote address of a provided connection: %w", ErrInvalidReload)
+	}
+	if cfg.RemoteAddress == c.remoteAddress {
+		return nil
+	}
+
+	conn, err := c.dial(cfg.RemoteAddress)
+	if err != nil {
+		return err
+	}
+
+	previous, _ := c.grpcConn.(*grpc.ClientConn)
+	c.grpcConn = conn
+	c.clientV1 = authzv1.NewAuthzServiceClient(conn)
+	c.watchV1 = authzv1.NewAuthzWatchServiceClient(conn)
+	c.remoteAddress = cfg.RemoteAddress
+
+	if previous != nil {
+		return previous.Close()
+	}
+	return nil
+}
+
+func (c *LegacyClientImpl) authzClient() authzv1.AuthzServiceClient {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.clientV1
+}
+
+func (c *LegacyClientImpl) watchClient() authzv1.AuthzWatchServiceClient {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.watchV1
+}
+
 // -----
 // Implementation
 // -----
@@ -192,20 +457,65 @@ func (r *CheckRequest) Validate(accessTokenEnabled bool) error {
 	if idClaims != nil && !idClaims.IsNil() && idClaims.Subject() == "" {
 		return ErrMissingSubject
 	}
+	if actor, ok := claims.GetActor(idClaims); ok && actor.Subject() == "" {
+		return ErrMissingSubject
+	}
+	if err := validateMetadata(r.Metadata); err != nil {
+		return err
+	}
+	// API keys must identify the key and the organization owning it
+	if claims.IsAPIKey(idClaims) {
+		if _, err := claims.GetAPIKey(idClaims); err != nil {
+			return ErrInvalidAPIKey
+		}
+	}
 	return nil
 }
 
 func (c *LegacyClientImpl) Check(ctx context.Context, req *CheckRequest) (bool, error) {
 	ctx, span := c.tracer.Start(ctx, "LegacyClientImpl.Check")
 	defer span.End()
+	c.legacyUsage.report(ctx, SurfaceLegacyClient, "Check")
+
+	if req.multiAction() {
+		res, err := c.checkActions(ctx, span, req)
+		return res.Allowed, err
+	}
+	req = c.withAttributes(req)
+	res, err := c.check(ctx, span, req)
+	return res.Allowed, err
+}
+
+// check decides on the request, then audits and logs the decision.
+func (c *LegacyClientImpl) check(ctx context.Context, span trace.Span, req *CheckRequest) (CheckResult, error) {
+	start := time.Now()
+	source := SourceNone
+	res, err := c.decideMemoized(ctx, span, req, &source)
+	span.SetAttributes(attribute.String("reason", string(res.Reason)))
 
+	c.recordDecision(ctx, req, res, err, start, source)
+	return res, err
+}
+
+// recordDecision audits and logs the decision made on the request.
+func (c *LegacyClientImpl) recordDecision(ctx context.Context, req *CheckRequest, res CheckResult, err error, start time.Time, source DecisionSource) {
+	if c.auditor != nil {
+		c.auditor.audit(ctx, c, req, res, err)
+	}
+	if c.decisionLog != nil && req != nil {
+		c.logDecision(ctx, req, res, err, start, source)
+	}
+}
+
+// decide returns the decision on the request. The source is set when the permissions of the identity are retrieved.
+func (c *LegacyClientImpl) decide(ctx context.Context, span trace.Span, req *CheckRequest, source *DecisionSource) (CheckResult, error) {
 	if err := req.Validate(c.authCfg.accessTokenAuthEnabled); err != nil {
 		span.RecordError(err)
-		return false, err
+		return deny(ReasonInvalidRequest), err
 	}
 
 	if !c.validateNamespace(req.Caller, req.StackID) {
-		return false, nil
+		return deny(ReasonNamespaceMismatch), nil
 	}
 
 	accessClaims := req.Caller.GetAccess()
@@ -219,35 +529,65 @@ func (c *LegacyClientImpl) Check(ctx context.Context, req *CheckRequest) (bool,
 	if req.Resource != nil {
 		span.SetAttributes(attribute.String("resource", req.Resource.Scope()))
 		span.SetAttributes(attribute.Int("contextual", len(req.Contextual)))
+		span.SetAttributes(attribute.Int("ancestors", len(req.Ancestors)))
+	}
+	if req.Attributes != nil {
+		span.SetAttributes(attribute.String("group", req.Attributes.Group))
+		span.SetAttributes(attribute.String("verb", req.Attributes.Verb))
 	}
 	span.SetAttributes(attribute.Bool("with_user", identityClaims != nil && !identityClaims.IsNil()))
 
+	actor, impersonated := claims.GetActor(identityClaims)
+
+	// Anonymous callers have no permissions of their own
+	if claims.IsAnonymous(identityClaims) {
+		span.SetAttributes(attribute.Bool("anonymous", true))
+		if !c.anonymousServiceChecks || impersonated {
+			return deny(ReasonAnonymous), nil
+		}
+		identityClaims = nil
+	}
+
 	// No user => check on the service permissions
 	if identityClaims == nil || identityClaims.IsNil() {
 		// access token check is disabled => we can skip the authz service
 		if !c.authCfg.accessTokenAuthEnabled {
-			return true, nil
+			return allow(ReasonServicePermission), nil
 		}
 
 		if accessClaims == nil || accessClaims.IsNil() {
-			return false, ErrMissingCaller
+			return deny(ReasonError), ErrMissingCaller
+		}
+
+		// Bootstrap services are allowed without querying the authz service
+		if c.staticPolicy.Allows(accessClaims.Subject(), req.Action) {
+			span.SetAttributes(attribute.Bool("static_policy", true))
+			return allow(ReasonStaticPolicy), nil
 		}
 
 		perms := accessClaims.Permissions()
 		for _, p := range perms {
 			if p == req.Action {
-				return true, nil
+				return allow(ReasonServicePermission), nil
 			}
 		}
-		return false, nil
+		return deny(ReasonMissingServicePermission), nil
 	}
 
 	span.SetAttributes(attribute.String("subject", identityClaims.Subject()))
 
+	// API keys act on their own behalf, they cannot be impersonated
+	if claims.IsAPIKey(identityClaims) {
+		span.SetAttributes(attribute.Bool("api_key", true))
+		if impersonated {
+			return deny(ReasonImpersonatedAPIKey), nil
+		}
+	}
+
 	// Only check the service permissions if the access token check is enabled
 	if c.authCfg.accessTokenAuthEnabled {
 		if accessClaims == nil || accessClaims.IsNil() {
-			return false, ErrMissingCaller
+			return deny(ReasonError), ErrMissingCaller
 		}
 
 		// Make sure the service is allowed to perform the requested action
@@ -259,28 +599,55 @@ func (c *LegacyClientImpl) Check(ctx context.Context, req *CheckRequest) (bool,
 			}
 		}
 		if !serviceIsAllowedAction {
-			return false, nil
+			return deny(ReasonMissingDelegatedPermission), nil
 		}
 	}
 
-	res, err := c.retrievePermissions(ctx, req.StackID, identityClaims.Subject(), req.Action)
-	if err != nil {
-		span.RecordError(err)
-		return false, err
+	// Bootstrap identities are allowed without querying the authz service,
+	// as long as the service was delegated the action
+	if !impersonated && c.staticPolicy.Allows(identityClaims.Subject(), req.Action) {
+		span.SetAttributes(attribute.Bool("static_policy", true))
+		return allow(ReasonStaticPolicy), nil
 	}
 
-	// No permissions found
-	if !res.Found {
-		return false, nil
+	// Impersonation => make sure the impersonator delegated the requested action
+	if impersonated {
+		span.SetAttributes(attribute.String("impersonator", actor.Subject()))
+
+		actorIsAllowedAction := false
+		for _, p := range actor.DelegatedPermissions() {
+			if p == req.Action {
+				actorIsAllowedAction = true
+				break
+			}
+		}
+		if !actorIsAllowedAction {
+			return deny(ReasonMissingDelegatedPermission), nil
+		}
 	}
 
-	// Action check only
-	if req.Resource == nil {
-		return true, nil
+	res, err := c.retrievePermissions(ctx, req, identityClaims.Subject())
+	if err != nil {
+		span.RecordError(err)
+		// Emergency identities can bypass a failing backend
+		if c.breakGlass.allows(ctx, req, identityClaims, err) {
+			span.SetAttributes(attribute.Bool("break_glass", true))
+			return allow(ReasonBreakGlass), nil
+		}
+		return deny(ReasonError), err
+	}
+	*source = SourceService
+	if res.cached {
+		*source = SourceCache
 	}
 
 	// Check if the user has access to any of the requested resources
-	return res.Check(append(req.Contextual, *req.Resource)...), nil
+	resources := req.resources()
+	scope, allowed := res.match(resources...)
+	if allowed && c.usage != nil {
+		c.usage.Record(req.StackID, identityClaims.Subject(), req.Action, scope)
+	}
+	return scopeResult(res.Found, scope, allowed, resources), nil
 }
 
 func (c *LegacyClientImpl) validateNamespace(caller claims.AuthInfo, stackID int64) bool {
@@ -289,52 +656,179 @@ func (c *LegacyClientImpl) validateNamespace(caller claims.AuthInfo, stackID int
 	// Check both AccessToken and IDToken (if present) for namespace match
 	accessClaims := caller.GetAccess()
 	accessTokenMatch := !c.authCfg.accessTokenAuthEnabled ||
-		(accessClaims != nil && !accessClaims.IsNil() && claims.NamespaceMatches(accessClaims, expectedNamespace))
+		(accessClaims != nil && !accessClaims.IsNil() && c.nsMatcher(accessClaims, expectedNamespace))
 
 	idClaims := caller.GetIdentity()
-	idTokenMatch := idClaims == nil || idClaims.IsNil() || claims.NamespaceMatches(idClaims, expectedNamespace)
+	idTokenMatch := idClaims == nil || idClaims.IsNil() || c.nsMatcher(idClaims, expectedNamespace)
 
 	return accessTokenMatch && idTokenMatch
 }
 
-func (c *LegacyClientImpl) retrievePermissions(ctx context.Context, stackID int64, subject, action string) (*controller, error) {
+// retrievePermissions returns the permissions of the subject for the action of the request.
+func (c *LegacyClientImpl) retrievePermissions(ctx context.Context, req *CheckRequest, subject string) (*controller, error) {
 	ctx, span := c.tracer.Start(ctx, "LegacyClientImpl.retrievePermissions")
 	defer span.End()
 
+	stackID, action, maxStaleness := req.StackID, req.Action, req.MaxStaleness
+
 	span.SetAttributes(attribute.Int64("stack_id", stackID))
+	if maxStaleness > 0 {
+		span.SetAttributes(attribute.Int64("max_staleness_ms", maxStaleness.Milliseconds()))
+	}
 
 	// Check the cache
 	key := controllerCacheKey(stackID, subject, action)
 	ctrl, err := c.getCachedController(ctx, key)
-	if err == nil || !errors.Is(err, cache.ErrNotFound) {
-		return ctrl, err
+	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		return nil, err
 	}
-
-	// Instantiate a new context for the request
-	outCtx := newOutgoingContext(ctx)
-
-	readReq := &authzv1.ReadRequest{
-		StackId: stackID,
-		Action:  action,
-		Subject: subject,
+	// The permissions changed since they were cached (see Watch)
+	if ctrl != nil && c.invalidations.stale(stackID, subject, action, ctrl.FetchedAt) {
+		span.SetAttributes(attribute.Bool("invalidated", true))
+		ctrl = nil
+	}
+	if ctrl != nil && (maxStaleness <= 0 || time.Since(ctrl.FetchedAt) <= maxStaleness) {
+		ctrl.cached = true
+		if c.swr != nil && c.swr.expired(ctrl) {
+			span.SetAttributes(attribute.Bool("stale", true))
+			c.revalidate(ctx, req, subject, key)
+		}
+		return ctrl, nil
+	}
+
+	// Deduplicate the concurrent reads of the same permissions, on a cold cache. The read is detached from
+	// the check starting it, so that its cancellation does not fail the other checks waiting for the read.
+	fetchReq := req.detached()
+	results := c.singlef.DoChan(key, func() (interface{}, error) {
+		fetchCtx, cancel := detachedContext(ctx)
+		defer cancel()
+		return c.fetchPermissions(fetchCtx, span, fetchReq, subject, key)
+	})
+	select {
+	case res := <-results:
+		span.SetAttributes(attribute.Bool("shared", res.Shared))
+		ctrl, _ = res.Val.(*controller)
+		return ctrl, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
+}
 
-	// Query the authz service
-	resp, err := c.clientV1.Read(outCtx, readReq)
+// fetchPermissions reads the permissions of the subject from the authz service, and caches them.
+func (c *LegacyClientImpl) fetchPermissions(ctx context.Context, span trace.Span, req *CheckRequest, subject, key string) (*controller, error) {
+	stackID, action := req.StackID, req.Action
+
+	// Query the authz service. The permissions are as old as the read, for changes received meanwhile (see Watch).
+	fetchedAt := time.Now()
+	outCtx, cancel := c.outgoingContext(ctx, req)
+	resp, err := c.read(outCtx, c.readRequest(req, subject))
+	cancel()
+	if errors.Is(err, ErrCircuitOpen) {
+		span.SetAttributes(attribute.Bool("circuit_open", true))
+		return nil, err
+	}
+	if errors.Is(err, ErrRateLimited) {
+		span.SetAttributes(attribute.Bool("rate_limited", true))
+		return nil, err
+	}
 	if err != nil {
+		c.logger.Warn("failed to read the permissions", "stack_id", stackID, "action", action, "error", err)
 		return nil, ErrReadPermission
 	}
 
 	res := newController(resp)
+	res.FetchedAt = fetchedAt
 
 	// Cache the result
 	err = c.cacheController(ctx, key, res)
 	return res, err
 }
 
-// newOutgoingContext creates a new context that will be canceled when the input context is canceled.
-func newOutgoingContext(ctx context.Context) context.Context {
-	outCtx, cancel := context.WithCancel(context.Background())
+// outgoingContext returns the context of the reads of the permissions of the check, with its metadata.
+func (c *LegacyClientImpl) outgoingContext(ctx context.Context, req *CheckRequest) (context.Context, context.CancelFunc) {
+	// Instantiate a new context for the request
+	outCtx, cancel := newOutgoingContext(ctx)
+	for k, v := range req.Metadata {
+		outCtx = metadata.AppendToOutgoingContext(outCtx, MetadataPrefix+k, v)
+	}
+	return outCtx, cancel
+}
+
+// readRequest returns the read of the permissions of the subject for the action of the check.
+func (c *LegacyClientImpl) readRequest(req *CheckRequest, subject string) *authzv1.ReadRequest {
+	readReq := &authzv1.ReadRequest{
+		StackId: req.StackID,
+		Action:  req.Action,
+		Subject: subject,
+		// Let the service know it can serve the request from a replica
+		MaxStalenessMs: req.MaxStaleness.Milliseconds(),
+	}
+	if attrs := req.Attributes; attrs != nil {
+		readReq.Group = attrs.Group
+		readReq.Resource = attrs.Resource
+		readReq.Subresource = attrs.Subresource
+		readReq.Verb = attrs.Verb
+		readReq.Name = attrs.Name
+	}
+	// Let the service resolve the permissions granted to the memberships of the caller
+	readReq.Groups = claims.MembershipGroups(req.Caller)
+	readReq.Teams = claims.MembershipTeams(req.Caller)
+	return readReq
+}
+
+// read reads the permissions from the authz service, with the retry policy, the rate limit and through the circuit breaker, if any.
+func (c *LegacyClientImpl) read(ctx context.Context, req *authzv1.ReadRequest) (*authzv1.ReadResponse, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.readOnce(ctx, req)
+		if err == nil || c.retry == nil || attempt >= c.retry.MaxAttempts || !isTransient(err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		c.logger.Debug("retrying the read of the permissions", "attempt", attempt, "error", err)
+		if !c.retry.wait(ctx, attempt) {
+			return resp, err
+		}
+	}
+}
+
+func (c *LegacyClientImpl) readOnce(ctx context.Context, req *authzv1.ReadRequest) (*authzv1.ReadResponse, error) {
+	if c.authCfg.CheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.authCfg.CheckTimeout)
+		defer cancel()
+	}
+
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.breaker == nil {
+		return c.authzClient().Read(ctx, req)
+	}
+
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := c.authzClient().Read(ctx, req)
+	if c.breaker.record(err) {
+		c.logger.Warn("authz service circuit opened", "error", err)
+	}
+	return resp, err
+}
+
+// detachedContext returns a context for the reads outliving the check: it keeps the values of ctx,
+// not its cancellation, and is bounded by detachedReadTimeout.
+func detachedContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), detachedReadTimeout)
+}
+
+// newOutgoingContext creates a new context with the deadline and the span of the input context, but none of its
+// values (ex: the metadata of the incoming request). It is canceled when the input context is canceled, without
+// a goroutine per call. The returned function must be called to release it.
+func newOutgoingContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	outCtx := context.Background()
 
 	// Propagate the span into the new context
 	spanContext := trace.SpanContextFromContext(ctx)
@@ -342,16 +836,18 @@ func newOutgoingContext(ctx context.Context) context.Context {
 		outCtx = trace.ContextWithSpanContext(outCtx, spanContext)
 	}
 
-	go func() {
-		select {
-		case <-ctx.Done():
-			cancel()
-		case <-outCtx.Done():
-			// exit
-		}
-	}()
+	var cancel context.CancelFunc
+	if deadline, ok := ctx.Deadline(); ok {
+		outCtx, cancel = context.WithDeadline(outCtx, deadline)
+	} else {
+		outCtx, cancel = context.WithCancel(outCtx)
+	}
 
-	return outCtx
+	stop := context.AfterFunc(ctx, cancel)
+	return outCtx, func() {
+		stop()
+		cancel()
+	}
 }
 
 // -----
@@ -361,61 +857,84 @@ func newOutgoingContext(ctx context.Context) context.Context {
 type controller struct {
 	// Whether the requested action was found in the users' permissions
 	Found bool
-	// All the scopes the user has access to for the requested action
-	Scopes map[string]bool
-	// Wildcard per kinds
-	Wildcard map[string]bool
+	// All the scopes the user has access to for the requested action, wildcards included
+	grants *grants
+	// When the permissions were read from the authz service
+	FetchedAt time.Time
+	// How long the permissions can be cached, as hinted by the authz service.
+	// Zero means the cache default expiration.
+	TTL time.Duration
+	// Whether the permissions were read from the cache. Not cached itself.
+	cached bool
 }
 
 func newController(resp *authzv1.ReadResponse) *controller {
-	if resp == nil || !resp.Found {
+	if resp == nil {
 		return &controller{Found: false}
 	}
 
-	res := &controller{
-		Found:    true,
-		Scopes:   make(map[string]bool, len(resp.Data)),
-		Wildcard: make(map[string]bool, 2),
+	ttl := time.Duration(resp.TtlMs) * time.Millisecond
+	if ttl < 0 {
+		ttl = 0
 	}
+	if !resp.Found {
+		return &controller{Found: false, TTL: ttl}
+	}
+
+	scopes := make([]string, 0, len(resp.Data))
 	for _, o := range resp.Data {
-		kind, _, id := splitScope(o.Object)
-		if id == "*" {
-			res.Wildcard[kind] = true
-		} else {
-			res.Scopes[o.Object] = true
-		}
+		scopes = append(scopes, o.Object)
 	}
-	return res
+	return &controller{Found: true, grants: newGrants(scopes...), TTL: ttl}
 }
 
 func (r *controller) Check(resources ...Resource) bool {
+	_, ok := r.match(resources...)
+	return ok
+}
+
+// match returns whether the user has access to any of the resources, and the granted scope that matched.
+// The scope is empty for action checks only.
+func (r *controller) match(resources ...Resource) (string, bool) {
 	// the user has no permissions
 	if !r.Found {
-		return false
+		return "", false
 	}
 
 	// it's an action check only
 	if len(resources) == 0 {
-		return true
+		return "", true
 	}
 
 	// the user has no permissions
-	if len(r.Scopes) == 0 && len(r.Wildcard) == 0 {
-		return false
+	if r.grants.empty() {
+		return "", false
 	}
 
 	// the user has access to all resources
-	if r.Wildcard["*"] {
-		return true
+	if s, ok := r.grants.wildcard("*"); ok {
+		return s, true
 	}
 
 	// the user has access to the requested resources
 	for _, res := range resources {
-		if r.Wildcard[res.Kind] || r.Scopes[res.Scope()] {
-			return true
+		if s, ok := r.grants.wildcard(res.Kind); ok {
+			return s, true
+		}
+		if scope := res.Scope(); r.grants.contains(scope) {
+			return scope, true
 		}
 	}
-	return false
+	return "", false
+}
+
+// eachScope yields the granted scopes, until yield returns false.
+// Wildcards are yielded first, as granted (ex: "*", "dashboards:*" or "dashboards:uid:*").
+func (r *controller) eachScope(yield func(string) bool) {
+	if !r.Found {
+		return
+	}
+	r.grants.each(yield)
 }
 
 // -----
@@ -423,7 +942,7 @@ func (r *controller) Check(resources ...Resource) bool {
 // -----
 
 func controllerCacheKey(stackID int64, subject, action string) string {
-	return fmt.Sprintf("read-%d-%s-%s", stackID, subject, action)
+	return cache.NewCacheKey("read", strconv.FormatInt(stackID, 10), subject, action).String()
 }
 
 func (c *LegacyClientImpl) cacheController(ctx context.Context, key string, ctrl *controller) error {
@@ -434,14 +953,21 @@ func (c *LegacyClientImpl) cacheController(ctx context.Context, key string, ctrl
 		return nil
 	}
 
-	buf := bytes.Buffer{}
-	err := gob.NewEncoder(&buf).Encode(*ctrl)
+	data, err := encodeController(ctrl)
 	if err != nil {
 		return err
 	}
 
-	// Cache with default expiry
-	return c.cache.Set(ctx, key, buf.Bytes(), cache.DefaultExpiration)
+	// Cache with the expiry hinted by the authz service, if any
+	expiry := cache.DefaultExpiration
+	if ctrl.TTL > 0 {
+		expiry = ctrl.TTL
+	}
+	// Keep the stale permissions to serve them while they are refreshed
+	if c.swr != nil {
+		expiry = c.swr.freshness(ctrl) + c.swr.Staleness
+	}
+	return c.cache.Set(ctx, key, data, expiry)
 }
 
 func (c *LegacyClientImpl) getCachedController(ctx context.Context, key string) (*controller, error) {
@@ -453,10 +979,11 @@ func (c *LegacyClientImpl) getCachedController(ctx context.Context, key string)
 		return nil, err
 	}
 
-	var ctrl controller
-	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&ctrl)
+	ctrl, err := decodeController(data)
 	if err != nil {
-		return nil, err
+		// Read the permissions again rather than failing the checks, ex: during the rollout of a new encoding
+		c.logger.Debug("failed to decode the cached permissions", "error", err)
+		return nil, cache.ErrNotFound
 	}
-	return &ctrl, nil
+	return ctrl, nil
 }
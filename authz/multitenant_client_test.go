@@ -1,18 +1,28 @@
 package authz
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
+	"runtime"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/go-jose/go-jose/v3/jwt"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/trace/noop"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 
+	"github.com/grafana/authlib/actions"
 	"github.com/grafana/authlib/authn"
 	authzv1 "github.com/grafana/authlib/authz/proto/v1"
 	"github.com/grafana/authlib/cache"
 	"github.com/grafana/authlib/claims"
+	"github.com/grafana/authlib/internal/logger"
 )
 
 func TestNewController(t *testing.T) {
@@ -46,9 +56,8 @@ func TestNewController(t *testing.T) {
 				Data:  []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}},
 			},
 			want: &controller{
-				Found:    true,
-				Scopes:   map[string]bool{"dashboards:uid:1": true},
-				Wildcard: map[string]bool{},
+				Found:  true,
+				grants: newGrants("dashboards:uid:1"),
 			},
 		},
 		{
@@ -58,9 +67,8 @@ func TestNewController(t *testing.T) {
 				Data:  []*authzv1.ReadResponse_Data{{Object: "dashboards:*"}},
 			},
 			want: &controller{
-				Found:    true,
-				Scopes:   map[string]bool{},
-				Wildcard: map[string]bool{"dashboards": true},
+				Found:  true,
+				grants: newGrants("dashboards:*"),
 			},
 		},
 		{
@@ -70,9 +78,8 @@ func TestNewController(t *testing.T) {
 				Data:  []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:*"}},
 			},
 			want: &controller{
-				Found:    true,
-				Scopes:   map[string]bool{},
-				Wildcard: map[string]bool{"dashboards": true},
+				Found:  true,
+				grants: newGrants("dashboards:uid:*"),
 			},
 		},
 		{
@@ -82,9 +89,8 @@ func TestNewController(t *testing.T) {
 				Data:  []*authzv1.ReadResponse_Data{{Object: "dashboards:*"}, {Object: "dashboards:uid:1"}},
 			},
 			want: &controller{
-				Found:    true,
-				Scopes:   map[string]bool{"dashboards:uid:1": true},
-				Wildcard: map[string]bool{"dashboards": true},
+				Found:  true,
+				grants: newGrants("dashboards:uid:1", "dashboards:*"),
 			},
 		},
 		{
@@ -94,9 +100,8 @@ func TestNewController(t *testing.T) {
 				Data:  []*authzv1.ReadResponse_Data{{Object: "dashboards:*"}, {Object: "folders:*"}},
 			},
 			want: &controller{
-				Found:    true,
-				Scopes:   map[string]bool{},
-				Wildcard: map[string]bool{"dashboards": true, "folders": true},
+				Found:  true,
+				grants: newGrants("dashboards:*", "folders:*"),
 			},
 		},
 		{
@@ -106,10 +111,33 @@ func TestNewController(t *testing.T) {
 				Data:  []*authzv1.ReadResponse_Data{{Object: "*"}},
 			},
 			want: &controller{
-				Found:    true,
-				Scopes:   map[string]bool{},
-				Wildcard: map[string]bool{"*": true},
+				Found:  true,
+				grants: newGrants("*"),
+			},
+		},
+		{
+			name: "Service hints the TTL",
+			resp: &authzv1.ReadResponse{
+				Found: true,
+				TtlMs: 1500,
 			},
+			want: &controller{Found: true, TTL: 1500 * time.Millisecond},
+		},
+		{
+			name: "Service hints the TTL of a missing action",
+			resp: &authzv1.ReadResponse{
+				Found: false,
+				TtlMs: 500,
+			},
+			want: &controller{Found: false, TTL: 500 * time.Millisecond},
+		},
+		{
+			name: "Service hints a negative TTL",
+			resp: &authzv1.ReadResponse{
+				Found: true,
+				TtlMs: -1,
+			},
+			want: &controller{Found: true},
 		},
 	}
 	for _, tt := range tests {
@@ -117,15 +145,8 @@ func TestNewController(t *testing.T) {
 			got := newController(tt.resp)
 
 			require.Equal(t, tt.want.Found, got.Found)
-			require.Len(t, got.Scopes, len(tt.want.Scopes))
-			require.Len(t, got.Wildcard, len(tt.want.Wildcard))
-
-			for k, v := range tt.want.Scopes {
-				require.Equal(t, v, got.Scopes[k])
-			}
-			for k, v := range tt.want.Wildcard {
-				require.Equal(t, v, got.Wildcard[k])
-			}
+			require.Equal(t, tt.want.TTL, got.TTL)
+			require.Equal(t, grantedScopes(tt.want), grantedScopes(got))
 		})
 	}
 }
@@ -165,7 +186,7 @@ func TestController_Check(t *testing.T) {
 			name: "User has action on a specific scope",
 			ctrl: controller{
 				Found:  true,
-				Scopes: map[string]bool{"dashboards:uid:1": true},
+				grants: newGrants("dashboards:uid:1"),
 			},
 			resources: []Resource{{Kind: "dashboards", Attr: "uid", ID: "1"}},
 			want:      true,
@@ -174,7 +195,7 @@ func TestController_Check(t *testing.T) {
 			name: "User has action on a specific scope but not on the requested resource",
 			ctrl: controller{
 				Found:  true,
-				Scopes: map[string]bool{"dashboards:uid:1": true},
+				grants: newGrants("dashboards:uid:1"),
 			},
 			resources: []Resource{{Kind: "dashboards", Attr: "uid", ID: "2"}},
 			want:      false,
@@ -182,8 +203,8 @@ func TestController_Check(t *testing.T) {
 		{
 			name: "User has action on a wildcard",
 			ctrl: controller{
-				Found:    true,
-				Wildcard: map[string]bool{"dashboards": true},
+				Found:  true,
+				grants: newGrants("dashboards:*"),
 			},
 			resources: []Resource{{Kind: "dashboards", Attr: "uid", ID: "1"}},
 			want:      true,
@@ -191,8 +212,8 @@ func TestController_Check(t *testing.T) {
 		{
 			name: "User has action on a wildcard but not on the requested resource",
 			ctrl: controller{
-				Found:    true,
-				Wildcard: map[string]bool{"dashboards": true},
+				Found:  true,
+				grants: newGrants("dashboards:*"),
 			},
 			resources: []Resource{{Kind: "folders", Attr: "uid", ID: "1"}},
 			want:      false,
@@ -200,8 +221,8 @@ func TestController_Check(t *testing.T) {
 		{
 			name: "User has action on the master wildcard",
 			ctrl: controller{
-				Found:    true,
-				Wildcard: map[string]bool{"*": true},
+				Found:  true,
+				grants: newGrants("*"),
 			},
 			resources: []Resource{{Kind: "dashboards", Attr: "uid", ID: "1"}},
 			want:      true,
@@ -209,8 +230,8 @@ func TestController_Check(t *testing.T) {
 		{
 			name: "User can access all resources of a kind thanks to wildcard",
 			ctrl: controller{
-				Found:    true,
-				Wildcard: map[string]bool{"dashboards": true},
+				Found:  true,
+				grants: newGrants("dashboards:*"),
 			},
 			resources: []Resource{{Kind: "dashboards", Attr: "*", ID: "*"}},
 			want:      true,
@@ -218,9 +239,8 @@ func TestController_Check(t *testing.T) {
 		{
 			name: "User has action on one of the requested resources",
 			ctrl: controller{
-				Found:    true,
-				Scopes:   map[string]bool{"folders:uid:1": true},
-				Wildcard: map[string]bool{},
+				Found:  true,
+				grants: newGrants("folders:uid:1"),
 			},
 			resources: []Resource{{Kind: "dashboards", Attr: "uid", ID: "1"}, {Kind: "folders", Attr: "uid", ID: "1"}},
 			want:      true,
@@ -228,9 +248,8 @@ func TestController_Check(t *testing.T) {
 		{
 			name: "User has action on none of the requested resources",
 			ctrl: controller{
-				Found:    true,
-				Scopes:   map[string]bool{"folders:uid:2": true},
-				Wildcard: map[string]bool{},
+				Found:  true,
+				grants: newGrants("folders:uid:2"),
 			},
 			resources: []Resource{{Kind: "dashboards", Attr: "uid", ID: "1"}, {Kind: "folders", Attr: "uid", ID: "1"}},
 			want:      false,
@@ -516,6 +535,65 @@ func TestLegacyClientImpl_Check(t *testing.T) {
 	}
 }
 
+func TestLegacyClientImpl_Check_Ancestors(t *testing.T) {
+	readResponse := func(scopes ...string) *authzv1.ReadResponse {
+		res := &authzv1.ReadResponse{Found: true}
+		for _, s := range scopes {
+			res.Data = append(res.Data, &authzv1.ReadResponse_Data{Object: s})
+		}
+		return res
+	}
+	caller := &authn.AuthInfo{
+		AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+			Claims: &jwt.Claims{Subject: "service"},
+			Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+		}),
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: "user:1"},
+			Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+		}),
+	}
+	req := &CheckRequest{
+		Caller:    caller,
+		StackID:   12,
+		Action:    "dashboards:read",
+		Resource:  &Resource{Kind: "dashboards", Attr: "uid", ID: "1"},
+		Ancestors: []Resource{{Kind: "folders", Attr: "uid", ID: "child"}, {Kind: "folders", Attr: "uid", ID: "root"}},
+	}
+
+	t.Run("should grant the permissions of the closest ancestor", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = readResponse("folders:uid:root", "folders:uid:child")
+
+		got, err := client.CheckDetailed(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, got.Allowed)
+		require.Equal(t, "folders:uid:child", got.Scope)
+	})
+
+	t.Run("should grant the permissions of the root", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = readResponse("folders:uid:root")
+
+		got, err := client.Check(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, got)
+	})
+
+	t.Run("should deny without permission on the resource or its ancestors", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = readResponse("folders:uid:other")
+
+		got, err := client.Check(context.Background(), req)
+		require.NoError(t, err)
+		require.False(t, got)
+	})
+
+	t.Run("should ignore the ancestors of action checks", func(t *testing.T) {
+		require.Empty(t, (&CheckRequest{Ancestors: req.Ancestors}).resources())
+	})
+}
+
 func TestLegacyClientImpl_Check_OnPremFmt(t *testing.T) {
 	client, authz := setupLegacyClient()
 	client.namespaceFmt = claims.OrgNamespaceFormatter
@@ -543,6 +621,82 @@ func TestLegacyClientImpl_Check_OnPremFmt(t *testing.T) {
 	require.True(t, got)
 }
 
+func TestLegacyClientImpl_Check_CacheTTL(t *testing.T) {
+	caller := &authn.AuthInfo{
+		AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+			Claims: &jwt.Claims{Subject: "service"},
+			Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+		}),
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: "user:1"},
+			Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+		}),
+	}
+
+	tests := []struct {
+		name       string
+		ttlMs      int64
+		wantExpiry time.Duration
+	}{
+		{name: "should use the cache default expiration", wantExpiry: cache.DefaultExpiration},
+		{name: "should use the ttl hinted by the service", ttlMs: 200, wantExpiry: 200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, authz := setupLegacyClient()
+			cw := &cacheWrap{cache: client.cache}
+			client.cache = cw
+			authz.res = &authzv1.ReadResponse{Found: true, TtlMs: tt.ttlMs}
+
+			got, err := client.Check(context.Background(), &CheckRequest{Caller: caller, StackID: 12, Action: "dashboards:read"})
+			require.NoError(t, err)
+			require.True(t, got)
+			require.Equal(t, 1, cw.successWriteCnt)
+			require.Equal(t, tt.wantExpiry, cw.lastExpiry)
+		})
+	}
+}
+
+func TestLegacyClientImpl_Check_OrgNamespaceMatcher(t *testing.T) {
+	caller := func(namespace string) *authn.AuthInfo {
+		return &authn.AuthInfo{
+			AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+				Claims: &jwt.Claims{Subject: "service"},
+				Rest:   authn.AccessTokenClaims{Namespace: "*", DelegatedPermissions: []string{"dashboards:read"}},
+			}),
+			IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+				Claims: &jwt.Claims{Subject: "user:1"},
+				Rest:   authn.IDTokenClaims{Namespace: namespace},
+			}),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		orgID     int64
+		want      bool
+	}{
+		{name: "default org", namespace: "default", orgID: 1, want: true},
+		{name: "default org with org id", namespace: "org-1", orgID: 1, want: true},
+		{name: "other org", namespace: "org-2", orgID: 2, want: true},
+		{name: "org mismatch", namespace: "org-2", orgID: 3, want: false},
+		{name: "stack namespace", namespace: "stacks-1", orgID: 1, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, authz := setupLegacyClient()
+			client.namespaceFmt = claims.OrgIDNamespaceFormatter
+			WithNamespaceMatcherLCOption(claims.OrgNamespaceMatches)(client)
+			authz.res = &authzv1.ReadResponse{Found: true}
+
+			got, err := client.Check(context.Background(), &CheckRequest{Caller: caller(tt.namespace), StackID: tt.orgID, Action: "dashboards:read"})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestLegacyClientImpl_Check_Cache(t *testing.T) {
 	client, authz := setupLegacyClient()
 	authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
@@ -573,11 +727,7 @@ func TestLegacyClientImpl_Check_Cache(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, ctrl)
 	require.True(t, ctrl.Found)
-	scopes := map[string]bool{"dashboards:uid:1": true}
-	require.Len(t, ctrl.Scopes, len(scopes))
-	for k, v := range scopes {
-		require.Equal(t, v, ctrl.Scopes[k])
-	}
+	require.Equal(t, []string{"dashboards:uid:1"}, grantedScopes(ctrl))
 
 	// Change the response to make sure the cache is used
 	authz.res = &authzv1.ReadResponse{Found: false}
@@ -677,6 +827,241 @@ func TestLegacyClientImpl_Check_DisableAccessToken(t *testing.T) {
 	}
 }
 
+func TestLegacyClientImpl_Check_MaxStaleness(t *testing.T) {
+	req := &CheckRequest{
+		Caller: &authn.AuthInfo{
+			AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+				Claims: &jwt.Claims{Subject: "service"},
+				Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+			}),
+			IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+				Claims: &jwt.Claims{Subject: "user:1"},
+				Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+			}),
+		},
+		StackID:  12,
+		Action:   "dashboards:read",
+		Resource: &Resource{Kind: "dashboards", Attr: "uid", ID: "1"},
+	}
+	key := controllerCacheKey(12, "user:1", "dashboards:read")
+
+	t.Run("should use cached permissions within the staleness bound", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		err := client.cacheController(context.Background(), key, &controller{
+			Found:     true,
+			grants:    newGrants("dashboards:uid:1"),
+			FetchedAt: time.Now().Add(-time.Second),
+		})
+		require.NoError(t, err)
+
+		req := *req
+		req.MaxStaleness = time.Minute
+		got, err := client.Check(context.Background(), &req)
+		require.NoError(t, err)
+		require.True(t, got)
+		require.Equal(t, 0, authz.reads)
+	})
+
+	t.Run("should refresh cached permissions beyond the staleness bound", func(t *testing.T) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: false}
+		err := client.cacheController(context.Background(), key, &controller{
+			Found:     true,
+			grants:    newGrants("dashboards:uid:1"),
+			FetchedAt: time.Now().Add(-time.Minute),
+		})
+		require.NoError(t, err)
+
+		req := *req
+		req.MaxStaleness = time.Second
+		got, err := client.Check(context.Background(), &req)
+		require.NoError(t, err)
+		require.False(t, got)
+		require.Equal(t, 1, authz.reads)
+		require.Equal(t, int64(1000), authz.lastReq.MaxStalenessMs)
+
+		// The refreshed permissions are cached
+		got, err = client.Check(context.Background(), &req)
+		require.NoError(t, err)
+		require.False(t, got)
+		require.Equal(t, 1, authz.reads)
+	})
+}
+
+func TestNewLegacyClient_DeclaredActions(t *testing.T) {
+	insecureOpt := WithGrpcDialOptionsLCOption(grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	_, err := NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: "localhost:10000"}, insecureOpt,
+		WithDeclaredActionsLCOption(actions.DashboardsRead, actions.FoldersRead))
+	require.NoError(t, err)
+
+	_, err = NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: "localhost:10000"}, insecureOpt,
+		WithDeclaredActionsLCOption(actions.DashboardsRead, "dashbords:write"))
+	require.ErrorIs(t, err, actions.ErrUnknownAction)
+}
+
+func TestLegacyClientImpl_Reload(t *testing.T) {
+	t.Run("should swap the connection when the remote address changes", func(t *testing.T) {
+		client, err := NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: "localhost:10000"},
+			WithGrpcDialOptionsLCOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
+		require.NoError(t, err)
+		previous := client.grpcConn
+
+		require.NoError(t, client.Reload(&MultiTenantClientConfig{RemoteAddress: "localhost:10000"}))
+		require.Equal(t, previous, client.grpcConn)
+
+		require.NoError(t, client.Reload(&MultiTenantClientConfig{RemoteAddress: "localhost:10001"}))
+		require.NotEqual(t, previous, client.grpcConn)
+		require.Equal(t, "localhost:10001", client.remoteAddress)
+		require.Equal(t, connectivity.Shutdown, previous.(*grpc.ClientConn).GetState())
+	})
+
+	t.Run("should not reload a provided connection", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		err := client.Reload(&MultiTenantClientConfig{RemoteAddress: "localhost:10001"})
+		require.ErrorIs(t, err, ErrInvalidReload)
+	})
+
+	t.Run("should require a remote address", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		err := client.Reload(&MultiTenantClientConfig{})
+		require.ErrorIs(t, err, ErrMissingConfig)
+	})
+}
+
+func TestLegacyClientImpl_Check_Impersonation(t *testing.T) {
+	caller := func(actor *authn.ActorClaims) *authn.AuthInfo {
+		return &authn.AuthInfo{
+			AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+				Claims: &jwt.Claims{Subject: "service"},
+				Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read", "dashboards:write"}},
+			}),
+			IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+				Claims: &jwt.Claims{Subject: "user:2"},
+				Rest:   authn.IDTokenClaims{Namespace: "stacks-12", Actor: actor},
+			}),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		actor   *authn.ActorClaims
+		action  string
+		found   bool
+		want    bool
+		wantErr error
+	}{
+		{
+			name:   "impersonator delegated the action and subject has it",
+			actor:  &authn.ActorClaims{Subject: "user:1", DelegatedPermissions: []string{"dashboards:read"}},
+			action: "dashboards:read",
+			found:  true,
+			want:   true,
+		},
+		{
+			name:   "impersonator delegated the action but subject does not have it",
+			actor:  &authn.ActorClaims{Subject: "user:1", DelegatedPermissions: []string{"dashboards:read"}},
+			action: "dashboards:read",
+			found:  false,
+			want:   false,
+		},
+		{
+			name:   "impersonator did not delegate the action",
+			actor:  &authn.ActorClaims{Subject: "user:1", DelegatedPermissions: []string{"dashboards:read"}},
+			action: "dashboards:write",
+			found:  true,
+			want:   false,
+		},
+		{
+			name:    "impersonator without subject",
+			actor:   &authn.ActorClaims{DelegatedPermissions: []string{"dashboards:read"}},
+			action:  "dashboards:read",
+			wantErr: ErrMissingSubject,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, authz := setupLegacyClient()
+			authz.res = &authzv1.ReadResponse{Found: tt.found}
+
+			got, err := client.Check(context.Background(), &CheckRequest{Caller: caller(tt.actor), StackID: 12, Action: tt.action})
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			if authz.reads > 0 {
+				require.Equal(t, "user:2", authz.lastReq.Subject)
+			}
+		})
+	}
+}
+
+func TestLegacyClientImpl_Check_APIKey(t *testing.T) {
+	caller := func(subject string, actor *authn.ActorClaims) *authn.AuthInfo {
+		return &authn.AuthInfo{
+			AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+				Claims: &jwt.Claims{Subject: "service"},
+				Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+			}),
+			IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+				Claims: &jwt.Claims{Subject: subject},
+				Rest:   authn.IDTokenClaims{Namespace: "stacks-12", Type: claims.TypeAPIKey, Actor: actor},
+			}),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		caller  *authn.AuthInfo
+		found   bool
+		want    bool
+		wantErr error
+	}{
+		{
+			name:   "api key has the action",
+			caller: caller("api-key:42", nil),
+			found:  true,
+			want:   true,
+		},
+		{
+			name:   "api key does not have the action",
+			caller: caller("api-key:42", nil),
+			found:  false,
+			want:   false,
+		},
+		{
+			name:    "api key without key id",
+			caller:  caller("api-key:", nil),
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name:   "api key cannot be impersonated",
+			caller: caller("api-key:42", &authn.ActorClaims{Subject: "user:1", DelegatedPermissions: []string{"dashboards:read"}}),
+			found:  true,
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, authz := setupLegacyClient()
+			authz.res = &authzv1.ReadResponse{Found: tt.found}
+
+			got, err := client.Check(context.Background(), &CheckRequest{Caller: tt.caller, StackID: 12, Action: "dashboards:read"})
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			if authz.reads > 0 {
+				require.Equal(t, "api-key:42", authz.lastReq.Subject)
+			}
+		})
+	}
+}
+
 func setupLegacyClient() (*LegacyClientImpl, *FakeAuthzServiceClient) {
 	fakeClient := &FakeAuthzServiceClient{}
 	return &LegacyClientImpl{
@@ -684,14 +1069,296 @@ func setupLegacyClient() (*LegacyClientImpl, *FakeAuthzServiceClient) {
 		clientV1:     fakeClient,
 		cache:        cache.NewLocalCache(cache.Config{}),
 		namespaceFmt: claims.CloudNamespaceFormatter,
+		nsMatcher:    claims.NamespaceMatches,
+		attrsMapper:  DefaultAttributesMapper,
 		tracer:       noop.NewTracerProvider().Tracer("noopTracer"),
+		logger:       logger.Nop{},
 	}, fakeClient
 }
 
 type FakeAuthzServiceClient struct {
-	res *authzv1.ReadResponse
+	res     *authzv1.ReadResponse
+	reads   int
+	lastReq *authzv1.ReadRequest
 }
 
 func (f *FakeAuthzServiceClient) Read(ctx context.Context, in *authzv1.ReadRequest, opts ...grpc.CallOption) (*authzv1.ReadResponse, error) {
+	f.reads++
+	f.lastReq = in
 	return f.res, nil
 }
+
+func TestLegacyClientImpl_Check_MappedExternalClaims(t *testing.T) {
+	src := &authn.Claims[authn.ExternalClaims]{
+		Claims: &jwt.Claims{Subject: "jdoe@example.org"},
+		Rest:   authn.ExternalClaims{"uid": "okta-1", "groups": []any{"Viewer"}},
+	}
+	caller, err := authn.MapExternalClaims(context.Background(), src,
+		authn.OktaClaimsMapper("stacks-12", map[string][]string{"Viewer": {"dashboards:read"}}))
+	require.NoError(t, err)
+
+	client, authz := setupLegacyClient()
+	authz.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:*"}}}
+
+	res, err := client.CheckDetailed(context.Background(), &CheckRequest{Caller: caller, StackID: 12, Action: "dashboards:read"})
+	require.NoError(t, err)
+	require.True(t, res.Allowed)
+	require.Equal(t, "user:okta-1", authz.lastReq.Subject)
+
+	// The roles of the user do not delegate the action
+	res, err = client.CheckDetailed(context.Background(), &CheckRequest{Caller: caller, StackID: 12, Action: "dashboards:write"})
+	require.NoError(t, err)
+	require.False(t, res.Allowed)
+	require.Equal(t, ReasonMissingDelegatedPermission, res.Reason)
+}
+
+func TestLegacyClientImpl_Check_Anonymous(t *testing.T) {
+	caller := func(namespace string) claims.AuthInfo {
+		return &anonymousCaller{
+			AuthInfo: &authn.AuthInfo{
+				AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+					Claims: &jwt.Claims{Subject: "service"},
+					Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", Permissions: []string{"dashboards:read"}},
+				}),
+			},
+			identity: claims.NewAnonymousIdentity(namespace),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		caller    claims.AuthInfo
+		action    string
+		allowAnon bool
+		want      bool
+	}{
+		{
+			name:   "should deny anonymous callers by default",
+			caller: caller("stacks-12"),
+			action: "dashboards:read",
+			want:   false,
+		},
+		{
+			name:      "should allow anonymous callers with the service permission",
+			caller:    caller("stacks-12"),
+			action:    "dashboards:read",
+			allowAnon: true,
+			want:      true,
+		},
+		{
+			name:      "should deny anonymous callers without the service permission",
+			caller:    caller("stacks-12"),
+			action:    "dashboards:write",
+			allowAnon: true,
+			want:      false,
+		},
+		{
+			name:      "should deny anonymous callers of another namespace",
+			caller:    caller("stacks-13"),
+			action:    "dashboards:read",
+			allowAnon: true,
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, authz := setupLegacyClient()
+			if tt.allowAnon {
+				WithAnonymousServiceChecksLCOption()(client)
+			}
+
+			got, err := client.Check(context.Background(), &CheckRequest{Caller: tt.caller, StackID: 12, Action: tt.action})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, 0, authz.reads)
+		})
+	}
+}
+
+type anonymousCaller struct {
+	*authn.AuthInfo
+	identity claims.IdentityClaims
+}
+
+func (a *anonymousCaller) GetIdentity() claims.IdentityClaims {
+	return a.identity
+}
+
+func TestControllerCacheKey(t *testing.T) {
+	require.NotEqual(t, controllerCacheKey(12, "user:1-dashboards", "read"), controllerCacheKey(12, "user:1", "dashboards-read"))
+	require.NotEqual(t, controllerCacheKey(1, "2-user:1", "read"), controllerCacheKey(12, "user:1", "read"))
+	require.Equal(t, controllerCacheKey(12, "user:1", "read"), controllerCacheKey(12, "user:1", "read"))
+}
+
+func TestLegacyClientImpl_Check_Logger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	client, _ := setupLegacyClient()
+	client.clientV1 = failingAuthzServiceClient{}
+	WithLoggerLCOption(slog.New(slog.NewTextHandler(buf, nil)))(client)
+
+	_, err := client.Check(context.Background(), inProcessCheckRequest())
+	require.ErrorIs(t, err, ErrReadPermission)
+	require.Contains(t, buf.String(), "failed to read the permissions")
+	require.Contains(t, buf.String(), "unavailable")
+}
+
+// gatedAuthzServiceClient blocks the reads until the gate is closed.
+type gatedAuthzServiceClient struct {
+	gate  chan struct{}
+	reads atomic.Int32
+}
+
+func (f *gatedAuthzServiceClient) Read(ctx context.Context, _ *authzv1.ReadRequest, _ ...grpc.CallOption) (*authzv1.ReadResponse, error) {
+	f.reads.Add(1)
+	<-f.gate
+	return &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}, nil
+}
+
+func TestLegacyClientImpl_Check_Singleflight(t *testing.T) {
+	type result struct {
+		allowed bool
+		err     error
+	}
+	check := func(ctx context.Context, client *LegacyClientImpl, results chan<- result) {
+		allowed, err := client.Check(ctx, inProcessCheckRequest())
+		results <- result{allowed: allowed, err: err}
+	}
+
+	t.Run("should share the read of the concurrent checks", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		authz := &gatedAuthzServiceClient{gate: make(chan struct{})}
+		client.clientV1 = authz
+
+		const callers = 10
+		results := make(chan result, callers)
+		for i := 0; i < callers; i++ {
+			go check(context.Background(), client, results)
+		}
+
+		// The callers join the in-flight read, or find the permissions cached once it is released
+		require.Eventually(t, func() bool { return authz.reads.Load() == 1 }, time.Second, time.Millisecond)
+		close(authz.gate)
+
+		for i := 0; i < callers; i++ {
+			res := <-results
+			require.NoError(t, res.err)
+			require.True(t, res.allowed)
+		}
+		require.Equal(t, int32(1), authz.reads.Load(), "concurrent checks should share the read")
+	})
+
+	t.Run("should not fail the other checks when the first one is canceled", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		authz := &gatedAuthzServiceClient{gate: make(chan struct{})}
+		client.clientV1 = authz
+
+		ctx, cancel := context.WithCancel(context.Background())
+		first := make(chan result, 1)
+		go check(ctx, client, first)
+		require.Eventually(t, func() bool { return authz.reads.Load() == 1 }, time.Second, time.Millisecond)
+
+		second := make(chan result, 1)
+		go check(context.Background(), client, second)
+
+		cancel()
+		res := <-first
+		require.ErrorIs(t, res.err, context.Canceled)
+
+		close(authz.gate)
+		res = <-second
+		require.NoError(t, res.err)
+		require.True(t, res.allowed)
+		require.Equal(t, int32(1), authz.reads.Load())
+	})
+}
+
+type ctxKey struct{}
+
+func TestNewOutgoingContext(t *testing.T) {
+	t.Run("should detach the values and keep the deadline", func(t *testing.T) {
+		deadline := time.Now().Add(time.Minute)
+		ctx, cancel := context.WithDeadline(context.WithValue(context.Background(), ctxKey{}, "value"), deadline)
+		defer cancel()
+
+		outCtx, release := newOutgoingContext(ctx)
+		defer release()
+		require.Nil(t, outCtx.Value(ctxKey{}))
+		got, ok := outCtx.Deadline()
+		require.True(t, ok)
+		require.Equal(t, deadline, got)
+	})
+
+	t.Run("should be canceled with the input context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		outCtx, release := newOutgoingContext(ctx)
+		defer release()
+
+		cancel()
+		select {
+		case <-outCtx.Done():
+		case <-time.After(time.Second):
+			t.Fatal("the outgoing context was not canceled")
+		}
+		require.ErrorIs(t, outCtx.Err(), context.Canceled)
+	})
+
+	t.Run("should be released without canceling the input context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		outCtx, release := newOutgoingContext(ctx)
+		release()
+		require.ErrorIs(t, outCtx.Err(), context.Canceled)
+		require.NoError(t, ctx.Err())
+	})
+
+	t.Run("should not start a goroutine per call", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		before := runtime.NumGoroutine()
+		releases := make([]context.CancelFunc, 0, 100)
+		for i := 0; i < 100; i++ {
+			outCtx, release := newOutgoingContext(ctx)
+			// gRPC derives cancelable contexts from the outgoing context
+			_, stop := context.WithTimeout(metadata.AppendToOutgoingContext(outCtx, "k", "v"), time.Minute)
+			releases = append(releases, release, stop)
+		}
+		require.LessOrEqual(t, runtime.NumGoroutine(), before)
+
+		for _, release := range releases {
+			release()
+		}
+	})
+}
+
+func BenchmarkNewOutgoingContext(b *testing.B) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, release := newOutgoingContext(ctx)
+		release()
+	}
+}
+
+func BenchmarkLegacyClientImpl_Check_Miss(b *testing.B) {
+	client, fake := setupLegacyClient()
+	fake.res = &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}
+	reqs := make([]*CheckRequest, b.N)
+	for i := range reqs {
+		reqs[i] = userCheckRequest(i)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.Check(ctx, reqs[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -161,7 +161,7 @@ func MetadataNamespaceExtractor(key string) NamespaceExtractor {
 // This function can be used with UnaryAuthorizeInterceptor and StreamAuthorizeInterceptor.
 func NamespaceAuthorizationFunc(na NamespaceAccessChecker, nsExtract NamespaceExtractor) AuthorizeFunc {
 	return func(ctx context.Context) error {
-		caller, ok := claims.From(ctx)
+		caller, ok := claims.AuthInfoFrom(ctx)
 		if !ok {
 			return ErrMissingCaller
 		}
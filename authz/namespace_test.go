@@ -53,6 +53,28 @@ func TestNamespaceAccessCheckerImpl_ValidateAccessTokenOnly(t *testing.T) {
 				AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{Rest: authn.AccessTokenClaims{Namespace: "*"}}),
 			},
 		},
+		{
+			name:         "access token stacks pattern match",
+			namespaceFmt: claims.CloudNamespaceFormatter,
+			caller: &authn.AuthInfo{
+				AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{Rest: authn.AccessTokenClaims{Namespace: "stacks-*"}}),
+			},
+		},
+		{
+			name:         "access token namespace list match",
+			namespaceFmt: claims.CloudNamespaceFormatter,
+			caller: &authn.AuthInfo{
+				AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{Rest: authn.AccessTokenClaims{Namespace: "stacks-11,stacks-12"}}),
+			},
+		},
+		{
+			name:         "access token stacks pattern mismatch for org checker",
+			namespaceFmt: claims.OrgNamespaceFormatter,
+			caller: &authn.AuthInfo{
+				AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{Rest: authn.AccessTokenClaims{Namespace: "stacks-*"}}),
+			},
+			wantErr: ErrorAccessTokenNamespaceMismatch,
+		},
 		{
 			name:         "access token mismatch",
 			namespaceFmt: claims.CloudNamespaceFormatter,
@@ -283,7 +305,7 @@ func TestNamespaceAuthorizationFunc(t *testing.T) {
 	})
 
 	t.Run("missing namespace", func(t *testing.T) {
-		ctx := claims.WithClaims(context.Background(), &authn.AuthInfo{
+		ctx := claims.WithAuthInfo(context.Background(), &authn.AuthInfo{
 			AccessClaims:   authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{Rest: authn.AccessTokenClaims{Namespace: "stacks-12"}}),
 			IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{Rest: authn.IDTokenClaims{Namespace: "stacks-12"}}),
 		})
@@ -293,7 +315,7 @@ func TestNamespaceAuthorizationFunc(t *testing.T) {
 
 	t.Run("ok", func(t *testing.T) {
 		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(DefaultNamespaceMetadataKey, "stacks-12"))
-		ctx = claims.WithClaims(ctx, &authn.AuthInfo{
+		ctx = claims.WithAuthInfo(ctx, &authn.AuthInfo{
 			AccessClaims:   authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{Rest: authn.AccessTokenClaims{Namespace: "stacks-12"}}),
 			IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{Rest: authn.IDTokenClaims{Namespace: "stacks-12"}}),
 		})
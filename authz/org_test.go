@@ -0,0 +1,42 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientImpl_Org(t *testing.T) {
+	var orgs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		org := r.Header.Get(orgHeader)
+		orgs = append(orgs, org)
+		if org == "2" {
+			_, _ = w.Write([]byte(`{"1": {"dashboards:read": ["dashboards:uid:2"]}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"1": {"dashboards:read": ["dashboards:uid:1"]}}`))
+	}))
+	defer server.Close()
+
+	c, err := newClient(Config{APIURL: server.URL, Token: "aabbcc", OrgID: 3})
+	require.NoError(t, err)
+	c.client = server.Client()
+	ctx := context.Background()
+	query := searchQuery{NamespacedID: "user:1"}
+
+	res, err := c.Search(ctx, query)
+	require.NoError(t, err)
+	require.Equal(t, []string{"dashboards:uid:1"}, (*res.Data)[1]["dashboards:read"])
+
+	res, err = c.Search(WithOrg(ctx, 2), query)
+	require.NoError(t, err)
+	require.Equal(t, []string{"dashboards:uid:2"}, (*res.Data)[1]["dashboards:read"], "should not share the cache across orgs")
+
+	_, err = c.Search(WithOrg(ctx, 2), query)
+	require.NoError(t, err)
+	require.Equal(t, []string{"3", "2"}, orgs)
+}
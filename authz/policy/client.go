@@ -0,0 +1,112 @@
+// Package policy provides a MultiTenantClient evaluating the checks locally with a policy engine,
+// instead of calling the authz service, for services that need offline or low-latency decisions.
+//
+// NewOPAClient evaluates the checks with an Open Policy Agent running next to the service, which the
+// bundles and the data documents are loaded into:
+//
+//	opa, err := policy.NewOPA(policy.OPAConfig{URL: "http://localhost:8181", Decision: "authz/allow"}, nil)
+//	err = opa.LoadBundle(ctx, "/etc/authz/bundle")
+//	client := policy.NewClient(opa.Evaluate)
+//
+// NewClient takes any Evaluator instead, ex: a query prepared with the rego package of OPA.
+package policy
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/authlib/authz"
+	"github.com/grafana/authlib/claims"
+)
+
+var ErrEvaluation = status.Errorf(codes.Internal, "policy evaluation failed")
+
+// Evaluator decides whether the check described by the input is allowed.
+type Evaluator func(ctx context.Context, input Input) (bool, error)
+
+type Option func(*Client)
+
+func WithTracerOption(tracer trace.Tracer) Option {
+	return func(c *Client) {
+		c.tracer = tracer
+	}
+}
+
+// WithNamespaceFormatterOption sets how stack IDs are formatted to namespaces. Defaults to claims.CloudNamespaceFormatter.
+func WithNamespaceFormatterOption(fmt claims.NamespaceFormatter) Option {
+	return func(c *Client) {
+		c.namespaceFmt = fmt
+	}
+}
+
+// WithNamespaceMatcherOption sets how the namespace of the caller claims is matched. Defaults to claims.NamespaceMatches.
+func WithNamespaceMatcherOption(matcher claims.NamespaceMatcher) Option {
+	return func(c *Client) {
+		c.nsMatcher = matcher
+	}
+}
+
+var _ authz.MultiTenantClient = (*Client)(nil)
+
+// Client is a MultiTenantClient evaluating the checks with an Evaluator.
+// The requests are validated, and the namespaces of the caller matched against the stack,
+// before the evaluation: the policies cannot break the isolation of the tenants.
+type Client struct {
+	eval         Evaluator
+	namespaceFmt claims.NamespaceFormatter
+	nsMatcher    claims.NamespaceMatcher
+	tracer       trace.Tracer
+}
+
+func NewClient(eval Evaluator, opts ...Option) *Client {
+	c := &Client{eval: eval}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.namespaceFmt == nil {
+		c.namespaceFmt = claims.CloudNamespaceFormatter
+	}
+
+	if c.nsMatcher == nil {
+		c.nsMatcher = claims.NamespaceMatches
+	}
+
+	if c.tracer == nil {
+		c.tracer = noop.Tracer{}
+	}
+
+	return c
+}
+
+func (c *Client) Check(ctx context.Context, req *authz.CheckRequest) (bool, error) {
+	ctx, span := c.tracer.Start(ctx, "policy.Client.Check")
+	defer span.End()
+
+	if err := req.Validate(true); err != nil {
+		span.RecordError(err)
+		return false, err
+	}
+	span.SetAttributes(attribute.Int64("stack_id", req.StackID))
+	span.SetAttributes(attribute.String("action", req.Action))
+
+	namespace := c.namespaceFmt(req.StackID)
+	if access := req.Caller.GetAccess(); !c.nsMatcher(access, namespace) {
+		return false, nil
+	}
+	if id := req.Caller.GetIdentity(); id != nil && !id.IsNil() && !c.nsMatcher(id, namespace) {
+		return false, nil
+	}
+
+	allowed, err := c.eval(ctx, NewInput(req, namespace))
+	if err != nil {
+		span.RecordError(err)
+		return false, ErrEvaluation
+	}
+	return allowed, nil
+}
@@ -0,0 +1,242 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/authz"
+	"github.com/grafana/authlib/authz/conformance"
+)
+
+// referenceEvaluator implements the rules of the authz service, as a policy would.
+func referenceEvaluator(perms []conformance.Permission) Evaluator {
+	return func(_ context.Context, input Input) (bool, error) {
+		if input.Identity == nil {
+			return slices.Contains(input.Service.Permissions, input.Action), nil
+		}
+		if !slices.Contains(input.Service.DelegatedPermissions, input.Action) {
+			return false, nil
+		}
+		if actor := input.Identity.Actor; actor != nil && !slices.Contains(actor.DelegatedPermissions, input.Action) {
+			return false, nil
+		}
+
+		resources := input.Contextual
+		if input.Resource != nil {
+			resources = append(resources, *input.Resource)
+		}
+		for _, p := range perms {
+			if p.StackID != input.StackID || p.Subject != input.Identity.Subject || p.Action != input.Action {
+				continue
+			}
+			if len(resources) == 0 || p.Scope == "*" {
+				return true, nil
+			}
+			for _, r := range resources {
+				if p.Scope == r.Scope || p.Scope == r.Kind+":"+r.Attr+":*" {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+}
+
+func TestConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T, perms []conformance.Permission) authz.MultiTenantClient {
+		return NewClient(referenceEvaluator(perms))
+	})
+}
+
+func TestClient_Check(t *testing.T) {
+	caller := &authn.AuthInfo{
+		AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+			Claims: &jwt.Claims{Subject: "access-policy:1"},
+			Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+		}),
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: "user:1"},
+			Rest: authn.IDTokenClaims{
+				Namespace: "stacks-12",
+				Type:      "user",
+				Actor:     &authn.ActorClaims{Subject: "user:2", DelegatedPermissions: []string{"dashboards:read"}},
+			},
+		}),
+	}
+	req := &authz.CheckRequest{
+		Caller:     caller,
+		StackID:    12,
+		Action:     "dashboards:read",
+		Resource:   &authz.Resource{Kind: "dashboards", Attr: "uid", ID: "1"},
+		Contextual: []authz.Resource{{Kind: "folders", Attr: "uid", ID: "2"}},
+		Metadata:   map[string]string{"correlation-id": "abc"},
+	}
+
+	t.Run("should evaluate the input document", func(t *testing.T) {
+		var got Input
+		allowed, err := NewClient(func(_ context.Context, input Input) (bool, error) {
+			got = input
+			return true, nil
+		}).Check(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, allowed)
+
+		data, err := json.Marshal(got)
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"stack_id": 12,
+			"namespace": "stack-12",
+			"action": "dashboards:read",
+			"resource": {"kind": "dashboards", "attr": "uid", "id": "1", "scope": "dashboards:uid:1"},
+			"contextual": [{"kind": "folders", "attr": "uid", "id": "2", "scope": "folders:uid:2"}],
+			"service": {"subject": "access-policy:1", "namespace": "stacks-12", "permissions": null, "delegated_permissions": ["dashboards:read"]},
+			"identity": {
+				"subject": "user:1",
+				"type": "user",
+				"namespace": "stacks-12",
+				"actor": {"subject": "user:2", "delegated_permissions": ["dashboards:read"]}
+			},
+			"metadata": {"correlation-id": "abc"}
+		}`, string(data))
+	})
+
+	t.Run("should not evaluate checks of other namespaces", func(t *testing.T) {
+		evaluated := false
+		client := NewClient(func(context.Context, Input) (bool, error) {
+			evaluated = true
+			return true, nil
+		})
+
+		other := *req
+		other.StackID = 13
+		allowed, err := client.Check(context.Background(), &other)
+		require.NoError(t, err)
+		require.False(t, allowed)
+		require.False(t, evaluated)
+	})
+
+	t.Run("should hide evaluation errors", func(t *testing.T) {
+		allowed, err := NewClient(func(context.Context, Input) (bool, error) {
+			return true, errors.New("undefined rule data.authz.allow")
+		}).Check(context.Background(), req)
+		require.ErrorIs(t, err, ErrEvaluation)
+		require.False(t, strings.Contains(err.Error(), "data.authz"))
+		require.False(t, allowed)
+	})
+}
+
+// fakeOPA serves the REST API of an Open Policy Agent, deciding data.authz.allow with the evaluator.
+type fakeOPA struct {
+	eval Evaluator
+	// puts are the bodies of the loaded policies and data documents, by path
+	puts map[string]string
+}
+
+func (f *fakeOPA) serve(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code": "unauthorized", "message": "missing token"}`))
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/data/authz/allow":
+			var req decisionRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			allowed, _ := f.eval(r.Context(), req.Input)
+			_ = json.NewEncoder(w).Encode(decisionResponse{Result: &allowed})
+		case r.Method == http.MethodPost:
+			// Undefined decision
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			f.puts[r.URL.Path] = string(body)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestConformance_OPA(t *testing.T) {
+	conformance.Run(t, func(t *testing.T, perms []conformance.Permission) authz.MultiTenantClient {
+		server := (&fakeOPA{eval: referenceEvaluator(perms)}).serve(t)
+		client, err := NewOPAClient(OPAConfig{URL: server.URL, Decision: "authz/allow", Token: "token"})
+		require.NoError(t, err)
+		return client
+	})
+}
+
+func TestOPA(t *testing.T) {
+	agent := &fakeOPA{eval: referenceEvaluator(nil), puts: map[string]string{}}
+	server := agent.serve(t)
+	opa, err := NewOPA(OPAConfig{URL: server.URL, Decision: "authz/allow", Token: "token"}, server.Client())
+	require.NoError(t, err)
+
+	t.Run("should load the policies and the data documents of the bundle", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "authz"), 0o755))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "roles", "editor"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "authz", "authz.rego"), []byte("package authz"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "roles", "editor", "data.json"), []byte(`{"actions": ["dashboards:write"]}`), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".manifest"), []byte(`{}`), 0o600))
+
+		require.NoError(t, opa.LoadBundle(context.Background(), dir))
+		require.Equal(t, map[string]string{
+			"/v1/policies/authz/authz.rego": "package authz",
+			"/v1/data/roles/editor":         `{"actions": ["dashboards:write"]}`,
+		}, agent.puts)
+	})
+
+	t.Run("should reject invalid data documents", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "data.json"), []byte(`{`), 0o600))
+		require.ErrorIs(t, opa.LoadBundle(context.Background(), dir), ErrInvalidOPAConfig)
+	})
+
+	t.Run("should put the data documents", func(t *testing.T) {
+		require.NoError(t, opa.PutData(context.Background(), "/teams/", map[string][]string{"1": {"user:1"}}))
+		require.Equal(t, `{"1":["user:1"]}`, agent.puts["/v1/data/teams"])
+	})
+
+	t.Run("should deny the undefined decisions", func(t *testing.T) {
+		undefined, err := NewOPA(OPAConfig{URL: server.URL, Decision: "authz/missing", Token: "token"}, server.Client())
+		require.NoError(t, err)
+		allowed, err := undefined.Evaluate(context.Background(), Input{Action: "dashboards:read"})
+		require.NoError(t, err)
+		require.False(t, allowed)
+	})
+
+	t.Run("should return the errors of the agent", func(t *testing.T) {
+		unauthorized, err := NewOPA(OPAConfig{URL: server.URL, Decision: "authz/allow"}, server.Client())
+		require.NoError(t, err)
+		_, err = unauthorized.Evaluate(context.Background(), Input{Action: "dashboards:read"})
+		require.ErrorContains(t, err, "missing token")
+	})
+
+	t.Run("should validate the configuration", func(t *testing.T) {
+		_, err := NewOPA(OPAConfig{URL: server.URL}, nil)
+		require.ErrorIs(t, err, ErrInvalidOPAConfig)
+		_, err = NewOPAClient(OPAConfig{Decision: "authz/allow"})
+		require.ErrorIs(t, err, ErrInvalidOPAConfig)
+	})
+}
@@ -0,0 +1,114 @@
+package policy
+
+import (
+	"github.com/grafana/authlib/authz"
+	"github.com/grafana/authlib/claims"
+)
+
+// Input is the input document of the policy evaluation.
+type Input struct {
+	StackID int64 `json:"stack_id"`
+	// Namespace is the namespace of the stack, as formatted by the client (ex: "stack-12").
+	Namespace string `json:"namespace"`
+	Action    string `json:"action"`
+	// Resource is the requested resource, nil for checks on the action only.
+	Resource *Resource `json:"resource,omitempty"`
+	// Contextual are the resources granting access to the requested resource (ex: its folder).
+	Contextual []Resource `json:"contextual,omitempty"`
+	// Ancestors are the parents of the requested resource, from its direct parent up to the root.
+	Ancestors []Resource `json:"ancestors,omitempty"`
+	// Attributes are the Kubernetes-style attributes of the check, if any.
+	Attributes *Attributes `json:"attributes,omitempty"`
+	// Service is the service calling, nil when the request has no access token.
+	Service *Service `json:"service,omitempty"`
+	// Identity is the identity the service acts on behalf of, nil for service only checks.
+	Identity *Identity `json:"identity,omitempty"`
+	// Metadata is the metadata of the check request.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type Resource struct {
+	Kind string `json:"kind"`
+	Attr string `json:"attr"`
+	ID   string `json:"id"`
+	// Scope is the scope of the resource (ex: "dashboards:uid:1").
+	Scope string `json:"scope"`
+}
+
+type Attributes struct {
+	Group       string `json:"group"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource"`
+	Verb        string `json:"verb"`
+	Name        string `json:"name"`
+}
+
+type Service struct {
+	Subject              string   `json:"subject"`
+	Namespace            string   `json:"namespace"`
+	Permissions          []string `json:"permissions"`
+	DelegatedPermissions []string `json:"delegated_permissions"`
+}
+
+type Identity struct {
+	Subject   string `json:"subject"`
+	Type      string `json:"type"`
+	Namespace string `json:"namespace"`
+	// Actor is the impersonator of the identity, if any.
+	Actor *Actor `json:"actor,omitempty"`
+}
+
+type Actor struct {
+	Subject              string   `json:"subject"`
+	DelegatedPermissions []string `json:"delegated_permissions"`
+}
+
+// NewInput returns the input document of the check request, for the namespace of the stack.
+func NewInput(req *authz.CheckRequest, namespace string) Input {
+	input := Input{
+		StackID:   req.StackID,
+		Namespace: namespace,
+		Action:    req.Action,
+		Metadata:  req.Metadata,
+	}
+
+	if req.Resource != nil {
+		r := newResource(*req.Resource)
+		input.Resource = &r
+	}
+	for _, r := range req.Contextual {
+		input.Contextual = append(input.Contextual, newResource(r))
+	}
+	for _, r := range req.Ancestors {
+		input.Ancestors = append(input.Ancestors, newResource(r))
+	}
+	if a := req.Attributes; a != nil {
+		input.Attributes = &Attributes{Group: a.Group, Resource: a.Resource, Subresource: a.Subresource, Verb: a.Verb, Name: a.Name}
+	}
+
+	if access := req.Caller.GetAccess(); access != nil && !access.IsNil() {
+		input.Service = &Service{
+			Subject:              access.Subject(),
+			Namespace:            access.Namespace(),
+			Permissions:          access.Permissions(),
+			DelegatedPermissions: access.DelegatedPermissions(),
+		}
+	}
+
+	if id := req.Caller.GetIdentity(); id != nil && !id.IsNil() {
+		input.Identity = &Identity{
+			Subject:   id.Subject(),
+			Type:      string(id.IdentityType()),
+			Namespace: id.Namespace(),
+		}
+		if actor, ok := claims.GetActor(id); ok {
+			input.Identity.Actor = &Actor{Subject: actor.Subject(), DelegatedPermissions: actor.DelegatedPermissions()}
+		}
+	}
+
+	return input
+}
+
+func newResource(r authz.Resource) Resource {
+	return Resource{Kind: r.Kind, Attr: r.Attr, ID: r.ID, Scope: r.Scope()}
+}
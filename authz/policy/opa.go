@@ -0,0 +1,172 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/authlib/internal/httpclient"
+)
+
+var ErrInvalidOPAConfig = errors.New("invalid open policy agent configuration")
+
+// OPAConfig is the configuration of the Open Policy Agent evaluating the policies, usually a sidecar of the service.
+type OPAConfig struct {
+	// URL is the URL of the REST API of the agent. Ex: http://localhost:8181
+	URL string
+	// Decision is the path of the decision document, a boolean evaluated with the Input. Ex: "authz/allow"
+	Decision string
+	// Token is the bearer token authenticating with the agent, if any.
+	Token string
+}
+
+// NewOPAClient returns a Client evaluating the checks with the decision of the agent.
+func NewOPAClient(cfg OPAConfig, opts ...Option) (*Client, error) {
+	opa, err := NewOPA(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(opa.Evaluate, opts...), nil
+}
+
+// OPA evaluates the policies with the REST API of an Open Policy Agent, and loads the policies
+// and the data documents of the bundles into it.
+type OPA struct {
+	cfg    OPAConfig
+	client *http.Client
+}
+
+// NewOPA returns the OPA of the configuration. A default HTTP client is used when client is nil.
+func NewOPA(cfg OPAConfig, client *http.Client) (*OPA, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("%w: missing url", ErrInvalidOPAConfig)
+	}
+	if cfg.Decision == "" {
+		return nil, fmt.Errorf("%w: missing decision", ErrInvalidOPAConfig)
+	}
+	if client == nil {
+		client = httpclient.New()
+	}
+	return &OPA{cfg: cfg, client: client}, nil
+}
+
+type decisionRequest struct {
+	Input Input `json:"input"`
+}
+
+type decisionResponse struct {
+	// Result is nil when the decision is undefined
+	Result *bool `json:"result"`
+}
+
+// Evaluate implements Evaluator: it returns the decision document evaluated with the input.
+// Undefined decisions are denied.
+func (o *OPA) Evaluate(ctx context.Context, input Input) (bool, error) {
+	body, err := json.Marshal(decisionRequest{Input: input})
+	if err != nil {
+		return false, err
+	}
+
+	res, err := o.do(ctx, http.MethodPost, "/v1/data/"+strings.Trim(o.cfg.Decision, "/"), "application/json", body)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	var decision decisionResponse
+	if err := json.NewDecoder(res.Body).Decode(&decision); err != nil {
+		return false, fmt.Errorf("opa decision: %w", err)
+	}
+	return decision.Result != nil && *decision.Result, nil
+}
+
+// LoadBundle loads the bundle of the directory into the agent: its Rego policies (".rego" files), and its
+// data documents ("data.json" files), at the path of their directory. Ex: "users/data.json" is loaded as data.users.
+func (o *OPA) LoadBundle(ctx context.Context, dir string) error {
+	return filepath.WalkDir(dir, func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case strings.HasSuffix(rel, ".rego"):
+			policy, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			return o.put(ctx, "/v1/policies/"+rel, "text/plain", policy)
+		case path.Base(rel) == "data.json":
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if !json.Valid(data) {
+				return fmt.Errorf("%w: invalid data document %s", ErrInvalidOPAConfig, rel)
+			}
+			return o.put(ctx, "/v1/data/"+strings.TrimPrefix(path.Dir(rel), "."), "application/json", data)
+		}
+		return nil
+	})
+}
+
+// PutData replaces the data document at the path (ex: "users" for data.users) with the document.
+func (o *OPA) PutData(ctx context.Context, dataPath string, document any) error {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return o.put(ctx, "/v1/data/"+strings.Trim(dataPath, "/"), "application/json", data)
+}
+
+func (o *OPA) put(ctx context.Context, urlPath, contentType string, body []byte) error {
+	res, err := o.do(ctx, http.MethodPut, urlPath, contentType, body)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return res.Body.Close()
+}
+
+// do sends the request to the agent, and returns the response when it succeeded.
+func (o *OPA) do(ctx context.Context, method, urlPath, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(o.cfg.URL, "/")+urlPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if o.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+o.cfg.Token)
+	}
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 != 2 {
+		defer res.Body.Close()
+		var e struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&e); err == nil && e.Message != "" {
+			return nil, fmt.Errorf("opa %s %s: %s: %s (%s)", method, urlPath, res.Status, e.Message, e.Code)
+		}
+		return nil, fmt.Errorf("opa %s %s: %s", method, urlPath, res.Status)
+	}
+	return res, nil
+}
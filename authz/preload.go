@@ -0,0 +1,83 @@
+package authz
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/grafana/authlib/errs"
+)
+
+// preloadConcurrency bounds the concurrent reads of Preload.
+const preloadConcurrency = 8
+
+// PreloadSubject is a subject which permissions are preloaded.
+type PreloadSubject struct {
+	// Subject of the identity (ex: "user:1").
+	Subject string
+	// Groups and Teams are the memberships of the identity (see claims.MembershipClaims).
+	// They must be the ones carried by the identity at check time: the preloaded permissions are served to its checks,
+	// so the permissions granted to missing memberships would be denied until the cache expires.
+	Groups []string
+	Teams  []string
+}
+
+// Preload reads and caches the permissions of the subjects for the actions, to warm the cache
+// at startup or before a burst of checks (ex: report rendering). The subjects are read concurrently, and the
+// actions of a subject in a single request. Permissions already cached are not read again.
+// The errors of the subjects which could not be read are reported in an *errs.Batch, with the index of the subject.
+func (c *LegacyClientImpl) Preload(ctx context.Context, stackID int64, subjects []PreloadSubject, actions []string) error {
+	ctx, span := c.tracer.Start(ctx, "LegacyClientImpl.Preload")
+	defer span.End()
+
+	if stackID <= 0 {
+		return ErrMissingStackID
+	}
+	if len(actions) == 0 {
+		return ErrMissingAction
+	}
+	span.SetAttributes(attribute.Int64("stack_id", stackID))
+	span.SetAttributes(attribute.Int("subjects", len(subjects)))
+	span.SetAttributes(attribute.Int("actions", len(actions)))
+
+	batch := &errs.Batch{}
+	g := errgroup.Group{}
+	g.SetLimit(preloadConcurrency)
+	for i, subject := range subjects {
+		if subject.Subject == "" {
+			batch.Add(i, ErrMissingSubject)
+			continue
+		}
+		i, subject := i, subject
+		g.Go(func() error {
+			if err := c.preloadSubject(ctx, stackID, subject, actions); err != nil {
+				batch.Add(i, err)
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	if err := batch.Err(); err != nil {
+		span.RecordError(err)
+		span.SetAttributes(attribute.Int("failed", batch.Len()))
+		return err
+	}
+	return nil
+}
+
+func (c *LegacyClientImpl) preloadSubject(ctx context.Context, stackID int64, subject PreloadSubject, actions []string) error {
+	req := &CheckRequest{StackID: stackID}
+	missing := c.uncachedActions(ctx, req, subject.Subject, actions)
+	for len(missing) > 0 {
+		unread, err := c.readActions(ctx, req, subject.Subject, subject.Groups, subject.Teams, missing)
+		if err != nil {
+			c.logger.Warn("failed to preload the permissions", "stack_id", stackID, "error", err)
+			return ErrReadPermission
+		}
+		// Services ignoring the additional actions return the first one only
+		missing = unread
+	}
+	return nil
+}
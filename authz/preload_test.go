@@ -0,0 +1,89 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/errs"
+)
+
+func TestLegacyClientImpl_Preload(t *testing.T) {
+	scopes := map[string][]string{
+		"dashboards:read": {"dashboards:uid:1"},
+		"folders:read":    {"folders:uid:1"},
+	}
+	caller := &authn.AuthInfo{
+		AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+			Claims: &jwt.Claims{Subject: "service"},
+			Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read", "folders:read"}},
+		}),
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: "user:2"},
+			Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+		}),
+	}
+
+	t.Run("should cache the permissions of the subjects", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		authz := &actionsAuthzClient{scopes: scopes, batched: true}
+		client.clientV1 = authz
+
+		err := client.Preload(context.Background(), 12, []PreloadSubject{{Subject: "user:1"}, {Subject: "user:2"}, {Subject: "user:3"}}, []string{"dashboards:read", "folders:read"})
+		require.NoError(t, err)
+		require.Equal(t, 3, authz.reads)
+
+		// The checks are served from the cache
+		got, err := client.Check(context.Background(), &CheckRequest{Caller: caller, StackID: 12, Action: "folders:read", Resource: &Resource{Kind: "folders", Attr: "uid", ID: "1"}})
+		require.NoError(t, err)
+		require.True(t, got)
+		require.Equal(t, 3, authz.reads)
+
+		// Cached permissions are not read again
+		require.NoError(t, client.Preload(context.Background(), 12, []PreloadSubject{{Subject: "user:1"}}, []string{"dashboards:read", "folders:read"}))
+		require.Equal(t, 3, authz.reads)
+	})
+
+	t.Run("should read the actions one by one from services ignoring the batch", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		authz := &actionsAuthzClient{scopes: scopes}
+		client.clientV1 = authz
+
+		require.NoError(t, client.Preload(context.Background(), 12, []PreloadSubject{{Subject: "user:1"}}, []string{"dashboards:read", "folders:read"}))
+		require.Equal(t, 2, authz.reads)
+
+		_, err := client.getCachedController(context.Background(), controllerCacheKey(12, "user:1", "folders:read"))
+		require.NoError(t, err)
+	})
+
+	t.Run("should read the permissions with the memberships of the subjects", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		authz := &actionsAuthzClient{scopes: scopes, batched: true}
+		client.clientV1 = authz
+
+		subjects := []PreloadSubject{{Subject: "user:1", Groups: []string{"admins"}, Teams: []string{"team-a"}}}
+		require.NoError(t, client.Preload(context.Background(), 12, subjects, []string{"dashboards:read"}))
+		require.Equal(t, []string{"admins"}, authz.groups)
+		require.Equal(t, []string{"team-a"}, authz.teams)
+	})
+
+	t.Run("should report the subjects which failed", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		client.clientV1 = &actionsAuthzClient{scopes: scopes, batched: true}
+
+		err := client.Preload(context.Background(), 12, []PreloadSubject{{Subject: "user:1"}, {}}, []string{"dashboards:read"})
+		var batch *errs.Batch
+		require.True(t, errors.As(err, &batch))
+		require.Equal(t, []int{1}, batch.Failed())
+	})
+
+	t.Run("should validate the request", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		require.ErrorIs(t, client.Preload(context.Background(), 0, []PreloadSubject{{Subject: "user:1"}}, []string{"dashboards:read"}), ErrMissingStackID)
+		require.ErrorIs(t, client.Preload(context.Background(), 12, []PreloadSubject{{Subject: "user:1"}}, nil), ErrMissingAction)
+	})
+}
@@ -107,3 +107,120 @@ var AuthzService_ServiceDesc = grpc.ServiceDesc{
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/v1/authz.proto",
 }
+
+const (
+	AuthzWatchService_Watch_FullMethodName = "/authz.v1.AuthzWatchService/Watch"
+)
+
+// AuthzWatchServiceClient is the client API for AuthzWatchService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AuthzWatchServiceClient interface {
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (AuthzWatchService_WatchClient, error)
+}
+
+type authzWatchServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthzWatchServiceClient(cc grpc.ClientConnInterface) AuthzWatchServiceClient {
+	return &authzWatchServiceClient{cc}
+}
+
+func (c *authzWatchServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (AuthzWatchService_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AuthzWatchService_ServiceDesc.Streams[0], AuthzWatchService_Watch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &authzWatchServiceWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AuthzWatchService_WatchClient interface {
+	Recv() (*PermissionChange, error)
+	grpc.ClientStream
+}
+
+type authzWatchServiceWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *authzWatchServiceWatchClient) Recv() (*PermissionChange, error) {
+	m := new(PermissionChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AuthzWatchServiceServer is the server API for AuthzWatchService service.
+// All implementations must embed UnimplementedAuthzWatchServiceServer
+// for forward compatibility
+type AuthzWatchServiceServer interface {
+	Watch(*WatchRequest, AuthzWatchService_WatchServer) error
+	mustEmbedUnimplementedAuthzWatchServiceServer()
+}
+
+// UnimplementedAuthzWatchServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedAuthzWatchServiceServer struct {
+}
+
+func (UnimplementedAuthzWatchServiceServer) Watch(*WatchRequest, AuthzWatchService_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedAuthzWatchServiceServer) mustEmbedUnimplementedAuthzWatchServiceServer() {}
+
+// UnsafeAuthzWatchServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuthzWatchServiceServer will
+// result in compilation errors.
+type UnsafeAuthzWatchServiceServer interface {
+	mustEmbedUnimplementedAuthzWatchServiceServer()
+}
+
+func RegisterAuthzWatchServiceServer(s grpc.ServiceRegistrar, srv AuthzWatchServiceServer) {
+	s.RegisterService(&AuthzWatchService_ServiceDesc, srv)
+}
+
+func _AuthzWatchService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AuthzWatchServiceServer).Watch(m, &authzWatchServiceWatchServer{stream})
+}
+
+type AuthzWatchService_WatchServer interface {
+	Send(*PermissionChange) error
+	grpc.ServerStream
+}
+
+type authzWatchServiceWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *authzWatchServiceWatchServer) Send(m *PermissionChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// AuthzWatchService_ServiceDesc is the grpc.ServiceDesc for AuthzWatchService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AuthzWatchService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "authz.v1.AuthzWatchService",
+	HandlerType: (*AuthzWatchServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _AuthzWatchService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/v1/authz.proto",
+}
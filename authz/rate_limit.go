@@ -0,0 +1,126 @@
+package authz
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrRateLimited = status.Errorf(codes.ResourceExhausted, "authz service client rate limit exceeded")
+
+// RateLimitBehavior is the behavior of the reads exceeding the rate limit.
+type RateLimitBehavior int
+
+const (
+	// RateLimitFail fails the reads exceeding the rate limit with ErrRateLimited.
+	RateLimitFail RateLimitBehavior = iota
+	// RateLimitQueue delays the reads exceeding the rate limit until they are allowed. The reads which
+	// would not be allowed before the deadline of their context fail with ErrRateLimited right away.
+	RateLimitQueue
+)
+
+// RateLimitConfig configures the token bucket limiting the reads of the authz service.
+type RateLimitConfig struct {
+	// Rate is the number of reads allowed per second.
+	Rate float64
+	// Burst is the number of reads allowed at once. Defaults to the rate, and at least 1.
+	Burst int
+	// OnSaturation is the behavior of the reads exceeding the rate limit. Defaults to RateLimitFail.
+	OnSaturation RateLimitBehavior
+}
+
+// WithRateLimitLCOption limits the rate of the reads of the authz service, including their retries,
+// to protect the authz service from a misbehaving service. Cached permissions are not limited.
+// The limit is ignored when the rate is not positive.
+func WithRateLimitLCOption(cfg RateLimitConfig) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		if cfg.Rate <= 0 {
+			return
+		}
+		c.limiter = newRateLimiter(cfg)
+	}
+}
+
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	queue bool
+	now   func() time.Time
+
+	mtx    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = max(int(math.Ceil(cfg.Rate)), 1)
+	}
+	l := &rateLimiter{
+		rate:  cfg.Rate,
+		burst: float64(burst),
+		queue: cfg.OnSaturation == RateLimitQueue,
+		now:   time.Now,
+	}
+	l.tokens, l.last = l.burst, l.now()
+	return l
+}
+
+// wait returns when the read is allowed, or ErrRateLimited.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	delay, ok := l.reserve(ctx)
+	if !ok {
+		return ErrRateLimited
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ErrRateLimited
+	}
+}
+
+// reserve takes a token and returns the delay before the read is allowed.
+// It returns false when the read must fail instead.
+func (l *rateLimiter) reserve(ctx context.Context) (time.Duration, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := l.now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0, true
+	}
+	if !l.queue {
+		return 0, false
+	}
+
+	delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+		return 0, false
+	}
+	l.tokens--
+	return delay, true
+}
+
+// cancel gives back the token of a read which stopped waiting.
+func (l *rateLimiter) cancel() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.tokens = min(l.burst, l.tokens+1)
+}
@@ -0,0 +1,110 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+)
+
+// userCheckRequest returns the check of a distinct user, so that its permissions are not cached.
+func userCheckRequest(i int) *CheckRequest {
+	req := inProcessCheckRequest()
+	req.Caller = &authn.AuthInfo{
+		AccessClaims: req.Caller.(*authn.AuthInfo).AccessClaims,
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: fmt.Sprintf("user:%d", i)},
+			Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+		}),
+	}
+	return req
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	t.Run("should fail the reads exceeding the burst", func(t *testing.T) {
+		l := newRateLimiter(RateLimitConfig{Rate: 10, Burst: 2})
+		l.now, l.last = clock, now
+
+		require.NoError(t, l.wait(context.Background()))
+		require.NoError(t, l.wait(context.Background()))
+		require.ErrorIs(t, l.wait(context.Background()), ErrRateLimited)
+
+		now = now.Add(100 * time.Millisecond)
+		require.NoError(t, l.wait(context.Background()), "a token is refilled")
+		require.ErrorIs(t, l.wait(context.Background()), ErrRateLimited)
+
+		now = now.Add(time.Hour)
+		require.NoError(t, l.wait(context.Background()))
+		require.NoError(t, l.wait(context.Background()))
+		require.ErrorIs(t, l.wait(context.Background()), ErrRateLimited, "the tokens are capped to the burst")
+	})
+
+	t.Run("should default the burst to the rate", func(t *testing.T) {
+		require.Equal(t, float64(3), newRateLimiter(RateLimitConfig{Rate: 2.5}).burst)
+		require.Equal(t, float64(1), newRateLimiter(RateLimitConfig{Rate: 0.1}).burst)
+	})
+
+	t.Run("should queue the reads exceeding the burst", func(t *testing.T) {
+		l := newRateLimiter(RateLimitConfig{Rate: 50, Burst: 1, OnSaturation: RateLimitQueue})
+
+		start := time.Now()
+		require.NoError(t, l.wait(context.Background()))
+		require.NoError(t, l.wait(context.Background()))
+		require.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+	})
+
+	t.Run("should fail the queued reads which would miss their deadline", func(t *testing.T) {
+		l := newRateLimiter(RateLimitConfig{Rate: 1, Burst: 1, OnSaturation: RateLimitQueue})
+		require.NoError(t, l.wait(context.Background()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		require.ErrorIs(t, l.wait(ctx), ErrRateLimited)
+		require.Less(t, time.Since(start), 10*time.Millisecond, "the read fails without waiting")
+	})
+
+	t.Run("should give back the token of canceled reads", func(t *testing.T) {
+		l := newRateLimiter(RateLimitConfig{Rate: 1, Burst: 1, OnSaturation: RateLimitQueue})
+		require.NoError(t, l.wait(context.Background()))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		require.ErrorIs(t, l.wait(ctx), ErrRateLimited)
+
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+		require.Less(t, l.tokens, float64(1))
+		require.Greater(t, l.tokens, float64(-1))
+	})
+}
+
+func TestLegacyClientImpl_Check_RateLimit(t *testing.T) {
+	client, _ := setupLegacyClient()
+	authz := &switchableAuthzServiceClient{}
+	client.clientV1 = authz
+	WithRateLimitLCOption(RateLimitConfig{Rate: 0.001, Burst: 2})(client)
+
+	for i := 0; i < 2; i++ {
+		allowed, err := client.Check(context.Background(), userCheckRequest(i))
+		require.NoError(t, err)
+		require.True(t, allowed)
+	}
+
+	_, err := client.Check(context.Background(), userCheckRequest(2))
+	require.ErrorIs(t, err, ErrRateLimited)
+	require.Equal(t, 2, authz.reads)
+
+	// Cached permissions are not limited
+	allowed, err := client.Check(context.Background(), userCheckRequest(0))
+	require.NoError(t, err)
+	require.True(t, allowed)
+}
@@ -0,0 +1,99 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultRetryInitialBackoff = 50 * time.Millisecond
+	DefaultRetryMaxBackoff     = time.Second
+)
+
+// RetryPolicy configures the retries of the reads of the authz service failing with transient errors
+// (Unavailable or DeadlineExceeded), or of the permission searches throttled or failing on the server.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, doubled after each retry. Defaults to 50ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between the retries. Defaults to 1s.
+	MaxBackoff time.Duration
+}
+
+// WithReadRetryLCOption retries the reads of the authz service failing with transient errors, with exponential
+// backoff and jitter, so that a single hiccup does not turn into a denied check. The reads are not retried
+// once the context of the check is done, nor when the circuit breaker is open.
+func WithReadRetryLCOption(policy RetryPolicy) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		policy = policy.withDefaults()
+		c.retry = &policy
+	}
+}
+
+// WithSearchRetry retries the permission searches throttled (429) or failing on the server (5xx), with exponential
+// backoff and jitter, honoring the Retry-After header, so that bursty searches do not fail against a briefly
+// overloaded Grafana.
+func WithSearchRetry(policy RetryPolicy) ClientOption {
+	return func(s *EnforcementClientImpl) error {
+		s.clientOpts = append(s.clientOpts, withRetry(policy))
+		return nil
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultRetryInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	return p
+}
+
+// backoff returns the delay before the retry following the attempt, with jitter between half and all of it.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// wait waits before the retry following the attempt, and returns whether the read can be retried.
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) bool {
+	return wait(ctx, p.backoff(attempt))
+}
+
+// wait waits for the delay, and returns false if the context is done first.
+func wait(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// isTransient returns whether the read failed with an error worth retrying.
+func isTransient(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
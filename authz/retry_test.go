@@ -0,0 +1,114 @@
+package authz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+// flakyAuthzServiceClient fails the first reads with err.
+type flakyAuthzServiceClient struct {
+	failures int
+	err      error
+	reads    int
+}
+
+func (f *flakyAuthzServiceClient) Read(context.Context, *authzv1.ReadRequest, ...grpc.CallOption) (*authzv1.ReadResponse, error) {
+	f.reads++
+	if f.reads <= f.failures {
+		return nil, f.err
+	}
+	return &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}, nil
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	p := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for i := 0; i < 10; i++ {
+		require.InDelta(t, 75*time.Millisecond, p.backoff(1), float64(25*time.Millisecond))
+		require.InDelta(t, 150*time.Millisecond, p.backoff(2), float64(50*time.Millisecond))
+		require.InDelta(t, 225*time.Millisecond, p.backoff(5), float64(75*time.Millisecond))
+	}
+}
+
+func TestLegacyClientImpl_Check_ReadRetry(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	t.Run("should retry transient errors", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		authz := &flakyAuthzServiceClient{failures: 2, err: unavailable}
+		client.clientV1 = authz
+		WithReadRetryLCOption(policy)(client)
+
+		allowed, err := client.Check(context.Background(), inProcessCheckRequest())
+		require.NoError(t, err)
+		require.True(t, allowed)
+		require.Equal(t, 3, authz.reads)
+	})
+
+	t.Run("should give up after the max attempts", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		authz := &flakyAuthzServiceClient{failures: 3, err: unavailable}
+		client.clientV1 = authz
+		WithReadRetryLCOption(policy)(client)
+
+		_, err := client.Check(context.Background(), inProcessCheckRequest())
+		require.ErrorIs(t, err, ErrReadPermission)
+		require.Equal(t, 3, authz.reads)
+	})
+
+	t.Run("should not retry other errors", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		authz := &flakyAuthzServiceClient{failures: 1, err: status.Error(codes.InvalidArgument, "invalid")}
+		client.clientV1 = authz
+		WithReadRetryLCOption(policy)(client)
+
+		_, err := client.Check(context.Background(), inProcessCheckRequest())
+		require.ErrorIs(t, err, ErrReadPermission)
+		require.Equal(t, 1, authz.reads)
+	})
+
+	t.Run("should stop retrying when the circuit opens", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		authz := &flakyAuthzServiceClient{failures: 3, err: unavailable}
+		client.clientV1 = authz
+		WithReadRetryLCOption(policy)(client)
+		WithCircuitBreakerLCOption(CircuitBreakerConfig{FailureThreshold: 2})(client)
+
+		_, err := client.Check(context.Background(), inProcessCheckRequest())
+		require.ErrorIs(t, err, ErrCircuitOpen)
+		require.Equal(t, 2, authz.reads)
+	})
+}
+
+// blockingAuthzServiceClient blocks the reads until their context is done.
+type blockingAuthzServiceClient struct {
+	reads int
+}
+
+func (f *blockingAuthzServiceClient) Read(ctx context.Context, _ *authzv1.ReadRequest, _ ...grpc.CallOption) (*authzv1.ReadResponse, error) {
+	f.reads++
+	<-ctx.Done()
+	return nil, status.FromContextError(ctx.Err()).Err()
+}
+
+func TestLegacyClientImpl_Check_CheckTimeout(t *testing.T) {
+	client, _ := setupLegacyClient()
+	authz := &blockingAuthzServiceClient{}
+	client.clientV1 = authz
+	client.authCfg.CheckTimeout = 10 * time.Millisecond
+	WithReadRetryLCOption(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})(client)
+
+	start := time.Now()
+	_, err := client.Check(context.Background(), inProcessCheckRequest())
+	require.ErrorIs(t, err, ErrReadPermission)
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, 2, authz.reads, "each attempt is bounded")
+}
@@ -0,0 +1,134 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+var ErrResourceNotFound = errors.New("resource not found")
+
+// ResourcePermission is the permission of a user on a resource, as managed in the permissions tab of the resource.
+type ResourcePermission struct {
+	// Resource is the type of the resource. Ex: "dashboards", "folders", "datasources"
+	Resource string
+	// ResourceID is the identifier of the resource. Ex: "YYxUSd7ik"
+	ResourceID string
+	UserID     int64
+	// Permission is the level of permission. Ex: "View", "Edit", "Admin". Empty removes the permission.
+	Permission string
+}
+
+// AssignRole assigns the role with the UID to the user, and invalidates the cached permissions of the user.
+func (c *clientImpl) AssignRole(ctx context.Context, userID int64, roleUID string) error {
+	if userID <= 0 || roleUID == "" {
+		return fmt.Errorf("%w: %v", ErrInvalidQuery, "missing user id or role uid")
+	}
+
+	body, _ := json.Marshal(map[string]string{"roleUid": roleUID})
+	path := "/api/access-control/users/" + strconv.FormatInt(userID, 10) + "/roles"
+	return c.write(ctx, http.MethodPost, path, string(body), ErrRoleNotFound, userSubject(userID))
+}
+
+// RevokeRole revokes the role with the UID from the user, and invalidates the cached permissions of the user.
+func (c *clientImpl) RevokeRole(ctx context.Context, userID int64, roleUID string) error {
+	if userID <= 0 || roleUID == "" {
+		return fmt.Errorf("%w: %v", ErrInvalidQuery, "missing user id or role uid")
+	}
+
+	path := "/api/access-control/users/" + strconv.FormatInt(userID, 10) + "/roles/" + url.PathEscape(roleUID)
+	return c.write(ctx, http.MethodDelete, path, "", ErrRoleNotFound, userSubject(userID))
+}
+
+// SetUserPermissions sets the permission of the user on the resource, and invalidates the cached permissions
+// of the user.
+func (c *clientImpl) SetUserPermissions(ctx context.Context, perm ResourcePermission) error {
+	if perm.Resource == "" || perm.ResourceID == "" || perm.UserID <= 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidQuery, "missing resource, resource id or user id")
+	}
+
+	body, _ := json.Marshal(map[string]string{"permission": perm.Permission})
+	path := "/api/access-control/" + url.PathEscape(perm.Resource) + "/" + url.PathEscape(perm.ResourceID) +
+		"/users/" + strconv.FormatInt(perm.UserID, 10)
+	return c.write(ctx, http.MethodPost, path, string(body), ErrResourceNotFound, userSubject(perm.UserID))
+}
+
+// write sends the request, and invalidates the cached permissions of the subject once it succeeded.
+func (c *clientImpl) write(ctx context.Context, method, path, body string, notFound error, subject string) error {
+	res, err := c.send(ctx, method, path, nil, body, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if err := checkStatus(res, notFound); err != nil {
+		return err
+	}
+	c.generations.bump(subject)
+	return nil
+}
+
+func userSubject(userID int64) string {
+	return NamespaceUser + ":" + strconv.FormatInt(userID, 10)
+}
+
+// generations version the cached searches, to invalidate the searches of the subjects whose permissions were
+// written. The searches of a subject are invalidated by bumping the generation of the subject, and of all the
+// subjects, for the searches across subjects. The caches have no way to delete the keys by prefix.
+//
+// The generations are local: the other instances sharing the cache serve the previous searches until they expire.
+type generations struct {
+	mtx      sync.Mutex
+	all      uint64
+	subjects map[string]uint64
+}
+
+// key returns the generation of the searches of the subject, or of all the subjects when empty.
+func (g *generations) key(subject string) string {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if subject == "" {
+		return strconv.FormatUint(g.all, 10)
+	}
+	return strconv.FormatUint(g.subjects[subject], 10)
+}
+
+func (g *generations) bump(subject string) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if g.subjects == nil {
+		g.subjects = map[string]uint64{}
+	}
+	g.all++
+	g.subjects[subject]++
+}
+
+// AssignRole assigns the role with the UID to the user.
+// The cached permissions of the user are invalidated, in this client only.
+func (s *EnforcementClientImpl) AssignRole(ctx context.Context, userID int64, roleUID string) error {
+	s.legacyUsage.report(ctx, SurfaceEnforcementClient, "AssignRole")
+	return s.client.AssignRole(ctx, userID, roleUID)
+}
+
+// RevokeRole revokes the role with the UID from the user.
+// The cached permissions of the user are invalidated, in this client only.
+func (s *EnforcementClientImpl) RevokeRole(ctx context.Context, userID int64, roleUID string) error {
+	s.legacyUsage.report(ctx, SurfaceEnforcementClient, "RevokeRole")
+	return s.client.RevokeRole(ctx, userID, roleUID)
+}
+
+// SetUserPermissions sets the permission of the user on the resource.
+// The cached permissions of the user are invalidated, in this client only.
+func (s *EnforcementClientImpl) SetUserPermissions(ctx context.Context, perm ResourcePermission) error {
+	s.legacyUsage.report(ctx, SurfaceEnforcementClient, "SetUserPermissions")
+	return s.client.SetUserPermissions(ctx, perm)
+}
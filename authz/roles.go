@@ -0,0 +1,138 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+var ErrRoleNotFound = errors.New("role not found")
+
+const rolesPath = "/api/access-control/roles"
+
+// Permission is an action granted on a scope. Ex: {Action: "dashboards:read", Scope: "dashboards:uid:1"}
+type Permission struct {
+	Action string `json:"action"`
+	Scope  string `json:"scope,omitempty"`
+}
+
+// Role is a role of Grafana access control.
+type Role struct {
+	UID         string       `json:"uid"`
+	Name        string       `json:"name"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Group       string       `json:"group,omitempty"`
+	Version     int64        `json:"version"`
+	Global      bool         `json:"global"`
+	Hidden      bool         `json:"hidden"`
+	Permissions []Permission `json:"permissions,omitempty"`
+	Created     time.Time    `json:"created"`
+	Updated     time.Time    `json:"updated"`
+}
+
+// RoleAssignments are the users, teams and service accounts a role is assigned to, by ID.
+type RoleAssignments struct {
+	RoleUID         string  `json:"roleUid"`
+	Users           []int64 `json:"users"`
+	Teams           []int64 `json:"teams"`
+	ServiceAccounts []int64 `json:"serviceAccounts"`
+}
+
+// ListRolesQuery filters the listed roles.
+type ListRolesQuery struct {
+	// Delegatable lists only the roles the caller can assign.
+	Delegatable bool `url:"delegatable,omitempty"`
+	// IncludeHidden lists the hidden roles too.
+	IncludeHidden bool `url:"includeHidden,omitempty"`
+}
+
+// ListRoles returns the roles of the org of the token, sorted by UID.
+func (c *clientImpl) ListRoles(ctx context.Context, query ListRolesQuery) ([]Role, error) {
+	var roles []Role
+	if err := c.getJSON(ctx, rolesPath, query, &roles); err != nil {
+		return nil, err
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].UID < roles[j].UID })
+	return roles, nil
+}
+
+// GetRole returns the role with the UID, or ErrRoleNotFound.
+func (c *clientImpl) GetRole(ctx context.Context, uid string) (*Role, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, "missing role uid")
+	}
+
+	var role Role
+	if err := c.getJSON(ctx, rolesPath+"/"+url.PathEscape(uid), nil, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// ListRoleAssignments returns the assignments of the role with the UID, or ErrRoleNotFound.
+func (c *clientImpl) ListRoleAssignments(ctx context.Context, uid string) (*RoleAssignments, error) {
+	if uid == "" {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, "missing role uid")
+	}
+
+	var assignments RoleAssignments
+	if err := c.getJSON(ctx, rolesPath+"/"+url.PathEscape(uid)+"/assignments", nil, &assignments); err != nil {
+		return nil, err
+	}
+	return &assignments, nil
+}
+
+// getJSON decodes the response to the GET request in out.
+func (c *clientImpl) getJSON(ctx context.Context, path string, query interface{}, out interface{}) error {
+	res, err := c.send(ctx, http.MethodGet, path, query, "", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if err := checkStatus(res, ErrRoleNotFound); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidResponse, err)
+	}
+	return nil
+}
+
+// checkStatus returns the error of the unsuccessful responses, notFound for 404.
+func checkStatus(res *http.Response, notFound error) error {
+	switch {
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		return ErrInvalidToken
+	case res.StatusCode == http.StatusNotFound:
+		return notFound
+	case res.StatusCode < 200 || res.StatusCode > 299:
+		return unexpectedStatus(res)
+	}
+	return nil
+}
+
+// ListRoles returns the roles of the org of the token, sorted by UID.
+func (s *EnforcementClientImpl) ListRoles(ctx context.Context, query ListRolesQuery) ([]Role, error) {
+	s.legacyUsage.report(ctx, SurfaceEnforcementClient, "ListRoles")
+	return s.client.ListRoles(ctx, query)
+}
+
+// GetRole returns the role with the UID, or ErrRoleNotFound.
+func (s *EnforcementClientImpl) GetRole(ctx context.Context, uid string) (*Role, error) {
+	s.legacyUsage.report(ctx, SurfaceEnforcementClient, "GetRole")
+	return s.client.GetRole(ctx, uid)
+}
+
+// ListRoleAssignments returns the users, teams and service accounts the role with the UID is assigned to,
+// or ErrRoleNotFound.
+func (s *EnforcementClientImpl) ListRoleAssignments(ctx context.Context, uid string) (*RoleAssignments, error) {
+	s.legacyUsage.report(ctx, SurfaceEnforcementClient, "ListRoleAssignments")
+	return s.client.ListRoleAssignments(ctx, uid)
+}
@@ -0,0 +1,120 @@
+package authz
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientImpl_Roles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer aabbcc", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case rolesPath:
+			require.Equal(t, "true", r.URL.Query().Get("delegatable"))
+			_, _ = w.Write([]byte(`[{"uid": "writer", "name": "custom:writer"}, {"uid": "reader", "name": "custom:reader"}]`))
+		case rolesPath + "/reader":
+			_, _ = w.Write([]byte(`{"uid": "reader", "name": "custom:reader", "version": 2,
+				"permissions": [{"action": "dashboards:read", "scope": "dashboards:*"}]}`))
+		case rolesPath + "/reader/assignments":
+			_, _ = w.Write([]byte(`{"roleUid": "reader", "users": [1, 2], "teams": [3], "serviceAccounts": []}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c, err := newClient(Config{APIURL: server.URL, Token: "aabbcc"})
+	require.NoError(t, err)
+	c.client = server.Client()
+	ctx := context.Background()
+
+	t.Run("should list the roles", func(t *testing.T) {
+		roles, err := c.ListRoles(ctx, ListRolesQuery{Delegatable: true})
+		require.NoError(t, err)
+		require.Len(t, roles, 2)
+		require.Equal(t, "reader", roles[0].UID)
+	})
+
+	t.Run("should get the role", func(t *testing.T) {
+		role, err := c.GetRole(ctx, "reader")
+		require.NoError(t, err)
+		require.Equal(t, int64(2), role.Version)
+		require.Equal(t, []Permission{{Action: "dashboards:read", Scope: "dashboards:*"}}, role.Permissions)
+
+		_, err = c.GetRole(ctx, "unknown")
+		require.ErrorIs(t, err, ErrRoleNotFound)
+
+		_, err = c.GetRole(ctx, "")
+		require.ErrorIs(t, err, ErrInvalidQuery)
+	})
+
+	t.Run("should list the assignments of the role", func(t *testing.T) {
+		assignments, err := c.ListRoleAssignments(ctx, "reader")
+		require.NoError(t, err)
+		require.Equal(t, &RoleAssignments{RoleUID: "reader", Users: []int64{1, 2}, Teams: []int64{3}, ServiceAccounts: []int64{}}, assignments)
+	})
+}
+
+func TestClientImpl_RoleWrites(t *testing.T) {
+	var searches, writes int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == searchPath:
+			searches++
+			_, _ = w.Write([]byte(`{"1": {"dashboards:read": ["dashboards:uid:1"]}}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/access-control/users/1/roles":
+			writes++
+			body, _ := io.ReadAll(r.Body)
+			require.JSONEq(t, `{"roleUid": "reader"}`, string(body))
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/access-control/users/1/roles/reader":
+			writes++
+		case r.Method == http.MethodPost && r.URL.Path == "/api/access-control/dashboards/YYxUSd7ik/users/1":
+			writes++
+			body, _ := io.ReadAll(r.Body)
+			require.JSONEq(t, `{"permission": "Edit"}`, string(body))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c, err := newClient(Config{APIURL: server.URL, Token: "aabbcc"})
+	require.NoError(t, err)
+	c.client = server.Client()
+	ctx := context.Background()
+
+	search := func() {
+		_, err := c.Search(ctx, searchQuery{NamespacedID: "user:1"})
+		require.NoError(t, err)
+	}
+
+	search()
+	search()
+	require.Equal(t, 1, searches, "should cache the search")
+
+	require.NoError(t, c.AssignRole(ctx, 1, "reader"))
+	search()
+	require.Equal(t, 2, searches, "should invalidate the search of the user")
+
+	require.NoError(t, c.RevokeRole(ctx, 1, "reader"))
+	search()
+	require.Equal(t, 3, searches)
+
+	require.NoError(t, c.SetUserPermissions(ctx, ResourcePermission{Resource: "dashboards", ResourceID: "YYxUSd7ik", UserID: 1, Permission: "Edit"}))
+	search()
+	require.Equal(t, 4, searches)
+	require.Equal(t, 3, writes)
+
+	// Failed writes do not invalidate
+	require.ErrorIs(t, c.RevokeRole(ctx, 1, "unknown"), ErrRoleNotFound)
+	require.ErrorIs(t, c.SetUserPermissions(ctx, ResourcePermission{Resource: "folders", ResourceID: "f", UserID: 1}), ErrResourceNotFound)
+	search()
+	require.Equal(t, 4, searches)
+
+	require.ErrorIs(t, c.AssignRole(ctx, 0, "reader"), ErrInvalidQuery)
+}
@@ -0,0 +1,135 @@
+// Package scope handles the scopes of the Grafana permissions, in the form "<kind>:<attribute>:<id>"
+// (ex: "dashboards:uid:my_dash"), and their wildcards ("*", "dashboards:*" or "dashboards:uid:*").
+package scope
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Separator separates the fragments of a scope.
+	Separator = ":"
+	// Wildcard matches all the kinds, attributes or identifiers.
+	Wildcard = "*"
+)
+
+var ErrInvalidScope = errors.New("invalid scope")
+
+// Scope is a parsed scope.
+type Scope struct {
+	// Kind is the type of resource. Ex: "teams", "dashboards", "datasources"
+	Kind string
+	// Attribute identifying the resource. Ex: "id", "uid"
+	Attribute string
+	// ID is the unique identifier of the resource. Ex: "2", "YYxUSd7ik", "test-datasource"
+	ID string
+}
+
+// New returns the scope of a resource.
+func New(kind, attribute, id string) Scope {
+	return Scope{Kind: kind, Attribute: attribute, ID: id}
+}
+
+// Parse parses and validates a scope. Short wildcards are expanded: "*" to "*:*:*" and "dashboards:*"
+// to "dashboards:*:*". Identifiers can contain the separator (ex: "dashboards:uid:a:b").
+func Parse(scope string) (Scope, error) {
+	if err := Validate(scope); err != nil {
+		return Scope{}, err
+	}
+	kind, attribute, id := Split(scope)
+	return New(kind, attribute, id), nil
+}
+
+// Validate returns ErrInvalidScope if the scope is neither a scope with all fields specified nor a wildcard.
+func Validate(scope string) error {
+	fragments := strings.SplitN(scope, Separator, 3)
+	for _, f := range fragments {
+		if f == "" {
+			return fmt.Errorf("%w: %q has an empty fragment", ErrInvalidScope, scope)
+		}
+	}
+	switch len(fragments) {
+	case 1:
+		if fragments[0] != Wildcard {
+			return fmt.Errorf("%w: %q is missing the attribute and identifier", ErrInvalidScope, scope)
+		}
+	case 2:
+		if fragments[1] != Wildcard {
+			return fmt.Errorf("%w: %q is missing the identifier", ErrInvalidScope, scope)
+		}
+	}
+	return nil
+}
+
+// Split returns the kind, attribute and identifier of the scope, without validating it.
+func Split(scope string) (kind string, attribute string, id string) {
+	fragments := strings.Split(scope, Separator)
+	switch l := len(fragments); l {
+	case 1: // Splitting a wildcard scope "*" -> kind: "*"; attribute: "*"; identifier: "*"
+		return fragments[0], fragments[0], fragments[0]
+	case 2: // Splitting a wildcard scope with specified kind "dashboards:*" -> kind: "dashboards"; attribute: "*"; identifier: "*"
+		return fragments[0], fragments[1], fragments[1]
+	default: // Splitting a scope with all fields specified "dashboards:uid:my_dash" -> kind: "dashboards"; attribute: "uid"; identifier: "my_dash"
+		return fragments[0], fragments[1], strings.Join(fragments[2:], Separator)
+	}
+}
+
+// String returns the scope in the form "<kind>:<attribute>:<id>".
+func (s Scope) String() string {
+	return s.Kind + Separator + s.Attribute + Separator + s.ID
+}
+
+// IsWildcard returns whether the scope grants all the resources of its kind.
+func (s Scope) IsWildcard() bool {
+	return s.ID == Wildcard
+}
+
+// Covers returns whether the scope grants the resource of the other scope, directly or with a wildcard.
+func (s Scope) Covers(other Scope) bool {
+	if s.Kind != Wildcard && s.Kind != other.Kind {
+		return false
+	}
+	if s.IsWildcard() {
+		return true
+	}
+	return s.Attribute == other.Attribute && s.ID == other.ID
+}
+
+// KindWildcard returns the wildcard scope of all the resources of the kind, ex: "dashboards:*".
+func KindWildcard(kind string) string {
+	return kind + Separator + Wildcard
+}
+
+// IsWildcard returns whether the scope grants all the resources of one of the kinds,
+// or of any kind when no kind is given.
+// ex: IsWildcard("datasources:uid:*", "datasources", "folders") => true
+func IsWildcard(scope string, kinds ...string) bool {
+	kind, _, id := Split(scope)
+	if id != Wildcard {
+		return false
+	}
+	if len(kinds) == 0 || kind == Wildcard {
+		return true
+	}
+	for i := range kinds {
+		if kind == kinds[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns whether one of the granted scopes grants the resource of the scope.
+func Matches(granted []string, scope string) bool {
+	kind, attribute, id := Split(scope)
+	target := New(kind, attribute, id)
+	for _, g := range granted {
+		kind, attribute, id := Split(g)
+		if New(kind, attribute, id).Covers(target) {
+			return true
+		}
+	}
+	return false
+}
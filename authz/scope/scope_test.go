@@ -0,0 +1,118 @@
+package scope
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name          string
+		scope         string
+		wantKind      string
+		wantAttribute string
+		wantID        string
+	}{
+		{
+			name:          "wildcard scope",
+			scope:         "*",
+			wantKind:      "*",
+			wantAttribute: "*",
+			wantID:        "*",
+		},
+		{
+			name:          "wildcard scope with specified kind",
+			scope:         "dashboards:*",
+			wantKind:      "dashboards",
+			wantAttribute: "*",
+			wantID:        "*",
+		},
+		{
+			name:          "scope with all fields specified",
+			scope:         "dashboards:uid:my_dash",
+			wantKind:      "dashboards",
+			wantAttribute: "uid",
+			wantID:        "my_dash",
+		},
+		{
+			name:          "identifier with separators",
+			scope:         "dashboards:uid:a:b",
+			wantKind:      "dashboards",
+			wantAttribute: "uid",
+			wantID:        "a:b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotKind, gotAttribute, gotID := Split(tt.scope)
+			assert.Equal(t, tt.wantKind, gotKind, "they should be equal")
+			assert.Equal(t, tt.wantAttribute, gotAttribute, "they should be equal")
+			assert.Equal(t, tt.wantID, gotID, "they should be equal")
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		scope   string
+		want    Scope
+		wantErr bool
+	}{
+		{scope: "dashboards:uid:my_dash", want: New("dashboards", "uid", "my_dash")},
+		{scope: "dashboards:*", want: New("dashboards", "*", "*")},
+		{scope: "*", want: New("*", "*", "*")},
+		{scope: "", wantErr: true},
+		{scope: "dashboards", wantErr: true},
+		{scope: "dashboards:uid", wantErr: true},
+		{scope: "dashboards::my_dash", wantErr: true},
+		{scope: "dashboards:uid:", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			got, err := Parse(tt.scope)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidScope)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	require.Equal(t, "dashboards:uid:my_dash", New("dashboards", "uid", "my_dash").String())
+}
+
+func TestIsWildcard(t *testing.T) {
+	assert.True(t, IsWildcard("datasources:uid:*", "datasources", "folders"))
+	assert.True(t, IsWildcard("*", "datasources"))
+	assert.True(t, IsWildcard(KindWildcard("folders"), "folders"))
+	assert.True(t, IsWildcard("folders:uid:*"))
+	assert.False(t, IsWildcard("dashboards:uid:*", "datasources"))
+	assert.False(t, IsWildcard("datasources:uid:1", "datasources"))
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []string
+		scope   string
+		want    bool
+	}{
+		{name: "direct match", granted: []string{"dashboards:uid:1"}, scope: "dashboards:uid:1", want: true},
+		{name: "kind wildcard", granted: []string{"dashboards:*"}, scope: "dashboards:uid:1", want: true},
+		{name: "attribute wildcard", granted: []string{"dashboards:uid:*"}, scope: "dashboards:uid:1", want: true},
+		{name: "wildcard", granted: []string{"*"}, scope: "folders:uid:1", want: true},
+		{name: "other identifier", granted: []string{"dashboards:uid:2"}, scope: "dashboards:uid:1"},
+		{name: "other attribute", granted: []string{"dashboards:id:1"}, scope: "dashboards:uid:1"},
+		{name: "other kind wildcard", granted: []string{"folders:*"}, scope: "dashboards:uid:1"},
+		{name: "no scope", scope: "dashboards:uid:1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Matches(tt.granted, tt.scope))
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// Permission is a permission granted to an identity of a stack.
+type Permission struct {
+	StackID int64
+	// Subject is the typed identifier of the identity (ex: "user:1").
+	Subject string
+	Action  string
+	// Scope is the resource the action applies to (ex: "dashboards:uid:1", "dashboards:uid:*" or "*").
+	// An empty scope grants the action only.
+	Scope string
+}
+
+var _ PermissionStore = (*MemoryStore)(nil)
+
+// MemoryStore is a PermissionStore holding the permissions in memory.
+// It is meant for embedded services and tests. It is safe for concurrent use.
+type MemoryStore struct {
+	mtx   sync.RWMutex
+	perms map[memoryKey][]string
+}
+
+type memoryKey struct {
+	stackID int64
+	subject string
+	action  string
+}
+
+func NewMemoryStore(perms ...Permission) *MemoryStore {
+	s := &MemoryStore{perms: map[memoryKey][]string{}}
+	s.Grant(perms...)
+	return s
+}
+
+// Grant adds the permissions to the store.
+func (s *MemoryStore) Grant(perms ...Permission) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for _, p := range perms {
+		key := memoryKey{stackID: p.StackID, subject: p.Subject, action: p.Action}
+		scopes := s.perms[key]
+		if scopes == nil {
+			scopes = []string{}
+		}
+		if p.Scope != "" {
+			scopes = append(scopes, p.Scope)
+		}
+		s.perms[key] = scopes
+	}
+}
+
+func (s *MemoryStore) Scopes(_ context.Context, query Query) ([]string, bool, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	scopes, found := s.perms[memoryKey{stackID: query.StackID, subject: query.Subject, action: query.Action}]
+	if !found {
+		return nil, false, nil
+	}
+	return append([]string(nil), scopes...), true, nil
+}
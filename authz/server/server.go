@@ -0,0 +1,228 @@
+// Package server implements the authz service read by the multi-tenant authz client.
+//
+// The permissions are read from a pluggable PermissionStore. Callers are not authenticated by the server,
+// use the authn interceptors to authenticate them. Authenticated callers can only read the permissions
+// of the stacks within the namespace of their access token (see WithNamespaceFormatterOption):
+//
+//	srv := server.New(store)
+//	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(authenticator.UnaryServerInterceptor()))
+//	srv.Register(grpcServer)
+//	srv.RegisterHealth(grpcServer)
+package server
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/authlib/authz"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+	"github.com/grafana/authlib/claims"
+)
+
+var (
+	ErrMissingStackID = status.Errorf(codes.InvalidArgument, "missing stack ID")
+	ErrMissingSubject = status.Errorf(codes.InvalidArgument, "missing subject")
+	ErrMissingAction  = status.Errorf(codes.InvalidArgument, "missing action")
+	ErrReadStore      = status.Errorf(codes.Internal, "failed to read permissions")
+	ErrStackMismatch  = status.Errorf(codes.PermissionDenied, "caller namespace does not match the stack")
+)
+
+// Query is the permissions read from the store.
+type Query struct {
+	StackID int64
+	// Subject is the typed identifier of the identity (ex: "user:1").
+	Subject string
+	Action  string
+	// MaxStaleness is the maximum age of the permissions tolerated by the client.
+	// Zero means the permissions must be read from the primary.
+	MaxStaleness time.Duration
+	// Metadata is the authz.CheckRequest metadata forwarded by the client.
+	Metadata map[string]string
+	// Groups and Teams are the memberships of the subject, so the store can resolve the permissions
+	// granted to them. They are empty when the caller does not carry them.
+	Groups []string
+	Teams  []string
+}
+
+// PermissionStore reads the permissions granted to the identities of the stacks.
+type PermissionStore interface {
+	// Scopes returns the scopes on which the subject is granted the action (ex: "dashboards:uid:1",
+	// "dashboards:*" or "*"), found is false when the subject is not granted the action.
+	// A subject granted the action without any scope has found set and no scopes.
+	Scopes(ctx context.Context, query Query) (scopes []string, found bool, err error)
+}
+
+// TTLFunc returns how long the client can cache the permissions for the action.
+// Zero lets the client use its default cache expiration.
+type TTLFunc func(action string) time.Duration
+
+type Option func(*Server)
+
+func WithTracerOption(tracer trace.Tracer) Option {
+	return func(s *Server) {
+		s.tracer = tracer
+	}
+}
+
+// WithNamespaceFormatterOption sets the namespace of the stacks, matched against the namespace of the callers.
+// Defaults to claims.CloudNamespaceFormatter and claims.NamespaceMatches, as the multi-tenant client.
+func WithNamespaceFormatterOption(fmt claims.NamespaceFormatter, matcher claims.NamespaceMatcher) Option {
+	return func(s *Server) {
+		s.namespaceFmt = fmt
+		s.nsMatcher = matcher
+	}
+}
+
+// WithTTLOption sets the cache TTL hinted to the clients, for instance to keep admin actions fresher.
+func WithTTLOption(ttl TTLFunc) Option {
+	return func(s *Server) {
+		s.ttl = ttl
+	}
+}
+
+var _ authzv1.AuthzServiceServer = (*Server)(nil)
+
+// Server implements authzv1.AuthzServiceServer on top of a PermissionStore.
+type Server struct {
+	authzv1.UnimplementedAuthzServiceServer
+
+	store        PermissionStore
+	tracer       trace.Tracer
+	ttl          TTLFunc
+	namespaceFmt claims.NamespaceFormatter
+	nsMatcher    claims.NamespaceMatcher
+}
+
+func New(store PermissionStore, opts ...Option) *Server {
+	s := &Server{store: store}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.tracer == nil {
+		s.tracer = noop.Tracer{}
+	}
+	if s.namespaceFmt == nil {
+		s.namespaceFmt = claims.CloudNamespaceFormatter
+	}
+	if s.nsMatcher == nil {
+		s.nsMatcher = claims.NamespaceMatches
+	}
+
+	return s
+}
+
+// Register registers the authz service on the gRPC server.
+func (s *Server) Register(sr grpc.ServiceRegistrar) {
+	authzv1.RegisterAuthzServiceServer(sr, s)
+}
+
+// RegisterHealth registers the gRPC health service on the gRPC server, reporting the authz service as serving.
+// Call Shutdown on the returned health server before stopping, so that the clients stop sending requests.
+func (s *Server) RegisterHealth(sr grpc.ServiceRegistrar) *health.Server {
+	hs := health.NewServer()
+	hs.SetServingStatus(authz.HealthService, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(sr, hs)
+	return hs
+}
+
+func (s *Server) Read(ctx context.Context, req *authzv1.ReadRequest) (*authzv1.ReadResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "Server.Read")
+	defer span.End()
+
+	if req.GetStackId() <= 0 {
+		return nil, ErrMissingStackID
+	}
+	if req.GetSubject() == "" {
+		return nil, ErrMissingSubject
+	}
+	if req.GetAction() == "" {
+		return nil, ErrMissingAction
+	}
+
+	if !s.validateNamespace(ctx, req.GetStackId()) {
+		return nil, ErrStackMismatch
+	}
+
+	span.SetAttributes(attribute.Int64("stack_id", req.GetStackId()))
+	span.SetAttributes(attribute.String("subject", req.GetSubject()))
+	span.SetAttributes(attribute.String("action", req.GetAction()))
+
+	span.SetAttributes(attribute.Int("actions", len(req.GetActions())))
+
+	data, found, err := s.read(ctx, req, req.GetAction())
+	if err != nil {
+		span.RecordError(err)
+		return nil, ErrReadStore
+	}
+	res := &authzv1.ReadResponse{Found: found, Data: data, TtlMs: s.ttlMs(req.GetAction())}
+
+	// The additional actions of multi-action checks share the shortest TTL
+	for _, action := range req.GetActions() {
+		data, found, err := s.read(ctx, req, action)
+		if err != nil {
+			span.RecordError(err)
+			return nil, ErrReadStore
+		}
+		res.Actions = append(res.Actions, &authzv1.ReadResponse_Action{Action: action, Data: data, Found: found})
+		if ttl := s.ttlMs(action); ttl > 0 && (res.TtlMs == 0 || ttl < res.TtlMs) {
+			res.TtlMs = ttl
+		}
+	}
+	return res, nil
+}
+
+// validateNamespace returns whether the authenticated caller, if any, can read the permissions of the stack.
+// The namespace of the access token is matched, or the namespace of the identity of callers without one.
+func (s *Server) validateNamespace(ctx context.Context, stackID int64) bool {
+	info, ok := claims.AuthInfoFrom(ctx)
+	if !ok {
+		return true
+	}
+
+	var caller claims.Namespaced
+	if access := info.GetAccess(); access != nil && !access.IsNil() {
+		caller = access
+	} else if id := info.GetIdentity(); id != nil && !id.IsNil() {
+		caller = id
+	}
+	return caller != nil && s.nsMatcher(caller, s.namespaceFmt(stackID))
+}
+
+// read returns the permissions of the subject of the request for the action.
+func (s *Server) read(ctx context.Context, req *authzv1.ReadRequest, action string) ([]*authzv1.ReadResponse_Data, bool, error) {
+	scopes, found, err := s.store.Scopes(ctx, Query{
+		StackID:      req.GetStackId(),
+		Subject:      req.GetSubject(),
+		Action:       action,
+		MaxStaleness: time.Duration(req.GetMaxStalenessMs()) * time.Millisecond,
+		Metadata:     authz.MetadataFromIncomingContext(ctx),
+		Groups:       req.GetGroups(),
+		Teams:        req.GetTeams(),
+	})
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	data := make([]*authzv1.ReadResponse_Data, 0, len(scopes))
+	for _, scope := range scopes {
+		data = append(data, &authzv1.ReadResponse_Data{Object: scope})
+	}
+	return data, true, nil
+}
+
+func (s *Server) ttlMs(action string) int64 {
+	if s.ttl == nil {
+		return 0
+	}
+	return s.ttl(action).Milliseconds()
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/authz"
+	"github.com/grafana/authlib/authz/conformance"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+	"github.com/grafana/authlib/claims"
+)
+
+type fakeStore struct {
+	scopes    []string
+	found     bool
+	err       error
+	lastQuery Query
+}
+
+func (f *fakeStore) Scopes(_ context.Context, query Query) ([]string, bool, error) {
+	f.lastQuery = query
+	return f.scopes, f.found, f.err
+}
+
+func TestServer_Read(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   *fakeStore
+		opts    []Option
+		req     *authzv1.ReadRequest
+		want    *authzv1.ReadResponse
+		wantErr error
+	}{
+		{
+			name:    "missing stack id",
+			store:   &fakeStore{},
+			req:     &authzv1.ReadRequest{Subject: "user:1", Action: "dashboards:read"},
+			wantErr: ErrMissingStackID,
+		},
+		{
+			name:    "missing subject",
+			store:   &fakeStore{},
+			req:     &authzv1.ReadRequest{StackId: 12, Action: "dashboards:read"},
+			wantErr: ErrMissingSubject,
+		},
+		{
+			name:    "missing action",
+			store:   &fakeStore{},
+			req:     &authzv1.ReadRequest{StackId: 12, Subject: "user:1"},
+			wantErr: ErrMissingAction,
+		},
+		{
+			name:    "store error",
+			store:   &fakeStore{err: errors.New("db down")},
+			req:     &authzv1.ReadRequest{StackId: 12, Subject: "user:1", Action: "dashboards:read"},
+			wantErr: ErrReadStore,
+		},
+		{
+			name:  "action not granted",
+			store: &fakeStore{},
+			req:   &authzv1.ReadRequest{StackId: 12, Subject: "user:1", Action: "dashboards:read"},
+			want:  &authzv1.ReadResponse{},
+		},
+		{
+			name:  "action granted on scopes",
+			store: &fakeStore{found: true, scopes: []string{"dashboards:uid:1", "folders:*"}},
+			req:   &authzv1.ReadRequest{StackId: 12, Subject: "user:1", Action: "dashboards:read"},
+			want: &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{
+				{Object: "dashboards:uid:1"}, {Object: "folders:*"},
+			}},
+		},
+		{
+			name:  "ttl hint",
+			store: &fakeStore{found: true},
+			opts: []Option{WithTTLOption(func(action string) time.Duration {
+				if action == "users:write" {
+					return time.Second
+				}
+				return 0
+			})},
+			req:  &authzv1.ReadRequest{StackId: 12, Subject: "user:1", Action: "users:write"},
+			want: &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{}, TtlMs: 1000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := New(tt.store, tt.opts...)
+
+			got, err := srv.Read(context.Background(), tt.req)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want.Found, got.Found)
+			require.Equal(t, tt.want.Data, got.Data)
+			require.Equal(t, tt.want.TtlMs, got.TtlMs)
+		})
+	}
+}
+
+func TestServer_Read_MaxStaleness(t *testing.T) {
+	store := &fakeStore{}
+	_, err := New(store).Read(context.Background(), &authzv1.ReadRequest{StackId: 12, Subject: "user:1", Action: "dashboards:read", MaxStalenessMs: 1500})
+	require.NoError(t, err)
+	require.Equal(t, Query{StackID: 12, Subject: "user:1", Action: "dashboards:read", MaxStaleness: 1500 * time.Millisecond}, store.lastQuery)
+}
+
+func TestServer_Read_Metadata(t *testing.T) {
+	store := &fakeStore{}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(authz.MetadataPrefix+"correlation-id", "abc"))
+	_, err := New(store).Read(ctx, &authzv1.ReadRequest{StackId: 12, Subject: "user:1", Action: "dashboards:read"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"correlation-id": "abc"}, store.lastQuery.Metadata)
+}
+
+func TestServer_Read_Namespace(t *testing.T) {
+	service := func(namespace string) context.Context {
+		return claims.WithAuthInfo(context.Background(), &authn.AuthInfo{
+			AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+				Claims: &jwt.Claims{Subject: "access-policy:1"},
+				Rest:   authn.AccessTokenClaims{Namespace: namespace},
+			}),
+		})
+	}
+	req := &authzv1.ReadRequest{StackId: 12, Subject: "user:1", Action: "dashboards:read"}
+
+	t.Run("should read the permissions of the stack of the caller", func(t *testing.T) {
+		_, err := New(&fakeStore{}).Read(service("stacks-12"), req)
+		require.NoError(t, err)
+		_, err = New(&fakeStore{}).Read(service("*"), req)
+		require.NoError(t, err)
+	})
+
+	t.Run("should reject the reads of other stacks", func(t *testing.T) {
+		store := &fakeStore{}
+		_, err := New(store).Read(service("stacks-13"), req)
+		require.ErrorIs(t, err, ErrStackMismatch)
+		require.Equal(t, Query{}, store.lastQuery)
+	})
+
+	t.Run("should use the namespace formatter", func(t *testing.T) {
+		srv := New(&fakeStore{}, WithNamespaceFormatterOption(claims.OrgNamespaceFormatter, claims.OrgNamespaceMatches))
+		_, err := srv.Read(service("org-12"), req)
+		require.NoError(t, err)
+		_, err = srv.Read(service("stacks-12"), req)
+		require.ErrorIs(t, err, ErrStackMismatch)
+	})
+}
+
+func TestServer_Read_Membership(t *testing.T) {
+	store := &fakeStore{}
+	_, err := New(store).Read(context.Background(), &authzv1.ReadRequest{
+		StackId: 12, Subject: "user:1", Action: "dashboards:read", Groups: []string{"admins"}, Teams: []string{"team-1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"admins"}, store.lastQuery.Groups)
+	require.Equal(t, []string{"team-1"}, store.lastQuery.Teams)
+}
+
+func TestServer_Read_Actions(t *testing.T) {
+	store := NewMemoryStore(
+		Permission{StackID: 12, Subject: "user:1", Action: "dashboards:read", Scope: "dashboards:uid:1"},
+		Permission{StackID: 12, Subject: "user:1", Action: "folders:read", Scope: "folders:uid:1"},
+	)
+	res, err := New(store).Read(context.Background(), &authzv1.ReadRequest{
+		StackId: 12, Subject: "user:1", Action: "dashboards:read", Actions: []string{"folders:read", "users:read"},
+	})
+	require.NoError(t, err)
+	require.True(t, res.Found)
+	require.Equal(t, "dashboards:uid:1", res.Data[0].Object)
+	require.Len(t, res.Actions, 2)
+	require.Equal(t, "folders:read", res.Actions[0].Action)
+	require.True(t, res.Actions[0].Found)
+	require.Equal(t, "folders:uid:1", res.Actions[0].Data[0].Object)
+	require.Equal(t, "users:read", res.Actions[1].Action)
+	require.False(t, res.Actions[1].Found)
+}
+
+func TestServerConformance(t *testing.T) {
+	conformance.Run(t, func(t *testing.T, perms []conformance.Permission) authz.MultiTenantClient {
+		store := NewMemoryStore()
+		for _, p := range perms {
+			store.Grant(Permission{StackID: p.StackID, Subject: p.Subject, Action: p.Action, Scope: p.Scope})
+		}
+
+		lis := bufconn.Listen(1024 * 1024)
+		grpcServer := grpc.NewServer()
+		New(store).Register(grpcServer)
+		go func() { _ = grpcServer.Serve(lis) }()
+		t.Cleanup(grpcServer.Stop)
+
+		client, err := authz.NewLegacyClient(
+			&authz.MultiTenantClientConfig{RemoteAddress: "passthrough:///bufnet"},
+			authz.WithGrpcDialOptionsLCOption(
+				grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+			),
+		)
+		require.NoError(t, err)
+		return client
+	})
+}
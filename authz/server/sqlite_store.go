@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// sqliteMigrations are the schema migrations of the SQLiteStore, applied in order.
+// Migrations are append-only: never edit or remove an applied migration.
+var sqliteMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS authz_permission (
+		stack_id INTEGER NOT NULL,
+		subject TEXT NOT NULL,
+		action TEXT NOT NULL,
+		scope TEXT NOT NULL DEFAULT '',
+		PRIMARY KEY (stack_id, subject, action, scope)
+	)`,
+	`CREATE INDEX IF NOT EXISTS authz_permission_subject ON authz_permission (stack_id, subject)`,
+}
+
+var _ PermissionStore = (*SQLiteStore)(nil)
+
+// SQLiteStore is a PermissionStore persisting the permissions in a SQLite database,
+// for single-binary deployments that need durable permissions without an external authz service.
+// The SQLite driver is registered by the application (ex: modernc.org/sqlite or github.com/mattn/go-sqlite3).
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore returns a store on the database, after applying the pending migrations.
+func NewSQLiteStore(ctx context.Context, db *sql.DB) (*SQLiteStore, error) {
+	s := &SQLiteStore{db: db}
+	if err := s.migrate(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate applies the migrations which version is not recorded yet, each in its own transaction.
+func (s *SQLiteStore) migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS authz_migration (version INTEGER PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("failed to create the migration table: %w", err)
+	}
+
+	var applied int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM authz_migration`).Scan(&applied); err != nil {
+		return fmt.Errorf("failed to read the migrations: %w", err)
+	}
+
+	for version := applied; version < len(sqliteMigrations); version++ {
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, sqliteMigrations[version]); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO authz_migration (version) VALUES (?)`, version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// Grant adds the permissions to the store. Granting an existing permission is a no-op.
+func (s *SQLiteStore) Grant(ctx context.Context, perms ...Permission) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, p := range perms {
+			_, err := tx.ExecContext(ctx,
+				`INSERT OR IGNORE INTO authz_permission (stack_id, subject, action, scope) VALUES (?, ?, ?, ?)`,
+				p.StackID, p.Subject, p.Action, p.Scope)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Revoke removes the permissions from the store. Revoking a missing permission is a no-op.
+func (s *SQLiteStore) Revoke(ctx context.Context, perms ...Permission) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, p := range perms {
+			_, err := tx.ExecContext(ctx,
+				`DELETE FROM authz_permission WHERE stack_id = ? AND subject = ? AND action = ? AND scope = ?`,
+				p.StackID, p.Subject, p.Action, p.Scope)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List returns the permissions granted to the subject in the stack.
+func (s *SQLiteStore) List(ctx context.Context, stackID int64, subject string) ([]Permission, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT action, scope FROM authz_permission WHERE stack_id = ? AND subject = ? ORDER BY action, scope`,
+		stackID, subject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var perms []Permission
+	for rows.Next() {
+		p := Permission{StackID: stackID, Subject: subject}
+		if err := rows.Scan(&p.Action, &p.Scope); err != nil {
+			return nil, err
+		}
+		perms = append(perms, p)
+	}
+	return perms, rows.Err()
+}
+
+func (s *SQLiteStore) Scopes(ctx context.Context, query Query) ([]string, bool, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT scope FROM authz_permission WHERE stack_id = ? AND subject = ? AND action = ?`,
+		query.StackID, query.Subject, query.Action)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	found := false
+	scopes := []string{}
+	for rows.Next() {
+		var scope string
+		if err := rows.Scan(&scope); err != nil {
+			return nil, false, err
+		}
+		found = true
+		// An empty scope grants the action only
+		if scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	if !found {
+		return nil, false, nil
+	}
+	return scopes, true, nil
+}
+
+func (s *SQLiteStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+// openSQLite opens a database in the temporary directory of the test, with the cgo SQLite driver.
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "authz.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestSQLiteStore(t *testing.T) {
+	db := openSQLite(t)
+	ctx := context.Background()
+
+	store, err := NewSQLiteStore(ctx, db)
+	require.NoError(t, err)
+
+	// Migrations are only applied once
+	_, err = NewSQLiteStore(ctx, db)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Grant(ctx,
+		Permission{StackID: 12, Subject: "user:1", Action: "dashboards:read", Scope: "dashboards:uid:1"},
+		Permission{StackID: 12, Subject: "user:1", Action: "dashboards:read", Scope: "dashboards:uid:1"},
+		Permission{StackID: 12, Subject: "user:1", Action: "dashboards:read", Scope: "folders:uid:*"},
+		Permission{StackID: 12, Subject: "user:1", Action: "users:read"},
+	))
+
+	scopes, found, err := store.Scopes(ctx, Query{StackID: 12, Subject: "user:1", Action: "dashboards:read"})
+	require.NoError(t, err)
+	require.True(t, found)
+	require.ElementsMatch(t, []string{"dashboards:uid:1", "folders:uid:*"}, scopes)
+
+	scopes, found, err = store.Scopes(ctx, Query{StackID: 12, Subject: "user:1", Action: "users:read"})
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Empty(t, scopes)
+
+	_, found, err = store.Scopes(ctx, Query{StackID: 13, Subject: "user:1", Action: "dashboards:read"})
+	require.NoError(t, err)
+	require.False(t, found)
+
+	require.NoError(t, store.Revoke(ctx, Permission{StackID: 12, Subject: "user:1", Action: "dashboards:read", Scope: "folders:uid:*"}))
+	perms, err := store.List(ctx, 12, "user:1")
+	require.NoError(t, err)
+	require.Equal(t, []Permission{
+		{StackID: 12, Subject: "user:1", Action: "dashboards:read", Scope: "dashboards:uid:1"},
+		{StackID: 12, Subject: "user:1", Action: "users:read"},
+	}, perms)
+}
+
+func TestNewSQLiteStore_Migrations(t *testing.T) {
+	// The migrations must be idempotent to survive partially applied upgrades
+	for _, m := range sqliteMigrations {
+		require.Contains(t, m, "IF NOT EXISTS")
+	}
+}
@@ -0,0 +1,126 @@
+package authz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+var ErrConflictingRetries = errors.New("the gRPC retries of the service config cannot be combined with the read retries")
+
+const (
+	DefaultLoadBalancingPolicy   = "round_robin"
+	DefaultMaxReceiveMessageSize = 16 << 20
+)
+
+// ServiceConfig is the default gRPC service config of the connection to the authz service.
+// A service config provided by the resolver (ex: xDS or DNS TXT records) takes precedence.
+type ServiceConfig struct {
+	// LoadBalancingPolicy is the policy balancing the requests between the resolved addresses
+	// (ex: "round_robin"). gRPC uses "pick_first", which sticks to a single backend, when empty.
+	LoadBalancingPolicy string
+	// Retry enables the retries of the requests failing with Unavailable by gRPC itself.
+	// MaxAttempts is capped to 5 by gRPC, the retries are disabled with less than 2 attempts.
+	Retry *RetryPolicy
+	// MaxReceiveMessageSize caps the size of the responses of the authz service, in bytes.
+	MaxReceiveMessageSize int
+}
+
+// DefaultServiceConfig returns the service config recommended for DNS-based deployments of the authz service
+// with several backends: round-robin load balancing, 3 attempts on Unavailable and 16MiB responses.
+func DefaultServiceConfig() ServiceConfig {
+	return ServiceConfig{
+		LoadBalancingPolicy: DefaultLoadBalancingPolicy,
+		Retry: &RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: DefaultRetryInitialBackoff,
+			MaxBackoff:     DefaultRetryMaxBackoff,
+		},
+		MaxReceiveMessageSize: DefaultMaxReceiveMessageSize,
+	}
+}
+
+// WithServiceConfigLCOption sets the default service config of the connection to the authz service,
+// see DefaultServiceConfig. The policy of WithLoadBalancingPolicyLCOption, if any, overrides its policy.
+// The retries of the service config cannot be combined with WithReadRetryLCOption, since the attempts
+// would multiply: the creation of the client fails with ErrConflictingRetries.
+func WithServiceConfigLCOption(cfg ServiceConfig) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.svcConfig = cfg
+	}
+}
+
+// serviceConfig returns the service config of the options, merged with the load balancing policy.
+func (c *LegacyClientImpl) serviceConfig() ServiceConfig {
+	cfg := c.svcConfig
+	if c.lbPolicy != "" {
+		cfg.LoadBalancingPolicy = c.lbPolicy
+	}
+	return cfg
+}
+
+// retries reports whether the service config enables the retries of gRPC.
+func (cfg ServiceConfig) retries() bool {
+	return cfg.Retry != nil && cfg.Retry.MaxAttempts > 1
+}
+
+func (cfg ServiceConfig) isZero() bool {
+	return cfg.LoadBalancingPolicy == "" && cfg.Retry == nil && cfg.MaxReceiveMessageSize == 0
+}
+
+// JSON returns the service config in the gRPC JSON format.
+func (cfg ServiceConfig) JSON() (string, error) {
+	type retryPolicy struct {
+		MaxAttempts          int      `json:"maxAttempts"`
+		InitialBackoff       string   `json:"initialBackoff"`
+		MaxBackoff           string   `json:"maxBackoff"`
+		BackoffMultiplier    float64  `json:"backoffMultiplier"`
+		RetryableStatusCodes []string `json:"retryableStatusCodes"`
+	}
+	type methodConfig struct {
+		Name                    []map[string]string `json:"name"`
+		RetryPolicy             *retryPolicy        `json:"retryPolicy,omitempty"`
+		MaxResponseMessageBytes int                 `json:"maxResponseMessageBytes,omitempty"`
+	}
+	type serviceConfig struct {
+		LoadBalancingConfig []map[string]struct{} `json:"loadBalancingConfig,omitempty"`
+		MethodConfig        []methodConfig        `json:"methodConfig,omitempty"`
+	}
+
+	var sc serviceConfig
+	if cfg.LoadBalancingPolicy != "" {
+		sc.LoadBalancingConfig = []map[string]struct{}{{cfg.LoadBalancingPolicy: {}}}
+	}
+
+	mc := methodConfig{
+		Name:                    []map[string]string{{"service": authzv1.AuthzService_ServiceDesc.ServiceName}},
+		MaxResponseMessageBytes: cfg.MaxReceiveMessageSize,
+	}
+	if cfg.retries() {
+		policy := cfg.Retry.withDefaults()
+		mc.RetryPolicy = &retryPolicy{
+			MaxAttempts:          min(policy.MaxAttempts, 5),
+			InitialBackoff:       durationJSON(policy.InitialBackoff),
+			MaxBackoff:           durationJSON(policy.MaxBackoff),
+			BackoffMultiplier:    2,
+			RetryableStatusCodes: []string{"UNAVAILABLE"},
+		}
+	}
+	if mc.RetryPolicy != nil || mc.MaxResponseMessageBytes > 0 {
+		sc.MethodConfig = []methodConfig{mc}
+	}
+
+	data, err := json.Marshal(sc)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// durationJSON formats the duration as a protobuf JSON duration (ex: "0.050000000s").
+func durationJSON(d time.Duration) string {
+	return fmt.Sprintf("%.9fs", d.Seconds())
+}
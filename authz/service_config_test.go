@@ -0,0 +1,126 @@
+package authz
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+type unavailableOnceAuthzServer struct {
+	authzv1.UnimplementedAuthzServiceServer
+	calls atomic.Int32
+}
+
+func (s *unavailableOnceAuthzServer) Read(ctx context.Context, req *authzv1.ReadRequest) (*authzv1.ReadResponse, error) {
+	if s.calls.Add(1) == 1 {
+		return nil, status.Error(codes.Unavailable, "restarting")
+	}
+	return &authzv1.ReadResponse{Found: true}, nil
+}
+
+func TestServiceConfig_JSON(t *testing.T) {
+	t.Run("should format the default service config", func(t *testing.T) {
+		got, err := DefaultServiceConfig().JSON()
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"loadBalancingConfig": [{"round_robin": {}}],
+			"methodConfig": [{
+				"name": [{"service": "authz.v1.AuthzService"}],
+				"retryPolicy": {
+					"maxAttempts": 3,
+					"initialBackoff": "0.050000000s",
+					"maxBackoff": "1.000000000s",
+					"backoffMultiplier": 2,
+					"retryableStatusCodes": ["UNAVAILABLE"]
+				},
+				"maxResponseMessageBytes": 16777216
+			}]
+		}`, got)
+
+		// The service config is valid for gRPC
+		_, err = grpc.NewClient("localhost:10000",
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultServiceConfig(got),
+		)
+		require.NoError(t, err)
+	})
+
+	t.Run("should cap the attempts and skip the retries with a single attempt", func(t *testing.T) {
+		got, err := ServiceConfig{Retry: &RetryPolicy{MaxAttempts: 10}}.JSON()
+		require.NoError(t, err)
+		require.Contains(t, got, `"maxAttempts":5`)
+
+		got, err = ServiceConfig{LoadBalancingPolicy: "pick_first", Retry: &RetryPolicy{MaxAttempts: 1}}.JSON()
+		require.NoError(t, err)
+		require.JSONEq(t, `{"loadBalancingConfig": [{"pick_first": {}}]}`, got)
+	})
+}
+
+func TestLegacyClientImpl_ServiceConfig(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	srv := &unavailableOnceAuthzServer{}
+	server := grpc.NewServer()
+	authzv1.RegisterAuthzServiceServer(server, srv)
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	cfg := DefaultServiceConfig()
+	cfg.Retry.InitialBackoff = time.Millisecond
+	client, err := NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: lis.Addr().String()},
+		WithGrpcDialOptionsLCOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		WithServiceConfigLCOption(cfg),
+	)
+	require.NoError(t, err)
+
+	// The read is retried by gRPC
+	got, err := authzv1.NewAuthzServiceClient(client.grpcConn).Read(context.Background(), &authzv1.ReadRequest{})
+	require.NoError(t, err)
+	require.True(t, got.GetFound())
+	require.Equal(t, int32(2), srv.calls.Load())
+}
+
+func TestLegacyClientImpl_ServiceConfigOptions(t *testing.T) {
+	t.Run("should override the policy of the service config whatever the order", func(t *testing.T) {
+		for _, opts := range [][]LegacyClientOption{
+			{WithLoadBalancingPolicyLCOption("pick_first"), WithServiceConfigLCOption(DefaultServiceConfig())},
+			{WithServiceConfigLCOption(DefaultServiceConfig()), WithLoadBalancingPolicyLCOption("pick_first")},
+		} {
+			client := &LegacyClientImpl{}
+			for _, opt := range opts {
+				opt(client)
+			}
+			cfg := client.serviceConfig()
+			require.Equal(t, "pick_first", cfg.LoadBalancingPolicy)
+			require.Equal(t, DefaultMaxReceiveMessageSize, cfg.MaxReceiveMessageSize)
+		}
+	})
+
+	t.Run("should refuse the retries of both gRPC and the client", func(t *testing.T) {
+		_, err := NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: "localhost:10000"},
+			WithServiceConfigLCOption(DefaultServiceConfig()),
+			WithReadRetryLCOption(RetryPolicy{MaxAttempts: 3}),
+		)
+		require.ErrorIs(t, err, ErrConflictingRetries)
+
+		cfg := DefaultServiceConfig()
+		cfg.Retry = nil
+		_, err = NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: "localhost:10000"},
+			WithGrpcDialOptionsLCOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+			WithServiceConfigLCOption(cfg),
+			WithReadRetryLCOption(RetryPolicy{MaxAttempts: 3}),
+		)
+		require.NoError(t, err)
+	})
+}
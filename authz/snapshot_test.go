@@ -0,0 +1,49 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	pages := map[string]permissionsByID{
+		"dashboards:": {
+			2: {"dashboards:read": {"dashboards:uid:1"}},
+			1: {"dashboards:read": {"dashboards:*"}, "dashboards:write": {"dashboards:uid:2"}},
+		},
+		"teams:": {2: {"teams:read": {"teams:id:1"}}},
+	}
+	mockClient := &MockClient{}
+	for prefix, page := range pages {
+		page := page
+		mockClient.On("SearchPages", mock.Anything, searchQuery{ActionPrefix: prefix}, mock.Anything).
+			Run(func(args mock.Arguments) {
+				args.Get(2).(func(permissionsByID) bool)(page)
+			}).Return(nil)
+	}
+	s := EnforcementClientImpl{client: mockClient}
+
+	buf := bytes.Buffer{}
+	require.NoError(t, s.ExportSnapshot(context.Background(), &buf, "dashboards:", "teams:"))
+	require.Equal(t, 3, strings.Count(buf.String(), "\n"))
+
+	snapshot, err := LoadSnapshot(&buf)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, snapshot.Users())
+	require.Equal(t, map[string][]string{"dashboards:read": {"dashboards:uid:1"}, "teams:read": {"teams:id:1"}}, snapshot.Permissions(2))
+
+	dash2 := Resource{Kind: "dashboards", Attr: "uid", ID: "2"}
+	require.True(t, snapshot.HasAccess(1, "dashboards:read", dash2))
+	require.False(t, snapshot.HasAccess(2, "dashboards:read", dash2))
+	require.Equal(t, []int64{1, 2}, snapshot.UsersWithAccess("dashboards:read", Resource{Kind: "dashboards", Attr: "uid", ID: "1"}))
+
+	require.ErrorIs(t, s.ExportSnapshot(context.Background(), &buf), ErrInvalidQuery)
+
+	_, err = LoadSnapshot(strings.NewReader(`{"id": 1}` + "\n" + `{`))
+	require.ErrorIs(t, err, ErrInvalidResponse)
+}
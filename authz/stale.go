@@ -0,0 +1,78 @@
+package authz
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	DefaultStaleFreshness = time.Minute
+	DefaultStaleness      = time.Minute
+)
+
+// StaleWhileRevalidateConfig configures how long the cached permissions are served, fresh then stale.
+type StaleWhileRevalidateConfig struct {
+	// Freshness is how long the cached permissions are served as is, unless the authz service hints a TTL.
+	// Defaults to 1m.
+	Freshness time.Duration
+	// Staleness is how long the permissions are still served once expired, while they are refreshed
+	// in the background. Defaults to 1m.
+	Staleness time.Duration
+}
+
+// WithStaleWhileRevalidateLCOption serves the expired permissions immediately, and refreshes them
+// asynchronously, instead of reading them from the authz service during the check. It smooths the latency spikes
+// when many cache keys expire together. The permissions are cached for the freshness plus the staleness.
+//
+// A check with a MaxStaleness the stale permissions exceed still reads the authz service synchronously.
+func WithStaleWhileRevalidateLCOption(cfg StaleWhileRevalidateConfig) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		if cfg.Freshness <= 0 {
+			cfg.Freshness = DefaultStaleFreshness
+		}
+		if cfg.Staleness <= 0 {
+			cfg.Staleness = DefaultStaleness
+		}
+		c.swr = &cfg
+	}
+}
+
+// freshness returns how long the permissions are fresh.
+func (cfg *StaleWhileRevalidateConfig) freshness(ctrl *controller) time.Duration {
+	if ctrl.TTL > 0 {
+		return ctrl.TTL
+	}
+	return cfg.Freshness
+}
+
+// expired returns whether the cached permissions must be refreshed.
+func (cfg *StaleWhileRevalidateConfig) expired(ctrl *controller) bool {
+	return time.Since(ctrl.FetchedAt) > cfg.freshness(ctrl)
+}
+
+// revalidate refreshes the permissions in the background, unless they are already being refreshed.
+func (c *LegacyClientImpl) revalidate(ctx context.Context, req *CheckRequest, subject, key string) {
+	if _, loaded := c.revalidating.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+
+	// Detach the refresh from the check, which returns with the stale permissions: the caller may cancel
+	// its context or reuse its request.
+	ctx, cancel := detachedContext(ctx)
+	refresh := req.detached()
+
+	go func() {
+		defer c.revalidating.Delete(key)
+		defer cancel()
+
+		ctx, span := c.tracer.Start(ctx, "LegacyClientImpl.revalidate")
+		defer span.End()
+
+		_, err, _ := c.singlef.Do(key, func() (interface{}, error) {
+			return c.fetchPermissions(ctx, span, refresh, subject, key)
+		})
+		if err != nil {
+			c.logger.Debug("failed to refresh the stale permissions", "stack_id", refresh.StackID, "action", refresh.Action, "error", err)
+		}
+	}()
+}
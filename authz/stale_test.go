@@ -0,0 +1,87 @@
+package authz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+func TestLegacyClientImpl_Check_StaleWhileRevalidate(t *testing.T) {
+	client, _ := setupLegacyClient()
+	authz := &gatedAuthzServiceClient{gate: make(chan struct{})}
+	close(authz.gate)
+	client.clientV1 = authz
+	WithStaleWhileRevalidateLCOption(StaleWhileRevalidateConfig{Freshness: 10 * time.Millisecond, Staleness: time.Minute})(client)
+
+	allowed, err := client.Check(context.Background(), inProcessCheckRequest())
+	require.NoError(t, err)
+	require.True(t, allowed)
+	require.Equal(t, int32(1), authz.reads.Load())
+
+	// Serve the fresh permissions
+	_, err = client.Check(context.Background(), inProcessCheckRequest())
+	require.NoError(t, err)
+	require.Equal(t, int32(1), authz.reads.Load())
+
+	// Serve the stale permissions, and refresh them in the background
+	time.Sleep(20 * time.Millisecond)
+	allowed, err = client.Check(context.Background(), inProcessCheckRequest())
+	require.NoError(t, err)
+	require.True(t, allowed)
+	require.Eventually(t, func() bool { return authz.reads.Load() == 2 }, time.Second, time.Millisecond)
+
+	t.Run("should read synchronously beyond the max staleness", func(t *testing.T) {
+		time.Sleep(20 * time.Millisecond)
+		reads := authz.reads.Load()
+		req := inProcessCheckRequest()
+		req.MaxStaleness = 10 * time.Millisecond
+		_, err := client.Check(context.Background(), req)
+		require.NoError(t, err)
+		require.Equal(t, reads+1, authz.reads.Load())
+	})
+}
+
+// recordingAuthzServiceClient sends the reads on reqs, with whether their context was done.
+type recordingAuthzServiceClient struct {
+	reqs chan *authzv1.ReadRequest
+	done chan bool
+}
+
+func (f *recordingAuthzServiceClient) Read(ctx context.Context, req *authzv1.ReadRequest, _ ...grpc.CallOption) (*authzv1.ReadResponse, error) {
+	f.reqs <- req
+	f.done <- ctx.Err() != nil
+	return &authzv1.ReadResponse{Found: true, Data: []*authzv1.ReadResponse_Data{{Object: "dashboards:uid:1"}}}, nil
+}
+
+func TestLegacyClientImpl_Check_RevalidateDetached(t *testing.T) {
+	client, _ := setupLegacyClient()
+	authz := &recordingAuthzServiceClient{reqs: make(chan *authzv1.ReadRequest, 1), done: make(chan bool, 1)}
+	client.clientV1 = authz
+	WithStaleWhileRevalidateLCOption(StaleWhileRevalidateConfig{Freshness: time.Millisecond, Staleness: time.Minute})(client)
+
+	_, err := client.Check(context.Background(), inProcessCheckRequest())
+	require.NoError(t, err)
+	<-authz.reqs
+	<-authz.done
+	time.Sleep(2 * time.Millisecond)
+
+	// The caller cancels the check and reuses its request once the stale permissions are served
+	ctx, cancel := context.WithCancel(context.Background())
+	req := inProcessCheckRequest()
+	req.Metadata = map[string]string{"request-id": "1"}
+	allowed, err := client.Check(ctx, req)
+	require.NoError(t, err)
+	require.True(t, allowed)
+	cancel()
+	req.Action = "folders:read"
+	req.Metadata["request-id"] = "2"
+
+	refreshed := <-authz.reqs
+	require.False(t, <-authz.done, "the refresh is not canceled with the check")
+	require.Equal(t, "dashboards:read", refreshed.GetAction())
+}
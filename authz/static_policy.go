@@ -0,0 +1,38 @@
+package authz
+
+// StaticRule allows a subject to perform an action without querying the authz service.
+type StaticRule struct {
+	// Subject is the subject of the caller identity (ex: "service-account:provisioning"),
+	// or the subject of the access token when the caller has no identity (ex: "access-policy:health-checker").
+	// Identities are only allowed the action when the service was delegated it.
+	Subject string
+	// Action is the allowed action, "*" allows all actions.
+	Action string
+}
+
+// StaticPolicy is a decision table for bootstrap and system identities (provisioning jobs, health checkers...).
+// It is consulted before the authz service so that core infrastructure keeps functioning
+// when the authz service is unavailable, for instance while it is being upgraded.
+// Allowed actions are granted on all resources, rules should be kept to a minimum.
+type StaticPolicy []StaticRule
+
+// Allows returns whether a rule allows the subject to perform the action.
+func (p StaticPolicy) Allows(subject, action string) bool {
+	if subject == "" {
+		return false
+	}
+	for _, r := range p {
+		if r.Subject == subject && (r.Action == "*" || r.Action == action) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithStaticPolicyLCOption sets the static decision table consulted before the authz service.
+// Namespace validation still applies to callers matching a rule.
+func WithStaticPolicyLCOption(rules ...StaticRule) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.staticPolicy = append(c.staticPolicy, rules...)
+	}
+}
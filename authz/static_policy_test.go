@@ -0,0 +1,92 @@
+package authz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+)
+
+func TestStaticPolicy_Allows(t *testing.T) {
+	policy := StaticPolicy{
+		{Subject: "service-account:provisioning", Action: "dashboards:write"},
+		{Subject: "access-policy:health-checker", Action: "*"},
+	}
+
+	require.True(t, policy.Allows("service-account:provisioning", "dashboards:write"))
+	require.False(t, policy.Allows("service-account:provisioning", "dashboards:delete"))
+	require.True(t, policy.Allows("access-policy:health-checker", "dashboards:delete"))
+	require.False(t, policy.Allows("user:1", "dashboards:write"))
+	require.False(t, policy.Allows("", "dashboards:write"))
+}
+
+func TestLegacyClientImpl_Check_StaticPolicy(t *testing.T) {
+	service := func(subject, namespace string) *authn.Access {
+		return authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+			Claims: &jwt.Claims{Subject: subject},
+			Rest:   authn.AccessTokenClaims{Namespace: namespace, DelegatedPermissions: []string{"dashboards:write"}},
+		})
+	}
+	identity := authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+		Claims: &jwt.Claims{Subject: "service-account:provisioning"},
+		Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+	})
+
+	tests := []struct {
+		name   string
+		caller *authn.AuthInfo
+		action string
+		rules  []StaticRule
+		want   bool
+	}{
+		{
+			name:   "should allow bootstrap identity",
+			caller: &authn.AuthInfo{AccessClaims: service("access-policy:provisioner", "stacks-12"), IdentityClaims: identity},
+			action: "dashboards:write",
+			want:   true,
+		},
+		{
+			name:   "should allow bootstrap service",
+			caller: &authn.AuthInfo{AccessClaims: service("access-policy:health-checker", "stacks-12")},
+			action: "datasources:query",
+			want:   true,
+		},
+		{
+			name:   "should not allow bootstrap identity without delegated permission",
+			caller: &authn.AuthInfo{AccessClaims: service("access-policy:provisioner", "stacks-12"), IdentityClaims: identity},
+			action: "dashboards:create",
+			rules:  []StaticRule{{Subject: "service-account:provisioning", Action: "dashboards:create"}},
+			want:   false,
+		},
+		{
+			name:   "should deny other actions without delegated permission",
+			caller: &authn.AuthInfo{AccessClaims: service("access-policy:provisioner", "stacks-12"), IdentityClaims: identity},
+			action: "dashboards:delete",
+			want:   false,
+		},
+		{
+			name:   "should validate the namespace",
+			caller: &authn.AuthInfo{AccessClaims: service("access-policy:health-checker", "stacks-13")},
+			action: "datasources:query",
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, authz := setupLegacyClient()
+			WithStaticPolicyLCOption(
+				StaticRule{Subject: "service-account:provisioning", Action: "dashboards:write"},
+				StaticRule{Subject: "access-policy:health-checker", Action: "*"},
+			)(client)
+			WithStaticPolicyLCOption(tt.rules...)(client)
+
+			got, err := client.Check(context.Background(), &CheckRequest{Caller: tt.caller, StackID: 12, Action: tt.action})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, 0, authz.reads)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package authz
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+
+	"github.com/grafana/authlib/internal/tlsconfig"
+)
+
+const DefaultTLSReloadInterval = tlsconfig.DefaultReloadInterval
+
+var (
+	ErrInvalidTLSConfig = tlsconfig.ErrInvalidTLSConfig
+	ErrUnexpectedSAN    = tlsconfig.ErrUnexpectedSAN
+)
+
+// TLSConfig configures the TLS, or mTLS when a client certificate is set, of the connection to the authz service.
+type TLSConfig struct {
+	// CertFile and KeyFile are the PEM encoded client certificate and key, for mTLS.
+	CertFile string
+	KeyFile  string
+	// CAFile is the PEM encoded bundle of the authorities of the server certificate, trusted instead of
+	// the system ones. The system authorities are used when empty.
+	CAFile string
+	// ServerSAN is the subject alternative name expected in the server certificate: a DNS name, an IP
+	// or a URI (ex: spiffe://grafana.net/authz). Defaults to the host of the remote address.
+	ServerSAN string
+	// ReloadInterval is how often the files are checked for changes, on new handshakes,
+	// so that rotated certificates are used without a restart. Defaults to 1 minute.
+	ReloadInterval time.Duration
+}
+
+// WithTLSConfigLCOption secures the connection to the authz service with TLS, or mTLS when a client certificate
+// is set. The creation of the client fails if WithGrpcConnectionLCOption is also used.
+func WithTLSConfigLCOption(cfg TLSConfig) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.tlsConfig = &cfg
+	}
+}
+
+// NewTLSCredentials returns the transport credentials of the TLS configuration, see WithTLSConfigLCOption.
+// The files are loaded right away, so that a misconfiguration fails at startup.
+func NewTLSCredentials(cfg TLSConfig) (credentials.TransportCredentials, error) {
+	files, err := tlsconfig.Load(tlsconfig.Config{
+		CertFile:       cfg.CertFile,
+		KeyFile:        cfg.KeyFile,
+		CAFile:         cfg.CAFile,
+		ServerSAN:      cfg.ServerSAN,
+		ReloadInterval: cfg.ReloadInterval,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tlsCredentials{TransportCredentials: credentials.NewTLS(files.ClientConfig("")), files: files}, nil
+}
+
+// tlsCredentials verifies the server certificate against the host of the authority when no SAN is expected.
+type tlsCredentials struct {
+	credentials.TransportCredentials
+	files *tlsconfig.Files
+}
+
+func (c *tlsCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	host := authority
+	if h, _, err := net.SplitHostPort(authority); err == nil {
+		host = h
+	}
+	return credentials.NewTLS(c.files.ClientConfig(host)).ClientHandshake(ctx, authority, conn)
+}
+
+func (c *tlsCredentials) Clone() credentials.TransportCredentials {
+	return &tlsCredentials{TransportCredentials: c.TransportCredentials.Clone(), files: c.files}
+}
@@ -0,0 +1,171 @@
+package authz
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "authz test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue returns the PEM encoded certificate and key, with the subject alternative names.
+func (ca *testCA) issue(t *testing.T, cn string, dnsNames []string, uris ...string) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     dnsNames,
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	for _, u := range uris {
+		parsed, err := url.Parse(u)
+		require.NoError(t, err)
+		tmpl.URIs = append(tmpl.URIs, parsed)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) pem() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func TestLegacyClientImpl_TLS(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+
+	serverCert, serverKey := ca.issue(t, "authz", []string{"authz.grafana.net"}, "spiffe://grafana.net/authz")
+	cert, err := tls.X509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})))
+	authzv1.RegisterAuthzServiceServer(server, &fakeAuthzServer{res: &authzv1.ReadResponse{Found: true}})
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	clientCert, clientKey := ca.issue(t, "grafana", nil)
+	writeFile(t, filepath.Join(dir, "client.crt"), clientCert)
+	writeFile(t, filepath.Join(dir, "client.key"), clientKey)
+	writeFile(t, filepath.Join(dir, "ca.crt"), ca.pem())
+
+	read := func(t *testing.T, cfg TLSConfig) error {
+		t.Helper()
+		client, err := NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: lis.Addr().String()}, WithTLSConfigLCOption(cfg))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = authzv1.NewAuthzServiceClient(client.grpcConn).Read(ctx, &authzv1.ReadRequest{})
+		return err
+	}
+
+	cfg := TLSConfig{
+		CertFile: filepath.Join(dir, "client.crt"),
+		KeyFile:  filepath.Join(dir, "client.key"),
+		CAFile:   filepath.Join(dir, "ca.crt"),
+	}
+
+	t.Run("should connect with mTLS and verify the expected SAN", func(t *testing.T) {
+		for _, san := range []string{"", "authz.grafana.net", "127.0.0.1", "spiffe://grafana.net/authz"} {
+			cfg := cfg
+			cfg.ServerSAN = san
+			require.NoError(t, read(t, cfg), san)
+		}
+	})
+
+	t.Run("should reject an unexpected SAN", func(t *testing.T) {
+		for _, san := range []string{"other.grafana.net", "spiffe://grafana.net/other"} {
+			cfg := cfg
+			cfg.ServerSAN = san
+			err := read(t, cfg)
+			require.Error(t, err, san)
+			require.Contains(t, err.Error(), ErrUnexpectedSAN.Error())
+		}
+	})
+
+	t.Run("should reject a server from another authority", func(t *testing.T) {
+		other := filepath.Join(dir, "other.crt")
+		writeFile(t, other, newTestCA(t).pem())
+
+		cfg := cfg
+		cfg.CAFile = other
+		require.Error(t, read(t, cfg))
+	})
+
+	t.Run("should fail on invalid configurations", func(t *testing.T) {
+		_, err := NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: lis.Addr().String()},
+			WithTLSConfigLCOption(TLSConfig{CertFile: cfg.CertFile}))
+		require.ErrorIs(t, err, ErrInvalidTLSConfig)
+
+		_, err = NewLegacyClient(&MultiTenantClientConfig{RemoteAddress: lis.Addr().String()},
+			WithTLSConfigLCOption(TLSConfig{CAFile: filepath.Join(dir, "missing.crt")}))
+		require.ErrorIs(t, err, ErrInvalidTLSConfig)
+	})
+}
@@ -0,0 +1,108 @@
+package authz
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ScopeUsage is the number of allowed checks a granted scope matched.
+type ScopeUsage struct {
+	StackID int64
+	Subject string
+	Action  string
+	// Scope is the granted scope that matched (ex: "dashboards:uid:1", "dashboards:uid:*" or "*").
+	// Empty for checks on the action only.
+	Scope string
+	// Count is the number of checks the scope matched since the last flush.
+	Count int64
+	// LastUsed is when the scope last matched.
+	LastUsed time.Time
+}
+
+type usageKey struct {
+	stackID int64
+	subject string
+	action  string
+	scope   string
+}
+
+// UsageCollector aggregates which granted scopes matched during checks.
+// Grants that never show up in the exported usage are candidates for revocation.
+// It is safe for concurrent use.
+type UsageCollector struct {
+	mtx   sync.Mutex
+	usage map[usageKey]*ScopeUsage
+	now   func() time.Time
+}
+
+func NewUsageCollector() *UsageCollector {
+	return &UsageCollector{
+		usage: map[usageKey]*ScopeUsage{},
+		now:   time.Now,
+	}
+}
+
+// Record counts a check allowed by the scope.
+func (u *UsageCollector) Record(stackID int64, subject, action, scope string) {
+	key := usageKey{stackID: stackID, subject: subject, action: action, scope: scope}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	usage, ok := u.usage[key]
+	if !ok {
+		usage = &ScopeUsage{StackID: stackID, Subject: subject, Action: action, Scope: scope}
+		u.usage[key] = usage
+	}
+	usage.Count++
+	usage.LastUsed = u.now()
+}
+
+// Snapshot returns the aggregated usage, sorted by stack, subject, action and scope.
+func (u *UsageCollector) Snapshot() []ScopeUsage {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	return u.export()
+}
+
+// Flush returns the aggregated usage and resets the counts.
+// It is meant to be called periodically to export the usage.
+func (u *UsageCollector) Flush() []ScopeUsage {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	res := u.export()
+	u.usage = map[usageKey]*ScopeUsage{}
+	return res
+}
+
+func (u *UsageCollector) export() []ScopeUsage {
+	res := make([]ScopeUsage, 0, len(u.usage))
+	for _, usage := range u.usage {
+		res = append(res, *usage)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.StackID != b.StackID {
+			return a.StackID < b.StackID
+		}
+		if a.Subject != b.Subject {
+			return a.Subject < b.Subject
+		}
+		if a.Action != b.Action {
+			return a.Action < b.Action
+		}
+		return a.Scope < b.Scope
+	})
+	return res
+}
+
+// WithUsageCollectorLCOption records the scopes matching the allowed checks of users and service accounts.
+// Disabled by default.
+func WithUsageCollectorLCOption(collector *UsageCollector) LegacyClientOption {
+	return func(c *LegacyClientImpl) {
+		c.usage = collector
+	}
+}
@@ -0,0 +1,79 @@
+package authz
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/authn"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+func TestUsageCollector(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	collector := NewUsageCollector()
+	collector.now = func() time.Time { return now }
+
+	collector.Record(12, "user:2", "dashboards:read", "dashboards:uid:1")
+	collector.Record(12, "user:1", "dashboards:read", "dashboards:uid:1")
+	now = now.Add(time.Minute)
+	collector.Record(12, "user:1", "dashboards:read", "dashboards:uid:1")
+
+	want := []ScopeUsage{
+		{StackID: 12, Subject: "user:1", Action: "dashboards:read", Scope: "dashboards:uid:1", Count: 2, LastUsed: now},
+		{StackID: 12, Subject: "user:2", Action: "dashboards:read", Scope: "dashboards:uid:1", Count: 1, LastUsed: now.Add(-time.Minute)},
+	}
+	require.Equal(t, want, collector.Snapshot())
+	require.Equal(t, want, collector.Flush())
+	require.Empty(t, collector.Snapshot())
+}
+
+func TestLegacyClientImpl_Check_Usage(t *testing.T) {
+	caller := &authn.AuthInfo{
+		AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+			Claims: &jwt.Claims{Subject: "service"},
+			Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read"}},
+		}),
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: "user:1"},
+			Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+		}),
+	}
+
+	client, authz := setupLegacyClient()
+	collector := NewUsageCollector()
+	WithUsageCollectorLCOption(collector)(client)
+	authz.res = &authzv1.ReadResponse{
+		Found: true,
+		Data: []*authzv1.ReadResponse_Data{
+			{Object: "dashboards:uid:1"},
+			{Object: "folders:uid:*"},
+			{Object: "dashboards:uid:2"},
+		},
+	}
+
+	check := func(resource *Resource, contextual ...Resource) bool {
+		ok, err := client.Check(context.Background(), &CheckRequest{
+			Caller: caller, StackID: 12, Action: "dashboards:read", Resource: resource, Contextual: contextual,
+		})
+		require.NoError(t, err)
+		return ok
+	}
+
+	require.True(t, check(nil))
+	require.True(t, check(&Resource{Kind: "dashboards", Attr: "uid", ID: "1"}))
+	require.True(t, check(&Resource{Kind: "dashboards", Attr: "uid", ID: "1"}))
+	require.True(t, check(&Resource{Kind: "dashboards", Attr: "uid", ID: "3"}, Resource{Kind: "folders", Attr: "uid", ID: "1"}))
+	require.False(t, check(&Resource{Kind: "dashboards", Attr: "uid", ID: "3"}))
+
+	got := map[string]int64{}
+	for _, u := range collector.Snapshot() {
+		require.Equal(t, "user:1", u.Subject)
+		got[u.Scope] = u.Count
+	}
+	// dashboards:uid:2 is never used
+	require.Equal(t, map[string]int64{"": 1, "dashboards:uid:1": 2, "folders:uid:*": 1}, got)
+}
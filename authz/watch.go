@@ -0,0 +1,157 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+var ErrWatchUnsupported = errors.New("the authz service does not support watching the permission changes")
+
+const (
+	watchMinBackoff = 100 * time.Millisecond
+	watchMaxBackoff = 30 * time.Second
+	// invalidationRetention is how long the invalidations are kept, longer than the permissions are cached.
+	invalidationRetention = 24 * time.Hour
+)
+
+// Watch subscribes to the permission changes of the stacks (all the stacks the client can watch when empty),
+// and invalidates the corresponding cached permissions, so that the checks do not wait for them to expire.
+// It blocks until the context is canceled, reconnecting when the stream fails: the permissions of the watched
+// stacks cached before a reconnection are invalidated, as changes may have been missed.
+// ErrWatchUnsupported is returned when the authz service does not implement the AuthzWatchService.
+func (c *LegacyClientImpl) Watch(ctx context.Context, stackIDs ...int64) error {
+	backoff := watchMinBackoff
+	for {
+		connected, err := c.watchOnce(ctx, stackIDs)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if status.Code(err) == codes.Unimplemented {
+			return ErrWatchUnsupported
+		}
+		c.logger.Warn("permission watch interrupted", "error", err)
+
+		if connected {
+			backoff = watchMinBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, watchMaxBackoff)
+	}
+}
+
+// watchOnce applies the changes streamed by the authz service, until the stream fails.
+// Connected is whether the stream received messages.
+func (c *LegacyClientImpl) watchOnce(ctx context.Context, stackIDs []int64) (connected bool, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.watchClient().Watch(ctx, &authzv1.WatchRequest{StackIds: stackIDs})
+	if err != nil {
+		return false, err
+	}
+
+	// Changes may have been missed while disconnected
+	c.invalidations.add(stackIDs, "", "", time.Now())
+
+	for {
+		change, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return connected, errors.New("stream closed by the authz service")
+		}
+		if err != nil {
+			return connected, err
+		}
+		connected = true
+		c.applyChange(ctx, change)
+	}
+}
+
+// applyChange invalidates the permissions impacted by the change.
+func (c *LegacyClientImpl) applyChange(ctx context.Context, change *authzv1.PermissionChange) {
+	stackID, subject, action := change.GetStackId(), change.GetSubject(), change.GetAction()
+	c.invalidations.add([]int64{stackID}, subject, action, time.Now())
+
+	// Evict the permissions of a single action, others are invalidated on read
+	if subject != "" && action != "" {
+		if err := c.cache.Delete(ctx, controllerCacheKey(stackID, subject, action)); err != nil {
+			c.logger.Debug("failed to evict the permissions", "stack_id", stackID, "action", action, "error", err)
+		}
+	}
+}
+
+// invalidations are the times the permissions were last invalidated, by stack, subject or action.
+// Cached permissions fetched before are stale. The zero value is ready to use.
+type invalidations struct {
+	mtx sync.RWMutex
+	// all is the invalidation of all the stacks
+	all    time.Time
+	byKey  map[invalidationKey]time.Time
+	pruned time.Time
+}
+
+// invalidationKey identifies the invalidated permissions, subject and action are empty for all of them.
+// An action without subject invalidates the action of all the subjects.
+type invalidationKey struct {
+	stackID int64
+	subject string
+	action  string
+}
+
+// add invalidates the permissions of the stacks (all of them when empty), of the subject and action if set.
+func (i *invalidations) add(stackIDs []int64, subject, action string, at time.Time) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	if len(stackIDs) == 0 {
+		i.all = at
+		return
+	}
+	if i.byKey == nil {
+		i.byKey = map[invalidationKey]time.Time{}
+	}
+	for _, stackID := range stackIDs {
+		i.byKey[invalidationKey{stackID: stackID, subject: subject, action: action}] = at
+	}
+
+	if at.Sub(i.pruned) > time.Minute {
+		i.pruned = at
+		for k, t := range i.byKey {
+			if at.Sub(t) > invalidationRetention {
+				delete(i.byKey, k)
+			}
+		}
+	}
+}
+
+// stale returns whether the permissions fetched at fetchedAt were invalidated since.
+func (i *invalidations) stale(stackID int64, subject, action string, fetchedAt time.Time) bool {
+	i.mtx.RLock()
+	defer i.mtx.RUnlock()
+
+	if !i.all.IsZero() && !fetchedAt.After(i.all) {
+		return true
+	}
+	for _, k := range []invalidationKey{
+		{stackID: stackID},
+		{stackID: stackID, subject: subject},
+		{stackID: stackID, action: action},
+		{stackID: stackID, subject: subject, action: action},
+	} {
+		if t, ok := i.byKey[k]; ok && !fetchedAt.After(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package authz
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/grafana/authlib/authn"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+// watchAuthzClient streams the changes sent on its channel.
+// The stream signals on receiving when it waits for the next change, once the previous one was applied.
+type watchAuthzClient struct {
+	changes   chan *authzv1.PermissionChange
+	receiving chan struct{}
+	err       error
+}
+
+func (f *watchAuthzClient) Watch(ctx context.Context, _ *authzv1.WatchRequest, _ ...grpc.CallOption) (authzv1.AuthzWatchService_WatchClient, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &fakeWatchStream{ctx: ctx, changes: f.changes, receiving: f.receiving}, nil
+}
+
+type fakeWatchStream struct {
+	grpc.ClientStream
+	ctx       context.Context
+	changes   chan *authzv1.PermissionChange
+	receiving chan struct{}
+}
+
+func (s *fakeWatchStream) Recv() (*authzv1.PermissionChange, error) {
+	select {
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	case s.receiving <- struct{}{}:
+	}
+
+	select {
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	case change, ok := <-s.changes:
+		if !ok {
+			return nil, io.EOF
+		}
+		return change, nil
+	}
+}
+
+func TestLegacyClientImpl_Watch(t *testing.T) {
+	caller := &authn.AuthInfo{
+		AccessClaims: authn.NewAccessClaims(authn.Claims[authn.AccessTokenClaims]{
+			Claims: &jwt.Claims{Subject: "service"},
+			Rest:   authn.AccessTokenClaims{Namespace: "stacks-12", DelegatedPermissions: []string{"dashboards:read", "folders:read"}},
+		}),
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: "user:1"},
+			Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+		}),
+	}
+	check := func(t *testing.T, client *LegacyClientImpl, action string) {
+		_, err := client.Check(context.Background(), &CheckRequest{Caller: caller, StackID: 12, Action: action})
+		require.NoError(t, err)
+	}
+	setup := func(t *testing.T) (*LegacyClientImpl, *FakeAuthzServiceClient, func(*authzv1.PermissionChange)) {
+		client, authz := setupLegacyClient()
+		authz.res = &authzv1.ReadResponse{Found: true}
+		watcher := &watchAuthzClient{changes: make(chan *authzv1.PermissionChange), receiving: make(chan struct{})}
+		client.watchV1 = watcher
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- client.Watch(ctx, 12) }()
+		t.Cleanup(func() {
+			cancel()
+			require.NoError(t, <-done)
+		})
+
+		// The cached permissions are fetched at a millisecond precision: the ones read in the millisecond
+		// of a change, or of the opening of the stream, are considered stale.
+		nextMillisecond := func() {
+			appliedAt := time.Now().UnixMilli()
+			require.Eventually(t, func() bool { return time.Now().UnixMilli() > appliedAt }, time.Second, 100*time.Microsecond)
+		}
+
+		// Wait for the stream to be opened
+		<-watcher.receiving
+		nextMillisecond()
+
+		// The change is applied once the stream waits for the next one
+		send := func(change *authzv1.PermissionChange) {
+			watcher.changes <- change
+			<-watcher.receiving
+			nextMillisecond()
+		}
+		return client, authz, send
+	}
+
+	t.Run("should evict the permissions of the changed action", func(t *testing.T) {
+		client, authz, send := setup(t)
+		check(t, client, "dashboards:read")
+		check(t, client, "folders:read")
+		require.Equal(t, 2, authz.reads)
+
+		send(&authzv1.PermissionChange{StackId: 12, Subject: "user:1", Action: "dashboards:read"})
+
+		check(t, client, "dashboards:read")
+		check(t, client, "folders:read")
+		require.Equal(t, 3, authz.reads)
+	})
+
+	t.Run("should invalidate all the permissions of the subject", func(t *testing.T) {
+		client, authz, send := setup(t)
+		check(t, client, "dashboards:read")
+		check(t, client, "folders:read")
+
+		send(&authzv1.PermissionChange{StackId: 12, Subject: "user:1"})
+
+		check(t, client, "dashboards:read")
+		check(t, client, "folders:read")
+		require.Equal(t, 4, authz.reads)
+
+		// The permissions read after the change are cached
+		check(t, client, "dashboards:read")
+		require.Equal(t, 4, authz.reads)
+	})
+
+	t.Run("should invalidate the action of all the subjects", func(t *testing.T) {
+		client, authz, send := setup(t)
+		check(t, client, "dashboards:read")
+		check(t, client, "folders:read")
+
+		send(&authzv1.PermissionChange{StackId: 12, Action: "dashboards:read"})
+
+		check(t, client, "dashboards:read")
+		check(t, client, "folders:read")
+		require.Equal(t, 3, authz.reads)
+	})
+
+	t.Run("should ignore the changes of other subjects", func(t *testing.T) {
+		client, authz, send := setup(t)
+		check(t, client, "dashboards:read")
+
+		send(&authzv1.PermissionChange{StackId: 12, Subject: "user:2"})
+		send(&authzv1.PermissionChange{StackId: 13})
+
+		check(t, client, "dashboards:read")
+		require.Equal(t, 1, authz.reads)
+	})
+
+	t.Run("should return when the service does not support the watch", func(t *testing.T) {
+		client, _ := setupLegacyClient()
+		client.watchV1 = &watchAuthzClient{err: status.Error(codes.Unimplemented, "")}
+		require.ErrorIs(t, client.Watch(context.Background()), ErrWatchUnsupported)
+	})
+}
+
+func TestInvalidations(t *testing.T) {
+	now := time.Now()
+	inv := invalidations{}
+	require.False(t, inv.stale(12, "user:1", "dashboards:read", time.Time{}))
+
+	inv.add([]int64{12}, "user:1", "dashboards:read", now)
+	require.True(t, inv.stale(12, "user:1", "dashboards:read", now))
+	require.False(t, inv.stale(12, "user:1", "dashboards:read", now.Add(time.Millisecond)))
+	require.False(t, inv.stale(12, "user:1", "folders:read", now))
+
+	// An action without subject invalidates the action of all the subjects
+	inv.add([]int64{12}, "", "dashboards:read", now.Add(time.Millisecond))
+	require.True(t, inv.stale(12, "user:1", "dashboards:read", now.Add(time.Millisecond)))
+	require.True(t, inv.stale(12, "user:2", "dashboards:read", now.Add(time.Millisecond)))
+	require.False(t, inv.stale(12, "user:1", "folders:read", now.Add(time.Millisecond)))
+
+	// Reconnections invalidate all the permissions of the watched stacks
+	inv.add([]int64{12}, "", "", now)
+	require.True(t, inv.stale(12, "user:2", "folders:read", now))
+	require.False(t, inv.stale(13, "user:2", "folders:read", now))
+
+	inv.add(nil, "", "", now)
+	require.True(t, inv.stale(13, "user:2", "folders:read", now))
+}
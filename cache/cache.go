@@ -6,6 +6,7 @@ import (
 	"time"
 
 	gocache "github.com/patrickmn/go-cache"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
@@ -32,6 +33,8 @@ type Cache interface {
 
 type LocalCache struct {
 	c *gocache.Cache
+	// loads deduplicates the concurrent loads of the same key, see GetOrSet
+	loads singleflight.Group
 }
 
 type Config struct {
@@ -0,0 +1,50 @@
+// Package cachetest provides test doubles of the caches, to test the resilience of services to cache failures.
+package cachetest
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/authlib/cache"
+	"github.com/grafana/authlib/internal/fault"
+)
+
+// ErrInjected is the default error of the failing calls.
+var ErrInjected = fault.ErrInjected
+
+// Faults is the latency and the errors injected in the calls.
+type Faults = fault.Config
+
+var _ cache.Cache = (*SlowCache)(nil)
+
+// SlowCache is a Cache injecting latency and errors in the calls to the wrapped cache.
+// The failing calls return ErrInjected, unless Faults.Err is set.
+type SlowCache struct {
+	cache  cache.Cache
+	faults *fault.Injector
+}
+
+func NewSlowCache(c cache.Cache, faults Faults) *SlowCache {
+	return &SlowCache{cache: c, faults: fault.New(faults, ErrInjected)}
+}
+
+func (c *SlowCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := c.faults.Inject(ctx); err != nil {
+		return nil, err
+	}
+	return c.cache.Get(ctx, key)
+}
+
+func (c *SlowCache) Set(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	if err := c.faults.Inject(ctx); err != nil {
+		return err
+	}
+	return c.cache.Set(ctx, key, value, expire)
+}
+
+func (c *SlowCache) Delete(ctx context.Context, key string) error {
+	if err := c.faults.Inject(ctx); err != nil {
+		return err
+	}
+	return c.cache.Delete(ctx, key)
+}
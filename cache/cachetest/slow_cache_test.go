@@ -0,0 +1,36 @@
+package cachetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/authlib/cache"
+)
+
+func TestSlowCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should forward the calls without faults", func(t *testing.T) {
+		c := NewSlowCache(cache.NewLocalCache(cache.Config{}), Faults{Latency: time.Millisecond})
+		require.NoError(t, c.Set(ctx, "key", []byte("value"), 0))
+
+		value, err := c.Get(ctx, "key")
+		require.NoError(t, err)
+		require.Equal(t, []byte("value"), value)
+
+		require.NoError(t, c.Delete(ctx, "key"))
+		_, err = c.Get(ctx, "key")
+		require.ErrorIs(t, err, cache.ErrNotFound)
+	})
+
+	t.Run("should fail the calls", func(t *testing.T) {
+		c := NewSlowCache(cache.NewLocalCache(cache.Config{}), Faults{ErrorRate: 1})
+		require.ErrorIs(t, c.Set(ctx, "key", []byte("value"), 0), ErrInjected)
+		_, err := c.Get(ctx, "key")
+		require.ErrorIs(t, err, ErrInjected)
+		require.ErrorIs(t, c.Delete(ctx, "key"), ErrInjected)
+	})
+}
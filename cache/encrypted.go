@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidEncryptionKey = errors.New("invalid cache encryption key: must be 16, 24 or 32 bytes")
+
+var _ Cache = (*EncryptedCache)(nil)
+
+// EncryptedCache is a Cache encrypting the values with AES-GCM, so that the permissions and tokens
+// stored in a shared cache (ex: Redis or memcached) are not in plaintext.
+// The values are authenticated with their key: a value copied to another key cannot be decrypted.
+type EncryptedCache struct {
+	cache Cache
+	aead  cipher.AEAD
+}
+
+// NewEncrypted wraps the cache to encrypt its values with the AES key, of 16, 24 or 32 bytes.
+func NewEncrypted(c Cache, key []byte) (*EncryptedCache, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEncryptionKey, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedCache{cache: c, aead: aead}, nil
+}
+
+// Get returns ErrNotFound for the values which cannot be decrypted, ex: encrypted with a previous key.
+func (c *EncryptedCache) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := c.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	size := c.aead.NonceSize()
+	if len(v) < size {
+		return nil, ErrNotFound
+	}
+	data, err := c.aead.Open(nil, v[:size], v[size:], []byte(key))
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (c *EncryptedCache) Set(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(value)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	return c.cache.Set(ctx, key, c.aead.Seal(nonce, nonce, value, []byte(key)), expire)
+}
+
+func (c *EncryptedCache) Delete(ctx context.Context, key string) error {
+	return c.cache.Delete(ctx, key)
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncrypted(t *testing.T) {
+	ctx := context.Background()
+	local := NewLocalCache(Config{})
+	key := bytes.Repeat([]byte("k"), 32)
+
+	c, err := NewEncrypted(local, key)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set(ctx, "perms", []byte("dashboards:uid:1"), 0))
+	raw, err := local.Get(ctx, "perms")
+	require.NoError(t, err)
+	require.NotContains(t, string(raw), "dashboards")
+
+	v, err := c.Get(ctx, "perms")
+	require.NoError(t, err)
+	require.Equal(t, []byte("dashboards:uid:1"), v)
+
+	t.Run("should not decrypt values copied to another key", func(t *testing.T) {
+		require.NoError(t, local.Set(ctx, "other", raw, 0))
+		_, err := c.Get(ctx, "other")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("should miss the values encrypted with another key", func(t *testing.T) {
+		rotated, err := NewEncrypted(local, bytes.Repeat([]byte("r"), 32))
+		require.NoError(t, err)
+		_, err = rotated.Get(ctx, "perms")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("should reject invalid keys", func(t *testing.T) {
+		_, err := NewEncrypted(local, []byte("short"))
+		require.ErrorIs(t, err, ErrInvalidEncryptionKey)
+	})
+}
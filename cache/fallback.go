@@ -0,0 +1,232 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultFallbackTimeout          = 100 * time.Millisecond
+	DefaultFallbackFailureThreshold = 3
+	DefaultFallbackReprobeInterval  = 30 * time.Second
+)
+
+// FallbackConfig configures the degradation of a remote cache to the local cache.
+type FallbackConfig struct {
+	// Timeout bounds each call to the remote cache. Defaults to 100ms.
+	Timeout time.Duration
+	// FailureThreshold is the number of consecutive failed calls degrading to the local cache. Defaults to 3.
+	FailureThreshold int
+	// ReprobeInterval is how often the remote cache is probed while degraded. Defaults to 30s.
+	ReprobeInterval time.Duration
+	// OnDegraded is called when the remote cache is detected unavailable (true), and when it recovers (false).
+	// It can be used to update a metric or a health flag.
+	OnDegraded func(degraded bool)
+}
+
+var _ Cache = (*FallbackCache)(nil)
+
+// FallbackCache is a Cache using a remote cache (ex: Redis), and degrading to the local cache while the remote
+// cache is unavailable, instead of paying a timeout on every call.
+// While degraded, a single call probes the remote cache every ReprobeInterval, the others only use the local cache.
+// The values are also written to the local cache, to be served while degraded.
+// The keys written or deleted while degraded are deleted from the remote cache once it recovers,
+// so that it does not serve values invalidated in the meantime.
+type FallbackCache struct {
+	remote     Cache
+	local      *LocalCache
+	timeout    time.Duration
+	threshold  int
+	reprobe    time.Duration
+	onDegraded func(bool)
+	now        func() time.Time
+
+	mtx       sync.Mutex
+	degraded  bool
+	failures  int
+	lastProbe time.Time
+	probing   bool
+	// dirty are the keys the remote cache missed the writes of, see replay
+	dirty map[string]struct{}
+}
+
+func NewFallbackCache(remote Cache, local *LocalCache, cfg FallbackConfig) *FallbackCache {
+	c := &FallbackCache{
+		remote:     remote,
+		local:      local,
+		timeout:    cfg.Timeout,
+		threshold:  cfg.FailureThreshold,
+		reprobe:    cfg.ReprobeInterval,
+		onDegraded: cfg.OnDegraded,
+		now:        time.Now,
+	}
+	if c.timeout <= 0 {
+		c.timeout = DefaultFallbackTimeout
+	}
+	if c.threshold <= 0 {
+		c.threshold = DefaultFallbackFailureThreshold
+	}
+	if c.reprobe <= 0 {
+		c.reprobe = DefaultFallbackReprobeInterval
+	}
+	return c
+}
+
+// Degraded returns whether the remote cache is considered unavailable.
+func (c *FallbackCache) Degraded() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.degraded
+}
+
+func (c *FallbackCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if c.useRemote() {
+		ctx, cancel := context.WithTimeout(ctx, c.timeout)
+		v, err := c.remote.Get(ctx, key)
+		cancel()
+		if c.record(err) {
+			// The remote value is stale until the write is replayed
+			dirty := c.isDirty(key)
+			c.replay(ctx)
+			if !dirty {
+				return v, err
+			}
+		}
+	}
+	return c.local.Get(ctx, key)
+}
+
+func (c *FallbackCache) Set(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	_ = c.local.Set(ctx, key, value, expire)
+	if !c.useRemote() {
+		c.markDirty(key)
+		return nil
+	}
+
+	rctx, cancel := context.WithTimeout(ctx, c.timeout)
+	err := c.remote.Set(rctx, key, value, expire)
+	cancel()
+	if !c.record(err) {
+		c.markDirty(key)
+		return nil
+	}
+	c.forget(key)
+	c.replay(ctx)
+	return err
+}
+
+func (c *FallbackCache) Delete(ctx context.Context, key string) error {
+	_ = c.local.Delete(ctx, key)
+	if !c.useRemote() {
+		c.markDirty(key)
+		return nil
+	}
+
+	rctx, cancel := context.WithTimeout(ctx, c.timeout)
+	err := c.remote.Delete(rctx, key)
+	cancel()
+	if !c.record(err) {
+		c.markDirty(key)
+		return nil
+	}
+	c.forget(key)
+	c.replay(ctx)
+	return err
+}
+
+func (c *FallbackCache) markDirty(key string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.markDirtyLocked(key)
+}
+
+func (c *FallbackCache) markDirtyLocked(key string) {
+	if c.dirty == nil {
+		c.dirty = map[string]struct{}{}
+	}
+	c.dirty[key] = struct{}{}
+}
+
+func (c *FallbackCache) forget(key string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.dirty, key)
+}
+
+func (c *FallbackCache) isDirty(key string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	_, ok := c.dirty[key]
+	return ok
+}
+
+// replay deletes the dirty keys from the remote cache, once it answers again.
+// The keys it could not delete are kept to be replayed after the next successful call.
+func (c *FallbackCache) replay(ctx context.Context) {
+	c.mtx.Lock()
+	keys := c.dirty
+	c.dirty = nil
+	c.mtx.Unlock()
+
+	for key := range keys {
+		rctx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := c.remote.Delete(rctx, key)
+		cancel()
+		if !c.record(err) {
+			break
+		}
+		delete(keys, key)
+	}
+
+	if len(keys) > 0 {
+		c.mtx.Lock()
+		for key := range keys {
+			c.markDirtyLocked(key)
+		}
+		c.mtx.Unlock()
+	}
+}
+
+// useRemote returns whether the call must use the remote cache. Otherwise, it only uses the local cache.
+// While degraded, it lets a single call probe the remote cache every reprobe interval.
+func (c *FallbackCache) useRemote() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if !c.degraded {
+		return true
+	}
+	if c.probing || c.now().Sub(c.lastProbe) < c.reprobe {
+		return false
+	}
+	c.probing, c.lastProbe = true, c.now()
+	return true
+}
+
+// record records the outcome of a call to the remote cache, and returns whether the remote cache answered.
+func (c *FallbackCache) record(err error) bool {
+	failed := err != nil && !errors.Is(err, ErrNotFound)
+
+	c.mtx.Lock()
+	c.probing = false
+	changed := false
+	switch {
+	case !failed:
+		c.failures = 0
+		changed, c.degraded = c.degraded, false
+	case !c.degraded:
+		c.failures++
+		if c.failures >= c.threshold {
+			changed, c.degraded, c.lastProbe = true, true, c.now()
+		}
+	}
+	degraded := c.degraded
+	c.mtx.Unlock()
+
+	if changed && c.onDegraded != nil {
+		c.onDegraded(degraded)
+	}
+	return !failed
+}
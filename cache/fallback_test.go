@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// switchableCache fails the calls with err, if set.
+type switchableCache struct {
+	Cache
+	err   error
+	calls int
+}
+
+func (c *switchableCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.Cache.Get(ctx, key)
+}
+
+func (c *switchableCache) Set(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	c.calls++
+	if c.err != nil {
+		return c.err
+	}
+	return c.Cache.Set(ctx, key, value, expire)
+}
+
+func (c *switchableCache) Delete(ctx context.Context, key string) error {
+	c.calls++
+	if c.err != nil {
+		return c.err
+	}
+	return c.Cache.Delete(ctx, key)
+}
+
+func TestFallbackCache(t *testing.T) {
+	ctx := context.Background()
+	remote := &switchableCache{Cache: NewLocalCache(Config{})}
+	var flags []bool
+	c := NewFallbackCache(remote, NewLocalCache(Config{}), FallbackConfig{
+		FailureThreshold: 2,
+		ReprobeInterval:  time.Minute,
+		OnDegraded:       func(degraded bool) { flags = append(flags, degraded) },
+	})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(ctx, "k", []byte("v"), 0))
+	v, err := remote.Cache.Get(ctx, "k")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v"), v)
+
+	// Misses are not failures
+	_, err = c.Get(ctx, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.False(t, c.Degraded())
+
+	// Degrade after consecutive failures, falling back to the local cache
+	remote.err = errors.New("connection refused")
+	for i := 0; i < 2; i++ {
+		v, err = c.Get(ctx, "k")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v"), v)
+	}
+	require.True(t, c.Degraded())
+	require.Equal(t, []bool{true}, flags)
+
+	// Only use the local cache while degraded
+	calls := remote.calls
+	require.NoError(t, c.Set(ctx, "k2", []byte("v2"), 0))
+	v, err = c.Get(ctx, "k2")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), v)
+	require.Equal(t, calls, remote.calls)
+
+	// Failed reprobe
+	now = now.Add(time.Minute)
+	_, err = c.Get(ctx, "k2")
+	require.NoError(t, err)
+	require.Equal(t, calls+1, remote.calls)
+	require.True(t, c.Degraded())
+	_, _ = c.Get(ctx, "k2")
+	require.Equal(t, calls+1, remote.calls, "should wait for the next reprobe")
+
+	// Recover on a successful reprobe
+	remote.err = nil
+	now = now.Add(time.Minute)
+	_, err = c.Get(ctx, "k")
+	require.NoError(t, err)
+	require.False(t, c.Degraded())
+	require.Equal(t, []bool{true, false}, flags)
+}
+
+func TestFallbackCache_ReplayOnRecovery(t *testing.T) {
+	ctx := context.Background()
+	remote := &switchableCache{Cache: NewLocalCache(Config{})}
+	c := NewFallbackCache(remote, NewLocalCache(Config{}), FallbackConfig{FailureThreshold: 1, ReprobeInterval: time.Minute})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	require.NoError(t, c.Set(ctx, "revoked", []byte("v"), 0))
+	require.NoError(t, c.Set(ctx, "updated", []byte("v1"), 0))
+
+	// Invalidate while degraded
+	remote.err = errors.New("connection refused")
+	_, _ = c.Get(ctx, "revoked")
+	require.True(t, c.Degraded())
+	require.NoError(t, c.Delete(ctx, "revoked"))
+	require.NoError(t, c.Set(ctx, "updated", []byte("v2"), 0))
+
+	// The recovering call must not serve the stale remote value
+	remote.err = nil
+	now = now.Add(time.Minute)
+	_, err := c.Get(ctx, "revoked")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.False(t, c.Degraded())
+
+	// The writes missed by the remote cache were replayed
+	_, err = remote.Cache.Get(ctx, "revoked")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = remote.Cache.Get(ctx, "updated")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = c.Get(ctx, "revoked")
+	require.ErrorIs(t, err, ErrNotFound)
+}
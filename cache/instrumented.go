@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricOperations is the name of the counter of the operations of the instrumented caches,
+// labelled by operation and result.
+const MetricOperations = "authlib_cache_operations_total"
+
+// Operations of the instrumented caches.
+const (
+	OpGet    = "get"
+	OpSet    = "set"
+	OpDelete = "delete"
+)
+
+// Results of the operations of the instrumented caches. Only gets hit or miss.
+const (
+	ResultHit     = "hit"
+	ResultMiss    = "miss"
+	ResultSuccess = "success"
+	ResultError   = "error"
+)
+
+// Recorder counts the operations of an instrumented cache, by operation and result.
+// See InstrumentedWithRecorder to count them elsewhere than in Prometheus, ex: with Stats.
+type Recorder interface {
+	Inc(operation, result string)
+}
+
+// RecorderFunc is a function implementing Recorder.
+type RecorderFunc func(operation, result string)
+
+func (f RecorderFunc) Inc(operation, result string) {
+	f(operation, result)
+}
+
+var _ Cache = (*InstrumentedCache)(nil)
+
+// InstrumentedCache is a Cache counting the operations of the wrapped cache.
+type InstrumentedCache struct {
+	cache    Cache
+	recorder Recorder
+}
+
+// Instrumented wraps the cache to count its gets, sets and deletes, its hits, misses and errors, with the
+// MetricOperations counter registered with the registerer. The counter is shared by the caches instrumented
+// with the same registerer. The counter is not registered when the registerer is nil.
+func Instrumented(c Cache, registerer prometheus.Registerer) (*InstrumentedCache, error) {
+	ops := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: MetricOperations,
+		Help: "Number of operations of the authlib caches, by operation and result.",
+	}, []string{"operation", "result"})
+
+	if registerer != nil {
+		if err := registerer.Register(ops); err != nil {
+			already := prometheus.AlreadyRegisteredError{}
+			if !errors.As(err, &already) {
+				return nil, err
+			}
+			registered, ok := already.ExistingCollector.(*prometheus.CounterVec)
+			if !ok {
+				return nil, err
+			}
+			ops = registered
+		}
+	}
+
+	return InstrumentedWithRecorder(c, RecorderFunc(func(operation, result string) {
+		ops.WithLabelValues(operation, result).Inc()
+	})), nil
+}
+
+// InstrumentedWithRecorder wraps the cache to count its gets, sets and deletes, its hits, misses and errors,
+// with the recorder.
+func InstrumentedWithRecorder(c Cache, recorder Recorder) *InstrumentedCache {
+	return &InstrumentedCache{cache: c, recorder: recorder}
+}
+
+func (c *InstrumentedCache) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := c.cache.Get(ctx, key)
+	switch {
+	case err == nil:
+		c.recorder.Inc(OpGet, ResultHit)
+	case errors.Is(err, ErrNotFound):
+		c.recorder.Inc(OpGet, ResultMiss)
+	default:
+		c.recorder.Inc(OpGet, ResultError)
+	}
+	return v, err
+}
+
+func (c *InstrumentedCache) Set(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	err := c.cache.Set(ctx, key, value, expire)
+	c.recorder.Inc(OpSet, result(err))
+	return err
+}
+
+func (c *InstrumentedCache) Delete(ctx context.Context, key string) error {
+	err := c.cache.Delete(ctx, key)
+	c.recorder.Inc(OpDelete, result(err))
+	return err
+}
+
+func result(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
+
+var _ Recorder = (*Stats)(nil)
+
+// Stats is a Recorder keeping the counts in memory, ex: to assert the cache efficiency in tests.
+// It is safe for concurrent use.
+type Stats struct {
+	mtx    sync.Mutex
+	counts map[[2]string]int64
+}
+
+func (s *Stats) Inc(operation, result string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.counts == nil {
+		s.counts = map[[2]string]int64{}
+	}
+	s.counts[[2]string{operation, result}]++
+}
+
+// Count returns the number of operations with the result.
+func (s *Stats) Count(operation, result string) int64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.counts[[2]string{operation, result}]
+}
+
+// HitRatio returns the ratio of the gets that hit, or zero without gets.
+func (s *Stats) HitRatio() float64 {
+	hits, misses, errs := s.Count(OpGet, ResultHit), s.Count(OpGet, ResultMiss), s.Count(OpGet, ResultError)
+	if total := hits + misses + errs; total > 0 {
+		return float64(hits) / float64(total)
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentedWithRecorder(t *testing.T) {
+	ctx := context.Background()
+	remote := &switchableCache{Cache: NewLocalCache(Config{})}
+	stats := &Stats{}
+	c := InstrumentedWithRecorder(remote, stats)
+
+	require.NoError(t, c.Set(ctx, "k", []byte("v"), 0))
+	_, err := c.Get(ctx, "k")
+	require.NoError(t, err)
+	_, err = c.Get(ctx, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, c.Delete(ctx, "k"))
+
+	remote.err = errors.New("connection refused")
+	_, err = c.Get(ctx, "k")
+	require.Error(t, err)
+	require.Error(t, c.Set(ctx, "k", []byte("v"), 0))
+
+	require.Equal(t, int64(1), stats.Count(OpGet, ResultHit))
+	require.Equal(t, int64(1), stats.Count(OpGet, ResultMiss))
+	require.Equal(t, int64(1), stats.Count(OpGet, ResultError))
+	require.Equal(t, int64(1), stats.Count(OpSet, ResultSuccess))
+	require.Equal(t, int64(1), stats.Count(OpSet, ResultError))
+	require.Equal(t, int64(1), stats.Count(OpDelete, ResultSuccess))
+	require.InDelta(t, 1.0/3, stats.HitRatio(), 0.001)
+}
+
+func TestInstrumented(t *testing.T) {
+	ctx := context.Background()
+	registry := prometheus.NewRegistry()
+	c, err := Instrumented(NewLocalCache(Config{}), registry)
+	require.NoError(t, err)
+
+	// The caches instrumented with the same registerer share the counter
+	other, err := Instrumented(NewLocalCache(Config{}), registry)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set(ctx, "k", []byte("v"), 0))
+	_, err = c.Get(ctx, "k")
+	require.NoError(t, err)
+	_, err = other.Get(ctx, "k")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	require.Equal(t, MetricOperations, families[0].GetName())
+
+	counts := map[string]float64{}
+	for _, m := range families[0].GetMetric() {
+		labels := map[string]string{}
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		counts[labels["operation"]+"/"+labels["result"]] = m.GetCounter().GetValue()
+	}
+	require.Equal(t, map[string]float64{"set/success": 1, "get/hit": 1, "get/miss": 1}, counts)
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CacheKey is a cache key built from length-delimited parts.
+// Unlike keys joined with separators, two different lists of parts can never produce the same key,
+// whatever characters the parts contain (ex: subjects or actions containing "-").
+type CacheKey string
+
+// NewCacheKey builds the key of the prefix and the parts.
+// Each element is encoded as "<length>:<value>", which makes the encoding injective.
+func NewCacheKey(prefix string, parts ...string) CacheKey {
+	size := len(prefix) + 4
+	for _, p := range parts {
+		size += len(p) + 4
+	}
+
+	b := strings.Builder{}
+	b.Grow(size)
+	writePart(&b, prefix)
+	for _, p := range parts {
+		writePart(&b, p)
+	}
+	return CacheKey(b.String())
+}
+
+func writePart(b *strings.Builder, part string) {
+	b.WriteString(strconv.Itoa(len(part)))
+	b.WriteByte(':')
+	b.WriteString(part)
+}
+
+func (k CacheKey) String() string {
+	return string(k)
+}
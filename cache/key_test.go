@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCacheKey(t *testing.T) {
+	require.Equal(t, CacheKey("4:read2:126:user:1"), NewCacheKey("read", "12", "user:1"))
+	require.Equal(t, CacheKey("4:read0:"), NewCacheKey("read", ""))
+	require.Equal(t, CacheKey("4:read"), NewCacheKey("read"))
+
+	// Keys joined with "-" would collide
+	require.NotEqual(t, NewCacheKey("read", "user:1-dashboards", "read"), NewCacheKey("read", "user:1", "dashboards-read"))
+	require.NotEqual(t, NewCacheKey("read-1", "a"), NewCacheKey("read", "1-a"))
+	require.NotEqual(t, NewCacheKey("read", "a", ""), NewCacheKey("read", "a"))
+	require.NotEqual(t, NewCacheKey("read", "1:a"), NewCacheKey("read", "1", "a"))
+}
+
+func FuzzNewCacheKey(f *testing.F) {
+	f.Add("read", "user:1-dashboards", "read", "read", "user:1", "dashboards-read")
+	f.Add("read", "1:a", "", "read", "1", "a")
+	f.Add("read-1", "a", "b", "read", "1-a", "b")
+	f.Add("", "", "", "", "", "")
+
+	f.Fuzz(func(t *testing.T, p1, a1, b1, p2, a2, b2 string) {
+		k1 := NewCacheKey(p1, a1, b1)
+		k2 := NewCacheKey(p2, a2, b2)
+		if (p1 == p2 && a1 == a2 && b1 == b2) != (k1 == k2) {
+			t.Fatalf("keys of (%q, %q, %q) and (%q, %q, %q): %q, %q", p1, a1, b1, p2, a2, b2, k1, k2)
+		}
+
+		// Different number of parts
+		if NewCacheKey(p1, a1) == NewCacheKey(p1, a1, b1) {
+			t.Fatalf("key of (%q, %q) collides with (%q, %q, %q)", p1, a1, p1, a1, b1)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var ErrWrite = errors.New("could not write value at cache key")
+
+// Loader loads the value of a key missing from the cache.
+type Loader func() ([]byte, error)
+
+// LoaderCache is a Cache loading the missing values, with stampede protection:
+// concurrent calls for the same missing key share a single load.
+type LoaderCache interface {
+	Cache
+
+	// GetOrSet gets the value of the key, or loads it and caches it for ttl.
+	// The errors of the cache, other than ErrNotFound, and of the loader are returned.
+	// When the loaded value cannot be cached, it is returned with an error wrapping ErrWrite.
+	GetOrSet(ctx context.Context, key string, ttl time.Duration, loader Loader) ([]byte, error)
+}
+
+var _ LoaderCache = (*LocalCache)(nil)
+
+func (lc *LocalCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader Loader) ([]byte, error) {
+	return getOrSet(ctx, lc, &lc.loads, key, ttl, loader)
+}
+
+type loadingCache struct {
+	Cache
+	loads singleflight.Group
+}
+
+// Loading returns the cache with the GetOrSet method. Caches already implementing LoaderCache are returned as is.
+// Wrap the cache once: the stampede protection is shared by the calls to the returned cache only.
+func Loading(c Cache) LoaderCache {
+	if lc, ok := c.(LoaderCache); ok {
+		return lc
+	}
+	return &loadingCache{Cache: c}
+}
+
+func (c *loadingCache) GetOrSet(ctx context.Context, key string, ttl time.Duration, loader Loader) ([]byte, error) {
+	return getOrSet(ctx, c.Cache, &c.loads, key, ttl, loader)
+}
+
+func getOrSet(ctx context.Context, c Cache, loads *singleflight.Group, key string, ttl time.Duration, loader Loader) ([]byte, error) {
+	v, err := c.Get(ctx, key)
+	if !errors.Is(err, ErrNotFound) {
+		return v, err
+	}
+
+	res, err, _ := loads.Do(key, func() (interface{}, error) {
+		v, err := loader()
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Set(ctx, key, v, ttl); err != nil {
+			return v, fmt.Errorf("%w: %w", ErrWrite, err)
+		}
+		return v, nil
+	})
+	v, _ = res.([]byte)
+	return v, err
+}
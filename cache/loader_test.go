@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrSet(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should load the missing values once", func(t *testing.T) {
+		c := NewLocalCache(Config{})
+		var loads atomic.Int32
+		gate := make(chan struct{})
+		loader := func() ([]byte, error) {
+			loads.Add(1)
+			<-gate
+			return []byte("v"), nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := c.GetOrSet(ctx, "k", 0, loader)
+				require.NoError(t, err)
+				require.Equal(t, []byte("v"), v)
+			}()
+		}
+		require.Eventually(t, func() bool { return loads.Load() == 1 }, time.Second, time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		close(gate)
+		wg.Wait()
+		require.Equal(t, int32(1), loads.Load())
+
+		v, err := c.GetOrSet(ctx, "k", 0, loader)
+		require.NoError(t, err)
+		require.Equal(t, []byte("v"), v)
+		require.Equal(t, int32(1), loads.Load(), "should get the cached value")
+	})
+
+	t.Run("should not cache the loader errors", func(t *testing.T) {
+		c := Loading(&switchableCache{Cache: NewLocalCache(Config{})})
+		_, err := c.GetOrSet(ctx, "k", 0, func() ([]byte, error) { return nil, errors.New("unavailable") })
+		require.Error(t, err)
+		_, err = c.Get(ctx, "k")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("should return the values failing to be cached", func(t *testing.T) {
+		remote := &switchableCache{Cache: NewLocalCache(Config{})}
+		c := Loading(remote)
+		remote.err = ErrNotFound
+		v, err := c.GetOrSet(ctx, "k", 0, func() ([]byte, error) { return []byte("v"), nil })
+		require.ErrorIs(t, err, ErrWrite)
+		require.Equal(t, []byte("v"), v)
+	})
+
+	t.Run("should return the cache errors", func(t *testing.T) {
+		remote := &switchableCache{Cache: NewLocalCache(Config{}), err: errors.New("connection refused")}
+		_, err := Loading(remote).GetOrSet(ctx, "k", 0, func() ([]byte, error) { return []byte("v"), nil })
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrWrite)
+	})
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+var _ Cache = (*VersionedCache)(nil)
+
+// VersionedCache is a Cache prefixing the values with the version of their schema.
+// The values of another version are cache misses, so that the instances of a fleet running different versions
+// of a schema, during a rollout, never decode the values of each other.
+type VersionedCache struct {
+	cache   Cache
+	version byte
+}
+
+// Versioned wraps the cache to version its values. Bump the version when the encoding of the values changes.
+func Versioned(c Cache, version byte) *VersionedCache {
+	return &VersionedCache{cache: c, version: version}
+}
+
+// Get returns ErrNotFound for the values of another version.
+func (c *VersionedCache) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := c.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(v) == 0 || v[0] != c.version {
+		return nil, ErrNotFound
+	}
+	return v[1:], nil
+}
+
+func (c *VersionedCache) Set(ctx context.Context, key string, value []byte, expire time.Duration) error {
+	v := make([]byte, 0, len(value)+1)
+	v = append(v, c.version)
+	return c.cache.Set(ctx, key, append(v, value...), expire)
+}
+
+func (c *VersionedCache) Delete(ctx context.Context, key string) error {
+	return c.cache.Delete(ctx, key)
+}
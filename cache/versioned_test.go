@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersioned(t *testing.T) {
+	ctx := context.Background()
+	local := NewLocalCache(Config{})
+	v1, v2 := Versioned(local, 1), Versioned(local, 2)
+
+	require.NoError(t, v1.Set(ctx, "k", []byte("v"), 0))
+	v, err := v1.Get(ctx, "k")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v"), v)
+
+	// Another version, or an unversioned value, is a miss
+	_, err = v2.Get(ctx, "k")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, local.Set(ctx, "k", nil, 0))
+	_, err = v1.Get(ctx, "k")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Empty values are kept
+	require.NoError(t, v1.Set(ctx, "k", []byte{}, 0))
+	v, err = v1.Get(ctx, "k")
+	require.NoError(t, err)
+	require.Empty(t, v)
+}
@@ -0,0 +1,41 @@
+package claims
+
+import "time"
+
+// AnonymousSubject is the subject of anonymous identities.
+// Anonymous identities have no identifier.
+var AnonymousSubject = NewTypeID(TypeAnonymous, "")
+
+var _ IdentityClaims = (*anonymousIdentity)(nil)
+
+// NewAnonymousIdentity returns the identity claims of an unauthenticated caller of the namespace,
+// for instance a viewer of a public dashboard.
+func NewAnonymousIdentity(namespace string) IdentityClaims {
+	return &anonymousIdentity{namespace: namespace}
+}
+
+// IsAnonymous returns true if the identity is anonymous.
+func IsAnonymous(id IdentityClaims) bool {
+	return identityType(id) == TypeAnonymous
+}
+
+type anonymousIdentity struct {
+	namespace string
+}
+
+func (a *anonymousIdentity) Issuer() string             { return "" }
+func (a *anonymousIdentity) Subject() string            { return AnonymousSubject }
+func (a *anonymousIdentity) Audience() []string         { return nil }
+func (a *anonymousIdentity) Expiry() *time.Time         { return nil }
+func (a *anonymousIdentity) NotBefore() *time.Time      { return nil }
+func (a *anonymousIdentity) IssuedAt() *time.Time       { return nil }
+func (a *anonymousIdentity) JTI() string                { return "" }
+func (a *anonymousIdentity) Namespace() string          { return a.namespace }
+func (a *anonymousIdentity) IdentityType() IdentityType { return TypeAnonymous }
+func (a *anonymousIdentity) Identifier() string         { return "" }
+func (a *anonymousIdentity) AuthenticatedBy() string    { return "" }
+func (a *anonymousIdentity) Email() string              { return "" }
+func (a *anonymousIdentity) EmailVerified() bool        { return false }
+func (a *anonymousIdentity) Username() string           { return "" }
+func (a *anonymousIdentity) DisplayName() string        { return "Anonymous" }
+func (a *anonymousIdentity) IsNil() bool                { return a == nil }
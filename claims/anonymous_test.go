@@ -0,0 +1,33 @@
+package claims_test
+
+import (
+	"testing"
+
+	"github.com/grafana/authlib/claims"
+)
+
+func TestAnonymousIdentity(t *testing.T) {
+	id := claims.NewAnonymousIdentity("stacks-12")
+
+	if !claims.IsAnonymous(id) {
+		t.Fatal("expected identity to be anonymous")
+	}
+	if id.Namespace() != "stacks-12" {
+		t.Fatalf("unexpected namespace: %s", id.Namespace())
+	}
+	if id.Identifier() != "" {
+		t.Fatalf("unexpected identifier: %s", id.Identifier())
+	}
+
+	typ, _, err := claims.ParseTypeID(id.Subject())
+	if err != nil || typ != claims.TypeAnonymous {
+		t.Fatalf("unexpected subject: %s", id.Subject())
+	}
+
+	if claims.IsAnonymous(&fakeIdentity{subject: "user:1"}) {
+		t.Fatal("expected user not to be anonymous")
+	}
+	if !claims.IsAnonymous(&fakeIdentity{subject: "anonymous:"}) {
+		t.Fatal("expected anonymous type from subject")
+	}
+}
@@ -0,0 +1,70 @@
+package claims
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotAPIKey     = errors.New("identity is not an api key")
+	ErrInvalidAPIKey = errors.New("invalid api key identity")
+)
+
+// APIKeyInfo describes an API-key principal.
+type APIKeyInfo struct {
+	// KeyID is the identifier of the API key.
+	KeyID string
+	// OrgID is the organization owning the API key.
+	OrgID int64
+	// Namespace is the namespace of the owning organization.
+	Namespace string
+}
+
+// NewAPIKeyTypeID returns the typed identifier of an API key (ex: "api-key:42").
+func NewAPIKeyTypeID(keyID string) string {
+	return NewTypeID(TypeAPIKey, keyID)
+}
+
+// IsAPIKey returns true if the identity is an API key.
+func IsAPIKey(id IdentityClaims) bool {
+	return identityType(id) == TypeAPIKey
+}
+
+// GetAPIKey returns the API key info of the identity.
+// ErrNotAPIKey is returned if the identity is not an API key,
+// ErrInvalidAPIKey if the key ID or the owning organization cannot be determined.
+func GetAPIKey(id IdentityClaims) (APIKeyInfo, error) {
+	if !IsAPIKey(id) {
+		return APIKeyInfo{}, ErrNotAPIKey
+	}
+
+	keyID := id.Identifier()
+	if keyID == "" {
+		_, keyID, _ = ParseTypeID(id.Subject())
+	}
+	if keyID == "" {
+		return APIKeyInfo{}, fmt.Errorf("%w: missing key id", ErrInvalidAPIKey)
+	}
+
+	ns, err := ParseNamespace(id.Namespace())
+	if err != nil || ns.OrgID < 1 {
+		return APIKeyInfo{}, fmt.Errorf("%w: invalid namespace '%s'", ErrInvalidAPIKey, id.Namespace())
+	}
+
+	return APIKeyInfo{KeyID: keyID, OrgID: ns.OrgID, Namespace: id.Namespace()}, nil
+}
+
+// identityType returns the type of the identity, falling back to the type of the subject.
+func identityType(id IdentityClaims) IdentityType {
+	if id == nil || id.IsNil() {
+		return TypeEmpty
+	}
+	if typ := id.IdentityType(); typ != TypeEmpty {
+		return typ
+	}
+	typ, _, err := ParseTypeID(id.Subject())
+	if err != nil {
+		return TypeEmpty
+	}
+	return typ
+}
@@ -0,0 +1,63 @@
+package claims_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/grafana/authlib/claims"
+)
+
+type fakeIdentity struct {
+	claims.IdentityClaims
+	subject    string
+	typ        claims.IdentityType
+	identifier string
+	namespace  string
+}
+
+func (f *fakeIdentity) Subject() string                   { return f.subject }
+func (f *fakeIdentity) IdentityType() claims.IdentityType { return f.typ }
+func (f *fakeIdentity) Identifier() string                { return f.identifier }
+func (f *fakeIdentity) Namespace() string                 { return f.namespace }
+func (f *fakeIdentity) IsNil() bool                       { return f == nil }
+
+func TestGetAPIKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity *fakeIdentity
+		expected claims.APIKeyInfo
+		err      error
+	}{
+		{
+			name:     "typed api key",
+			identity: &fakeIdentity{subject: "api-key:42", typ: claims.TypeAPIKey, identifier: "42", namespace: "org-3"},
+			expected: claims.APIKeyInfo{KeyID: "42", OrgID: 3, Namespace: "org-3"},
+		},
+		{
+			name:     "api key type from subject",
+			identity: &fakeIdentity{subject: "api-key:42", namespace: "stacks-12"},
+			expected: claims.APIKeyInfo{KeyID: "42", OrgID: 1, Namespace: "stacks-12"},
+		},
+		{
+			name:     "user",
+			identity: &fakeIdentity{subject: "user:1", typ: claims.TypeUser, identifier: "1", namespace: "default"},
+			err:      claims.ErrNotAPIKey,
+		},
+		{
+			name:     "api key without owning org",
+			identity: &fakeIdentity{subject: "api-key:42", typ: claims.TypeAPIKey, identifier: "42"},
+			err:      claims.ErrInvalidAPIKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := claims.GetAPIKey(tt.identity)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("GetAPIKey() returned error %v, expected %v", err, tt.err)
+			}
+			if info != tt.expected {
+				t.Errorf("GetAPIKey() returned %+v, expected %+v", info, tt.expected)
+			}
+		})
+	}
+}
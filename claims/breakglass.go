@@ -0,0 +1,32 @@
+package claims
+
+import "time"
+
+// BreakGlass is an emergency access grant carried by the identity claims.
+// It lets configured emergency identities bypass a failing authorization backend,
+// it does not grant any permission by itself.
+type BreakGlass interface {
+	// Reason of the emergency access (ex: an incident reference).
+	Reason() string
+	// IssuedAt is when the emergency access was granted.
+	IssuedAt() time.Time
+}
+
+// BreakGlassClaims is implemented by the identity claims that can carry a break-glass grant.
+type BreakGlassClaims interface {
+	// BreakGlass returns the break-glass grant, nil if the identity does not carry one.
+	BreakGlass() BreakGlass
+}
+
+// GetBreakGlass returns the break-glass grant of the identity, if any.
+func GetBreakGlass(id IdentityClaims) (BreakGlass, bool) {
+	if id == nil || id.IsNil() {
+		return nil, false
+	}
+	bg, ok := id.(BreakGlassClaims)
+	if !ok {
+		return nil, false
+	}
+	grant := bg.BreakGlass()
+	return grant, grant != nil
+}
@@ -2,6 +2,7 @@ package claims
 
 import (
 	"context"
+	"net/http"
 )
 
 // The key type is unexported to prevent collisions
@@ -12,11 +13,32 @@ const (
 	claimsKey key = iota
 )
 
+// From returns the AuthInfo stored in the context. It is equivalent to AuthInfoFrom.
 func From(ctx context.Context) (AuthInfo, bool) {
-	v, ok := ctx.Value(claimsKey).(AuthInfo)
-	return v, ok
+	return AuthInfoFrom(ctx)
 }
 
+// WithClaims returns a copy of the context holding the AuthInfo. It is equivalent to WithAuthInfo.
 func WithClaims(ctx context.Context, claims AuthInfo) context.Context {
-	return context.WithValue(ctx, claimsKey, claims)
+	return WithAuthInfo(ctx, claims)
+}
+
+// WithAuthInfo returns a copy of the context holding the AuthInfo of the caller.
+func WithAuthInfo(ctx context.Context, info AuthInfo) context.Context {
+	return context.WithValue(ctx, claimsKey, info)
+}
+
+// AuthInfoFrom returns the AuthInfo of the caller stored in the context.
+func AuthInfoFrom(ctx context.Context) (AuthInfo, bool) {
+	v, ok := ctx.Value(claimsKey).(AuthInfo)
+	return v, ok && v != nil
+}
+
+// AuthInfoFromRequest returns the AuthInfo of the caller stored in the request context,
+// typically by an authentication middleware.
+func AuthInfoFromRequest(r *http.Request) (AuthInfo, bool) {
+	if r == nil {
+		return nil, false
+	}
+	return AuthInfoFrom(r.Context())
 }
@@ -0,0 +1,43 @@
+package claims_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/authlib/claims"
+)
+
+type fakeAuthInfo struct {
+	claims.AuthInfo
+	uid string
+}
+
+func (f *fakeAuthInfo) GetUID() string {
+	return f.uid
+}
+
+func TestAuthInfoFrom(t *testing.T) {
+	if _, ok := claims.AuthInfoFrom(context.Background()); ok {
+		t.Fatal("expected no auth info")
+	}
+	if _, ok := claims.AuthInfoFrom(claims.WithAuthInfo(context.Background(), nil)); ok {
+		t.Fatal("expected no auth info for nil value")
+	}
+
+	ctx := claims.WithAuthInfo(context.Background(), &fakeAuthInfo{uid: "user:1"})
+	info, ok := claims.AuthInfoFrom(ctx)
+	if !ok || info.GetUID() != "user:1" {
+		t.Fatalf("unexpected auth info: %v", info)
+	}
+
+	// Legacy helpers share the same context key
+	if info, ok := claims.From(ctx); !ok || info.GetUID() != "user:1" {
+		t.Fatalf("unexpected auth info: %v", info)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	if info, ok := claims.AuthInfoFromRequest(req); !ok || info.GetUID() != "user:1" {
+		t.Fatalf("unexpected auth info: %v", info)
+	}
+}
@@ -0,0 +1,31 @@
+package claims
+
+// Actor is the identity acting as (impersonating) the subject of the identity claims.
+// It is modeled after the "act" claim of the token exchange specification:
+// [RFC 8693 §4.1]: https://datatracker.ietf.org/doc/html/rfc8693#section-4.1
+type Actor interface {
+	// Subject of the impersonator, in the form: <IdentityType>:<Identifier>
+	Subject() string
+	// DelegatedPermissions are the actions the impersonator delegates to the impersonation.
+	// Only these actions can be performed while acting as the subject.
+	DelegatedPermissions() []string
+}
+
+// ImpersonatedClaims is implemented by the identity claims that can carry an impersonator.
+type ImpersonatedClaims interface {
+	// Actor returns the impersonator, nil if the identity is not impersonated.
+	Actor() Actor
+}
+
+// GetActor returns the impersonator of the identity, if any.
+func GetActor(id IdentityClaims) (Actor, bool) {
+	if id == nil || id.IsNil() {
+		return nil, false
+	}
+	impersonated, ok := id.(ImpersonatedClaims)
+	if !ok {
+		return nil, false
+	}
+	actor := impersonated.Actor()
+	return actor, actor != nil
+}
@@ -0,0 +1,41 @@
+package claims
+
+// MembershipClaims is implemented by the identity claims that can carry the memberships of the identity.
+// The groups come from the identity provider (ex: SAML or OIDC groups) and the teams are the Grafana teams.
+// They are distinct from the Kubernetes groups returned by AuthInfo.GetGroups.
+type MembershipClaims interface {
+	// Groups returns the names of the groups the identity is a member of.
+	Groups() []string
+	// Teams returns the UIDs of the teams the identity is a member of.
+	Teams() []string
+}
+
+// GetMembership returns the groups and teams of the identity, if any.
+func GetMembership(id IdentityClaims) (groups, teams []string) {
+	if id == nil || id.IsNil() {
+		return nil, nil
+	}
+	membership, ok := id.(MembershipClaims)
+	if !ok {
+		return nil, nil
+	}
+	return membership.Groups(), membership.Teams()
+}
+
+// MembershipGroups returns the groups the identity of the caller is a member of.
+func MembershipGroups(info AuthInfo) []string {
+	if info == nil {
+		return nil
+	}
+	groups, _ := GetMembership(info.GetIdentity())
+	return groups
+}
+
+// MembershipTeams returns the teams the identity of the caller is a member of.
+func MembershipTeams(info AuthInfo) []string {
+	if info == nil {
+		return nil
+	}
+	_, teams := GetMembership(info.GetIdentity())
+	return teams
+}
@@ -24,19 +24,96 @@ func OrgNamespaceFormatter(id int64) string {
 	return fmt.Sprintf("org-%d", id)
 }
 
+// OrgIDNamespaceFormatter formats all organizations as "org-<id>", including the default organization.
+// Use it with OrgNamespaceMatches for single-tenant deployments issuing "org-1" rather than "default".
+func OrgIDNamespaceFormatter(id int64) string {
+	return fmt.Sprintf("org-%d", id)
+}
+
+// NamespaceMatcher returns whether the claims are valid within the namespace.
+type NamespaceMatcher func(c Namespaced, namespace string) bool
+
+var (
+	_ NamespaceMatcher = NamespaceMatches
+	_ NamespaceMatcher = OrgNamespaceMatches
+)
+
 // disambiguateNamespace is a helper to temporarily navigate the issue with cloud namespace claims being ambiguous (stack vs stacks).
 func disambiguateNamespace(namespace string) string {
 	return strings.Replace(namespace, "stack-", "stacks-", 1)
 }
 
+// NamespaceMatches returns whether the claims are valid within the namespace.
+// The namespace of the claims can be "*" (all namespaces), a pattern matching a kind of namespaces
+// (ex: "stacks-*"), or a comma-separated list of namespaces and patterns (ex: "stacks-1,stacks-2").
+// Patterns and lists are only supported for the claims of access tokens: the namespace of the ID tokens
+// must be the namespace itself.
 func NamespaceMatches(c Namespaced, namespace string) bool {
-	actual := disambiguateNamespace(c.Namespace())
-	expected := disambiguateNamespace(namespace)
+	if isIdentity(c) {
+		return matchIdentityNamespace(c.Namespace(), namespace, disambiguateNamespace)
+	}
+	return matchNamespace(c.Namespace(), namespace, disambiguateNamespace)
+}
+
+// OrgNamespaceMatches matches organization namespaces of single-tenant deployments,
+// where "default" and "org-1" both refer to the default organization.
+// Patterns and lists are supported as in NamespaceMatches, "org-*" does not match "default".
+func OrgNamespaceMatches(c Namespaced, namespace string) bool {
+	if isIdentity(c) {
+		return matchIdentityNamespace(c.Namespace(), namespace, normalizeOrgNamespace)
+	}
+	return matchNamespace(c.Namespace(), namespace, normalizeOrgNamespace)
+}
+
+// isIdentity returns whether the claims are the claims of an ID token.
+func isIdentity(c Namespaced) bool {
+	if _, ok := c.(AccessClaims); ok {
+		return false
+	}
+	_, ok := c.(IdentityClaims)
+	return ok
+}
+
+// matchIdentityNamespace returns whether the namespace of ID token claims is the expected namespace.
+func matchIdentityNamespace(actual, expected string, normalize func(string) string) bool {
 	// actual should never be a "*" where ID token claims are concerned
 	if actual == "*" {
 		return true
 	}
-	return actual == expected
+	return normalize(actual) == normalize(expected)
+}
+
+func normalizeOrgNamespace(namespace string) string {
+	if namespace == "org-1" {
+		return "default"
+	}
+	return namespace
+}
+
+// matchNamespace returns whether any of the comma-separated patterns matches the expected namespace.
+func matchNamespace(patterns, expected string, normalize func(string) string) bool {
+	expected = normalize(expected)
+	for _, p := range strings.Split(patterns, ",") {
+		p = strings.TrimSpace(p)
+		if p == "*" {
+			return true
+		}
+		if p == "" {
+			continue
+		}
+
+		p = normalize(p)
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasSuffix(prefix, "-") && len(expected) > len(prefix) && strings.HasPrefix(expected, prefix) {
+				return true
+			}
+			continue
+		}
+		if p == expected {
+			return true
+		}
+	}
+	return false
 }
 
 type NamespaceInfo struct {
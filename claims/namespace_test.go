@@ -149,3 +149,77 @@ func TestParseNamespace(t *testing.T) {
 		})
 	}
 }
+
+type namespaced string
+
+func (n namespaced) Namespace() string { return string(n) }
+
+func TestOrgNamespaceMatches(t *testing.T) {
+	tests := []struct {
+		actual   string
+		expected string
+		matches  bool
+	}{
+		{actual: "default", expected: "default", matches: true},
+		{actual: "org-1", expected: "default", matches: true},
+		{actual: "default", expected: "org-1", matches: true},
+		{actual: "org-2", expected: "org-2", matches: true},
+		{actual: "*", expected: "org-2", matches: true},
+		{actual: "org-2", expected: "org-3", matches: false},
+		{actual: "stacks-1", expected: "org-1", matches: false},
+		{actual: "org-*", expected: "org-3", matches: true},
+		{actual: "org-*", expected: "default", matches: false},
+		{actual: "org-2,org-1", expected: "default", matches: true},
+	}
+	for _, tt := range tests {
+		if got := claims.OrgNamespaceMatches(namespaced(tt.actual), tt.expected); got != tt.matches {
+			t.Errorf("OrgNamespaceMatches(%s, %s) = %v, expected %v", tt.actual, tt.expected, got, tt.matches)
+		}
+	}
+
+	if ns := claims.OrgIDNamespaceFormatter(1); ns != "org-1" {
+		t.Errorf("unexpected namespace: %s", ns)
+	}
+}
+
+func TestNamespaceMatches(t *testing.T) {
+	tests := []struct {
+		actual   string
+		expected string
+		matches  bool
+	}{
+		{actual: "stacks-1", expected: "stacks-1", matches: true},
+		{actual: "stack-1", expected: "stacks-1", matches: true},
+		{actual: "stacks-1", expected: "stacks-2", matches: false},
+		{actual: "*", expected: "stacks-2", matches: true},
+		{actual: "stacks-*", expected: "stacks-2", matches: true},
+		{actual: "stack-*", expected: "stacks-2", matches: true},
+		{actual: "stacks-*", expected: "stack-2", matches: true},
+		{actual: "stacks-*", expected: "stacks-", matches: false},
+		{actual: "stacks-*", expected: "org-2", matches: false},
+		{actual: "stacks*", expected: "stacks-2", matches: false},
+		{actual: "stacks-1,stacks-2", expected: "stacks-2", matches: true},
+		{actual: "stacks-1, stacks-2", expected: "stacks-2", matches: true},
+		{actual: "stacks-1,stacks-2", expected: "stacks-3", matches: false},
+		{actual: "stacks-1,org-*", expected: "org-3", matches: true},
+		{actual: "", expected: "stacks-1", matches: false},
+	}
+	for _, tt := range tests {
+		if got := claims.NamespaceMatches(namespaced(tt.actual), tt.expected); got != tt.matches {
+			t.Errorf("NamespaceMatches(%s, %s) = %v, expected %v", tt.actual, tt.expected, got, tt.matches)
+		}
+	}
+
+	// The namespace of the ID tokens must be the namespace itself
+	for _, actual := range []string{"stacks-*", "stacks-1,stacks-2", "stacks-2,stacks-1"} {
+		if claims.NamespaceMatches(claims.NewAnonymousIdentity(actual), "stacks-2") {
+			t.Errorf("NamespaceMatches(identity %s, stacks-2) = true, expected false", actual)
+		}
+	}
+	if !claims.NamespaceMatches(claims.NewAnonymousIdentity("stack-2"), "stacks-2") {
+		t.Errorf("NamespaceMatches(identity stack-2, stacks-2) = false, expected true")
+	}
+	if claims.OrgNamespaceMatches(claims.NewAnonymousIdentity("org-*"), "org-2") {
+		t.Errorf("OrgNamespaceMatches(identity org-*, org-2) = true, expected false")
+	}
+}
@@ -0,0 +1,193 @@
+package authlib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+var ErrConnProviderClosed = status.Errorf(codes.Unavailable, "grpc connection provider closed")
+
+const DefaultConnShutdownTimeout = 10 * time.Second
+
+// DefaultKeepalive pings the idle connections every 5 minutes, the minimum interval accepted by default by gRPC servers.
+var DefaultKeepalive = keepalive.ClientParameters{
+	Time:    5 * time.Minute,
+	Timeout: 20 * time.Second,
+}
+
+type GrpcConnProviderOption func(*GrpcConnProvider)
+
+// WithConnDialOptions sets the dial options of the connections (ex: transport credentials, interceptors).
+func WithConnDialOptions(opts ...grpc.DialOption) GrpcConnProviderOption {
+	return func(p *GrpcConnProvider) {
+		p.dialOpts = append(p.dialOpts, opts...)
+	}
+}
+
+// WithConnKeepalive sets the keepalive parameters of the connections. Defaults to DefaultKeepalive.
+// Shorter intervals must be allowed by the enforcement policy of the servers.
+func WithConnKeepalive(params keepalive.ClientParameters) GrpcConnProviderOption {
+	return func(p *GrpcConnProvider) {
+		p.keepalive = params
+	}
+}
+
+// WithConnShutdownTimeout sets how long Close waits for the in-flight calls and the open streams. Defaults to 10s.
+func WithConnShutdownTimeout(timeout time.Duration) GrpcConnProviderOption {
+	return func(p *GrpcConnProvider) {
+		p.shutdownTimeout = timeout
+	}
+}
+
+// GrpcConnProvider shares a single connection per target between the gRPC clients of an application
+// (ex: the authz client), instead of each client dialing its own. Close shuts the connections down gracefully.
+// It is safe for concurrent use.
+type GrpcConnProvider struct {
+	dialOpts        []grpc.DialOption
+	keepalive       keepalive.ClientParameters
+	shutdownTimeout time.Duration
+
+	mtx    sync.Mutex
+	conns  map[string]*grpc.ClientConn
+	closed bool
+	// inflight are the unary calls and the streams drained by Close
+	inflight sync.WaitGroup
+}
+
+func NewGrpcConnProvider(opts ...GrpcConnProviderOption) *GrpcConnProvider {
+	p := &GrpcConnProvider{
+		keepalive:       DefaultKeepalive,
+		shutdownTimeout: DefaultConnShutdownTimeout,
+		conns:           map[string]*grpc.ClientConn{},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Conn returns the connection to the target, dialing it on first use.
+func (p *GrpcConnProvider) Conn(target string) (grpc.ClientConnInterface, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.closed {
+		return nil, ErrConnProviderClosed
+	}
+	if conn, ok := p.conns[target]; ok {
+		return &sharedConn{provider: p, conn: conn}, nil
+	}
+
+	opts := append([]grpc.DialOption{grpc.WithKeepaliveParams(p.keepalive)}, p.dialOpts...)
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[target] = conn
+	return &sharedConn{provider: p, conn: conn}, nil
+}
+
+// Close stops accepting calls, waits for the in-flight unary calls and the open streams for at most
+// the shutdown timeout or until the context is done, then closes the connections, which ends the remaining streams.
+func (p *GrpcConnProvider) Close(ctx context.Context) error {
+	p.mtx.Lock()
+	if p.closed {
+		p.mtx.Unlock()
+		return nil
+	}
+	p.closed = true
+	conns := p.conns
+	p.conns = nil
+	p.mtx.Unlock()
+
+	if p.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.shutdownTimeout)
+		defer cancel()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		p.inflight.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+	}
+
+	var errs []error
+	for _, conn := range conns {
+		errs = append(errs, conn.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// acquire registers an in-flight call, unless the provider is closed.
+func (p *GrpcConnProvider) acquire() bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.closed {
+		return false
+	}
+	p.inflight.Add(1)
+	return true
+}
+
+// sharedConn tracks the calls on a shared connection, for the graceful shutdown of the provider.
+type sharedConn struct {
+	provider *GrpcConnProvider
+	conn     *grpc.ClientConn
+}
+
+func (c *sharedConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	if !c.provider.acquire() {
+		return ErrConnProviderClosed
+	}
+	defer c.provider.inflight.Done()
+	return c.conn.Invoke(ctx, method, args, reply, opts...)
+}
+
+func (c *sharedConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if !c.provider.acquire() {
+		return nil, ErrConnProviderClosed
+	}
+	stream, err := c.conn.NewStream(ctx, desc, method, opts...)
+	if err != nil {
+		c.provider.inflight.Done()
+		return nil, err
+	}
+
+	s := &sharedStream{ClientStream: stream, provider: c.provider}
+	s.stop = context.AfterFunc(stream.Context(), s.release)
+	return s, nil
+}
+
+// sharedStream releases the stream from the in-flight calls of the provider once it ends,
+// that is when a message cannot be received anymore or the context of the stream is done.
+type sharedStream struct {
+	grpc.ClientStream
+	provider *GrpcConnProvider
+	once     sync.Once
+	stop     func() bool
+}
+
+func (s *sharedStream) release() {
+	s.once.Do(s.provider.inflight.Done)
+}
+
+func (s *sharedStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.stop()
+		s.release()
+	}
+	return err
+}
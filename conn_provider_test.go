@@ -0,0 +1,169 @@
+package authlib
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/grafana/authlib/authz"
+	authzv1 "github.com/grafana/authlib/authz/proto/v1"
+)
+
+// blockingAuthzServer blocks the reads until released.
+type blockingAuthzServer struct {
+	authzv1.UnimplementedAuthzServiceServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingAuthzServer) Read(ctx context.Context, _ *authzv1.ReadRequest) (*authzv1.ReadResponse, error) {
+	s.started <- struct{}{}
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &authzv1.ReadResponse{Found: true}, nil
+}
+
+func setupConnProvider(t *testing.T, opts ...GrpcConnProviderOption) (*GrpcConnProvider, *blockingAuthzServer) {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	srv := &blockingAuthzServer{started: make(chan struct{}, 10), release: make(chan struct{})}
+	server := grpc.NewServer()
+	authzv1.RegisterAuthzServiceServer(server, srv)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	opts = append(opts, WithConnDialOptions(
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	))
+	return NewGrpcConnProvider(opts...), srv
+}
+
+func TestGrpcConnProvider(t *testing.T) {
+	t.Run("should share the connection of a target", func(t *testing.T) {
+		conns, _ := setupConnProvider(t)
+		defer conns.Close(context.Background())
+
+		first, err := conns.Conn("passthrough:///authz")
+		require.NoError(t, err)
+		second, err := conns.Conn("passthrough:///authz")
+		require.NoError(t, err)
+		other, err := conns.Conn("passthrough:///other")
+		require.NoError(t, err)
+
+		require.Same(t, first.(*sharedConn).conn, second.(*sharedConn).conn)
+		require.NotSame(t, first.(*sharedConn).conn, other.(*sharedConn).conn)
+	})
+
+	t.Run("should be used by the clients of the provider", func(t *testing.T) {
+		conns, srv := setupConnProvider(t)
+		defer conns.Close(context.Background())
+		close(srv.release)
+
+		p := NewProvider(Config{Authz: authz.MultiTenantClientConfig{RemoteAddress: "passthrough:///authz"}}, WithGrpcConnProvider(conns))
+		client, err := p.AuthzClient()
+		require.NoError(t, err)
+
+		// The authz service is not registered on the health server
+		require.Equal(t, codes.NotFound, status.Code(client.Health(context.Background())))
+		require.Len(t, conns.conns, 1)
+	})
+
+	t.Run("should refuse the dial options of the clients", func(t *testing.T) {
+		conns, _ := setupConnProvider(t)
+		defer conns.Close(context.Background())
+
+		p := NewProvider(Config{Authz: authz.MultiTenantClientConfig{RemoteAddress: "passthrough:///authz"}},
+			WithGrpcConnProvider(conns), WithAuthzClientOptions(authz.WithWaitForReadyLCOption(time.Second)))
+		_, err := p.AuthzClient()
+		require.ErrorIs(t, err, authz.ErrConflictingConn)
+	})
+
+	t.Run("should drain the open streams on close", func(t *testing.T) {
+		conns, _ := setupConnProvider(t)
+		conn, err := conns.Conn("passthrough:///authz")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.NoError(t, err)
+
+		closed := make(chan error)
+		go func() { closed <- conns.Close(context.Background()) }()
+
+		select {
+		case <-closed:
+			t.Fatal("closed before the stream ended")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		cancel()
+		require.NoError(t, <-closed)
+	})
+
+	t.Run("should drain the in-flight calls on close", func(t *testing.T) {
+		conns, srv := setupConnProvider(t)
+		conn, err := conns.Conn("passthrough:///authz")
+		require.NoError(t, err)
+
+		called := make(chan error)
+		go func() {
+			_, err := authzv1.NewAuthzServiceClient(conn).Read(context.Background(), &authzv1.ReadRequest{})
+			called <- err
+		}()
+		<-srv.started
+
+		closed := make(chan error)
+		go func() { closed <- conns.Close(context.Background()) }()
+
+		require.Eventually(t, func() bool {
+			_, err := authzv1.NewAuthzServiceClient(conn).Read(context.Background(), &authzv1.ReadRequest{})
+			return err == ErrConnProviderClosed
+		}, time.Second, time.Millisecond, "new calls are rejected")
+		_, err = conns.Conn("passthrough:///authz")
+		require.ErrorIs(t, err, ErrConnProviderClosed)
+
+		select {
+		case <-closed:
+			t.Fatal("closed before the in-flight call completed")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(srv.release)
+		require.NoError(t, <-called)
+		require.NoError(t, <-closed)
+	})
+
+	t.Run("should close the connections after the shutdown timeout", func(t *testing.T) {
+		conns, srv := setupConnProvider(t, WithConnShutdownTimeout(20*time.Millisecond))
+		defer close(srv.release)
+		conn, err := conns.Conn("passthrough:///authz")
+		require.NoError(t, err)
+
+		called := make(chan error)
+		go func() {
+			_, err := authzv1.NewAuthzServiceClient(conn).Read(context.Background(), &authzv1.ReadRequest{})
+			called <- err
+		}()
+		<-srv.started
+
+		require.NoError(t, conns.Close(context.Background()))
+		require.Error(t, <-called)
+	})
+}
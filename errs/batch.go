@@ -0,0 +1,98 @@
+// Package errs provides error types shared by the authn and authz packages.
+// Kind classifies their errors, see KindOf, GRPCCode and HTTPStatus.
+package errs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ItemError is the error of an item of a batch operation.
+type ItemError struct {
+	// Index is the position of the item in the batch.
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %s", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// Batch aggregates the errors of the items of a batch operation, so that callers can retry only the failed items.
+// errors.Is and errors.As match the errors of the items (ex: errors.As(err, &itemErr) finds the first failed item).
+// It is safe for concurrent use.
+type Batch struct {
+	mtx  sync.Mutex
+	errs []*ItemError
+}
+
+// Add records the error of the item at the index. Nil errors are ignored.
+func (b *Batch) Add(index int, err error) {
+	if err == nil {
+		return
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.errs = append(b.errs, &ItemError{Index: index, Err: err})
+}
+
+// Len returns the number of failed items.
+func (b *Batch) Len() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return len(b.errs)
+}
+
+// Errors returns the errors of the failed items, ordered by index.
+func (b *Batch) Errors() []*ItemError {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	res := append([]*ItemError(nil), b.errs...)
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Index < res[j].Index })
+	return res
+}
+
+// Failed returns the indices of the failed items, in order.
+func (b *Batch) Failed() []int {
+	errs := b.Errors()
+	res := make([]int, 0, len(errs))
+	for _, e := range errs {
+		res = append(res, e.Index)
+	}
+	return res
+}
+
+// Err returns the batch if any item failed, nil otherwise.
+func (b *Batch) Err() error {
+	if b.Len() == 0 {
+		return nil
+	}
+	return b
+}
+
+func (b *Batch) Error() string {
+	errs := b.Errors()
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	return fmt.Sprintf("%d failed items: %s", len(errs), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the failed items, for errors.Is and errors.As.
+func (b *Batch) Unwrap() []error {
+	errs := b.Errors()
+	res := make([]error, 0, len(errs))
+	for _, e := range errs {
+		res = append(res, e)
+	}
+	return res
+}
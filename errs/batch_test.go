@@ -0,0 +1,50 @@
+package errs
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errNotFound = errors.New("not found")
+
+func TestBatch(t *testing.T) {
+	b := &Batch{}
+	require.NoError(t, b.Err())
+
+	b.Add(3, errNotFound)
+	b.Add(1, errors.New("timeout"))
+	b.Add(2, nil)
+
+	err := b.Err()
+	require.Error(t, err)
+	require.Equal(t, 2, b.Len())
+	require.Equal(t, []int{1, 3}, b.Failed())
+	require.Equal(t, "2 failed items: item 1: timeout; item 3: not found", err.Error())
+
+	require.ErrorIs(t, err, errNotFound)
+	var itemErr *ItemError
+	require.ErrorAs(t, err, &itemErr)
+	require.Equal(t, 1, itemErr.Index)
+}
+
+func TestBatch_Concurrent(t *testing.T) {
+	b := &Batch{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			b.Add(i, errNotFound)
+		}(i)
+	}
+	wg.Wait()
+
+	failed := b.Failed()
+	require.Len(t, failed, 100)
+	for i, idx := range failed {
+		require.Equal(t, i, idx)
+	}
+}
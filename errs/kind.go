@@ -0,0 +1,213 @@
+package errs
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Kind classifies the errors of the authn and authz packages, regardless of the transport.
+type Kind uint8
+
+const (
+	KindUnknown Kind = iota
+	// KindUnauthenticated is the kind of the errors of missing or invalid credentials.
+	KindUnauthenticated
+	// KindForbidden is the kind of the errors of authenticated callers lacking the permission.
+	KindForbidden
+	// KindUnavailable is the kind of the transient errors of the dependencies (signing keys, authz service...).
+	KindUnavailable
+	// KindBadRequest is the kind of the errors of invalid requests.
+	KindBadRequest
+	// KindRateLimited is the kind of the errors of the requests throttled by a rate limit or a quota.
+	KindRateLimited
+)
+
+var (
+	ErrUnauthenticated = &Error{Kind: KindUnauthenticated, Msg: "unauthenticated"}
+	ErrForbidden       = &Error{Kind: KindForbidden, Msg: "forbidden"}
+	ErrUnavailable     = &Error{Kind: KindUnavailable, Msg: "unavailable"}
+	ErrBadRequest      = &Error{Kind: KindBadRequest, Msg: "bad request"}
+	ErrRateLimited     = &Error{Kind: KindRateLimited, Msg: "rate limited"}
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindUnauthenticated:
+		return "unauthenticated"
+	case KindForbidden:
+		return "forbidden"
+	case KindUnavailable:
+		return "unavailable"
+	case KindBadRequest:
+		return "bad request"
+	case KindRateLimited:
+		return "rate limited"
+	}
+	return "unknown"
+}
+
+// GRPCCode returns the gRPC code of the kind.
+func (k Kind) GRPCCode() codes.Code {
+	switch k {
+	case KindUnauthenticated:
+		return codes.Unauthenticated
+	case KindForbidden:
+		return codes.PermissionDenied
+	case KindUnavailable:
+		return codes.Unavailable
+	case KindBadRequest:
+		return codes.InvalidArgument
+	case KindRateLimited:
+		return codes.ResourceExhausted
+	}
+	return codes.Unknown
+}
+
+// HTTPStatus returns the HTTP status of the kind.
+func (k Kind) HTTPStatus() int {
+	switch k {
+	case KindUnauthenticated:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	case KindBadRequest:
+		return http.StatusBadRequest
+	case KindRateLimited:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// sentinel returns the sentinel error of the kind.
+func (k Kind) sentinel() *Error {
+	switch k {
+	case KindUnauthenticated:
+		return ErrUnauthenticated
+	case KindForbidden:
+		return ErrForbidden
+	case KindUnavailable:
+		return ErrUnavailable
+	case KindBadRequest:
+		return ErrBadRequest
+	case KindRateLimited:
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// Error is an error of a kind. errors.Is matches the sentinel error of its kind (ex: ErrUnauthenticated),
+// and the gRPC status of the error has the code of its kind.
+type Error struct {
+	Kind Kind
+	Msg  string
+	Err  error
+}
+
+// New returns an error of the kind with the message.
+func New(kind Kind, msg string) *Error {
+	return &Error{Kind: kind, Msg: msg}
+}
+
+// Wrap sets the kind of the error, keeping its message. Nil errors and the unknown kind return the error unchanged.
+func Wrap(kind Kind, err error) error {
+	if err == nil || kind == KindUnknown {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Msg
+	case e.Msg == "":
+		return e.Err.Error()
+	}
+	return e.Msg + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	s := e.Kind.sentinel()
+	return s != nil && target == s
+}
+
+// GRPCStatus returns the status of the error, so that the gRPC servers return the code of its kind.
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(e.Kind.GRPCCode(), e.Error())
+}
+
+// KindOf returns the kind of the error, or of its gRPC status code.
+func KindOf(err error) Kind {
+	if err == nil {
+		return KindUnknown
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	if s, ok := status.FromError(err); ok {
+		return KindFromGRPCCode(s.Code())
+	}
+	return KindUnknown
+}
+
+// KindFromGRPCCode returns the kind of the gRPC code.
+func KindFromGRPCCode(code codes.Code) Kind {
+	switch code {
+	case codes.Unauthenticated:
+		return KindUnauthenticated
+	case codes.PermissionDenied:
+		return KindForbidden
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return KindUnavailable
+	case codes.InvalidArgument, codes.OutOfRange:
+		return KindBadRequest
+	case codes.ResourceExhausted:
+		return KindRateLimited
+	}
+	return KindUnknown
+}
+
+// KindFromHTTPStatus returns the kind of the HTTP status.
+func KindFromHTTPStatus(code int) Kind {
+	switch code {
+	case http.StatusUnauthorized:
+		return KindUnauthenticated
+	case http.StatusForbidden:
+		return KindForbidden
+	case http.StatusTooManyRequests:
+		return KindRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return KindUnavailable
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return KindBadRequest
+	}
+	return KindUnknown
+}
+
+// GRPCCode returns the gRPC code of the error: the code of its kind, or of its gRPC status otherwise.
+func GRPCCode(err error) codes.Code {
+	if kind := KindOf(err); kind != KindUnknown {
+		return kind.GRPCCode()
+	}
+	return status.Code(err)
+}
+
+// HTTPStatus returns the HTTP status of the error: the status of its kind, 200 for nil errors
+// and 500 otherwise.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return KindOf(err).HTTPStatus()
+}
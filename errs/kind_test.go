@@ -0,0 +1,80 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestError(t *testing.T) {
+	errExpired := New(KindUnauthenticated, "expired token")
+	errRevoked := New(KindUnauthenticated, "revoked token")
+
+	t.Run("should match the sentinel of the kind", func(t *testing.T) {
+		err := fmt.Errorf("%w: kid 1", errExpired)
+		require.ErrorIs(t, err, ErrUnauthenticated)
+		require.ErrorIs(t, err, errExpired)
+		require.NotErrorIs(t, err, ErrForbidden)
+		require.NotErrorIs(t, err, errRevoked)
+		require.Equal(t, "expired token: kid 1", err.Error())
+	})
+
+	t.Run("should wrap errors keeping their message", func(t *testing.T) {
+		cause := errors.New("connection refused")
+		err := Wrap(KindUnavailable, cause)
+		require.ErrorIs(t, err, ErrUnavailable)
+		require.ErrorIs(t, err, cause)
+		require.Equal(t, "connection refused", err.Error())
+
+		require.NoError(t, Wrap(KindUnavailable, nil))
+		require.Equal(t, cause, Wrap(KindUnknown, cause))
+	})
+
+	t.Run("should convert to a gRPC status", func(t *testing.T) {
+		st := status.Convert(fmt.Errorf("%w: kid 1", errExpired))
+		require.Equal(t, codes.Unauthenticated, st.Code())
+		require.Equal(t, "expired token: kid 1", st.Message())
+	})
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		kind       Kind
+		grpcCode   codes.Code
+		httpStatus int
+	}{
+		{name: "nil", err: nil, kind: KindUnknown, grpcCode: codes.OK, httpStatus: http.StatusOK},
+		{name: "plain", err: errors.New("boom"), kind: KindUnknown, grpcCode: codes.Unknown, httpStatus: http.StatusInternalServerError},
+		{name: "unauthenticated", err: ErrUnauthenticated, kind: KindUnauthenticated, grpcCode: codes.Unauthenticated, httpStatus: http.StatusUnauthorized},
+		{name: "forbidden", err: New(KindForbidden, "missing scope"), kind: KindForbidden, grpcCode: codes.PermissionDenied, httpStatus: http.StatusForbidden},
+		{name: "unavailable", err: Wrap(KindUnavailable, errors.New("timeout")), kind: KindUnavailable, grpcCode: codes.Unavailable, httpStatus: http.StatusServiceUnavailable},
+		{name: "bad request", err: fmt.Errorf("%w: missing action", ErrBadRequest), kind: KindBadRequest, grpcCode: codes.InvalidArgument, httpStatus: http.StatusBadRequest},
+		{name: "grpc status", err: status.Error(codes.PermissionDenied, "denied"), kind: KindForbidden, grpcCode: codes.PermissionDenied, httpStatus: http.StatusForbidden},
+		{name: "grpc deadline", err: status.Error(codes.DeadlineExceeded, "deadline"), kind: KindUnavailable, grpcCode: codes.Unavailable, httpStatus: http.StatusServiceUnavailable},
+		{name: "grpc resource exhausted", err: status.Error(codes.ResourceExhausted, "quota"), kind: KindRateLimited, grpcCode: codes.ResourceExhausted, httpStatus: http.StatusTooManyRequests},
+		{name: "other grpc status", err: status.Error(codes.NotFound, "not found"), kind: KindUnknown, grpcCode: codes.NotFound, httpStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.kind, KindOf(tt.err))
+			require.Equal(t, tt.grpcCode, GRPCCode(tt.err))
+			require.Equal(t, tt.httpStatus, HTTPStatus(tt.err))
+		})
+	}
+}
+
+func TestKindFromHTTPStatus(t *testing.T) {
+	require.Equal(t, KindUnauthenticated, KindFromHTTPStatus(http.StatusUnauthorized))
+	require.Equal(t, KindForbidden, KindFromHTTPStatus(http.StatusForbidden))
+	require.Equal(t, KindUnavailable, KindFromHTTPStatus(http.StatusServiceUnavailable))
+	require.Equal(t, KindRateLimited, KindFromHTTPStatus(http.StatusTooManyRequests))
+	require.Equal(t, KindBadRequest, KindFromHTTPStatus(http.StatusBadRequest))
+	require.Equal(t, KindUnknown, KindFromHTTPStatus(http.StatusNotFound))
+}
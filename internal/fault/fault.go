@@ -0,0 +1,68 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var ErrInjected = errors.New("injected fault")
+
+// Config is the faults injected in the calls to a dependency.
+type Config struct {
+	// ErrorRate is the rate, between 0 and 1, of the calls failing with Err.
+	ErrorRate float64
+	// Err is the error of the failing calls.
+	Err error
+	// Latency is added to every call, before it fails or is forwarded.
+	Latency time.Duration
+	// Jitter is the maximum random latency added to Latency.
+	Jitter time.Duration
+	// Seed seeds the random failures and jitter, so that the faults are reproducible in CI.
+	Seed int64
+}
+
+// Injector injects the faults of its configuration. It is safe for concurrent use.
+type Injector struct {
+	cfg Config
+
+	mtx  sync.Mutex
+	rand *rand.Rand
+}
+
+// New returns an injector of the faults, failing with defaultErr when the configuration has no error.
+func New(cfg Config, defaultErr error) *Injector {
+	if cfg.Err == nil {
+		cfg.Err = defaultErr
+	}
+	return &Injector{cfg: cfg, rand: rand.New(rand.NewSource(cfg.Seed))}
+}
+
+// Inject waits for the latency, then returns the injected error, if the call fails.
+// The context error is returned when the context is done before the latency elapsed.
+func (i *Injector) Inject(ctx context.Context) error {
+	i.mtx.Lock()
+	latency := i.cfg.Latency
+	if i.cfg.Jitter > 0 {
+		latency += time.Duration(i.rand.Int63n(int64(i.cfg.Jitter)))
+	}
+	fail := i.cfg.ErrorRate >= 1 || (i.cfg.ErrorRate > 0 && i.rand.Float64() < i.cfg.ErrorRate)
+	i.mtx.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if fail {
+		return i.cfg.Err
+	}
+	return nil
+}
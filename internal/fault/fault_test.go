@@ -0,0 +1,58 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjector_Inject(t *testing.T) {
+	errDefault := errors.New("default")
+
+	t.Run("should fail at the error rate", func(t *testing.T) {
+		i := New(Config{ErrorRate: 0.3, Seed: 1}, errDefault)
+
+		failed := 0
+		for n := 0; n < 1000; n++ {
+			if err := i.Inject(context.Background()); err != nil {
+				require.ErrorIs(t, err, errDefault)
+				failed++
+			}
+		}
+		require.InDelta(t, 300, failed, 50)
+	})
+
+	t.Run("should be reproducible with the seed", func(t *testing.T) {
+		run := func() []bool {
+			i := New(Config{ErrorRate: 0.5, Seed: 42}, errDefault)
+			res := make([]bool, 20)
+			for n := range res {
+				res[n] = i.Inject(context.Background()) != nil
+			}
+			return res
+		}
+		require.Equal(t, run(), run())
+	})
+
+	t.Run("should use the configured error", func(t *testing.T) {
+		errConfigured := errors.New("configured")
+		err := New(Config{ErrorRate: 1, Err: errConfigured}, errDefault).Inject(context.Background())
+		require.ErrorIs(t, err, errConfigured)
+	})
+
+	t.Run("should add the latency", func(t *testing.T) {
+		start := time.Now()
+		require.NoError(t, New(Config{Latency: 20 * time.Millisecond}, errDefault).Inject(context.Background()))
+		require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	})
+
+	t.Run("should stop waiting when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := New(Config{Latency: time.Hour}, errDefault).Inject(ctx)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,15 @@
+package logger
+
+// Logger surfaces the internal failures that do not fail the requests to the logging of the application.
+// *slog.Logger implements it.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Warn(msg string, args ...any)
+}
+
+// Nop discards the logs. It is the default logger of the clients.
+type Nop struct{}
+
+func (Nop) Debug(string, ...any) {}
+
+func (Nop) Warn(string, ...any) {}
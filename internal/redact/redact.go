@@ -0,0 +1,67 @@
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	prefixLen = 3
+	hashLen   = 8
+)
+
+// Token renders the token as its first characters followed by a short hash (ex: "eyJ...1f2e3d4c").
+// The hash allows to correlate log lines of the same token without revealing it.
+func Token(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	hash := hex.EncodeToString(sum[:])[:hashLen]
+	if len(token) <= prefixLen*2 {
+		return "..." + hash
+	}
+	return token[:prefixLen] + "..." + hash
+}
+
+// Secret is a token which is redacted whenever it is printed, marshaled or logged.
+type Secret string
+
+// Reveal returns the raw token.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	return Token(string(s))
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("%q", Token(string(s)))
+}
+
+// Format redacts the token for all verbs, including %v, %s, %q and %x.
+func (s Secret) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'q':
+		fmt.Fprintf(f, "%q", Token(string(s)))
+	default:
+		fmt.Fprint(f, Token(string(s)))
+	}
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Token(string(s)))
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(Token(string(s))), nil
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(Token(string(s)))
+}
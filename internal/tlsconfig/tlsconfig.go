@@ -0,0 +1,204 @@
+// Package tlsconfig loads the TLS configuration of the clients, reloading the certificates when their files change.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultReloadInterval = time.Minute
+
+var (
+	ErrInvalidTLSConfig = errors.New("invalid tls configuration")
+	ErrUnexpectedSAN    = errors.New("server certificate does not match the expected subject alternative name")
+)
+
+// Config is the TLS configuration of a client, or mTLS when a client certificate is set.
+type Config struct {
+	// CertFile and KeyFile are the PEM encoded client certificate and key, for mTLS.
+	CertFile string
+	KeyFile  string
+	// CAFile is the PEM encoded bundle of the authorities of the server certificate, trusted instead of
+	// the system ones. The system authorities are used when empty.
+	CAFile string
+	// ServerSAN is the subject alternative name expected in the server certificate: a DNS name, an IP
+	// or a URI (ex: spiffe://grafana.net/authz). Defaults to the host of the connection.
+	ServerSAN string
+	// InsecureSkipVerify disables the verification of the server certificate.
+	InsecureSkipVerify bool
+	// ReloadInterval is how often the files are checked for changes, on new handshakes. Defaults to 1 minute.
+	ReloadInterval time.Duration
+}
+
+// Files holds the certificate and authorities loaded from the files of the configuration.
+// They are reloaded on handshakes when the files changed, so that rotated certificates are used without a restart.
+type Files struct {
+	cfg Config
+
+	mtx      sync.Mutex
+	checked  time.Time
+	modTimes map[string]time.Time
+	cert     *tls.Certificate
+	roots    *x509.CertPool
+}
+
+// Load reads the files of the configuration right away, so that a misconfiguration fails at startup.
+func Load(cfg Config) (*Files, error) {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, fmt.Errorf("%w: both the certificate and the key files must be set", ErrInvalidTLSConfig)
+	}
+	if cfg.ReloadInterval <= 0 {
+		cfg.ReloadInterval = DefaultReloadInterval
+	}
+
+	f := &Files{cfg: cfg}
+	if err := f.load(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTLSConfig, err)
+	}
+	return f, nil
+}
+
+// ClientConfig returns the configuration of a connection to host. The server certificate is verified
+// against the current authorities, and the expected SAN or the host otherwise. When the host is empty,
+// the server name of the handshake is expected.
+func (f *Files) ClientConfig(host string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// The chain is verified in VerifyConnection, against the reloaded authorities
+		InsecureSkipVerify: true,
+		ServerName:         host,
+	}
+	if f.cfg.ServerSAN != "" && net.ParseIP(f.cfg.ServerSAN) == nil && !isURI(f.cfg.ServerSAN) {
+		cfg.ServerName = f.cfg.ServerSAN
+	}
+	if f.cfg.CertFile != "" {
+		cfg.GetClientCertificate = f.clientCertificate
+	}
+	if !f.cfg.InsecureSkipVerify {
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return f.verify(cs, host)
+		}
+	}
+	return cfg
+}
+
+// load reads the files again when they changed since the last load. The previous certificates are kept on errors,
+// since the files might be in the middle of a rotation.
+func (f *Files) load() error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if !f.checked.IsZero() && time.Since(f.checked) < f.cfg.ReloadInterval {
+		return nil
+	}
+	f.checked = time.Now()
+
+	modTimes := map[string]time.Time{}
+	changed := f.modTimes == nil
+	for _, path := range []string{f.cfg.CertFile, f.cfg.KeyFile, f.cfg.CAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		modTimes[path] = info.ModTime()
+		changed = changed || !info.ModTime().Equal(f.modTimes[path])
+	}
+	if !changed {
+		return nil
+	}
+
+	var cert *tls.Certificate
+	if f.cfg.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(f.cfg.CertFile, f.cfg.KeyFile)
+		if err != nil {
+			return err
+		}
+		cert = &c
+	}
+
+	var roots *x509.CertPool
+	if f.cfg.CAFile != "" {
+		pem, err := os.ReadFile(f.cfg.CAFile)
+		if err != nil {
+			return err
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate found in %s", f.cfg.CAFile)
+		}
+	}
+
+	f.cert, f.roots, f.modTimes = cert, roots, modTimes
+	return nil
+}
+
+func (f *Files) current() (*tls.Certificate, *x509.CertPool) {
+	// Reload errors are ignored, the previous certificates are used until the files are fixed
+	_ = f.load()
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.cert, f.roots
+}
+
+func (f *Files) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, _ := f.current()
+	return cert, nil
+}
+
+// verify verifies the chain of the server certificate and its subject alternative name.
+// The host, or the server name of the handshake, is expected when the configuration has no SAN.
+func (f *Files) verify(cs tls.ConnectionState, host string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("no server certificate")
+	}
+	_, roots := f.current()
+
+	leaf := cs.PeerCertificates[0]
+	opts := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		return err
+	}
+
+	san := f.cfg.ServerSAN
+	if san == "" {
+		san = host
+	}
+	if san == "" {
+		san = cs.ServerName
+	}
+	return verifySAN(leaf, san)
+}
+
+func verifySAN(cert *x509.Certificate, san string) error {
+	if isURI(san) {
+		for _, uri := range cert.URIs {
+			if uri.String() == san {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrUnexpectedSAN, san)
+	}
+	if err := cert.VerifyHostname(san); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnexpectedSAN, err)
+	}
+	return nil
+}
+
+func isURI(san string) bool {
+	_, _, ok := strings.Cut(san, "://")
+	return ok
+}
@@ -0,0 +1,88 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a self-signed certificate and its key.
+func writeCertificate(t *testing.T, certFile, keyFile, cn string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	writeCertificate(t, certFile, keyFile, "grafana")
+
+	_, err := Load(Config{CertFile: certFile})
+	require.ErrorIs(t, err, ErrInvalidTLSConfig)
+
+	_, err = Load(Config{CAFile: filepath.Join(dir, "missing.crt")})
+	require.ErrorIs(t, err, ErrInvalidTLSConfig)
+
+	_, err = Load(Config{CAFile: keyFile})
+	require.ErrorIs(t, err, ErrInvalidTLSConfig)
+
+	files, err := Load(Config{CertFile: certFile, KeyFile: keyFile, CAFile: certFile})
+	require.NoError(t, err)
+	cert, roots := files.current()
+	require.NotNil(t, cert)
+	require.NotNil(t, roots)
+}
+
+func TestFiles_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	writeCertificate(t, certFile, keyFile, "first")
+
+	files, err := Load(Config{CertFile: certFile, KeyFile: keyFile, ReloadInterval: time.Millisecond})
+	require.NoError(t, err)
+	first, _ := files.current()
+
+	// Rotate the certificate
+	writeCertificate(t, certFile, keyFile, "second")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+	time.Sleep(2 * time.Millisecond)
+
+	second, _ := files.current()
+	require.NotEqual(t, first.Certificate[0], second.Certificate[0])
+
+	// The previous certificate is kept while the rotation is in progress
+	require.NoError(t, os.WriteFile(keyFile, []byte("partial"), 0o600))
+	require.NoError(t, os.Chtimes(keyFile, later.Add(time.Minute), later.Add(time.Minute)))
+	time.Sleep(2 * time.Millisecond)
+
+	current, _ := files.current()
+	require.Equal(t, second.Certificate[0], current.Certificate[0])
+}
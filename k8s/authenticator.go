@@ -0,0 +1,48 @@
+package k8s
+
+import (
+	"context"
+	"net/http"
+
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/authn/apiserver"
+)
+
+var (
+	_ authenticator.Token   = (*Authenticator)(nil)
+	_ authenticator.Request = (*Authenticator)(nil)
+)
+
+// Authenticator implements authenticator.Token and authenticator.Request with Grafana ID tokens.
+// The users of the responses are apiserver.User, with the namespace, org and stack of the identity as extras.
+type Authenticator struct {
+	authenticator *apiserver.Authenticator
+}
+
+// NewAuthenticator creates an authenticator verifying ID tokens with the verifier (see apiserver.New for the options).
+func NewAuthenticator(idVerifier authn.Verifier[authn.IDTokenClaims], opts ...apiserver.Option) *Authenticator {
+	return &Authenticator{authenticator: apiserver.New(idVerifier, opts...)}
+}
+
+// AuthenticateToken implements authenticator.Token. The token is an ID token.
+func (a *Authenticator) AuthenticateToken(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+	return response(a.authenticator.AuthenticateToken(ctx, token))
+}
+
+// AuthenticateRequest implements authenticator.Request.
+// Requests without ID token are left to the other authenticators.
+func (a *Authenticator) AuthenticateRequest(req *http.Request) (*authenticator.Response, bool, error) {
+	return response(a.authenticator.AuthenticateRequest(req))
+}
+
+func response(u *apiserver.User, ok bool, err error) (*authenticator.Response, bool, error) {
+	if !ok || err != nil {
+		return nil, false, err
+	}
+	return &authenticator.Response{
+		Audiences: authenticator.Audiences(u.GetIdentity().Audience()),
+		User:      u,
+	}, true, nil
+}
@@ -0,0 +1,72 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/authn/apiserver"
+	"github.com/grafana/authlib/claims"
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+type fakeVerifier struct {
+	tokens map[string]*authn.Claims[authn.IDTokenClaims]
+}
+
+func (f fakeVerifier) Verify(_ context.Context, token string) (*authn.Claims[authn.IDTokenClaims], error) {
+	c, ok := f.tokens[token]
+	if !ok {
+		return nil, errInvalidToken
+	}
+	return c, nil
+}
+
+func newAuthenticator() *Authenticator {
+	return NewAuthenticator(fakeVerifier{tokens: map[string]*authn.Claims[authn.IDTokenClaims]{
+		"stack-user": {
+			Claims: &jwt.Claims{Subject: "user:1", Audience: jwt.Audience{"stacks-12"}},
+			Rest:   authn.IDTokenClaims{Identifier: "1", Type: claims.TypeUser, Namespace: "stacks-12"},
+		},
+	}})
+}
+
+func TestAuthenticator_AuthenticateToken(t *testing.T) {
+	res, ok, err := newAuthenticator().AuthenticateToken(context.Background(), "stack-user")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, authenticator.Audiences{"stacks-12"}, res.Audiences)
+	require.Equal(t, "user:1", res.User.GetUID())
+	require.Equal(t, []string{"12"}, res.User.GetExtra()[apiserver.ExtraStackIDKey])
+
+	res, ok, err = newAuthenticator().AuthenticateToken(context.Background(), "unknown")
+	require.ErrorIs(t, err, errInvalidToken)
+	require.False(t, ok)
+	require.Nil(t, res)
+}
+
+func TestAuthenticator_AuthenticateRequest(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/apis", nil)
+
+	t.Run("should leave the requests without id token to the other authenticators", func(t *testing.T) {
+		res, ok, err := newAuthenticator().AuthenticateRequest(req)
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Nil(t, res)
+	})
+
+	t.Run("should authenticate the id token", func(t *testing.T) {
+		req.Header.Set("X-Grafana-Id", "stack-user")
+		res, ok, err := newAuthenticator().AuthenticateRequest(req)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, "user:1", res.User.GetUID())
+	})
+}
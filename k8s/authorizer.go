@@ -0,0 +1,48 @@
+// Package k8s implements the authorizer and the authenticators of k8s.io/apiserver with authlib,
+// for Grafana apps embedding an aggregated API server.
+//
+// It is a separate module, so that the other packages of authlib do not depend on k8s.io/apiserver.
+// The checks and the verifications are done by the authz/apiserver and authn/apiserver packages.
+package k8s
+
+import (
+	"context"
+
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	"github.com/grafana/authlib/authz"
+	"github.com/grafana/authlib/authz/apiserver"
+	"github.com/grafana/authlib/claims"
+)
+
+var _ authorizer.Authorizer = (*Authorizer)(nil)
+
+// Authorizer implements authorizer.Authorizer with a MultiTenantClient. The resource requests are
+// checked with their attributes, mapped to actions by the client (see authz.ResourceAttributes).
+type Authorizer struct {
+	authorizer *apiserver.Authorizer
+}
+
+// NewAuthorizer creates an authorizer checking the requests with the client (see apiserver.New for the options).
+// The caller is read from the context (see claims.WithAuthInfo), or else from the user of the request
+// when it carries the claims of the caller (ex: the users authenticated by Authenticator).
+func NewAuthorizer(client authz.MultiTenantClient, opts ...apiserver.Option) *Authorizer {
+	opts = append([]apiserver.Option{apiserver.WithCallerOption(userCaller)}, opts...)
+	return &Authorizer{authorizer: apiserver.New(client, opts...)}
+}
+
+// Authorize implements authorizer.Authorizer.
+// Non-resource requests and cluster-scoped requests are left to the other authorizers.
+func (a *Authorizer) Authorize(ctx context.Context, attrs authorizer.Attributes) (authorizer.Decision, string, error) {
+	decision, reason, err := a.authorizer.Authorize(ctx, attrs)
+	return authorizer.Decision(decision), reason, err
+}
+
+func userCaller(_ context.Context, attrs apiserver.Attributes) (claims.AuthInfo, bool) {
+	k8sAttrs, ok := attrs.(authorizer.Attributes)
+	if !ok || k8sAttrs.GetUser() == nil {
+		return nil, false
+	}
+	caller, ok := k8sAttrs.GetUser().(claims.AuthInfo)
+	return caller, ok
+}
@@ -0,0 +1,98 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/authz"
+	"github.com/grafana/authlib/authz/apiserver"
+	"github.com/grafana/authlib/claims"
+)
+
+type fakeClient struct {
+	allowed bool
+	lastReq *authz.CheckRequest
+}
+
+func (f *fakeClient) Check(_ context.Context, req *authz.CheckRequest) (bool, error) {
+	f.lastReq = req
+	return f.allowed, nil
+}
+
+func newCaller() *authn.AuthInfo {
+	return &authn.AuthInfo{
+		IdentityClaims: authn.NewIdentityClaims(authn.Claims[authn.IDTokenClaims]{
+			Claims: &jwt.Claims{Subject: "user:1"},
+			Rest:   authn.IDTokenClaims{Namespace: "stacks-12"},
+		}),
+	}
+}
+
+func TestAuthorizer_Authorize(t *testing.T) {
+	caller := newCaller()
+	ctx := claims.WithAuthInfo(context.Background(), caller)
+	get := authorizer.AttributesRecord{
+		Verb:            "get",
+		Namespace:       "stacks-12",
+		APIGroup:        "dashboard.grafana.app",
+		Resource:        "dashboards",
+		Name:            "abc",
+		ResourceRequest: true,
+	}
+
+	t.Run("should allow", func(t *testing.T) {
+		client := &fakeClient{allowed: true}
+		decision, _, err := NewAuthorizer(client).Authorize(ctx, get)
+		require.NoError(t, err)
+		require.Equal(t, authorizer.DecisionAllow, decision)
+		require.Equal(t, &authz.CheckRequest{
+			Caller:  caller,
+			StackID: 12,
+			Attributes: &authz.ResourceAttributes{
+				Group:    "dashboard.grafana.app",
+				Resource: "dashboards",
+				Verb:     "get",
+				Name:     "abc",
+			},
+		}, client.lastReq)
+	})
+
+	t.Run("should deny", func(t *testing.T) {
+		decision, _, err := NewAuthorizer(&fakeClient{}).Authorize(ctx, get)
+		require.NoError(t, err)
+		require.Equal(t, authorizer.DecisionDeny, decision)
+	})
+
+	t.Run("should have no opinion on non-resource requests", func(t *testing.T) {
+		decision, _, err := NewAuthorizer(&fakeClient{allowed: true}).Authorize(ctx, authorizer.AttributesRecord{Verb: "get", Path: "/healthz"})
+		require.NoError(t, err)
+		require.Equal(t, authorizer.DecisionNoOpinion, decision)
+	})
+
+	t.Run("should resolve the caller from the user of the request", func(t *testing.T) {
+		res, _, err := newAuthenticator().AuthenticateToken(context.Background(), "stack-user")
+		require.NoError(t, err)
+
+		client := &fakeClient{allowed: true}
+		attrs := get
+		attrs.User = res.User
+		decision, _, err := NewAuthorizer(client).Authorize(context.Background(), attrs)
+		require.NoError(t, err)
+		require.Equal(t, authorizer.DecisionAllow, decision)
+		require.Equal(t, "user:1", client.lastReq.Caller.GetIdentity().Subject())
+	})
+
+	t.Run("should deny the users without claims", func(t *testing.T) {
+		attrs := get
+		attrs.User = &user.DefaultInfo{Name: "system:anonymous"}
+		decision, _, err := NewAuthorizer(&fakeClient{allowed: true}).Authorize(context.Background(), attrs)
+		require.ErrorIs(t, err, apiserver.ErrMissingCaller)
+		require.Equal(t, authorizer.DecisionDeny, decision)
+	})
+}
@@ -0,0 +1,14 @@
+module github.com/grafana/authlib/k8s
+
+go 1.21
+
+require (
+	github.com/grafana/authlib v0.0.0-00010101000000-000000000000
+	github.com/grafana/authlib/claims v0.0.0-20240926100702-4aee62663da0
+	k8s.io/apiserver v0.29.3
+)
+
+replace (
+	github.com/grafana/authlib => ../
+	github.com/grafana/authlib/claims => ../claims
+)
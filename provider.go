@@ -0,0 +1,139 @@
+// Package authlib provides a Provider sharing the authn and authz clients of an application.
+package authlib
+
+import (
+	"sync"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/authz"
+)
+
+// Config is the configuration of all the clients built by the Provider.
+type Config struct {
+	KeyRetriever        authn.KeyRetrieverConfig
+	AccessTokenVerifier authn.VerifierConfig
+	IDTokenVerifier     authn.VerifierConfig
+	TokenExchange       authn.TokenExchangeConfig
+	Authz               authz.MultiTenantClientConfig
+}
+
+type ProviderOption func(*Provider)
+
+// WithKeyRetrieverOptions sets the options used to create the key retriever.
+func WithKeyRetrieverOptions(opts ...authn.DefaultKeyRetrieverOption) ProviderOption {
+	return func(p *Provider) {
+		p.keyRetrieverOpts = opts
+	}
+}
+
+// WithTokenExchangeOptions sets the options used to create the token exchange client.
+func WithTokenExchangeOptions(opts ...authn.ExchangeClientOpts) ProviderOption {
+	return func(p *Provider) {
+		p.exchangeOpts = opts
+	}
+}
+
+// WithAuthzClientOptions sets the options used to create the authz client.
+func WithAuthzClientOptions(opts ...authz.LegacyClientOption) ProviderOption {
+	return func(p *Provider) {
+		p.authzOpts = opts
+	}
+}
+
+// WithGrpcConnProvider makes the gRPC clients use the connections of the provider, shared with the other clients
+// of the application. The options of the provider apply: the creation of a client configured with its own dial options
+// (ex: WithTLSConfigLCOption, WithServiceConfigLCOption, WithWaitForReadyLCOption) fails with authz.ErrConflictingConn.
+func WithGrpcConnProvider(conns *GrpcConnProvider) ProviderOption {
+	return func(p *Provider) {
+		p.conns = conns
+	}
+}
+
+// Provider lazily creates the clients of an application from a single configuration
+// and memoizes them, so all the components of the application share their caches and connections.
+// It is safe for concurrent use: concurrent first calls wait for a single client to be created.
+type Provider struct {
+	cfg Config
+
+	keyRetrieverOpts []authn.DefaultKeyRetrieverOption
+	exchangeOpts     []authn.ExchangeClientOpts
+	authzOpts        []authz.LegacyClientOption
+	conns            *GrpcConnProvider
+
+	keyRetriever        lazy[*authn.DefaultKeyRetriever]
+	accessTokenVerifier lazy[*authn.AccessTokenVerifier]
+	idTokenVerifier     lazy[*authn.IDTokenVerifier]
+	exchanger           lazy[*authn.TokenExchangeClient]
+	authzClient         lazy[*authz.LegacyClientImpl]
+}
+
+func NewProvider(cfg Config, opts ...ProviderOption) *Provider {
+	p := &Provider{cfg: cfg}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// KeyRetriever returns the key retriever shared by the token verifiers.
+func (p *Provider) KeyRetriever() *authn.DefaultKeyRetriever {
+	r, _ := p.keyRetriever.get(func() (*authn.DefaultKeyRetriever, error) {
+		return authn.NewKeyRetriever(p.cfg.KeyRetriever, p.keyRetrieverOpts...), nil
+	})
+	return r
+}
+
+// AccessTokenVerifier returns the access token verifier.
+func (p *Provider) AccessTokenVerifier() *authn.AccessTokenVerifier {
+	v, _ := p.accessTokenVerifier.get(func() (*authn.AccessTokenVerifier, error) {
+		return authn.NewAccessTokenVerifier(p.cfg.AccessTokenVerifier, p.KeyRetriever()), nil
+	})
+	return v
+}
+
+// IDTokenVerifier returns the ID token verifier.
+func (p *Provider) IDTokenVerifier() *authn.IDTokenVerifier {
+	v, _ := p.idTokenVerifier.get(func() (*authn.IDTokenVerifier, error) {
+		return authn.NewIDTokenVerifier(p.cfg.IDTokenVerifier, p.KeyRetriever()), nil
+	})
+	return v
+}
+
+// TokenExchangeClient returns the token exchange client.
+// The error of the first creation is returned to all callers, the configuration being immutable.
+func (p *Provider) TokenExchangeClient() (*authn.TokenExchangeClient, error) {
+	return p.exchanger.get(func() (*authn.TokenExchangeClient, error) {
+		return authn.NewTokenExchangeClient(p.cfg.TokenExchange, p.exchangeOpts...)
+	})
+}
+
+// AuthzClient returns the multi-tenant authz client.
+// The error of the first creation is returned to all callers, the configuration being immutable.
+func (p *Provider) AuthzClient() (*authz.LegacyClientImpl, error) {
+	return p.authzClient.get(func() (*authz.LegacyClientImpl, error) {
+		cfg := p.cfg.Authz
+		opts := p.authzOpts
+		if p.conns != nil {
+			conn, err := p.conns.Conn(cfg.RemoteAddress)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts[:len(opts):len(opts)], authz.WithGrpcConnectionLCOption(conn))
+		}
+		return authz.NewLegacyClient(&cfg, opts...)
+	})
+}
+
+// lazy memoizes the result of the first call to get.
+type lazy[T any] struct {
+	once sync.Once
+	val  T
+	err  error
+}
+
+func (l *lazy[T]) get(create func() (T, error)) (T, error) {
+	l.once.Do(func() {
+		l.val, l.err = create()
+	})
+	return l.val, l.err
+}
@@ -0,0 +1,64 @@
+package authlib
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/grafana/authlib/authn"
+	"github.com/grafana/authlib/authz"
+)
+
+func TestProvider_ConcurrentFirstUse(t *testing.T) {
+	p := NewProvider(Config{
+		KeyRetriever:  authn.KeyRetrieverConfig{SigningKeysURL: "http://localhost/jwks"},
+		TokenExchange: authn.TokenExchangeConfig{Token: "token", TokenExchangeURL: "http://localhost/exchange"},
+		Authz:         authz.MultiTenantClientConfig{RemoteAddress: "localhost:10000"},
+	}, WithAuthzClientOptions(authz.WithGrpcDialOptionsLCOption(grpc.WithTransportCredentials(insecure.NewCredentials()))))
+
+	const callers = 50
+	var (
+		wg           sync.WaitGroup
+		retrievers   = make([]*authn.DefaultKeyRetriever, callers)
+		verifiers    = make([]*authn.AccessTokenVerifier, callers)
+		exchangers   = make([]*authn.TokenExchangeClient, callers)
+		authzClients = make([]*authz.LegacyClientImpl, callers)
+	)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			retrievers[i] = p.KeyRetriever()
+			verifiers[i] = p.AccessTokenVerifier()
+			exchangers[i], _ = p.TokenExchangeClient()
+			authzClients[i], _ = p.AuthzClient()
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		require.NotNil(t, retrievers[i])
+		require.Same(t, retrievers[0], retrievers[i])
+		require.Same(t, verifiers[0], verifiers[i])
+		require.Same(t, exchangers[0], exchangers[i])
+		require.Same(t, authzClients[0], authzClients[i])
+	}
+	require.NotNil(t, authzClients[0])
+	require.NotNil(t, exchangers[0])
+	require.NotNil(t, p.IDTokenVerifier())
+}
+
+func TestProvider_MemoizesErrors(t *testing.T) {
+	p := NewProvider(Config{})
+
+	_, err := p.TokenExchangeClient()
+	require.Error(t, err)
+	_, err2 := p.TokenExchangeClient()
+	require.Equal(t, err, err2)
+
+	_, err = p.AuthzClient()
+	require.ErrorIs(t, err, authz.ErrMissingConfig)
+}
@@ -0,0 +1,80 @@
+package authlib
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/grafana/authlib/claims"
+	"github.com/grafana/authlib/internal/redact"
+)
+
+// Redact renders the token as its first characters followed by a short hash (ex: "eyJ...1f2e3d4c"),
+// so that log lines of the same token can be correlated without leaking it.
+func Redact(token string) string {
+	return redact.Token(token)
+}
+
+// Token is a raw token that is redacted whenever it is printed (including with %v and %#v),
+// marshaled to JSON or text, or logged with log/slog. Use Reveal to get the raw token.
+type Token = redact.Secret
+
+var (
+	_ fmt.Stringer   = RedactedAuthInfo{}
+	_ slog.LogValuer = RedactedAuthInfo{}
+)
+
+// RedactedAuthInfo renders the caller of a request without tokens nor personal information
+// (email, username, display name). Only subjects, types and namespaces are rendered.
+type RedactedAuthInfo struct {
+	info claims.AuthInfo
+}
+
+// RedactAuthInfo returns the caller ready to be logged or printed.
+func RedactAuthInfo(info claims.AuthInfo) RedactedAuthInfo {
+	return RedactedAuthInfo{info: info}
+}
+
+func (r RedactedAuthInfo) String() string {
+	if r.info == nil {
+		return "<nil>"
+	}
+
+	parts := make([]string, 0, 3)
+	if access := r.info.GetAccess(); access != nil && !access.IsNil() {
+		parts = append(parts, fmt.Sprintf("access=%s@%s", access.Subject(), access.Namespace()))
+	}
+	if id := r.info.GetIdentity(); id != nil && !id.IsNil() {
+		parts = append(parts, fmt.Sprintf("identity=%s@%s", id.Subject(), id.Namespace()))
+		if actor, ok := claims.GetActor(id); ok {
+			parts = append(parts, "actor="+actor.Subject())
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (r RedactedAuthInfo) LogValue() slog.Value {
+	if r.info == nil {
+		return slog.Value{}
+	}
+
+	var attrs []slog.Attr
+	if access := r.info.GetAccess(); access != nil && !access.IsNil() {
+		attrs = append(attrs, slog.Group("access",
+			slog.String("subject", access.Subject()),
+			slog.String("namespace", access.Namespace()),
+		))
+	}
+	if id := r.info.GetIdentity(); id != nil && !id.IsNil() {
+		idAttrs := []any{
+			slog.String("subject", id.Subject()),
+			slog.String("type", id.IdentityType().String()),
+			slog.String("namespace", id.Namespace()),
+		}
+		if actor, ok := claims.GetActor(id); ok {
+			idAttrs = append(idAttrs, slog.String("actor", actor.Subject()))
+		}
+		attrs = append(attrs, slog.Group("identity", idAttrs...))
+	}
+	return slog.GroupValue(attrs...)
+}